@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"log"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,6 +12,8 @@ import (
 	"github.com/skycoin/services/otc-watcher/pkg/api"
 	"github.com/skycoin/services/otc-watcher/pkg/currency"
 	"github.com/skycoin/services/otc-watcher/pkg/currency/btc"
+	"github.com/skycoin/services/otc-watcher/pkg/currency/sky"
+	"github.com/skycoin/services/otc-watcher/pkg/notify"
 	"github.com/skycoin/services/otc-watcher/pkg/scanner"
 	"github.com/skycoin/services/otc/pkg/otc"
 )
@@ -21,6 +25,29 @@ type Config struct {
 	WalletAccount string
 	WalletPass    string
 	ListenStr     string
+	// certificate and key files, the api is served over https when set
+	TlsCert string
+	TlsKey  string
+	// bearer tokens accepted by the api, which is open if there are none
+	ApiKeys []string
+	// where BTC blocks are read from: btcwallet (default), bitcoind, esplora
+	// or electrum
+	BtcBackend string
+	// network of the BTC addresses for esplora and electrum, and of those
+	// derived from extended keys, mainnet by default
+	BtcNetwork string
+	// Esplora api url, e.g. https://blockstream.info/api
+	EsploraUrl string
+	// Electrum server host:port, over TLS if ElectrumTLS
+	ElectrumNode string
+	ElectrumTLS  bool
+	// bolt database file, otc-watcher.db by default
+	Database string
+	// Skycoin node webrpc address, SKY isn't watched if empty
+	SkyNode string
+	// outputs get confirmed events up to this many confirmations, 6 by
+	// default
+	Confirmations uint64
 }
 
 var (
@@ -31,6 +58,7 @@ var (
 	)
 
 	scnr *scanner.Scanner
+	ntfr *notify.Notifier
 )
 
 func init() {
@@ -44,35 +72,100 @@ func init() {
 	}
 
 	// get btc connection
-	b, err := btc.New(
-		config.WalletAccount, config.WalletPass, config.RpcNode, config.RpcUser, config.RpcPass)
+	b, err := connectBtc(config)
 
 	if err != nil {
 		panic(err)
 	}
 
-	// get scnr using btc connection
-	scnr, err = scanner.New(
-		map[otc.Currency]currency.Connection{otc.BTC: b},
-	)
+	cons := currency.Connections{otc.BTC: b}
+
+	if config.SkyNode != "" {
+		if cons[otc.SKY], err = sky.New(config.SkyNode); err != nil {
+			panic(err)
+		}
+	}
 
+	if config.Database == "" {
+		config.Database = "otc-watcher.db"
+	}
+
+	db, err := scanner.Open(config.Database)
 	if err != nil {
 		panic(err)
 	}
 
-	// start listening on http port
-	//
-	// TODO: https
-	go http.ListenAndServe(config.ListenStr, api.New(scnr))
+	db.Confirmations = config.Confirmations
+	if db.Confirmations == 0 {
+		db.Confirmations = 6
+	}
+
+	// get scnr using connections
+	scnr, err = scanner.New(cons, db)
+
+	if err != nil {
+		panic(err)
+	}
+
+	ntfr = notify.New(db, log.New(os.Stdout, "", log.LstdFlags))
+	if err = ntfr.Start(); err != nil {
+		panic(err)
+	}
+
+	if len(config.ApiKeys) == 0 {
+		log.Println("no ApiKeys set, the api is open to anyone who can reach it")
+	}
+	handler := api.New(scnr, ntfr, config.ApiKeys)
+
+	// start listening on http(s) port
+	go func() {
+		var err error
+		if config.TlsCert != "" {
+			err = http.ListenAndServeTLS(config.ListenStr, config.TlsCert, config.TlsKey, handler)
+		} else {
+			err = http.ListenAndServe(config.ListenStr, handler)
+		}
+		log.Fatal(err)
+	}()
 	println("listening on" + config.ListenStr)
 }
 
+func connectBtc(config *Config) (*btc.Connection, error) {
+	params, err := btc.Params(config.BtcNetwork)
+	if err != nil {
+		return nil, err
+	}
+
+	var c *btc.Connection
+	switch config.BtcBackend {
+	case "", "btcwallet":
+		c, err = btc.New(
+			config.WalletAccount, config.WalletPass, config.RpcNode, config.RpcUser, config.RpcPass)
+	case "bitcoind":
+		c, err = btc.NewBitcoind(config.RpcNode, config.RpcUser, config.RpcPass)
+	case "esplora":
+		c, err = btc.NewEsplora(config.EsploraUrl, params)
+	case "electrum":
+		c, err = btc.NewElectrum(config.ElectrumNode, config.ElectrumTLS, params)
+	default:
+		return nil, errors.New("unknown BtcBackend " + config.BtcBackend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// addresses derived from extended keys are of this network
+	c.Params = params
+	return c, nil
+}
+
 func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt)
 
 	<-stop
 	println("stopping")
+	ntfr.Stop()
 	if err := scnr.Stop(); err != nil {
 		panic(err)
 	}
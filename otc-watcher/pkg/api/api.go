@@ -1,41 +1,122 @@
 package api
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc-watcher/pkg/notify"
 	"github.com/skycoin/services/otc-watcher/pkg/scanner"
 	"github.com/skycoin/services/otc/pkg/otc"
 )
 
-func New(scnr *scanner.Scanner) *http.ServeMux {
+// New serves the api, requiring one of keys on every endpoint but /health.
+// Without keys it's open.
+func New(scnr *scanner.Scanner, ntfr *notify.Notifier, keys []string) http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/outputs", Outputs(scnr))
-	return mux
+	mux.HandleFunc("/reorgs", Reorgs(scnr))
+	mux.HandleFunc("/register", Register(scnr))
+	mux.HandleFunc("/addresses", Addresses(scnr))
+	mux.HandleFunc("/backfills", Backfills(scnr))
+	mux.HandleFunc("/pending", Pending(scnr))
+	mux.HandleFunc("/status", Status(scnr))
+	mux.HandleFunc("/transaction", Transaction(scnr))
+	mux.HandleFunc("/block", Block(scnr))
+	mux.HandleFunc("/wallets", Wallets(scnr))
+	mux.HandleFunc("/health", Health(scnr))
+	mux.HandleFunc("/subscriptions", Subscriptions(scnr, ntfr))
+	mux.HandleFunc("/events", Events(ntfr))
+	return Auth(keys, mux)
+}
+
+// Auth passes on requests with one of keys as a bearer token, and those
+// to /health, which load balancers check without one.
+func Auth(keys []string, next http.Handler) http.Handler {
+	if len(keys) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			token := []byte(strings.TrimPrefix(header, "Bearer "))
+			for _, key := range keys {
+				if subtle.ConstantTimeCompare(token, []byte(key)) == 1 {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// Registration is an address to watch, optionally rescanning blocks from
+// SinceHeight, or from the first block mined at or after SinceTime, for
+// outputs sent before it was registered.
+type Registration struct {
+	*otc.Drop
+
+	SinceHeight *uint64 `json:"since_height,omitempty"`
+	SinceTime   int64   `json:"since_time,omitempty"`
+}
+
+// register watches an address and queues its backfill if asked for one.
+func register(scnr *scanner.Scanner, req *Registration) (*scanner.Backfill, error) {
+	if err := scnr.Register(req.Drop); err != nil {
+		return nil, err
+	}
+
+	if req.SinceHeight != nil {
+		return scnr.Backfill(req.Drop, *req.SinceHeight)
+	}
+
+	if req.SinceTime != 0 {
+		since, err := scnr.HeightAt(req.Currency, req.SinceTime)
+		if err != nil {
+			return nil, err
+		}
+		return scnr.Backfill(req.Drop, since)
+	}
+
+	return nil, nil
 }
 
 func Outputs(scnr *scanner.Scanner) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var (
 			outputs otc.Outputs
-			req     *otc.Drop
+			req     *Registration
 			err     error
 		)
 
-		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil || req == nil || req.Drop == nil {
 			http.Error(w, "invalid JSON", http.StatusBadRequest)
 			return
 		}
 
 		log.Printf("Request for balance of address %s in %s\n", req.Address, req.Currency)
 
-		if outputs, err = scnr.Outputs(req); err != nil {
+		if outputs, err = scnr.Outputs(req.Drop); err != nil {
 			if err == scanner.ErrAddressMissing {
 				// Register address if it missing in watch-list
 				log.Printf("Register address %s", req.Address)
 
-				if err := scnr.Register(req); err != nil {
+				if _, err := register(scnr, req); err != nil {
 					log.Println(err)
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 					return
@@ -52,3 +133,460 @@ func Outputs(scnr *scanner.Scanner) http.HandlerFunc {
 		json.NewEncoder(w).Encode(&outputs)
 	}
 }
+
+// Register watches an address, returning its backfill if since_height or
+// since_time was given, or null.
+func Register(scnr *scanner.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req *Registration
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req == nil || req.Drop == nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		backfill, err := register(scnr, req)
+		if err == currency.ErrConnMissing {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(backfill)
+	}
+}
+
+// Result is what became of one address of a bulk request.
+type Result struct {
+	*otc.Drop
+
+	Backfill *scanner.Backfill `json:"backfill,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// Addresses lists the addresses watched in ?currency= (GET), registers a
+// list of registrations (POST) or unregisters a list of addresses, forgetting
+// their outputs (DELETE). Bulk requests return a result for each address,
+// in order, with the error if it failed.
+func Addresses(scnr *scanner.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			addrs, err := scnr.Addresses(otc.Currency(r.URL.Query().Get("currency")))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(addrs)
+		case http.MethodPost:
+			var reqs []*Registration
+			if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+				http.Error(w, "invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			for _, req := range reqs {
+				if req == nil || req.Drop == nil {
+					http.Error(w, "invalid JSON", http.StatusBadRequest)
+					return
+				}
+			}
+
+			results := make([]*Result, 0, len(reqs))
+			for _, req := range reqs {
+				result := &Result{Drop: req.Drop}
+				if b, err := register(scnr, req); err != nil {
+					result.Error = err.Error()
+				} else {
+					result.Backfill = b
+				}
+				results = append(results, result)
+			}
+			json.NewEncoder(w).Encode(results)
+		case http.MethodDelete:
+			var drops []*otc.Drop
+			if err := json.NewDecoder(r.Body).Decode(&drops); err != nil {
+				http.Error(w, "invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			for _, drop := range drops {
+				if drop == nil {
+					http.Error(w, "invalid JSON", http.StatusBadRequest)
+					return
+				}
+			}
+
+			results := make([]*Result, 0, len(drops))
+			for _, drop := range drops {
+				result := &Result{Drop: drop}
+				if err := scnr.Unregister(drop); err != nil {
+					result.Error = err.Error()
+				}
+				results = append(results, result)
+			}
+			json.NewEncoder(w).Encode(results)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// Status returns how far ?currency= is scanned against its node, or every
+// currency if not given.
+func Status(scnr *scanner.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cur := r.URL.Query().Get("currency")
+		if cur == "" {
+			json.NewEncoder(w).Encode(scnr.Statuses())
+			return
+		}
+
+		status, err := scnr.Status(otc.Currency(cur))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+// Transaction returns the outputs transaction ?hash= of ?currency= paid to
+// or spent from watched addresses, and pending if it isn't mined.
+func Transaction(scnr *scanner.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		summary, err := scnr.Transaction(otc.Currency(r.URL.Query().Get("currency")), r.URL.Query().Get("hash"))
+		if err == scanner.ErrNotFound {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(summary)
+	}
+}
+
+// Block returns block ?height= of ?currency= as the node has it, with its
+// outputs to and spends from watched addresses.
+func Block(scnr *scanner.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		height, err := strconv.ParseUint(r.URL.Query().Get("height"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid height", http.StatusBadRequest)
+			return
+		}
+
+		summary, err := scnr.Block(otc.Currency(r.URL.Query().Get("currency")), height)
+		if err == currency.ErrConnMissing {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(summary)
+	}
+}
+
+// WalletRequest is an extended public key or output descriptor to watch
+// the addresses of, Gap unused ones ahead, scanner.GAP_LIMIT if 0.
+type WalletRequest struct {
+	Currency   otc.Currency `json:"currency"`
+	Descriptor string       `json:"descriptor"`
+	Gap        uint32       `json:"gap,omitempty"`
+}
+
+// Wallets watches a wallet (POST), lists the wallets of ?currency= or
+// returns wallet ?id= with its addresses and their outputs (GET), or stops
+// watching wallet ?id= along with its addresses (DELETE).
+func Wallets(scnr *scanner.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cur := otc.Currency(r.URL.Query().Get("currency"))
+
+		var (
+			id  uint64
+			err error
+		)
+		if param := r.URL.Query().Get("id"); param != "" {
+			if id, err = strconv.ParseUint(param, 10, 64); err != nil {
+				http.Error(w, "invalid id", http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			var req *WalletRequest
+			if err = json.NewDecoder(r.Body).Decode(&req); err != nil || req == nil {
+				http.Error(w, "invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			wallet, err := scnr.AddWallet(req.Currency, req.Descriptor, req.Gap)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(wallet)
+		case http.MethodGet:
+			if id == 0 {
+				wallets, err := scnr.ListWallets(cur)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				json.NewEncoder(w).Encode(wallets)
+				return
+			}
+
+			summary, err := scnr.Wallet(cur, id)
+			if err == scanner.ErrWalletMissing {
+				http.NotFound(w, r)
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(summary)
+		case http.MethodDelete:
+			if err = scnr.RemoveWallet(cur, id); err == scanner.ErrWalletMissing {
+				http.NotFound(w, r)
+			} else if err == currency.ErrConnMissing {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// Health reports whether every currency's node can be reached, with 503
+// if one can't.
+func Health(scnr *scanner.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses := scnr.Statuses()
+
+		healthy := true
+		for _, status := range statuses {
+			if status.Error != "" {
+				healthy = false
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(struct {
+			Healthy    bool              `json:"healthy"`
+			Currencies []*scanner.Status `json:"currencies"`
+		}{healthy, statuses})
+	}
+}
+
+// Backfills lists the backfills of ?currency=, only those of ?address= if
+// given.
+func Backfills(scnr *scanner.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		backfills, err := scnr.Backfills(otc.Currency(r.URL.Query().Get("currency")))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if addr := r.URL.Query().Get("address"); addr != "" {
+			matching := make([]*scanner.Backfill, 0)
+			for _, b := range backfills {
+				if b.Address == addr {
+					matching = append(matching, b)
+				}
+			}
+			backfills = matching
+		}
+
+		json.NewEncoder(w).Encode(backfills)
+	}
+}
+
+// Pending lists the unconfirmed outputs of ?address= in ?currency=, and
+// those replaced or evicted in the last hour.
+func Pending(scnr *scanner.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pending, err := scnr.Pending(&otc.Drop{
+			Address:  r.URL.Query().Get("address"),
+			Currency: otc.Currency(r.URL.Query().Get("currency")),
+		})
+		if err == scanner.ErrAddressMissing {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(pending)
+	}
+}
+
+// Reorgs lists the chain reorganisations of ?currency= rolled back by the
+// scanner, after id ?since= if given.
+func Reorgs(scnr *scanner.Scanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var since uint64
+
+		if s := r.URL.Query().Get("since"); s != "" {
+			var err error
+			if since, err = strconv.ParseUint(s, 10, 64); err != nil {
+				http.Error(w, "invalid since", http.StatusBadRequest)
+				return
+			}
+		}
+
+		reorgs, err := scnr.Reorgs(otc.Currency(r.URL.Query().Get("currency")), since)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(reorgs)
+	}
+}
+
+// Subscriptions creates (POST), lists or gets by ?id= (GET) and removes by
+// ?id= (DELETE) subscriptions. Addresses subscribed to are registered.
+func Subscriptions(scnr *scanner.Scanner, ntfr *notify.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+
+		switch r.Method {
+		case http.MethodPost:
+			var sub *notify.Subscription
+			if err := json.NewDecoder(r.Body).Decode(&sub); err != nil || sub == nil {
+				http.Error(w, "invalid JSON", http.StatusBadRequest)
+				return
+			}
+			if len(sub.Addresses) == 0 && len(sub.Wallets) == 0 {
+				http.Error(w, "no addresses", http.StatusBadRequest)
+				return
+			}
+
+			for _, drop := range sub.Addresses {
+				if err := scnr.Register(drop); err != nil {
+					http.Error(w, fmt.Sprintf("%s: %v", drop.Address, err), http.StatusBadRequest)
+					return
+				}
+			}
+
+			if err := ntfr.Subscribe(sub); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(sub)
+		case http.MethodGet:
+			if id == "" {
+				subs, err := ntfr.List()
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				json.NewEncoder(w).Encode(subs)
+				return
+			}
+
+			sub, err := ntfr.Get(id)
+			if err == notify.ErrMissing {
+				http.NotFound(w, r)
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(sub)
+		case http.MethodDelete:
+			if err := ntfr.Unsubscribe(id); err == notify.ErrMissing {
+				http.NotFound(w, r)
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// Events streams the events of subscription ?id= as server-sent events,
+// after ?since= or the Last-Event-ID header when reconnecting.
+func Events(ntfr *notify.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub, err := ntfr.Get(r.URL.Query().Get("id"))
+		if err == notify.ErrMissing {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		cursor := sub.Cursor
+		since := r.URL.Query().Get("since")
+		if last := r.Header.Get("Last-Event-ID"); last != "" {
+			since = last
+		}
+		if since != "" {
+			if cursor, err = strconv.ParseUint(since, 10, 64); err != nil {
+				http.Error(w, "invalid since", http.StatusBadRequest)
+				return
+			}
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		flusher.Flush()
+
+		for {
+			// waits at most 30s so idle connections are kept alive
+			wait, cancel := context.WithTimeout(r.Context(), time.Second*30)
+			events, next, err := ntfr.Next(sub, cursor, wait.Done())
+			cancel()
+			if err != nil {
+				log.Printf("events %s: %v\n", sub.Id, err)
+				return
+			}
+			cursor = next
+
+			if r.Context().Err() != nil {
+				return
+			}
+
+			if len(events) == 0 {
+				fmt.Fprint(w, ": keepalive\n\n")
+			}
+			for _, event := range events {
+				data, err := json.Marshal(event)
+				if err != nil {
+					return
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+				cursor = event.Id
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc-watcher/pkg/scanner"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// MockNode is a connection whose node is up or down.
+type MockNode struct {
+	Down bool
+}
+
+func (n *MockNode) Stop() error                            { return nil }
+func (n *MockNode) Scan(h uint64) (chan *otc.Block, error) { return nil, nil }
+func (n *MockNode) Get(h uint64) (*otc.Block, error)       { return nil, errors.New("unused") }
+
+func (n *MockNode) Height() (uint64, error) {
+	if n.Down {
+		return 0, errors.New("connection refused")
+	}
+	return 12, nil
+}
+
+func MockScanner(node *MockNode) *scanner.Scanner {
+	storage := scanner.NewStorage(otc.BTC)
+	storage.Updated.Height = 10
+
+	return &scanner.Scanner{
+		Connections: currency.Connections{otc.BTC: node},
+		Scanning:    map[otc.Currency]*scanner.Storage{otc.BTC: storage},
+	}
+}
+
+func TestAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		keys   []string
+		path   string
+		header string
+		code   int
+	}{
+		{nil, "/status", "", http.StatusOK},
+		{[]string{"a", "b"}, "/status", "", http.StatusUnauthorized},
+		{[]string{"a", "b"}, "/status", "Bearer c", http.StatusUnauthorized},
+		{[]string{"a", "b"}, "/status", "b", http.StatusUnauthorized},
+		{[]string{"a", "b"}, "/status", "Bearer b", http.StatusOK},
+		{[]string{"a", "b"}, "/health", "", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.header != "" {
+			req.Header.Set("Authorization", test.header)
+		}
+
+		res := httptest.NewRecorder()
+		Auth(test.keys, ok).ServeHTTP(res, req)
+
+		if res.Code != test.code {
+			t.Fatalf("%v %s %q: expected %d, got %d", test.keys, test.path, test.header, test.code, res.Code)
+		}
+	}
+}
+
+func TestHealth(t *testing.T) {
+	node := &MockNode{}
+	handler := Health(MockScanner(node))
+
+	res := httptest.NewRecorder()
+	handler(res, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var health struct {
+		Healthy    bool
+		Currencies []*scanner.Status
+	}
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusOK || !health.Healthy || health.Currencies[0].Lag != 2 {
+		t.Fatalf("expected healthy with a lag of 2, got %d %+v", res.Code, health)
+	}
+
+	node.Down = true
+	res = httptest.NewRecorder()
+	handler(res, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusServiceUnavailable || health.Healthy || health.Currencies[0].Error == "" {
+		t.Fatalf("expected unhealthy, got %d %+v", res.Code, health)
+	}
+}
@@ -1,6 +1,7 @@
 package btc
 
 import (
+	"errors"
 	"io/ioutil"
 	"log"
 	"os"
@@ -8,16 +9,24 @@ import (
 	"time"
 
 	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/rpcclient"
 	"github.com/btcsuite/btcutil"
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
 	"github.com/skycoin/services/otc/pkg/otc"
 )
 
+var ErrNoBlock = errors.New("block not found")
+
+// Client is what the connection needs of a backend: btcwallet or bitcoind
+// through rpcclient, or an Esplora or Electrum adapter.
 type Client interface {
 	GetBlockHash(int64) (*chainhash.Hash, error)
 	GetBlockVerboseTx(*chainhash.Hash) (*btcjson.GetBlockVerboseResult, error)
 	GetBlockCount() (int64, error)
+	GetRawMempool() ([]*chainhash.Hash, error)
+	GetRawTransactionVerbose(*chainhash.Hash) (*btcjson.TxRawResult, error)
 	WaitForShutdown()
 }
 
@@ -25,7 +34,9 @@ type Connection struct {
 	Logs    *log.Logger
 	Client  Client
 	Account string
-	stop    chan struct{}
+	// network of the addresses derived, mainnet by default
+	Params *chaincfg.Params
+	stop   chan struct{}
 }
 
 func New(account, pass, rNode, rUser, rPass string) (*Connection, error) {
@@ -72,12 +83,85 @@ func New(account, pass, rNode, rUser, rPass string) (*Connection, error) {
 		}
 	}
 
+	c := NewConnection(client)
+	c.Account = account
+	return c, nil
+}
+
+// NewBitcoind connects to a bitcoind node's JSON-RPC over plain HTTP. No
+// wallet is needed, only blocks and the mempool are read.
+func NewBitcoind(node, user, pass string) (*Connection, error) {
+	client, err := rpcclient.New(
+		&rpcclient.ConnConfig{
+			HTTPPostMode: true,
+			DisableTLS:   true,
+			Host:         node,
+			User:         user,
+			Pass:         pass,
+		},
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewConnection(client)
+
+	// check the node is reachable
+	if _, err = c.Height(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func NewConnection(client Client) *Connection {
 	return &Connection{
-		Logs:    log.New(os.Stdout, "", log.LstdFlags),
-		Client:  client,
-		Account: account,
-		stop:    make(chan struct{}, 0),
-	}, nil
+		Logs:   log.New(os.Stdout, "", log.LstdFlags),
+		Client: client,
+		Params: &chaincfg.MainNetParams,
+		stop:   make(chan struct{}, 0),
+	}
+}
+
+// Watch tells backends that only look up watched addresses about another.
+func (c *Connection) Watch(addr string) error {
+	if watcher, ok := c.Client.(currency.Watcher); ok {
+		return watcher.Watch(addr)
+	}
+	return nil
+}
+
+// Derive returns the addresses of a descriptor or extended public key from
+// index from up to to, excluded.
+func (c *Connection) Derive(descriptor string, from, to uint32) ([]string, error) {
+	d, err := ParseDescriptor(descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, to-from)
+	for index := from; index < to; index++ {
+		addr, err := d.Address(index, c.Params)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// missing returns whether err means the block doesn't exist yet.
+func missing(err error) bool {
+	switch err.Error() {
+	case ErrNoBlock.Error(),
+		// btcwallet and btcd
+		"-1: Block number out of range",
+		// bitcoind
+		"-8: Block height out of range":
+		return true
+	}
+	return false
 }
 
 func (c *Connection) Scan(from uint64) (chan *otc.Block, error) {
@@ -92,8 +176,7 @@ func (c *Connection) Scan(from uint64) (chan *otc.Block, error) {
 			default:
 				block, err := c.Get(height)
 				if err != nil {
-					// TODO: use variable from btc* package rather than str
-					if err.Error() == "-1: Block number out of range" {
+					if missing(err) {
 						c.Logs.Printf("waiting for block: %d\n", height)
 					} else {
 						c.Logs.Printf("scan error: %v\n", err)
@@ -128,41 +211,88 @@ func (c *Connection) Get(height uint64) (*otc.Block, error) {
 
 	block := &otc.Block{
 		Height:       height,
+		Hash:         bb.Hash,
+		Previous:     bb.PreviousHash,
+		Time:         bb.Time,
 		Transactions: make(map[string]*otc.Transaction, len(bb.RawTx)),
 	}
 
 	for _, tx := range bb.RawTx {
-		block.Transactions[tx.Hash] = &otc.Transaction{
-			Id:            tx.Txid,
-			BlockHash:     tx.BlockHash,
-			Hash:          tx.Hash,
-			Confirmations: tx.Confirmations,
-			In:            make([]otc.Input, len(tx.Vin)),
-			Out:           make(map[int]*otc.Output, len(tx.Vout)),
+		// keyed by id, which inputs spending the outputs refer to
+		transaction, err := Transaction(&tx)
+		if err != nil {
+			return nil, err
 		}
+		block.Transactions[tx.Txid] = transaction
+	}
 
-		for _, out := range tx.Vout {
-			amount, err := btcutil.NewAmount(out.Value)
-			if err != nil {
-				return nil, err
-			}
+	return block, nil
+}
 
-			block.Transactions[tx.Hash].Out[int(out.N)] = &otc.Output{
-				Amount:    uint64(amount),
-				Addresses: out.ScriptPubKey.Addresses,
-			}
+// Transaction maps a Bitcoin transaction to an otc one.
+func Transaction(tx *btcjson.TxRawResult) (*otc.Transaction, error) {
+	transaction := &otc.Transaction{
+		Id:            tx.Txid,
+		BlockHash:     tx.BlockHash,
+		Hash:          tx.Hash,
+		Confirmations: tx.Confirmations,
+		In:            make([]otc.Input, 0, len(tx.Vin)),
+		Out:           make(map[int]*otc.Output, len(tx.Vout)),
+	}
+
+	for _, out := range tx.Vout {
+		amount, err := btcutil.NewAmount(out.Value)
+		if err != nil {
+			return nil, err
 		}
 
-		// Gather all tx in to keep track of whether tx was spent by something
-		for _, in := range tx.Vin {
-			block.Transactions[tx.Hash].In = append(block.Transactions[tx.Hash].In, otc.Input{
-				To:   tx.Hash,
-				From: in.Txid,
-			})
+		transaction.Out[int(out.N)] = &otc.Output{
+			Amount:    uint64(amount),
+			Addresses: out.ScriptPubKey.Addresses,
 		}
 	}
 
-	return block, nil
+	// gather inputs to keep track of spent outputs, coinbase has none
+	for _, in := range tx.Vin {
+		if in.IsCoinBase() {
+			continue
+		}
+		transaction.In = append(transaction.In, otc.Input{
+			Hash:  in.Txid,
+			Index: int(in.Vout),
+		})
+	}
+
+	return transaction, nil
+}
+
+// Mempool returns the ids of the transactions in the node's mempool.
+func (c *Connection) Mempool() ([]string, error) {
+	hashes, err := c.Client.GetRawMempool()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		ids = append(ids, hash.String())
+	}
+	return ids, nil
+}
+
+// Unconfirmed returns a transaction of the node's mempool.
+func (c *Connection) Unconfirmed(id string) (*otc.Transaction, error) {
+	hash, err := chainhash.NewHashFromStr(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := c.Client.GetRawTransactionVerbose(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return Transaction(tx)
 }
 
 func (c *Connection) Height() (uint64, error) {
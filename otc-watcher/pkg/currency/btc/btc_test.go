@@ -2,9 +2,13 @@ package btc
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,6 +20,8 @@ type Mock struct {
 	getBlockHash      func(int64) (*chainhash.Hash, error)
 	getBlockVerboseTx func(*chainhash.Hash) (*btcjson.GetBlockVerboseResult, error)
 	getBlockCount     func() (int64, error)
+	getRawMempool     func() ([]*chainhash.Hash, error)
+	getRawTxVerbose   func(*chainhash.Hash) (*btcjson.TxRawResult, error)
 	waitForShutdown   func()
 }
 
@@ -31,6 +37,14 @@ func (m *Mock) GetBlockCount() (int64, error) {
 	return m.getBlockCount()
 }
 
+func (m *Mock) GetRawMempool() ([]*chainhash.Hash, error) {
+	return m.getRawMempool()
+}
+
+func (m *Mock) GetRawTransactionVerbose(h *chainhash.Hash) (*btcjson.TxRawResult, error) {
+	return m.getRawTxVerbose(h)
+}
+
 func (m *Mock) WaitForShutdown() {
 	m.waitForShutdown()
 }
@@ -55,8 +69,13 @@ func GetBlockVerboseTx(err error, a float64) func(*chainhash.Hash) (*btcjson.Get
 		return &btcjson.GetBlockVerboseResult{
 			RawTx: []btcjson.TxRawResult{
 				{
+					Txid:          "id",
 					Hash:          "hash",
 					Confirmations: 3,
+					Vin: []btcjson.Vin{
+						{Coinbase: "03a0"},
+						{Txid: "spent", Vout: 2},
+					},
 					Vout: []btcjson.Vout{
 						{
 							Value: a,
@@ -94,10 +113,10 @@ func TestStop(t *testing.T) {
 
 	connection := &Connection{
 		Client: &Mock{
-			GetBlockHash(nil),
-			GetBlockVerboseTx(nil, 0),
-			GetBlockCount(0, nil),
-			WaitForShutdown(shut),
+			getBlockHash:      GetBlockHash(nil),
+			getBlockVerboseTx: GetBlockVerboseTx(nil, 0),
+			getBlockCount:     GetBlockCount(0, nil),
+			waitForShutdown:   WaitForShutdown(shut),
 		},
 		Account: "",
 		stop:    stop,
@@ -125,10 +144,10 @@ func TestStop(t *testing.T) {
 func TestHeight(t *testing.T) {
 	connection := &Connection{
 		Client: &Mock{
-			GetBlockHash(nil),
-			GetBlockVerboseTx(nil, 0),
-			GetBlockCount(32, nil),
-			WaitForShutdown(nil),
+			getBlockHash:      GetBlockHash(nil),
+			getBlockVerboseTx: GetBlockVerboseTx(nil, 0),
+			getBlockCount:     GetBlockCount(32, nil),
+			waitForShutdown:   WaitForShutdown(nil),
 		},
 		Account: "",
 		stop:    nil,
@@ -142,10 +161,10 @@ func TestHeight(t *testing.T) {
 func TestGetGood(t *testing.T) {
 	connection := &Connection{
 		Client: &Mock{
-			GetBlockHash(nil),
-			GetBlockVerboseTx(nil, 0),
-			GetBlockCount(0, nil),
-			WaitForShutdown(nil),
+			getBlockHash:      GetBlockHash(nil),
+			getBlockVerboseTx: GetBlockVerboseTx(nil, 0),
+			getBlockCount:     GetBlockCount(0, nil),
+			waitForShutdown:   WaitForShutdown(nil),
 		},
 		Account: "",
 		stop:    nil,
@@ -156,15 +175,40 @@ func TestGetGood(t *testing.T) {
 	}
 }
 
+func TestGetInputs(t *testing.T) {
+	connection := &Connection{
+		Client: &Mock{
+			getBlockHash:      GetBlockHash(nil),
+			getBlockVerboseTx: GetBlockVerboseTx(nil, 1.0),
+			getBlockCount:     GetBlockCount(0, nil),
+			waitForShutdown:   WaitForShutdown(nil),
+		},
+	}
+
+	block, err := connection.Get(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := block.Transactions["id"]
+	if tx == nil || tx.Hash != "hash" {
+		t.Fatal("transaction should be keyed by id")
+	}
+
+	if len(tx.In) != 1 || tx.In[0].Hash != "spent" || tx.In[0].Index != 2 {
+		t.Fatalf("expected coinbase to be skipped, got %+v", tx.In)
+	}
+}
+
 func TestGetBadHash(t *testing.T) {
 	bad := fmt.Errorf("bad error!")
 
 	connection := &Connection{
 		Client: &Mock{
-			GetBlockHash(bad),
-			GetBlockVerboseTx(nil, 0),
-			GetBlockCount(0, nil),
-			WaitForShutdown(nil),
+			getBlockHash:      GetBlockHash(bad),
+			getBlockVerboseTx: GetBlockVerboseTx(nil, 0),
+			getBlockCount:     GetBlockCount(0, nil),
+			waitForShutdown:   WaitForShutdown(nil),
 		},
 		Account: "",
 		stop:    nil,
@@ -180,10 +224,10 @@ func TestGetBadVerboseTx(t *testing.T) {
 
 	connection := &Connection{
 		Client: &Mock{
-			GetBlockHash(nil),
-			GetBlockVerboseTx(bad, 1.0),
-			GetBlockCount(0, nil),
-			WaitForShutdown(nil),
+			getBlockHash:      GetBlockHash(nil),
+			getBlockVerboseTx: GetBlockVerboseTx(bad, 1.0),
+			getBlockCount:     GetBlockCount(0, nil),
+			waitForShutdown:   WaitForShutdown(nil),
 		},
 		Account: "",
 		stop:    nil,
@@ -197,10 +241,10 @@ func TestGetBadVerboseTx(t *testing.T) {
 func TestGetBadAmount(t *testing.T) {
 	connection := &Connection{
 		Client: &Mock{
-			GetBlockHash(nil),
-			GetBlockVerboseTx(nil, math.NaN()),
-			GetBlockCount(0, nil),
-			WaitForShutdown(nil),
+			getBlockHash:      GetBlockHash(nil),
+			getBlockVerboseTx: GetBlockVerboseTx(nil, math.NaN()),
+			getBlockCount:     GetBlockCount(0, nil),
+			waitForShutdown:   WaitForShutdown(nil),
 		},
 		Account: "",
 		stop:    nil,
@@ -214,10 +258,10 @@ func TestGetBadAmount(t *testing.T) {
 func TestScanStop(t *testing.T) {
 	connection := &Connection{
 		Client: &Mock{
-			GetBlockHash(nil),
-			GetBlockVerboseTx(nil, 1.0),
-			GetBlockCount(0, nil),
-			WaitForShutdown(nil),
+			getBlockHash:      GetBlockHash(nil),
+			getBlockVerboseTx: GetBlockVerboseTx(nil, 1.0),
+			getBlockCount:     GetBlockCount(0, nil),
+			waitForShutdown:   WaitForShutdown(nil),
 		},
 		Account: "",
 		stop:    make(chan struct{}, 1),
@@ -236,12 +280,12 @@ func TestScanMaxed(t *testing.T) {
 	connection := &Connection{
 		Logs: log.New(&buf, "", 0),
 		Client: &Mock{
-			GetBlockHash(nil),
-			GetBlockVerboseTx(fmt.Errorf(
+			getBlockHash: GetBlockHash(nil),
+			getBlockVerboseTx: GetBlockVerboseTx(fmt.Errorf(
 				"-1: Block number out of range",
 			), 1.0),
-			GetBlockCount(0, nil),
-			WaitForShutdown(nil),
+			getBlockCount:   GetBlockCount(0, nil),
+			waitForShutdown: WaitForShutdown(nil),
 		},
 		Account: "",
 		stop:    nil,
@@ -265,10 +309,10 @@ func TestScanErr(t *testing.T) {
 	connection := &Connection{
 		Logs: log.New(&buf, "", 0),
 		Client: &Mock{
-			GetBlockHash(nil),
-			GetBlockVerboseTx(fmt.Errorf("bad"), 1.0),
-			GetBlockCount(0, nil),
-			WaitForShutdown(nil),
+			getBlockHash:      GetBlockHash(nil),
+			getBlockVerboseTx: GetBlockVerboseTx(fmt.Errorf("bad"), 1.0),
+			getBlockCount:     GetBlockCount(0, nil),
+			waitForShutdown:   WaitForShutdown(nil),
 		},
 		Account: "",
 		stop:    nil,
@@ -289,10 +333,10 @@ func TestScanErr(t *testing.T) {
 func TestScan(t *testing.T) {
 	connection := &Connection{
 		Client: &Mock{
-			GetBlockHash(nil),
-			GetBlockVerboseTx(nil, 1.0),
-			GetBlockCount(0, nil),
-			WaitForShutdown(nil),
+			getBlockHash:      GetBlockHash(nil),
+			getBlockVerboseTx: GetBlockVerboseTx(nil, 1.0),
+			getBlockCount:     GetBlockCount(0, nil),
+			waitForShutdown:   WaitForShutdown(nil),
 		},
 		Account: "",
 		stop:    nil,
@@ -312,3 +356,94 @@ func TestScan(t *testing.T) {
 		t.Fatal("blocks not send from scanner")
 	}
 }
+
+func TestMempool(t *testing.T) {
+	hash, _ := chainhash.NewHashFromStr("aa")
+
+	connection := &Connection{
+		Client: &Mock{
+			getRawMempool: func() ([]*chainhash.Hash, error) {
+				return []*chainhash.Hash{hash}, nil
+			},
+			getRawTxVerbose: func(h *chainhash.Hash) (*btcjson.TxRawResult, error) {
+				if !h.IsEqual(hash) {
+					t.Fatalf("unexpected hash %s", h)
+				}
+				return &btcjson.TxRawResult{
+					Txid: h.String(),
+					Vin:  []btcjson.Vin{{Txid: "spent", Vout: 1}},
+					Vout: []btcjson.Vout{
+						{Value: 0.5, N: 0, ScriptPubKey: btcjson.ScriptPubKeyResult{Addresses: []string{"address"}}},
+					},
+				}, nil
+			},
+		},
+	}
+
+	ids, err := connection.Mempool()
+	if err != nil || len(ids) != 1 || ids[0] != hash.String() {
+		t.Fatalf("expected mempool ids, got %v %v", ids, err)
+	}
+
+	tx, err := connection.Unconfirmed(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Id != hash.String() || tx.Out[0].Amount != 50000000 || tx.In[0].Hash != "spent" {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+
+	if _, err = connection.Unconfirmed("not hex"); err == nil {
+		t.Fatal("expected invalid id error")
+	}
+}
+
+func TestBitcoind(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		req := struct {
+			Id     interface{} `json:"id"`
+			Method string      `json:"method"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		switch req.Method {
+		case "getblockcount":
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": req.Id, "result": 42, "error": nil})
+		case "getblockhash":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":     req.Id,
+				"result": nil,
+				"error":  map[string]interface{}{"code": -8, "message": "Block height out of range"},
+			})
+		default:
+			t.Fatalf("unexpected method %s", req.Method)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	c, err := NewBitcoind(host, "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if height, err := c.Height(); err != nil || height != 42 {
+		t.Fatalf("expected height 42, got %d %v", height, err)
+	}
+
+	if _, err = c.Get(43); err == nil || !missing(err) {
+		t.Fatalf("expected a missing block, got %v", err)
+	}
+
+	if _, err = NewBitcoind(host, "user", "wrong"); err == nil {
+		t.Fatal("expected error with the wrong password")
+	}
+}
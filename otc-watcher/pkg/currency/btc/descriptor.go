@@ -0,0 +1,282 @@
+package btc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// first child index of hardened derivation, only derivable from private keys
+const HARDENED = 0x80000000
+
+var (
+	ErrDescriptor    = errors.New("unsupported descriptor")
+	ErrPrivateKey    = errors.New("private keys aren't accepted, use the extended public key")
+	ErrChecksum      = errors.New("descriptor checksum mismatch")
+	ErrInvalidChild  = errors.New("invalid child, skip the index")
+	ErrExtendedKey   = errors.New("invalid extended key")
+	ErrHardenedChild = errors.New("hardened children can't be derived from a public key")
+)
+
+// output script types addresses are derived as
+const (
+	PKH     = "pkh"
+	WPKH    = "wpkh"
+	SH_WPKH = "sh(wpkh)"
+)
+
+// version bytes of extended public keys, which tell the script type of bare
+// keys (BIP 49 and 84)
+var versions = map[string]string{
+	"0488b21e": PKH,     // xpub
+	"043587cf": PKH,     // tpub
+	"049d7cb2": SH_WPKH, // ypub
+	"044a5262": SH_WPKH, // upub
+	"04b24746": WPKH,    // zpub
+	"045f1cf6": WPKH,    // vpub
+}
+
+// version bytes of extended private keys
+var private = map[string]bool{
+	"0488ade4": true, // xprv
+	"04358394": true, // tprv
+	"049d7878": true, // yprv
+	"044a4e28": true, // uprv
+	"04b2430c": true, // zprv
+	"045f18bc": true, // vprv
+}
+
+// ExtendedKey is a BIP 32 extended public key.
+type ExtendedKey struct {
+	Version   []byte
+	Key       *btcec.PublicKey
+	ChainCode []byte
+}
+
+// ParseExtendedKey decodes a base58 extended public key.
+func ParseExtendedKey(s string) (*ExtendedKey, error) {
+	data := base58.Decode(s)
+	if len(data) != 82 {
+		return nil, ErrExtendedKey
+	}
+
+	payload, sum := data[:78], data[78:]
+	check := chainhash.DoubleHashB(payload)
+	if !bytes.Equal(check[:4], sum) {
+		return nil, ErrExtendedKey
+	}
+
+	version := fmt.Sprintf("%x", payload[:4])
+	if private[version] {
+		return nil, ErrPrivateKey
+	}
+
+	key, err := btcec.ParsePubKey(payload[45:78], btcec.S256())
+	if err != nil {
+		return nil, ErrExtendedKey
+	}
+
+	return &ExtendedKey{Version: payload[:4], Key: key, ChainCode: payload[13:45]}, nil
+}
+
+// Child derives the public child key at index, which can't be hardened.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if index >= HARDENED {
+		return nil, ErrHardenedChild
+	}
+
+	data := make([]byte, 37)
+	copy(data, k.Key.SerializeCompressed())
+	binary.BigEndian.PutUint32(data[33:], index)
+
+	mac := hmac.New(sha512.New, k.ChainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	curve := btcec.S256()
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(curve.N) >= 0 {
+		return nil, ErrInvalidChild
+	}
+
+	x, y := curve.ScalarBaseMult(sum[:32])
+	x, y = curve.Add(x, y, k.Key.X, k.Key.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, ErrInvalidChild
+	}
+
+	return &ExtendedKey{
+		Version:   k.Version,
+		Key:       &btcec.PublicKey{Curve: curve, X: x, Y: y},
+		ChainCode: sum[32:],
+	}, nil
+}
+
+// Descriptor derives the addresses of a ranged output descriptor.
+type Descriptor struct {
+	Type string
+	// key the ranged index is derived from, after the fixed steps
+	Key *ExtendedKey
+}
+
+// ParseDescriptor reads pkh(KEY), wpkh(KEY) and sh(wpkh(KEY)) descriptors
+// where KEY is an extended public key, optionally with its origin, followed
+// by unhardened steps and a final /*. A trailing #checksum is verified. A
+// bare extended public key is taken as KEY/0/*, its receiving addresses,
+// with the script type its version tells.
+func ParseDescriptor(s string) (*Descriptor, error) {
+	s = strings.TrimSpace(s)
+
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		sum, err := DescriptorChecksum(s[:i])
+		if err != nil {
+			return nil, err
+		}
+		if sum != s[i+1:] {
+			return nil, ErrChecksum
+		}
+		s = s[:i]
+	}
+
+	d := &Descriptor{}
+	switch {
+	case strings.HasPrefix(s, "sh(wpkh(") && strings.HasSuffix(s, "))"):
+		d.Type, s = SH_WPKH, s[len("sh(wpkh("):len(s)-2]
+	case strings.HasPrefix(s, "wpkh(") && strings.HasSuffix(s, ")"):
+		d.Type, s = WPKH, s[len("wpkh("):len(s)-1]
+	case strings.HasPrefix(s, "pkh(") && strings.HasSuffix(s, ")"):
+		d.Type, s = PKH, s[len("pkh("):len(s)-1]
+	case !strings.ContainsAny(s, "()/[]"):
+		// bare key
+		s += "/0/*"
+	default:
+		return nil, ErrDescriptor
+	}
+
+	// key origin, which derivation doesn't need
+	if strings.HasPrefix(s, "[") {
+		i := strings.IndexByte(s, ']')
+		if i < 0 {
+			return nil, ErrDescriptor
+		}
+		s = s[i+1:]
+	}
+
+	steps := strings.Split(s, "/")
+	if len(steps) < 2 || steps[len(steps)-1] != "*" {
+		return nil, fmt.Errorf("%v: only ranged keys ending in /* are watched", ErrDescriptor)
+	}
+
+	key, err := ParseExtendedKey(steps[0])
+	if err != nil {
+		return nil, err
+	}
+	if d.Type == "" {
+		if d.Type = versions[fmt.Sprintf("%x", key.Version)]; d.Type == "" {
+			return nil, ErrExtendedKey
+		}
+	}
+
+	for _, step := range steps[1 : len(steps)-1] {
+		index, err := strconv.ParseUint(step, 10, 32)
+		if err != nil || index >= HARDENED {
+			return nil, ErrHardenedChild
+		}
+		if key, err = key.Child(uint32(index)); err != nil {
+			return nil, err
+		}
+	}
+
+	d.Key = key
+	return d, nil
+}
+
+// Address returns the address at index of the range.
+func (d *Descriptor) Address(index uint32, params *chaincfg.Params) (string, error) {
+	child, err := d.Key.Child(index)
+	if err != nil {
+		return "", err
+	}
+
+	hash := btcutil.Hash160(child.Key.SerializeCompressed())
+
+	var addr btcutil.Address
+	switch d.Type {
+	case PKH:
+		addr, err = btcutil.NewAddressPubKeyHash(hash, params)
+	case WPKH:
+		addr, err = btcutil.NewAddressWitnessPubKeyHash(hash, params)
+	case SH_WPKH:
+		addr, err = btcutil.NewAddressScriptHash(append([]byte{OP_0, OP_DATA_20}, hash...), params)
+	default:
+		err = ErrDescriptor
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return addr.EncodeAddress(), nil
+}
+
+// characters of descriptors and of their checksums (BIP 380)
+const (
+	DESCRIPTOR_CHARSET = "0123456789()[],'/*abcdefgh@:$%{}" +
+		"IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~" +
+		"ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	CHECKSUM_CHARSET = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+func polymod(c uint64, value int) uint64 {
+	top := c >> 35
+	c = (c&0x7ffffffff)<<5 ^ uint64(value)
+
+	for i, gen := range []uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd} {
+		if top>>uint(i)&1 == 1 {
+			c ^= gen
+		}
+	}
+	return c
+}
+
+// DescriptorChecksum returns the 8 character checksum of a descriptor.
+func DescriptorChecksum(s string) (string, error) {
+	c, class, count := uint64(1), 0, 0
+
+	for _, ch := range s {
+		pos := strings.IndexRune(DESCRIPTOR_CHARSET, ch)
+		if pos < 0 {
+			return "", ErrDescriptor
+		}
+
+		c = polymod(c, pos&31)
+		class = class*3 + pos>>5
+		if count++; count == 3 {
+			c, class, count = polymod(c, class), 0, 0
+		}
+	}
+	if count > 0 {
+		c = polymod(c, class)
+	}
+	for i := 0; i < 8; i++ {
+		c = polymod(c, 0)
+	}
+	c ^= 1
+
+	sum := make([]byte, 8)
+	for i := range sum {
+		sum[i] = CHECKSUM_CHARSET[c>>(5*uint(7-i))&31]
+	}
+	return string(sum), nil
+}
@@ -0,0 +1,136 @@
+package btc
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestExtendedKeyChild(t *testing.T) {
+	// BIP 32 test vector 1, m/0H to m/0H/1
+	key, err := ParseExtendedKey("xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := key.Child(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := ParseExtendedKey("xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !child.Key.IsEqual(expected.Key) || hex.EncodeToString(child.ChainCode) != hex.EncodeToString(expected.ChainCode) {
+		t.Fatal("child differs from the test vector")
+	}
+
+	if _, err = key.Child(HARDENED); err != ErrHardenedChild {
+		t.Fatalf("expected hardened child refused, got %v", err)
+	}
+
+	tests := []struct {
+		key string
+		err error
+	}{
+		{"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi", ErrPrivateKey},
+		{"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnX", ErrExtendedKey},
+		{"not a key", ErrExtendedKey},
+	}
+	for _, test := range tests {
+		if _, err = ParseExtendedKey(test.key); err != test.err {
+			t.Fatalf("%s: expected %v, got %v", test.key, test.err, err)
+		}
+	}
+}
+
+func TestDescriptor(t *testing.T) {
+	// BIP 84 test vector, m/84'/0'/0'
+	zpub := "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+
+	d, err := ParseDescriptor(zpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Type != WPKH {
+		t.Fatalf("expected a zpub to derive wpkh, got %s", d.Type)
+	}
+
+	for index, expected := range []string{
+		"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu",
+		"bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g",
+	} {
+		addr, err := d.Address(uint32(index), &chaincfg.MainNetParams)
+		if err != nil || addr != expected {
+			t.Fatalf("index %d: expected %s, got %s %v", index, expected, addr, err)
+		}
+	}
+
+	// the same key as a descriptor, whatever its version
+	same, err := ParseDescriptor("wpkh([73c5da0a/84h/0h/0h]" + zpub + "/0/*)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr, _ := same.Address(0, &chaincfg.MainNetParams); addr != "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu" {
+		t.Fatalf("expected the same address from the descriptor, got %s", addr)
+	}
+
+	change, err := ParseDescriptor("wpkh(" + zpub + "/1/*)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr, _ := change.Address(0, &chaincfg.MainNetParams); addr != "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el" {
+		t.Fatalf("expected the first change address, got %s", addr)
+	}
+
+	xpub := "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj"
+	for _, test := range []struct {
+		descriptor string
+		typ        string
+	}{
+		{"pkh(" + xpub + "/0/*)", PKH},
+		{"sh(wpkh(" + xpub + "/0/*))", SH_WPKH},
+		{xpub, PKH},
+	} {
+		d, err := ParseDescriptor(test.descriptor)
+		if err != nil || d.Type != test.typ {
+			t.Fatalf("%s: expected %s, got %+v %v", test.descriptor, test.typ, d, err)
+		}
+
+		addr, err := d.Address(0, &chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		script, err := Script(addr, &chaincfg.MainNetParams)
+		if err != nil || len(Addresses(script, &chaincfg.MainNetParams)) != 1 {
+			t.Fatalf("%s: expected a standard address, got %s %v", test.descriptor, addr, err)
+		}
+	}
+
+	for _, bad := range []string{
+		"wpkh(" + xpub + "/0h/*)",
+		"wpkh(" + xpub + "/0/1)",
+		"tr(" + xpub + "/0/*)",
+		"wpkh(" + xpub + "/0/*)#aaaaaaaa",
+	} {
+		if _, err = ParseDescriptor(bad); err == nil {
+			t.Fatalf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestDescriptorChecksum(t *testing.T) {
+	// from Bitcoin Core's descriptors documentation
+	descriptor := "wpkh([d34db33f/84h/0h/0h]xpub6DJ2dNUysrn5Vt36jH2KLBT2i1auw1tTSSomg8PhqNiUtx8QX2SvC9nrHu81fT41fvDUnhMjEzQgXnQjKEu3oaqMSzhSrHMxyyoEAmUHQbY/0/*)"
+
+	sum, err := DescriptorChecksum(descriptor)
+	if err != nil || sum != "cjjspncu" {
+		t.Fatalf("expected cjjspncu, got %s %v", sum, err)
+	}
+
+	if _, err = ParseDescriptor(fmt.Sprintf("%s#%s", descriptor, sum)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,503 @@
+package btc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// requests sent to an Electrum server in one batch at most
+const ELECTRUM_BATCH = 100
+
+var ErrUnknownBlock = errors.New("block hash wasn't returned by GetBlockHash")
+
+// Electrum reads from an Electrum server, which only indexes transactions
+// by address. Blocks hold the transactions of the watched addresses alone,
+// found from their history, which is fetched again when its status changes.
+type Electrum struct {
+	Addr    string
+	TLS     bool
+	Params  *chaincfg.Params
+	Timeout time.Duration
+
+	// one request, or batch, at a time
+	sync.Mutex
+
+	conn   net.Conn
+	reader *bufio.Reader
+	id     uint64
+
+	// scripthash -> address watched
+	watched map[string]*electrumWatched
+	// headers returned by GetBlockHash, until GetBlockVerboseTx
+	headers map[chainhash.Hash]*electrumHeader
+}
+
+type electrumWatched struct {
+	Address string
+	Status  string
+	History []electrumHistory
+	// server's height when the status was last checked, -1 if never
+	Checked int64
+}
+
+type electrumHistory struct {
+	TxHash string `json:"tx_hash"`
+	// 0 or below while unconfirmed
+	Height int64 `json:"height"`
+}
+
+type electrumHeader struct {
+	Height int64
+	Header wire.BlockHeader
+}
+
+type electrumRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Id      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type electrumResponse struct {
+	// missing from notifications
+	Id     *uint64         `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// NewElectrum connects to the Electrum server at addr (host:port), for
+// addresses of params.
+func NewElectrum(addr string, useTLS bool, params *chaincfg.Params) (*Connection, error) {
+	c := NewConnection(&Electrum{
+		Addr:    addr,
+		TLS:     useTLS,
+		Params:  params,
+		Timeout: time.Second * 30,
+		watched: make(map[string]*electrumWatched),
+		headers: make(map[chainhash.Hash]*electrumHeader),
+	})
+	c.Params = params
+
+	// check the server is reachable
+	if _, err := c.Height(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Scripthash returns how Electrum refers to the output script of an
+// address: its sha256, reversed.
+func Scripthash(script []byte) string {
+	hash := sha256.Sum256(script)
+	for i, j := 0, len(hash)-1; i < j; i, j = i+1, j-1 {
+		hash[i], hash[j] = hash[j], hash[i]
+	}
+	return hex.EncodeToString(hash[:])
+}
+
+// Watch adds an address whose transactions blocks should have.
+func (e *Electrum) Watch(addr string) error {
+	script, err := Script(addr, e.Params)
+	if err != nil {
+		return err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	if hash := Scripthash(script); e.watched[hash] == nil {
+		e.watched[hash] = &electrumWatched{Address: addr, Checked: -1}
+	}
+	return nil
+}
+
+func (e *Electrum) GetBlockCount() (int64, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	return e.tip()
+}
+
+func (e *Electrum) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	var raw string
+	if err := e.call("blockchain.block.header", &raw, height); err != nil {
+		if tip, tipErr := e.tip(); tipErr == nil && height > tip {
+			return nil, ErrNoBlock
+		}
+		return nil, err
+	}
+
+	data, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &electrumHeader{Height: height}
+	if err = header.Header.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	hash := header.Header.BlockHash()
+	e.headers[hash] = header
+	return &hash, nil
+}
+
+func (e *Electrum) GetBlockVerboseTx(hash *chainhash.Hash) (*btcjson.GetBlockVerboseResult, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	header := e.headers[*hash]
+	if header == nil {
+		return nil, ErrUnknownBlock
+	}
+	delete(e.headers, *hash)
+
+	if err := e.refresh(header.Height); err != nil {
+		return nil, err
+	}
+
+	txs, err := e.transactions(e.history(func(h int64) bool { return h == header.Height }))
+	if err != nil {
+		return nil, err
+	}
+
+	return &btcjson.GetBlockVerboseResult{
+		Hash:         hash.String(),
+		PreviousHash: header.Header.PrevBlock.String(),
+		Time:         header.Header.Timestamp.Unix(),
+		RawTx:        txs,
+	}, nil
+}
+
+// GetRawMempool returns the unconfirmed transactions of the watched
+// addresses, the only ones the server can tell about.
+func (e *Electrum) GetRawMempool() ([]*chainhash.Hash, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	// statuses change with the mempool too, check them all
+	tip, err := e.tip()
+	if err != nil {
+		return nil, err
+	}
+	if err = e.refresh(tip + 1); err != nil {
+		return nil, err
+	}
+
+	ids := e.history(func(h int64) bool { return h <= 0 })
+	hashes := make([]*chainhash.Hash, 0, len(ids))
+	for _, id := range ids {
+		hash, err := chainhash.NewHashFromStr(id)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func (e *Electrum) GetRawTransactionVerbose(hash *chainhash.Hash) (*btcjson.TxRawResult, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	txs, err := e.transactions([]string{hash.String()})
+	if err != nil {
+		return nil, err
+	}
+	return &txs[0], nil
+}
+
+func (e *Electrum) WaitForShutdown() {
+	e.Lock()
+	defer e.Unlock()
+
+	e.close()
+}
+
+func (e *Electrum) tip() (int64, error) {
+	var header struct {
+		Height int64 `json:"height"`
+	}
+	err := e.call("blockchain.headers.subscribe", &header)
+	return header.Height, err
+}
+
+// refresh checks the status of addresses not checked since height, and
+// fetches the history of those whose status changed.
+func (e *Electrum) refresh(height int64) error {
+	tip, err := e.tip()
+	if err != nil {
+		return err
+	}
+
+	stale := make([]string, 0)
+	for hash, w := range e.watched {
+		if w.Checked < height {
+			stale = append(stale, hash)
+		}
+	}
+
+	statuses, err := e.each("blockchain.scripthash.subscribe", stale)
+	if err != nil {
+		return err
+	}
+
+	changed := make([]string, 0)
+	for i, hash := range stale {
+		var status string
+		if err = json.Unmarshal(statuses[i], &status); err != nil && string(statuses[i]) != "null" {
+			return err
+		}
+
+		w := e.watched[hash]
+		if status != w.Status || w.Checked < 0 {
+			w.Status = status
+			changed = append(changed, hash)
+		}
+		w.Checked = tip
+	}
+
+	histories, err := e.each("blockchain.scripthash.get_history", changed)
+	if err != nil {
+		return err
+	}
+
+	for i, hash := range changed {
+		history := make([]electrumHistory, 0)
+		if err = json.Unmarshal(histories[i], &history); err != nil {
+			return err
+		}
+		e.watched[hash].History = history
+	}
+
+	return nil
+}
+
+// history returns the ids of the watched addresses' transactions at the
+// heights matching, sorted.
+func (e *Electrum) history(match func(int64) bool) []string {
+	found := make(map[string]bool)
+	for _, w := range e.watched {
+		for _, item := range w.History {
+			if match(item.Height) {
+				found[item.TxHash] = true
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(found))
+	for id := range found {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// transactions fetches raw transactions and decodes them.
+func (e *Electrum) transactions(ids []string) ([]btcjson.TxRawResult, error) {
+	raws, err := e.each("blockchain.transaction.get", ids)
+	if err != nil {
+		return nil, err
+	}
+
+	txs := make([]btcjson.TxRawResult, 0, len(ids))
+	for _, data := range raws {
+		var raw string
+		if err = json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+
+		tx, err := DecodeTx(raw)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, Verbose(tx, e.Params))
+	}
+
+	return txs, nil
+}
+
+// each calls method once for each param, in batches, returning the results
+// in the same order.
+func (e *Electrum) each(method string, params []string) ([]json.RawMessage, error) {
+	results := make([]json.RawMessage, 0, len(params))
+
+	for start := 0; start < len(params); start += ELECTRUM_BATCH {
+		end := start + ELECTRUM_BATCH
+		if end > len(params) {
+			end = len(params)
+		}
+
+		requests := make([]*electrumRequest, 0, end-start)
+		for _, param := range params[start:end] {
+			requests = append(requests, &electrumRequest{Method: method, Params: []interface{}{param}})
+		}
+
+		batch, err := e.batch(requests)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batch...)
+	}
+
+	return results, nil
+}
+
+func (e *Electrum) call(method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	results, err := e.batch([]*electrumRequest{{Method: method, Params: params}})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(results[0], result)
+}
+
+// batch sends requests at once, connecting first if needed, and returns
+// their results in the same order. The connection is dropped on errors
+// reading or writing, the next batch connects again.
+func (e *Electrum) batch(requests []*electrumRequest) ([]json.RawMessage, error) {
+	if e.conn == nil {
+		if err := e.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	results, err := e.roundtrip(requests)
+	if _, ok := err.(*electrumError); err != nil && !ok {
+		e.close()
+	}
+	return results, err
+}
+
+func (e *Electrum) connect() error {
+	dialer := &net.Dialer{Timeout: e.Timeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if e.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", e.Addr, &tls.Config{})
+	} else {
+		conn, err = dialer.Dial("tcp", e.Addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.conn, e.reader = conn, bufio.NewReader(conn)
+
+	// servers expect the protocol version first
+	_, err = e.roundtrip([]*electrumRequest{{Method: "server.version", Params: []interface{}{"otc-watcher", "1.4"}}})
+	if err != nil {
+		e.close()
+	}
+	return err
+}
+
+func (e *Electrum) close() {
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn, e.reader = nil, nil
+	}
+}
+
+type electrumError struct {
+	Method  string
+	Message json.RawMessage
+}
+
+func (e *electrumError) Error() string {
+	return fmt.Sprintf("electrum %s: %s", e.Method, e.Message)
+}
+
+func (e *Electrum) roundtrip(requests []*electrumRequest) ([]json.RawMessage, error) {
+	e.conn.SetDeadline(time.Now().Add(e.Timeout))
+
+	index := make(map[uint64]int, len(requests))
+	for i, req := range requests {
+		e.id++
+		req.JSONRPC, req.Id = "2.0", e.id
+		index[req.Id] = i
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if len(requests) == 1 {
+		data, err = json.Marshal(requests[0])
+	} else {
+		data, err = json.Marshal(requests)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = e.conn.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+
+	results := make([]json.RawMessage, len(requests))
+	for received := 0; received < len(requests); {
+		line, err := e.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+
+		responses := make([]*electrumResponse, 0)
+		if bytes.HasPrefix(line, []byte("[")) {
+			err = json.Unmarshal(line, &responses)
+		} else {
+			res := &electrumResponse{}
+			err = json.Unmarshal(line, res)
+			responses = append(responses, res)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, res := range responses {
+			// notifications of subscriptions, statuses are checked anyway
+			if res.Id == nil {
+				continue
+			}
+
+			i, ok := index[*res.Id]
+			if !ok {
+				continue
+			}
+			if len(res.Error) > 0 && string(res.Error) != "null" {
+				return nil, &electrumError{requests[i].Method, res.Error}
+			}
+
+			results[i] = res.Result
+			received++
+		}
+	}
+
+	return results, nil
+}
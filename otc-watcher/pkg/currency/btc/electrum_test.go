@@ -0,0 +1,273 @@
+package btc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ElectrumStub answers the methods the adapter uses for a chain of one
+// block, with the history of a single address.
+type ElectrumStub struct {
+	sync.Mutex
+
+	Listener net.Listener
+	Header   string
+	Txs      map[string]string
+	// scripthash watched
+	Scripthash string
+	Status     string
+	History    []electrumHistory
+	// number of calls per method
+	Calls map[string]int
+
+	conns []net.Conn
+}
+
+func NewElectrumStub(t *testing.T) *ElectrumStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &ElectrumStub{
+		Listener: listener,
+		Txs:      make(map[string]string),
+		Calls:    make(map[string]int),
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			s.Lock()
+			s.conns = append(s.conns, conn)
+			s.Unlock()
+
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+// Drop closes the connections open, as a server restarting would.
+func (s *ElectrumStub) Drop() {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *ElectrumStub) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		requests := make([]*electrumRequest, 0)
+		batch := bytes.HasPrefix(line, []byte("["))
+		if batch {
+			json.Unmarshal(line, &requests)
+		} else {
+			req := &electrumRequest{}
+			json.Unmarshal(line, req)
+			requests = append(requests, req)
+		}
+
+		// a notification the adapter should skip
+		fmt.Fprintln(conn, `{"jsonrpc":"2.0","method":"blockchain.headers.subscribe","params":[{"height":1}]}`)
+
+		responses := make([]map[string]interface{}, 0, len(requests))
+		for _, req := range requests {
+			result, failed := s.answer(req)
+
+			res := map[string]interface{}{"jsonrpc": "2.0", "id": req.Id}
+			if failed != "" {
+				res["error"] = map[string]interface{}{"code": 1, "message": failed}
+			} else {
+				res["result"] = result
+			}
+			responses = append(responses, res)
+		}
+
+		var data []byte
+		if batch {
+			data, _ = json.Marshal(responses)
+		} else {
+			data, _ = json.Marshal(responses[0])
+		}
+		conn.Write(append(data, '\n'))
+	}
+}
+
+func (s *ElectrumStub) answer(req *electrumRequest) (interface{}, string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.Calls[req.Method]++
+
+	switch req.Method {
+	case "server.version":
+		return []string{"stub", "1.4"}, ""
+	case "blockchain.headers.subscribe":
+		return map[string]interface{}{"height": 1, "hex": s.Header}, ""
+	case "blockchain.block.header":
+		if req.Params[0].(float64) != 1 {
+			return nil, "height out of range"
+		}
+		return s.Header, ""
+	case "blockchain.scripthash.subscribe":
+		if req.Params[0] != s.Scripthash {
+			return nil, ""
+		}
+		return s.Status, ""
+	case "blockchain.scripthash.get_history":
+		if req.Params[0] != s.Scripthash {
+			return []electrumHistory{}, ""
+		}
+		return s.History, ""
+	case "blockchain.transaction.get":
+		if raw, ok := s.Txs[req.Params[0].(string)]; ok {
+			return raw, ""
+		}
+		return nil, "transaction not found"
+	}
+
+	return nil, "unknown method " + req.Method
+}
+
+func serialize(t *testing.T, tx *wire.MsgTx) string {
+	buf := &bytes.Buffer{}
+	if err := tx.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func TestElectrum(t *testing.T) {
+	block, addrs := Fixture(t)
+
+	stub := NewElectrumStub(t)
+	defer stub.Listener.Close()
+
+	header := &bytes.Buffer{}
+	if err := block.Header.Serialize(header); err != nil {
+		t.Fatal(err)
+	}
+	stub.Header = hex.EncodeToString(header.Bytes())
+
+	script, err := Script(addrs[1], params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// paying the address again, unconfirmed
+	spent := chainhash.DoubleHashH([]byte("other"))
+	unconfirmed := wire.NewMsgTx(1)
+	unconfirmed.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&spent, 0), nil, nil))
+	unconfirmed.AddTxOut(wire.NewTxOut(1000, script))
+
+	mined, pending := block.Transactions[1].TxHash().String(), unconfirmed.TxHash().String()
+	stub.Txs[mined] = serialize(t, block.Transactions[1])
+	stub.Txs[pending] = serialize(t, unconfirmed)
+	stub.Scripthash = Scripthash(script)
+	stub.Status = "a"
+	stub.History = []electrumHistory{{TxHash: mined, Height: 1}}
+
+	c, err := NewElectrum(stub.Listener.Addr().String(), false, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stop waits for a scan, there is none
+	defer c.Client.WaitForShutdown()
+
+	if err = c.Watch(addrs[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Watch("not an address"); err == nil {
+		t.Fatal("expected invalid address error")
+	}
+
+	got, err := c.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Hash != block.BlockHash().String() || got.Time != 1500000000 {
+		t.Fatalf("unexpected block %+v", got)
+	}
+
+	// only the transactions of watched addresses, not the coinbase
+	tx := got.Transactions[mined]
+	if len(got.Transactions) != 1 || tx == nil || tx.Out[1].Addresses[0] != addrs[1] {
+		t.Fatalf("unexpected transactions %+v", got.Transactions)
+	}
+
+	if _, err = c.Get(2); err != ErrNoBlock {
+		t.Fatalf("expected ErrNoBlock past the tip, got %v", err)
+	}
+
+	// the status didn't change, so neither did the history
+	stub.Lock()
+	stub.History = append(stub.History, electrumHistory{TxHash: pending, Height: 0})
+	stub.Unlock()
+
+	ids, err := c.Mempool()
+	if err != nil || len(ids) != 0 {
+		t.Fatalf("expected the history kept, got %v %v", ids, err)
+	}
+
+	stub.Lock()
+	stub.Status = "b"
+	stub.Unlock()
+
+	ids, err = c.Mempool()
+	if err != nil || len(ids) != 1 || ids[0] != pending {
+		t.Fatalf("expected the unconfirmed transaction, got %v %v", ids, err)
+	}
+	if tx, err = c.Unconfirmed(ids[0]); err != nil || tx.Out[0].Amount != 1000 {
+		t.Fatalf("unexpected unconfirmed transaction %+v %v", tx, err)
+	}
+
+	stub.Lock()
+	if stub.Calls["blockchain.scripthash.get_history"] != 2 {
+		t.Fatalf("expected the history fetched twice, got %d", stub.Calls["blockchain.scripthash.get_history"])
+	}
+	stub.Unlock()
+
+	// server errors keep the connection, dropped connections are made again
+	if _, err = c.Unconfirmed(block.BlockHash().String()); err == nil {
+		t.Fatal("expected transaction not found")
+	}
+
+	stub.Drop()
+	if _, err = c.Height(); err == nil {
+		t.Fatal("expected error on the dropped connection")
+	}
+	if height, err := c.Height(); err != nil || height != 1 {
+		t.Fatalf("expected to connect again, got %d %v", height, err)
+	}
+
+	stub.Lock()
+	if stub.Calls["server.version"] != 2 {
+		t.Fatalf("expected to connect twice, got %d", stub.Calls["server.version"])
+	}
+	stub.Unlock()
+}
@@ -0,0 +1,144 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Esplora reads blocks from an Esplora REST api, e.g.
+// https://blockstream.info/api. Blocks are fetched raw, one request each.
+type Esplora struct {
+	URL    string
+	Params *chaincfg.Params
+	HTTP   *http.Client
+}
+
+// NewEsplora connects to the Esplora api at url, for addresses of params.
+func NewEsplora(url string, params *chaincfg.Params) (*Connection, error) {
+	c := NewConnection(&Esplora{
+		URL:    strings.TrimSuffix(url, "/"),
+		Params: params,
+		HTTP:   &http.Client{Timeout: time.Second * 30},
+	})
+	c.Params = params
+
+	// check the api is reachable
+	if _, err := c.Height(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// get returns the body of a GET request, ErrNoBlock if not found.
+func (e *Esplora) get(path string) ([]byte, error) {
+	res, err := e.HTTP.Get(e.URL + path)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return nil, ErrNoBlock
+	case res.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("esplora returned %s: %s", res.Status, bytes.TrimSpace(body))
+	}
+
+	return body, nil
+}
+
+func (e *Esplora) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	body, err := e.get("/block-height/" + strconv.FormatInt(height, 10))
+	if err != nil {
+		return nil, err
+	}
+	return chainhash.NewHashFromStr(string(bytes.TrimSpace(body)))
+}
+
+func (e *Esplora) GetBlockVerboseTx(hash *chainhash.Hash) (*btcjson.GetBlockVerboseResult, error) {
+	body, err := e.get("/block/" + hash.String() + "/raw")
+	if err != nil {
+		return nil, err
+	}
+
+	block := &wire.MsgBlock{}
+	if err = block.Deserialize(bytes.NewReader(body)); err != nil {
+		return nil, err
+	}
+
+	result := &btcjson.GetBlockVerboseResult{
+		Hash:         block.BlockHash().String(),
+		PreviousHash: block.Header.PrevBlock.String(),
+		Time:         block.Header.Timestamp.Unix(),
+		RawTx:        make([]btcjson.TxRawResult, 0, len(block.Transactions)),
+	}
+	for _, tx := range block.Transactions {
+		result.RawTx = append(result.RawTx, Verbose(tx, e.Params))
+	}
+
+	return result, nil
+}
+
+func (e *Esplora) GetBlockCount() (int64, error) {
+	body, err := e.get("/blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(bytes.TrimSpace(body)), 10, 64)
+}
+
+func (e *Esplora) GetRawMempool() ([]*chainhash.Hash, error) {
+	body, err := e.get("/mempool/txids")
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	if err = json.Unmarshal(body, &ids); err != nil {
+		return nil, err
+	}
+
+	hashes := make([]*chainhash.Hash, 0, len(ids))
+	for _, id := range ids {
+		hash, err := chainhash.NewHashFromStr(id)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func (e *Esplora) GetRawTransactionVerbose(hash *chainhash.Hash) (*btcjson.TxRawResult, error) {
+	body, err := e.get("/tx/" + hash.String() + "/hex")
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := DecodeTx(string(bytes.TrimSpace(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	result := Verbose(tx, e.Params)
+	return &result, nil
+}
+
+func (e *Esplora) WaitForShutdown() {}
@@ -0,0 +1,104 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// EsploraStub serves a block at height 1 and its second transaction in the
+// mempool.
+func EsploraStub(t *testing.T) *httptest.Server {
+	block, _ := Fixture(t)
+
+	raw := &bytes.Buffer{}
+	if err := block.Serialize(raw); err != nil {
+		t.Fatal(err)
+	}
+	tx := &bytes.Buffer{}
+	if err := block.Transactions[1].Serialize(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, txid := block.BlockHash().String(), block.Transactions[1].TxHash().String()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blocks/tip/height", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "1")
+	})
+	mux.HandleFunc("/block-height/1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, hash)
+	})
+	mux.HandleFunc("/block/"+hash+"/raw", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(raw.Bytes())
+	})
+	mux.HandleFunc("/mempool/txids", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `["%s"]`, txid)
+	})
+	mux.HandleFunc("/tx/"+txid+"/hex", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, hex.EncodeToString(tx.Bytes()))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestEsplora(t *testing.T) {
+	server := EsploraStub(t)
+	defer server.Close()
+
+	block, addrs := Fixture(t)
+
+	c, err := NewEsplora(server.URL+"/", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if height, err := c.Height(); err != nil || height != 1 {
+		t.Fatalf("expected height 1, got %d %v", height, err)
+	}
+
+	got, err := c.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Hash != block.BlockHash().String() || got.Time != 1500000000 || len(got.Transactions) != 2 {
+		t.Fatalf("unexpected block %+v", got)
+	}
+
+	tx := got.Transactions[block.Transactions[1].TxHash().String()]
+	if tx == nil || tx.Out[1].Addresses[0] != addrs[1] || tx.Out[1].Amount != 25000000 {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+
+	if _, err = c.Get(2); err != ErrNoBlock || !missing(err) {
+		t.Fatalf("expected ErrNoBlock past the tip, got %v", err)
+	}
+
+	ids, err := c.Mempool()
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("expected one mempool id, got %v %v", ids, err)
+	}
+	if tx, err = c.Unconfirmed(ids[0]); err != nil || tx.Out[1].Addresses[0] != addrs[1] {
+		t.Fatalf("unexpected unconfirmed transaction %+v %v", tx, err)
+	}
+
+	esplora := c.Client.(*Esplora)
+	if _, err = esplora.get("/broken"); err == nil || err == ErrNoBlock {
+		t.Fatalf("expected server error, got %v", err)
+	}
+}
+
+func TestEsploraUnreachable(t *testing.T) {
+	server := EsploraStub(t)
+	server.Close()
+
+	if _, err := NewEsplora(server.URL, params); err == nil {
+		t.Fatal("expected error connecting to a closed server")
+	}
+}
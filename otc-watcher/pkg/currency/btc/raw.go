@@ -0,0 +1,141 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+var ErrAddressType = errors.New("unsupported address type")
+
+// script opcodes of the standard outputs
+const (
+	OP_0           = 0x00
+	OP_DATA_20     = 0x14
+	OP_DATA_32     = 0x20
+	OP_DUP         = 0x76
+	OP_EQUAL       = 0x87
+	OP_EQUALVERIFY = 0x88
+	OP_HASH160     = 0xa9
+	OP_CHECKSIG    = 0xac
+)
+
+// Params returns the parameters of a network by name, mainnet if empty.
+func Params(network string) (*chaincfg.Params, error) {
+	switch network {
+	case "", "mainnet":
+		return &chaincfg.MainNetParams, nil
+	case "testnet3":
+		return &chaincfg.TestNet3Params, nil
+	case "regtest":
+		return &chaincfg.RegressionNetParams, nil
+	}
+	return nil, errors.New("unknown network " + network)
+}
+
+// Addresses returns the address an output script pays, for pay to pubkey
+// hash, script hash, witness pubkey hash and witness script hash outputs.
+// Others have none.
+func Addresses(script []byte, params *chaincfg.Params) []string {
+	var (
+		addr btcutil.Address
+		err  error
+	)
+
+	switch {
+	case len(script) == 25 && script[0] == OP_DUP && script[1] == OP_HASH160 &&
+		script[2] == OP_DATA_20 && script[23] == OP_EQUALVERIFY && script[24] == OP_CHECKSIG:
+		addr, err = btcutil.NewAddressPubKeyHash(script[3:23], params)
+	case len(script) == 23 && script[0] == OP_HASH160 && script[1] == OP_DATA_20 && script[22] == OP_EQUAL:
+		addr, err = btcutil.NewAddressScriptHashFromHash(script[2:22], params)
+	case len(script) == 22 && script[0] == OP_0 && script[1] == OP_DATA_20:
+		addr, err = btcutil.NewAddressWitnessPubKeyHash(script[2:], params)
+	case len(script) == 34 && script[0] == OP_0 && script[1] == OP_DATA_32:
+		addr, err = btcutil.NewAddressWitnessScriptHash(script[2:], params)
+	default:
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+
+	return []string{addr.EncodeAddress()}
+}
+
+// Script returns the output script paying an address.
+func Script(address string, params *chaincfg.Params) ([]byte, error) {
+	addr, err := btcutil.DecodeAddress(address, params)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := addr.ScriptAddress()
+	switch addr.(type) {
+	case *btcutil.AddressPubKeyHash:
+		return append(append([]byte{OP_DUP, OP_HASH160, OP_DATA_20}, hash...), OP_EQUALVERIFY, OP_CHECKSIG), nil
+	case *btcutil.AddressScriptHash:
+		return append(append([]byte{OP_HASH160, OP_DATA_20}, hash...), OP_EQUAL), nil
+	case *btcutil.AddressWitnessPubKeyHash:
+		return append([]byte{OP_0, OP_DATA_20}, hash...), nil
+	case *btcutil.AddressWitnessScriptHash:
+		return append([]byte{OP_0, OP_DATA_32}, hash...), nil
+	}
+	return nil, ErrAddressType
+}
+
+// Verbose maps a raw transaction to what a node returns for it, for
+// backends that only serve raw transactions.
+func Verbose(tx *wire.MsgTx, params *chaincfg.Params) btcjson.TxRawResult {
+	txid := tx.TxHash().String()
+
+	result := btcjson.TxRawResult{
+		Txid: txid,
+		Hash: txid,
+		Vin:  make([]btcjson.Vin, 0, len(tx.TxIn)),
+		Vout: make([]btcjson.Vout, 0, len(tx.TxOut)),
+	}
+
+	for _, in := range tx.TxIn {
+		if in.PreviousOutPoint.Index == wire.MaxPrevOutIndex && in.PreviousOutPoint.Hash == (chainhash.Hash{}) {
+			result.Vin = append(result.Vin, btcjson.Vin{Coinbase: hex.EncodeToString(in.SignatureScript)})
+			continue
+		}
+		result.Vin = append(result.Vin, btcjson.Vin{
+			Txid: in.PreviousOutPoint.Hash.String(),
+			Vout: in.PreviousOutPoint.Index,
+		})
+	}
+
+	for n, out := range tx.TxOut {
+		result.Vout = append(result.Vout, btcjson.Vout{
+			Value: btcutil.Amount(out.Value).ToBTC(),
+			N:     uint32(n),
+			ScriptPubKey: btcjson.ScriptPubKeyResult{
+				Hex:       hex.EncodeToString(out.PkScript),
+				Addresses: Addresses(out.PkScript, params),
+			},
+		})
+	}
+
+	return result
+}
+
+// DecodeTx decodes a hex encoded raw transaction.
+func DecodeTx(raw string) (*wire.MsgTx, error) {
+	data, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &wire.MsgTx{}
+	if err = tx.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
@@ -0,0 +1,157 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+var params = &chaincfg.RegressionNetParams
+
+// Fixture returns a block with a coinbase paying one address and a
+// transaction paying another, and the addresses paid.
+func Fixture(t *testing.T) (*wire.MsgBlock, []string) {
+	witness, err := btcutil.NewAddressWitnessPubKeyHash(bytes.Repeat([]byte{1}, 20), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{2}, 20), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addrs := []string{witness.EncodeAddress(), legacy.EncodeAddress()}
+	scripts := make([][]byte, 0, len(addrs))
+	for _, addr := range addrs {
+		script, err := Script(addr, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scripts = append(scripts, script)
+	}
+
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), []byte{1, 2}, nil))
+	coinbase.AddTxOut(wire.NewTxOut(5000000000, scripts[0]))
+
+	spent := chainhash.DoubleHashH([]byte("spent"))
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&spent, 3), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(100, []byte{0x6a}))
+	tx.AddTxOut(wire.NewTxOut(25000000, scripts[1]))
+
+	block := wire.NewMsgBlock(wire.NewBlockHeader(1, &chainhash.Hash{}, &chainhash.Hash{}, 0, 0))
+	block.Header.Timestamp = time.Unix(1500000000, 0)
+	block.AddTransaction(coinbase)
+	block.AddTransaction(tx)
+
+	return block, addrs
+}
+
+func TestScriptAddresses(t *testing.T) {
+	for _, hash := range [][]byte{bytes.Repeat([]byte{3}, 20), bytes.Repeat([]byte{4}, 32)} {
+		var (
+			addrs []btcutil.Address
+			err   error
+		)
+
+		if len(hash) == 20 {
+			var pkh, sh, wpkh btcutil.Address
+			pkh, err = btcutil.NewAddressPubKeyHash(hash, params)
+			sh, _ = btcutil.NewAddressScriptHashFromHash(hash, params)
+			wpkh, _ = btcutil.NewAddressWitnessPubKeyHash(hash, params)
+			addrs = append(addrs, pkh, sh, wpkh)
+		} else {
+			var wsh btcutil.Address
+			wsh, err = btcutil.NewAddressWitnessScriptHash(hash, params)
+			addrs = append(addrs, wsh)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, addr := range addrs {
+			script, err := Script(addr.EncodeAddress(), params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := Addresses(script, params)
+			if len(got) != 1 || got[0] != addr.EncodeAddress() {
+				t.Fatalf("expected %s back from its script, got %v", addr, got)
+			}
+		}
+	}
+
+	if got := Addresses([]byte{0x6a}, params); got != nil {
+		t.Fatalf("expected no address for a data output, got %v", got)
+	}
+
+	// the generator point
+	key, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	pk, err := btcutil.NewAddressPubKey(key, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Script(pk.String(), params); err == nil {
+		t.Fatal("expected pubkey addresses to be unsupported")
+	}
+}
+
+func TestVerbose(t *testing.T) {
+	block, addrs := Fixture(t)
+
+	coinbase := Verbose(block.Transactions[0], params)
+	if len(coinbase.Vin) != 1 || !coinbase.Vin[0].IsCoinBase() {
+		t.Fatalf("expected coinbase input, got %+v", coinbase.Vin)
+	}
+
+	tx := Verbose(block.Transactions[1], params)
+	if tx.Txid != block.Transactions[1].TxHash().String() {
+		t.Fatalf("unexpected txid %s", tx.Txid)
+	}
+	if tx.Vin[0].Txid != chainhash.DoubleHashH([]byte("spent")).String() || tx.Vin[0].Vout != 3 {
+		t.Fatalf("unexpected input %+v", tx.Vin[0])
+	}
+	if len(tx.Vout[0].ScriptPubKey.Addresses) != 0 {
+		t.Fatalf("expected no address for a data output, got %v", tx.Vout[0].ScriptPubKey.Addresses)
+	}
+	if tx.Vout[1].N != 1 || tx.Vout[1].Value != 0.25 || tx.Vout[1].ScriptPubKey.Addresses[0] != addrs[1] {
+		t.Fatalf("unexpected output %+v", tx.Vout[1])
+	}
+
+	transaction, err := Transaction(&tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transaction.Out[1].Amount != 25000000 {
+		t.Fatalf("unexpected amount %d", transaction.Out[1].Amount)
+	}
+}
+
+func TestDecodeTx(t *testing.T) {
+	block, _ := Fixture(t)
+
+	buf := &bytes.Buffer{}
+	if err := block.Transactions[1].Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := DecodeTx(hex.EncodeToString(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.TxHash() != block.Transactions[1].TxHash() {
+		t.Fatal("decoded transaction differs")
+	}
+
+	if _, err = DecodeTx("zz"); err == nil {
+		t.Fatal("expected invalid hex error")
+	}
+}
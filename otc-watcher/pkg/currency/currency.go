@@ -17,6 +17,27 @@ type Connection interface {
 	Height() (uint64, error)
 }
 
+// Mempool is implemented by connections that can see transactions before
+// they're mined.
+type Mempool interface {
+	// ids of the transactions waiting to be mined
+	Mempool() ([]string, error)
+	Unconfirmed(id string) (*otc.Transaction, error)
+}
+
+// Watcher is implemented by connections that only find the outputs of
+// addresses they're told about, rather than reading every transaction.
+type Watcher interface {
+	Watch(addr string) error
+}
+
+// Deriver is implemented by connections that can derive addresses from an
+// extended public key or output descriptor.
+type Deriver interface {
+	// addresses at indexes from up to to, excluded
+	Derive(descriptor string, from, to uint32) ([]string, error)
+}
+
 type Connections map[otc.Currency]Connection
 
 func (c Connections) Get(cur otc.Currency, height uint64) (*otc.Block, error) {
@@ -0,0 +1,152 @@
+package sky
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+	"github.com/skycoin/skycoin/src/api/webrpc"
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+var ErrNoBlock = errors.New("block not found")
+
+// Skycoin blocks come about every 10 seconds
+const WAIT = time.Second * 10
+
+type Client interface {
+	GetBlocksBySeq([]uint64) (*visor.ReadableBlocks, error)
+	GetLastBlocks(uint64) (*visor.ReadableBlocks, error)
+}
+
+// Connection reads blocks from a Skycoin node's webrpc api. Outputs keep
+// their uxid as Id and inputs refer to them by it.
+type Connection struct {
+	Logs   *log.Logger
+	Client Client
+	// time to wait for a block that doesn't exist yet, WAIT by default
+	Wait time.Duration
+	stop chan struct{}
+}
+
+func New(node string) (*Connection, error) {
+	c := &Connection{
+		Logs:   log.New(os.Stdout, "", log.LstdFlags),
+		Client: &webrpc.Client{Addr: node},
+		Wait:   WAIT,
+		stop:   make(chan struct{}, 1),
+	}
+
+	// check the node is reachable
+	if _, err := c.Height(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Connection) Scan(from uint64) (chan *otc.Block, error) {
+	blocks := make(chan *otc.Block, 0)
+	height := from
+
+	go func() {
+		for {
+			select {
+			case <-c.stop:
+				return
+			default:
+				block, err := c.Get(height)
+				if err != nil {
+					if err == ErrNoBlock {
+						c.Logs.Printf("waiting for block: %d\n", height)
+					} else {
+						c.Logs.Printf("scan error: %v\n", err)
+					}
+
+					time.Sleep(c.Wait)
+				} else {
+					// send block to scanner
+					blocks <- block
+					// next iteration attempt to get next block
+					height++
+				}
+			}
+		}
+	}()
+
+	return blocks, nil
+}
+
+func (c *Connection) Get(height uint64) (*otc.Block, error) {
+	rb, err := c.Client.GetBlocksBySeq([]uint64{height})
+	if err != nil {
+		return nil, err
+	}
+	if len(rb.Blocks) == 0 {
+		return nil, ErrNoBlock
+	}
+
+	return Block(&rb.Blocks[0])
+}
+
+// Block maps a Skycoin block to an otc one.
+func Block(rb *visor.ReadableBlock) (*otc.Block, error) {
+	block := &otc.Block{
+		Height:       rb.Head.BkSeq,
+		Hash:         rb.Head.BlockHash,
+		Previous:     rb.Head.PreviousBlockHash,
+		Time:         int64(rb.Head.Time),
+		Transactions: make(map[string]*otc.Transaction, len(rb.Body.Transactions)),
+	}
+
+	for _, tx := range rb.Body.Transactions {
+		transaction := &otc.Transaction{
+			Id:        tx.Hash,
+			BlockHash: rb.Head.BlockHash,
+			Hash:      tx.Hash,
+			In:        make([]otc.Input, 0, len(tx.In)),
+			Out:       make(map[int]*otc.Output, len(tx.Out)),
+		}
+		block.Transactions[tx.Hash] = transaction
+
+		for index, out := range tx.Out {
+			coins, err := droplet.FromString(out.Coins)
+			if err != nil {
+				return nil, err
+			}
+
+			transaction.Out[index] = &otc.Output{
+				Id:        out.Hash,
+				Amount:    coins,
+				Addresses: []string{out.Address},
+				Hours:     out.Hours,
+			}
+		}
+
+		for _, uxid := range tx.In {
+			transaction.In = append(transaction.In, otc.Input{Id: uxid})
+		}
+	}
+
+	return block, nil
+}
+
+func (c *Connection) Height() (uint64, error) {
+	rb, err := c.Client.GetLastBlocks(1)
+	if err != nil {
+		return 0, err
+	}
+	if len(rb.Blocks) == 0 {
+		return 0, ErrNoBlock
+	}
+
+	return rb.Blocks[0].Head.BkSeq, nil
+}
+
+func (c *Connection) Stop() error {
+	c.stop <- struct{}{}
+	return nil
+}
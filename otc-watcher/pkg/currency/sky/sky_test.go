@@ -0,0 +1,170 @@
+package sky
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+type Mock struct {
+	blocks map[uint64]visor.ReadableBlock
+	err    error
+}
+
+func (m *Mock) GetBlocksBySeq(seqs []uint64) (*visor.ReadableBlocks, error) {
+	rb := &visor.ReadableBlocks{}
+	for _, seq := range seqs {
+		if block, ok := m.blocks[seq]; ok {
+			rb.Blocks = append(rb.Blocks, block)
+		}
+	}
+	return rb, m.err
+}
+
+func (m *Mock) GetLastBlocks(n uint64) (*visor.ReadableBlocks, error) {
+	rb := &visor.ReadableBlocks{}
+	var last uint64
+	for seq := range m.blocks {
+		if seq >= last {
+			last = seq
+		}
+	}
+	if block, ok := m.blocks[last]; ok {
+		rb.Blocks = append(rb.Blocks, block)
+	}
+	return rb, m.err
+}
+
+func MockBlock(seq uint64, coins string) visor.ReadableBlock {
+	return visor.ReadableBlock{
+		Head: visor.ReadableBlockHeader{
+			BkSeq:             seq,
+			BlockHash:         fmt.Sprintf("block%d", seq),
+			PreviousBlockHash: fmt.Sprintf("block%d", seq-1),
+		},
+		Body: visor.ReadableBlockBody{
+			Transactions: []visor.ReadableTransaction{
+				{
+					Hash: "txid",
+					In:   []string{"spent_uxid"},
+					Out: []visor.ReadableTransactionOutput{
+						{Hash: "uxid_zero", Address: "address", Coins: coins, Hours: 7},
+						{Hash: "uxid_one", Address: "change", Coins: "1", Hours: 1},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGet(t *testing.T) {
+	connection := &Connection{Client: &Mock{blocks: map[uint64]visor.ReadableBlock{
+		32: MockBlock(32, "2.5"),
+	}}}
+
+	block, err := connection.Get(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if block.Height != 32 || block.Hash != "block32" || block.Previous != "block31" {
+		t.Fatalf("unexpected block %+v", block)
+	}
+
+	tx := block.Transactions["txid"]
+	if tx == nil || len(tx.In) != 1 || tx.In[0].Id != "spent_uxid" {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+
+	out := tx.Out[0]
+	if out.Id != "uxid_zero" || out.Amount != 2500000 || out.Hours != 7 || out.Addresses[0] != "address" {
+		t.Fatalf("unexpected output %+v", out)
+	}
+}
+
+func TestGetMissing(t *testing.T) {
+	connection := &Connection{Client: &Mock{}}
+
+	if _, err := connection.Get(32); err != ErrNoBlock {
+		t.Fatalf("expected %v, got %v", ErrNoBlock, err)
+	}
+}
+
+func TestGetBadCoins(t *testing.T) {
+	connection := &Connection{Client: &Mock{blocks: map[uint64]visor.ReadableBlock{
+		32: MockBlock(32, "two"),
+	}}}
+
+	if _, err := connection.Get(32); err == nil {
+		t.Fatal("should have returned an error")
+	}
+}
+
+func TestHeight(t *testing.T) {
+	connection := &Connection{Client: &Mock{blocks: map[uint64]visor.ReadableBlock{
+		31: MockBlock(31, "1"),
+		32: MockBlock(32, "1"),
+	}}}
+
+	if height, err := connection.Height(); height != 32 || err != nil {
+		t.Fatal("couldn't get height")
+	}
+
+	bad := fmt.Errorf("bad")
+	connection.Client = &Mock{err: bad}
+	if _, err := connection.Height(); err != bad {
+		t.Fatal("should have returned an error")
+	}
+}
+
+func TestScan(t *testing.T) {
+	var buf bytes.Buffer
+
+	connection := &Connection{
+		Logs: log.New(&buf, "", 0),
+		Client: &Mock{blocks: map[uint64]visor.ReadableBlock{
+			32: MockBlock(32, "1"),
+		}},
+		Wait: time.Hour,
+		stop: make(chan struct{}, 1),
+	}
+
+	blocks, err := connection.Scan(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case block := <-blocks:
+		if block.Height != 32 {
+			t.Fatalf("expected block 32, got %d", block.Height)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocks not sent from scanner")
+	}
+
+	// wait for goroutine to log missing block
+	<-time.After(time.Second / 10)
+
+	if buf.String() != "waiting for block: 33\n" {
+		t.Fatalf("expected waiting log, got '%s'\n", buf.String())
+	}
+}
+
+func TestStop(t *testing.T) {
+	connection := &Connection{stop: make(chan struct{}, 1)}
+
+	if err := connection.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-connection.stop:
+	default:
+		t.Fatal("stop chan not filled")
+	}
+}
@@ -0,0 +1,334 @@
+// Package notify pushes scanner events to subscribed clients: to a webhook
+// URL, retried until it accepts them, or to clients following a stream.
+package notify
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/skycoin/services/otc-watcher/pkg/scanner"
+	"github.com/skycoin/services/otc/pkg/otc"
+	bolt "go.etcd.io/bbolt"
+)
+
+// id -> Subscription
+var SUBSCRIPTIONS = []byte("subscriptions")
+
+// events posted to a webhook at once
+const BATCH = 100
+
+var ErrMissing = errors.New("subscription not found")
+
+// Subscription is a client's interest in events of some addresses. Reorg
+// events of their currencies are included.
+type Subscription struct {
+	Id        string      `json:"id"`
+	Addresses []*otc.Drop `json:"addresses"`
+	// wallets whose derived addresses' events are sent too
+	Wallets []*WalletRef `json:"wallets,omitempty"`
+	// events are posted here if set, otherwise the client follows a stream
+	URL string `json:"url,omitempty"`
+	// id of the last event the webhook accepted
+	Cursor  uint64 `json:"cursor"`
+	Created int64  `json:"created"`
+}
+
+func (s *Subscription) Match(event *scanner.Event) bool {
+	for _, drop := range s.Addresses {
+		if drop.Currency == event.Currency && (event.Type == scanner.REORG || drop.Address == event.Address) {
+			return true
+		}
+	}
+	for _, wallet := range s.Wallets {
+		if wallet.Currency != event.Currency {
+			continue
+		}
+		if event.Type == scanner.REORG || event.Derivation != nil && event.Derivation.Wallet == wallet.Id {
+			return true
+		}
+	}
+	return false
+}
+
+// WalletRef is a wallet watched in a currency.
+type WalletRef struct {
+	Currency otc.Currency `json:"currency"`
+	Id       uint64       `json:"id"`
+}
+
+// Notifier keeps subscriptions in the scanner's database and delivers
+// events to webhooks, each from its own goroutine.
+type Notifier struct {
+	sync.Mutex
+
+	DB     *scanner.DB
+	Client *http.Client
+	Logs   *log.Logger
+	// first wait after a failed delivery, doubled up to MaxRetry
+	Retry    time.Duration
+	MaxRetry time.Duration
+
+	stops map[string]chan struct{}
+}
+
+func New(db *scanner.DB, logs *log.Logger) *Notifier {
+	return &Notifier{
+		DB:       db,
+		Client:   &http.Client{Timeout: time.Second * 30},
+		Logs:     logs,
+		Retry:    time.Second * 5,
+		MaxRetry: time.Minute * 10,
+		stops:    make(map[string]chan struct{}),
+	}
+}
+
+// Start delivers to the webhooks of existing subscriptions.
+func (n *Notifier) Start() error {
+	subs, err := n.List()
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		if sub.URL != "" {
+			n.start(sub)
+		}
+	}
+	return nil
+}
+
+func (n *Notifier) Stop() {
+	n.Lock()
+	defer n.Unlock()
+
+	for id, stop := range n.stops {
+		close(stop)
+		delete(n.stops, id)
+	}
+}
+
+// Subscribe saves a new subscription starting after the latest event, and
+// starts delivering to its webhook.
+func (n *Notifier) Subscribe(sub *Subscription) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	sub.Id = hex.EncodeToString(id)
+	sub.Created = time.Now().UTC().Unix()
+
+	latest, err := n.DB.LatestEvent()
+	if err != nil {
+		return err
+	}
+	sub.Cursor = latest
+
+	if err = n.save(sub); err != nil {
+		return err
+	}
+
+	if sub.URL != "" {
+		n.start(sub)
+	}
+	return nil
+}
+
+func (n *Notifier) Unsubscribe(id string) error {
+	n.Lock()
+	if stop := n.stops[id]; stop != nil {
+		close(stop)
+		delete(n.stops, id)
+	}
+	n.Unlock()
+
+	return n.DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(SUBSCRIPTIONS)
+		if b == nil || b.Get([]byte(id)) == nil {
+			return ErrMissing
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+func (n *Notifier) Get(id string) (*Subscription, error) {
+	var sub *Subscription
+
+	err := n.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(SUBSCRIPTIONS)
+		if b == nil || b.Get([]byte(id)) == nil {
+			return ErrMissing
+		}
+		sub = &Subscription{}
+		return json.Unmarshal(b.Get([]byte(id)), sub)
+	})
+
+	return sub, err
+}
+
+func (n *Notifier) List() ([]*Subscription, error) {
+	subs := make([]*Subscription, 0)
+
+	err := n.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(SUBSCRIPTIONS)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			sub := &Subscription{}
+			if err := json.Unmarshal(v, sub); err != nil {
+				return err
+			}
+			subs = append(subs, sub)
+			return nil
+		})
+	})
+
+	return subs, err
+}
+
+func (n *Notifier) save(sub *Subscription) error {
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+
+	return n.DB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(SUBSCRIPTIONS)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(sub.Id), data)
+	})
+}
+
+func (n *Notifier) start(sub *Subscription) {
+	n.Lock()
+	defer n.Unlock()
+
+	// the goroutine's own copy, it moves the cursor
+	own := *sub
+	stop := make(chan struct{})
+	n.stops[sub.Id] = stop
+	go n.Deliver(&own, stop)
+}
+
+// advance saves a subscription's cursor, unless it was unsubscribed.
+func (n *Notifier) advance(sub *Subscription) error {
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+
+	return n.DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(SUBSCRIPTIONS)
+		if b == nil || b.Get([]byte(sub.Id)) == nil {
+			return nil
+		}
+		return b.Put([]byte(sub.Id), data)
+	})
+}
+
+// Next returns the events after cursor matching sub, waiting for some until
+// stop is closed. The returned cursor is past events that didn't match.
+func (n *Notifier) Next(sub *Subscription, cursor uint64, stop <-chan struct{}) ([]*scanner.Event, uint64, error) {
+	for {
+		// before reading so events added meanwhile aren't missed
+		added := n.DB.Added()
+
+		events, err := n.DB.Events(cursor, BATCH)
+		if err != nil {
+			return nil, cursor, err
+		}
+
+		matched := make([]*scanner.Event, 0)
+		for _, event := range events {
+			if sub.Match(event) {
+				matched = append(matched, event)
+			} else if len(matched) == 0 {
+				cursor = event.Id
+			}
+		}
+		if len(matched) > 0 {
+			return matched, cursor, nil
+		}
+		if len(events) == BATCH {
+			continue
+		}
+
+		select {
+		case <-stop:
+			return nil, cursor, nil
+		case <-added:
+		}
+	}
+}
+
+// Deliver posts events to a subscription's webhook until stop is closed.
+// Its cursor only moves once the webhook accepts a batch, so every event is
+// delivered at least once.
+func (n *Notifier) Deliver(sub *Subscription, stop chan struct{}) {
+	retry := n.Retry
+
+	for {
+		events, cursor, err := n.Next(sub, sub.Cursor, stop)
+		if err != nil {
+			n.Logs.Printf("subscription %s: %v\n", sub.Id, err)
+		} else if len(events) == 0 {
+			// stopped
+			return
+		} else if err = n.Post(sub, events); err != nil {
+			n.Logs.Printf("subscription %s: %v\n", sub.Id, err)
+		} else {
+			sub.Cursor = events[len(events)-1].Id
+			if err = n.advance(sub); err != nil {
+				n.Logs.Printf("subscription %s: %v\n", sub.Id, err)
+			}
+			retry = n.Retry
+			continue
+		}
+
+		// events skipped as not matching needn't be looked at again
+		sub.Cursor = cursor
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(retry):
+		}
+		if retry *= 2; retry > n.MaxRetry {
+			retry = n.MaxRetry
+		}
+	}
+}
+
+// Payload is what webhooks receive.
+type Payload struct {
+	Subscription string           `json:"subscription"`
+	Events       []*scanner.Event `json:"events"`
+}
+
+func (n *Notifier) Post(sub *Subscription, events []*scanner.Event) error {
+	data, err := json.Marshal(&Payload{sub.Id, events})
+	if err != nil {
+		return err
+	}
+
+	res, err := n.Client.Post(sub.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", res.Status)
+	}
+	return nil
+}
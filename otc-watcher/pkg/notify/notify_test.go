@@ -0,0 +1,194 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc-watcher/pkg/scanner"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func MockNotifier(t *testing.T) (*Notifier, string) {
+	dir, err := ioutil.TempDir("", "otc-watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := scanner.Open(filepath.Join(dir, "otc-watcher.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := New(db, log.New(ioutil.Discard, "", 0))
+	n.Retry, n.MaxRetry = time.Millisecond, time.Millisecond
+	return n, dir
+}
+
+func Save(t *testing.T, db *scanner.DB, height uint64, addr, hash string) {
+	changes := []*scanner.Change{{
+		Address: addr,
+		Hash:    hash,
+		Output:  &otc.OutputVerbose{Amount: 1, Height: height},
+	}}
+	if err := db.Save(otc.BTC, scanner.Updated{Height: height}, changes); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	sub := &Subscription{
+		Addresses: []*otc.Drop{{Address: "address", Currency: otc.BTC}},
+		Wallets:   []*WalletRef{{Currency: otc.BTC, Id: 1}},
+	}
+
+	tests := []struct {
+		Event *scanner.Event
+		Match bool
+	}{
+		{&scanner.Event{Type: scanner.OUTPUT, Currency: otc.BTC, Address: "address"}, true},
+		{&scanner.Event{Type: scanner.OUTPUT, Currency: otc.BTC, Address: "other"}, false},
+		{&scanner.Event{Type: scanner.OUTPUT, Currency: otc.SKY, Address: "address"}, false},
+		{&scanner.Event{Type: scanner.REORG, Currency: otc.BTC}, true},
+		{&scanner.Event{Type: scanner.REORG, Currency: otc.SKY}, false},
+		{&scanner.Event{Type: scanner.OUTPUT, Currency: otc.BTC, Address: "derived", Derivation: &scanner.Derivation{Wallet: 1, Index: 5}}, true},
+		{&scanner.Event{Type: scanner.OUTPUT, Currency: otc.BTC, Address: "derived", Derivation: &scanner.Derivation{Wallet: 2}}, false},
+		{&scanner.Event{Type: scanner.OUTPUT, Currency: otc.SKY, Address: "derived", Derivation: &scanner.Derivation{Wallet: 1}}, false},
+	}
+
+	for i, test := range tests {
+		if sub.Match(test.Event) != test.Match {
+			t.Fatalf("test %d: expected %v", i, test.Match)
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	n, dir := MockNotifier(t)
+	defer os.RemoveAll(dir)
+	defer n.DB.Close()
+
+	// events before subscribing aren't delivered
+	Save(t, n.DB, 1, "address", "before")
+
+	failures := 2
+	received := make(chan *Payload, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		payload := &Payload{}
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			t.Error(err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	sub := &Subscription{
+		Addresses: []*otc.Drop{{Address: "address", Currency: otc.BTC}},
+		URL:       server.URL,
+	}
+	if err := n.Subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+
+	Save(t, n.DB, 2, "other", "skipped")
+	Save(t, n.DB, 3, "address", "after")
+
+	select {
+	case payload := <-received:
+		if payload.Subscription != sub.Id || len(payload.Events) != 1 || payload.Events[0].Hash != "after" {
+			t.Fatalf("unexpected payload %+v", payload)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("webhook not called")
+	}
+
+	// the cursor is saved once accepted
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		saved, err := n.Get(sub.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved.Cursor == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected cursor 3, got %d", saved.Cursor)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	n, dir := MockNotifier(t)
+	defer os.RemoveAll(dir)
+	defer n.DB.Close()
+
+	sub := &Subscription{
+		Addresses: []*otc.Drop{{Address: "address", Currency: otc.BTC}},
+		Wallets:   []*WalletRef{{Currency: otc.BTC, Id: 1}},
+	}
+	if err := n.Subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if subs, _ := n.List(); len(subs) != 1 {
+		t.Fatalf("expected 1 subscription, got %d", len(subs))
+	}
+
+	if err := n.Unsubscribe(sub.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Unsubscribe(sub.Id); err != ErrMissing {
+		t.Fatalf("expected %v, got %v", ErrMissing, err)
+	}
+	if _, err := n.Get(sub.Id); err != ErrMissing {
+		t.Fatalf("expected %v, got %v", ErrMissing, err)
+	}
+}
+
+func TestNext(t *testing.T) {
+	n, dir := MockNotifier(t)
+	defer os.RemoveAll(dir)
+	defer n.DB.Close()
+
+	sub := &Subscription{
+		Addresses: []*otc.Drop{{Address: "address", Currency: otc.BTC}},
+		Wallets:   []*WalletRef{{Currency: otc.BTC, Id: 1}},
+	}
+
+	Save(t, n.DB, 1, "other", "skipped")
+
+	// waits for a matching event
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		Save(t, n.DB, 2, "address", "wanted")
+	}()
+
+	events, cursor, err := n.Next(sub, 0, make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Hash != "wanted" || cursor != 1 {
+		t.Fatalf("expected wanted after cursor 1, got %+v %d", events, cursor)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	if events, _, _ = n.Next(sub, 2, stop); len(events) != 0 {
+		t.Fatal("expected nothing once stopped")
+	}
+}
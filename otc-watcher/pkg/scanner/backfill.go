@@ -0,0 +1,286 @@
+package scanner
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// blocks fetched at once while backfilling, and by how many goroutines
+const (
+	BACKFILL_BATCH   = 64
+	BACKFILL_WORKERS = 8
+)
+
+// time to wait after failing to fetch blocks, or meeting a block the
+// scanner is about to roll back
+var BACKFILL_RETRY = time.Second * 5
+
+var ErrStale = errors.New("block differs from the one scanned")
+
+// Backfill is a rescan of blocks from before an address was registered,
+// for outputs it was sent then.
+type Backfill struct {
+	Id       uint64       `json:"id"`
+	Currency otc.Currency `json:"currency"`
+	Address  string       `json:"address"`
+	// first block rescanned
+	From uint64 `json:"from"`
+	// block the scanner was at when it was queued, the rescan goes on to
+	// wherever the scanner is when it gets there
+	To uint64 `json:"to"`
+	// next block to rescan
+	Next uint64 `json:"next"`
+	// outputs found so far
+	Found    int    `json:"found"`
+	Started  int64  `json:"started"`
+	Finished int64  `json:"finished,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Backfill queues a rescan for a registered address from block from up to
+// the block scanned, which runs in the background.
+func (s *Scanner) Backfill(drop *otc.Drop, from uint64) (*Backfill, error) {
+	storage := s.Scanning[drop.Currency]
+	if storage == nil {
+		return nil, currency.ErrConnMissing
+	}
+
+	if !storage.Watched(drop.Address) {
+		return nil, ErrAddressMissing
+	}
+
+	to := storage.Status().Height
+	b := &Backfill{
+		Currency: drop.Currency,
+		Address:  drop.Address,
+		From:     from,
+		To:       to,
+		Next:     from,
+		Started:  time.Now().UTC().Unix(),
+	}
+	if err := s.DB.SaveBackfill(b, nil); err != nil {
+		return nil, err
+	}
+
+	// the rescan moves its own copy along
+	queued := *b
+	s.backfill(b)
+	return &queued, nil
+}
+
+// HeightAt returns the height of the first block mined at or after t, or
+// the height scanned if there's none yet.
+func (s *Scanner) HeightAt(cur otc.Currency, t int64) (uint64, error) {
+	storage := s.Scanning[cur]
+	if storage == nil {
+		return 0, currency.ErrConnMissing
+	}
+
+	low, high := uint64(0), storage.Status().Height
+	for low < high {
+		middle := low + (high-low)/2
+
+		block, err := s.Connections.Get(cur, middle)
+		if err != nil {
+			return 0, err
+		}
+
+		if block.Time < t {
+			low = middle + 1
+		} else {
+			high = middle
+		}
+	}
+
+	return low, nil
+}
+
+// Backfills returns the backfills of a currency, oldest first.
+func (s *Scanner) Backfills(cur otc.Currency) ([]*Backfill, error) {
+	if s.Scanning[cur] == nil {
+		return nil, currency.ErrConnMissing
+	}
+	return s.DB.Backfills(cur)
+}
+
+// Resume restarts the backfills that hadn't finished when the scanner last
+// stopped.
+func (s *Scanner) Resume() error {
+	for cur := range s.Scanning {
+		backfills, err := s.DB.Backfills(cur)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range backfills {
+			if b.Finished == 0 {
+				s.backfill(b)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Scanner) backfill(b *Backfill) {
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.Rescan(b)
+	}()
+}
+
+// Rescan fetches the blocks of a backfill in batches, in parallel, and
+// applies them in order for its address until it gets to the block
+// scanned. Progress is saved with each block that had outputs or spends,
+// and after each batch.
+func (s *Scanner) Rescan(b *Backfill) {
+	storage := s.Scanning[b.Currency]
+
+	for {
+		tip := storage.Status().Height
+		if b.Next > tip {
+			b.Error = ""
+			break
+		}
+
+		end := b.Next + BACKFILL_BATCH - 1
+		if end > tip {
+			end = tip
+		}
+
+		blocks, err := s.fetch(b.Currency, b.Next, end)
+		if err == nil {
+			var done bool
+			if done, err = s.rescan(b, blocks); done {
+				b.Error = ""
+				break
+			}
+		}
+		if err == ErrAddressMissing {
+			// unregistered meanwhile
+			b.Error = err.Error()
+			break
+		}
+		if err != nil {
+			b.Error = err.Error()
+			log.Printf("backfill %d of %s: %v\n", b.Id, b.Address, err)
+		} else {
+			b.Error = ""
+		}
+
+		if err = s.DB.SaveBackfill(b, nil); err != nil {
+			log.Printf("backfill %d of %s: %v\n", b.Id, b.Address, err)
+		}
+
+		if b.Error != "" {
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(BACKFILL_RETRY):
+			}
+		}
+
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+	}
+
+	b.Finished = time.Now().UTC().Unix()
+	if err := s.DB.SaveBackfill(b, nil); err != nil {
+		log.Printf("backfill %d of %s: %v\n", b.Id, b.Address, err)
+	}
+	log.Printf("backfill %d of %s: %d outputs found in blocks %d to %d\n",
+		b.Id, b.Address, b.Found, b.From, b.Next-1)
+}
+
+// rescan applies blocks in order, returning true once the block scanned
+// was reached.
+func (s *Scanner) rescan(b *Backfill, blocks []*otc.Block) (bool, error) {
+	storage := s.Scanning[b.Currency]
+
+	for _, block := range blocks {
+		storage.Applying.Lock()
+		changes, applied, done, err := storage.Backfill(b.Address, block)
+		if err != nil {
+			storage.Applying.Unlock()
+			return false, err
+		}
+
+		if applied {
+			b.Next = block.Height + 1
+		}
+		for _, change := range changes {
+			if !change.Spend {
+				b.Found++
+			}
+		}
+
+		if len(changes) > 0 {
+			err = s.DB.SaveBackfill(b, changes)
+		}
+		storage.Applying.Unlock()
+		if err != nil {
+			return false, err
+		}
+
+		if done {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// fetch gets the blocks from height from to to with BACKFILL_WORKERS
+// goroutines, lowest first.
+func (s *Scanner) fetch(cur otc.Currency, from, to uint64) ([]*otc.Block, error) {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed error
+	)
+
+	heights := make(chan uint64)
+	blocks := make([]*otc.Block, 0, to-from+1)
+
+	for i := 0; i < BACKFILL_WORKERS; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for height := range heights {
+				block, err := s.Connections.Get(cur, height)
+
+				mu.Lock()
+				if err != nil {
+					failed = err
+				} else {
+					blocks = append(blocks, block)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for height := from; height <= to; height++ {
+		heights <- height
+	}
+	close(heights)
+	wg.Wait()
+
+	if failed != nil {
+		return nil, failed
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Height < blocks[j].Height })
+	return blocks, nil
+}
@@ -0,0 +1,197 @@
+package scanner
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func MockBackfill(t *testing.T) (*Scanner, *MockChain, string) {
+	db, dir := MockDB(t)
+
+	chain := &MockChain{Blocks: make(map[uint64]*otc.Block)}
+	for height := uint64(0); height <= 200; height++ {
+		block := MockBlock(height, "", "", "", 0)
+		block.Time = int64(1000 + height*10)
+		block.Transactions = map[string]*otc.Transaction{}
+		chain.Blocks[height] = block
+	}
+	chain.Blocks[20] = MockBlock(20, "", "", "t20", 20)
+	chain.Blocks[150] = MockBlock(150, "", "", "t150", 150, otc.Input{Hash: "t20", Index: 0})
+
+	storage := NewStorage(otc.BTC)
+	storage.Updated.Height = 200
+	if err := db.Import(otc.BTC, storage); err != nil {
+		t.Fatal(err)
+	}
+
+	scnr := &Scanner{
+		Connections: currency.Connections{otc.BTC: chain},
+		Scanning:    map[otc.Currency]*Storage{otc.BTC: storage},
+		DB:          db,
+		stop:        make(chan struct{}),
+	}
+
+	return scnr, chain, dir
+}
+
+// Finished waits for the first backfill to finish.
+func Finished(t *testing.T, scnr *Scanner) *Backfill {
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		backfills, err := scnr.Backfills(otc.BTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backfills) > 0 && backfills[0].Finished != 0 {
+			return backfills[0]
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("backfill didn't finish, got %+v", backfills)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	scnr, _, dir := MockBackfill(t)
+	defer os.RemoveAll(dir)
+	defer scnr.Stop()
+
+	drop := &otc.Drop{Address: "address", Currency: otc.BTC}
+	if _, err := scnr.Backfill(drop, 0); err != ErrAddressMissing {
+		t.Fatalf("expected %v, got %v", ErrAddressMissing, err)
+	}
+
+	if err := scnr.Register(drop); err != nil {
+		t.Fatal(err)
+	}
+	queued, err := scnr.Backfill(drop, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued.Id != 1 || queued.From != 10 || queued.To != 200 {
+		t.Fatalf("unexpected backfill %+v", queued)
+	}
+
+	if backfill := Finished(t, scnr); backfill.Next != 201 || backfill.Found != 2 || backfill.Error != "" {
+		t.Fatalf("unexpected finished backfill %+v", backfill)
+	}
+
+	outputs := scnr.Scanning[otc.BTC].Outputs("address")
+	if outputs["t20"][0].Spent != "t150" || outputs["t20"][0].Confirmations != 181 || outputs["t150"] == nil {
+		t.Fatalf("expected t20 spent by t150, got %+v", outputs)
+	}
+
+	// saved, with events for subscribers
+	loaded, err := scnr.DB.Load(otc.BTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Addresses["address"].Outputs["t20"][0].Spent != "t150" {
+		t.Fatal("expected backfilled outputs saved")
+	}
+
+	events, err := scnr.DB.Events(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[0].Hash != "t20" || events[1].Hash != "t150" ||
+		events[2].Type != SPENT || events[2].Output.Spent != "t150" {
+		t.Fatalf("expected t20 and t150 outputs then the spend, got %+v %+v %+v", events[0], events[1], events[2])
+	}
+}
+
+func TestBackfillResume(t *testing.T) {
+	scnr, _, dir := MockBackfill(t)
+	defer os.RemoveAll(dir)
+	defer scnr.Stop()
+
+	drop := &otc.Drop{Address: "address", Currency: otc.BTC}
+	if err := scnr.Register(drop); err != nil {
+		t.Fatal(err)
+	}
+
+	// cut short after block 100
+	b := &Backfill{Currency: otc.BTC, Address: "address", From: 0, To: 200, Next: 101}
+	if err := scnr.DB.SaveBackfill(b, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scnr.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if backfill := Finished(t, scnr); backfill.Found != 1 {
+		t.Fatalf("expected 1 output found, got %+v", backfill)
+	}
+
+	outputs := scnr.Scanning[otc.BTC].Outputs("address")
+	if outputs["t20"] != nil || outputs["t150"] == nil {
+		t.Fatalf("expected only blocks from 101 rescanned, got %+v", outputs)
+	}
+}
+
+func TestHeightAt(t *testing.T) {
+	scnr, _, dir := MockBackfill(t)
+	defer os.RemoveAll(dir)
+	defer scnr.DB.Close()
+
+	tests := []struct {
+		Time   int64
+		Height uint64
+	}{
+		{0, 0},
+		{1000, 0},
+		{1001, 1},
+		{1500, 50},
+		{1505, 51},
+		{3000, 200},
+	}
+
+	for _, test := range tests {
+		height, err := scnr.HeightAt(otc.BTC, test.Time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if height != test.Height {
+			t.Fatalf("time %d: expected %d, got %d", test.Time, test.Height, height)
+		}
+	}
+}
+
+func TestStorageBackfill(t *testing.T) {
+	storage := NewStorage(otc.BTC)
+	storage.Register("address")
+	storage.Register("other")
+	storage.Updated.Height = 10
+	storage.Blocks[5] = "a5"
+
+	block := MockBlock(5, "b5", "a4", "t5", 5)
+	if _, _, _, err := storage.Backfill("address", block); err != ErrStale {
+		t.Fatalf("expected %v, got %v", ErrStale, err)
+	}
+
+	// rolled back below it
+	if changes, applied, done, _ := storage.Backfill("address", MockBlock(11, "", "", "t11", 11)); applied || !done || len(changes) != 0 {
+		t.Fatal("expected block above the one scanned skipped")
+	}
+
+	// other addresses aren't backfilled
+	if changes, _, _, _ := storage.Backfill("other", MockBlock(6, "", "", "t6", 6)); len(changes) != 0 {
+		t.Fatalf("expected nothing for other, got %+v", changes)
+	}
+
+	changes, applied, done, err := storage.Backfill("address", MockBlock(10, "", "", "t10", 10))
+	if err != nil || !applied || !done || len(changes) != 1 || changes[0].Output.Confirmations != 1 {
+		t.Fatalf("expected t10 applied and done, got %+v %v %v %v", changes, applied, done, err)
+	}
+
+	// again, e.g. from a second backfill
+	if changes, _, _, _ = storage.Backfill("address", MockBlock(10, "", "", "t10", 10)); len(changes) != 0 {
+		t.Fatalf("expected known output skipped, got %+v", changes)
+	}
+}
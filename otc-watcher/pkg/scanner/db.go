@@ -0,0 +1,530 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+	bolt "go.etcd.io/bbolt"
+)
+
+// buckets kept for every currency, inside a bucket named after it
+var (
+	// "updated" -> Updated
+	META = []byte("meta")
+	// address -> Updated when registered
+	ADDRESSES = []byte("addresses")
+	// "hash:index" -> Stored
+	OUTPUTS = []byte("outputs")
+	// "address:hash:index" -> nothing
+	BY_ADDRESS = []byte("by_address")
+	// big endian height + "hash:index" -> nothing
+	BY_HEIGHT = []byte("by_height")
+	// big endian height -> block hash, for the last KEEP blocks
+	BLOCKS = []byte("blocks")
+	// big endian id -> Reorg
+	REORGS = []byte("reorgs")
+	// big endian id -> Backfill
+	BACKFILLS = []byte("backfills")
+
+	UPDATED = []byte("updated")
+)
+
+// DB keeps what the scanner found in a bolt database, so a block costs one
+// transaction writing what changed instead of rewriting everything.
+type DB struct {
+	*bolt.DB
+
+	// outputs get confirmed events until they have this many confirmations
+	Confirmations uint64
+
+	mu    sync.Mutex
+	added chan struct{}
+}
+
+// Stored is an output as kept in the database. Confirmations aren't kept
+// up to date there, they follow from the height scanned when read.
+type Stored struct {
+	Address string `json:"address"`
+	*otc.OutputVerbose
+}
+
+func Open(path string) (*DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &DB{DB: db, added: make(chan struct{})}, nil
+}
+
+func Outpoint(hash string, index int) string {
+	return hash + ":" + strconv.Itoa(index)
+}
+
+func ParseOutpoint(outpoint string) (string, int, error) {
+	i := strings.LastIndexByte(outpoint, ':')
+	if i < 0 {
+		return "", 0, fmt.Errorf("invalid outpoint %s", outpoint)
+	}
+
+	index, err := strconv.Atoi(outpoint[i+1:])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid outpoint %s", outpoint)
+	}
+	return outpoint[:i], index, nil
+}
+
+// Load reads a currency's storage, or returns nil if it has none yet.
+func (d *DB) Load(cur otc.Currency) (*Storage, error) {
+	var storage *Storage
+
+	err := d.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(cur))
+		if root == nil {
+			return nil
+		}
+
+		storage = NewStorage(cur)
+		if err := json.Unmarshal(root.Bucket(META).Get(UPDATED), storage.Updated); err != nil {
+			return err
+		}
+
+		err := root.Bucket(BLOCKS).ForEach(func(k, v []byte) error {
+			storage.Blocks[binary.BigEndian.Uint64(k)] = string(v)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		err = root.Bucket(ADDRESSES).ForEach(func(k, v []byte) error {
+			storage.Addresses[string(k)] = &Relevant{Outputs: make(otc.Outputs, 0)}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return root.Bucket(OUTPUTS).ForEach(func(k, v []byte) error {
+			stored := &Stored{}
+			if err := json.Unmarshal(v, stored); err != nil {
+				return err
+			}
+
+			hash, index, err := ParseOutpoint(string(k))
+			if err != nil {
+				return err
+			}
+
+			if storage.Addresses[stored.Address] == nil {
+				// left behind by an unregister, skipped so the watcher
+				// still starts
+				log.Printf("loading %s: skipping output %s of unregistered address %s\n", cur, k, stored.Address)
+				return nil
+			}
+
+			storage.add(stored.Address, hash, index, stored.OutputVerbose)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %v", cur, err)
+	}
+
+	return storage, nil
+}
+
+// Register records that addr is watched from the given height and time.
+func (d *DB) Register(cur otc.Currency, addr string, at Updated) error {
+	return d.Update(func(tx *bolt.Tx) error {
+		root, err := buckets(tx, cur)
+		if err != nil {
+			return err
+		}
+		return put(root.Bucket(ADDRESSES), []byte(addr), at)
+	})
+}
+
+// Save writes the changes of a block along with the height it's at, all or
+// nothing.
+func (d *DB) Save(cur otc.Currency, updated Updated, changes []*Change) error {
+	err := d.Update(func(tx *bolt.Tx) error {
+		root, err := buckets(tx, cur)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			if err = putOutput(root, change); err != nil {
+				return err
+			}
+		}
+
+		if err = putUpdated(root, updated); err != nil {
+			return err
+		}
+
+		return d.addEvents(tx, root, cur, updated, changes)
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notify()
+	return nil
+}
+
+// Rollback saves what Storage.Rollback undid along with the reorganisation
+// that caused it, all or nothing. The reorg gets its id here.
+func (d *DB) Rollback(cur otc.Currency, updated Updated, removed, unspent []*Change, reorg *Reorg) error {
+	err := d.Update(func(tx *bolt.Tx) error {
+		root, err := buckets(tx, cur)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range removed {
+			if err = deleteOutput(root, change); err != nil {
+				return err
+			}
+		}
+		for _, change := range unspent {
+			if err = putOutput(root, change); err != nil {
+				return err
+			}
+		}
+
+		// blocks above the fork are gone
+		above := make([][]byte, 0)
+		c := root.Bucket(BLOCKS).Cursor()
+		for k, _ := c.Seek(Height(updated.Height + 1)); k != nil; k, _ = c.Next() {
+			above = append(above, k)
+		}
+		if err = deleteKeys(root.Bucket(BLOCKS), above); err != nil {
+			return err
+		}
+
+		if reorg.Id, err = root.Bucket(REORGS).NextSequence(); err != nil {
+			return err
+		}
+		if err = put(root.Bucket(REORGS), Height(reorg.Id), reorg); err != nil {
+			return err
+		}
+
+		if err = putUpdated(root, updated); err != nil {
+			return err
+		}
+
+		return addEvent(tx, &Event{Type: REORG, Currency: cur, Time: reorg.Time, Reorg: reorg})
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notify()
+	return nil
+}
+
+// Reorgs returns the reorganisations with ids above since, oldest first.
+func (d *DB) Reorgs(cur otc.Currency, since uint64) ([]*Reorg, error) {
+	reorgs := make([]*Reorg, 0)
+
+	err := d.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(cur))
+		if root == nil {
+			return nil
+		}
+
+		c := root.Bucket(REORGS).Cursor()
+		for k, v := c.Seek(Height(since + 1)); k != nil; k, v = c.Next() {
+			reorg := &Reorg{}
+			if err := json.Unmarshal(v, reorg); err != nil {
+				return err
+			}
+			reorgs = append(reorgs, reorg)
+		}
+		return nil
+	})
+
+	return reorgs, err
+}
+
+// SaveBackfill writes a backfill's progress along with the outputs and
+// spends it found, all or nothing. The backfill gets its id here.
+func (d *DB) SaveBackfill(b *Backfill, changes []*Change) error {
+	err := d.Update(func(tx *bolt.Tx) error {
+		root, err := buckets(tx, b.Currency)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			if err = putOutput(root, change); err != nil {
+				return err
+			}
+		}
+
+		if b.Id == 0 {
+			if b.Id, err = root.Bucket(BACKFILLS).NextSequence(); err != nil {
+				return err
+			}
+		}
+		if err = put(root.Bucket(BACKFILLS), Height(b.Id), b); err != nil {
+			return err
+		}
+
+		return addChanges(tx, b.Currency, changes)
+	})
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	d.notify()
+	return nil
+}
+
+// Backfills returns the backfills of a currency, oldest first.
+func (d *DB) Backfills(cur otc.Currency) ([]*Backfill, error) {
+	backfills := make([]*Backfill, 0)
+
+	err := d.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(cur))
+		if root == nil || root.Bucket(BACKFILLS) == nil {
+			return nil
+		}
+
+		return root.Bucket(BACKFILLS).ForEach(func(k, v []byte) error {
+			b := &Backfill{}
+			if err := json.Unmarshal(v, b); err != nil {
+				return err
+			}
+			backfills = append(backfills, b)
+			return nil
+		})
+	})
+
+	return backfills, err
+}
+
+// Import writes a whole storage, replacing what the currency had.
+func (d *DB) Import(cur otc.Currency, storage *Storage) error {
+	storage.RLock()
+	defer storage.RUnlock()
+
+	return d.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(cur)) != nil {
+			if err := tx.DeleteBucket([]byte(cur)); err != nil {
+				return err
+			}
+		}
+
+		root, err := buckets(tx, cur)
+		if err != nil {
+			return err
+		}
+
+		for addr, rel := range storage.Addresses {
+			if err = put(root.Bucket(ADDRESSES), []byte(addr), storage.Updated); err != nil {
+				return err
+			}
+
+			for hash, outputs := range rel.Outputs {
+				for index, output := range outputs {
+					if err = putOutput(root, &Change{Address: addr, Hash: hash, Index: index, Output: output}); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		for height, hash := range storage.Blocks {
+			if err = root.Bucket(BLOCKS).Put(Height(height), []byte(hash)); err != nil {
+				return err
+			}
+		}
+
+		return put(root.Bucket(META), UPDATED, storage.Updated)
+	})
+}
+
+// Migrate moves a JSON storage file (from before the database) into it.
+// The file is renamed to <filename>.migrated rather than removed. Returns
+// nil if there's no file.
+func (d *DB) Migrate(cur otc.Currency, filename string) (*Storage, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	storage := NewStorage(cur)
+	if err = json.NewDecoder(file).Decode(storage); err == io.EOF {
+		// created empty, nothing was ever scanned
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("migrating %s: %v", filename, err)
+	}
+
+	if storage.Updated == nil {
+		storage.Updated = &Updated{}
+	}
+	if storage.Addresses == nil {
+		storage.Addresses = make(map[string]*Relevant, 0)
+	}
+	for _, rel := range storage.Addresses {
+		if rel.Outputs == nil {
+			rel.Outputs = make(otc.Outputs, 0)
+		}
+	}
+	storage.Reindex()
+
+	if err = d.Import(cur, storage); err != nil {
+		return nil, err
+	}
+
+	return storage, os.Rename(filename, filename+".migrated")
+}
+
+// Outpoints returns the outpoints of an address's outputs, using the
+// address index.
+func (d *DB) Outpoints(cur otc.Currency, addr string) ([]string, error) {
+	outpoints := make([]string, 0)
+
+	err := d.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(cur))
+		if root == nil {
+			return nil
+		}
+
+		prefix := []byte(addr + ":")
+		c := root.Bucket(BY_ADDRESS).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, _ = c.Next() {
+			outpoints = append(outpoints, string(k[len(prefix):]))
+		}
+		return nil
+	})
+
+	return outpoints, err
+}
+
+// Above returns the outpoints of outputs in blocks at or above height,
+// using the height index.
+func (d *DB) Above(cur otc.Currency, height uint64) ([]string, error) {
+	outpoints := make([]string, 0)
+
+	err := d.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(cur))
+		if root == nil {
+			return nil
+		}
+
+		c := root.Bucket(BY_HEIGHT).Cursor()
+		for k, _ := c.Seek(Height(height)); k != nil; k, _ = c.Next() {
+			outpoints = append(outpoints, string(k[8:]))
+		}
+		return nil
+	})
+
+	return outpoints, err
+}
+
+// Height encodes a height so keys sort by it.
+func Height(height uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, height)
+	return key
+}
+
+// buckets returns a currency's bucket, creating it and the buckets in it.
+func buckets(tx *bolt.Tx, cur otc.Currency) (*bolt.Bucket, error) {
+	root, err := tx.CreateBucketIfNotExists([]byte(cur))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range [][]byte{META, ADDRESSES, OUTPUTS, BY_ADDRESS, BY_HEIGHT, BLOCKS, REORGS, BACKFILLS, WALLETS, DERIVED} {
+		if _, err = root.CreateBucketIfNotExists(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return root, nil
+}
+
+func put(b *bolt.Bucket, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+// putOutput writes an output and its index entries.
+func putOutput(root *bolt.Bucket, change *Change) error {
+	outpoint := Outpoint(change.Hash, change.Index)
+
+	if err := put(root.Bucket(OUTPUTS), []byte(outpoint), &Stored{change.Address, change.Output}); err != nil {
+		return err
+	}
+	if err := root.Bucket(BY_ADDRESS).Put([]byte(change.Address+":"+outpoint), []byte{}); err != nil {
+		return err
+	}
+	return root.Bucket(BY_HEIGHT).Put(append(Height(change.Output.Height), outpoint...), []byte{})
+}
+
+// deleteOutput removes an output and its index entries.
+func deleteOutput(root *bolt.Bucket, change *Change) error {
+	outpoint := Outpoint(change.Hash, change.Index)
+
+	if err := root.Bucket(OUTPUTS).Delete([]byte(outpoint)); err != nil {
+		return err
+	}
+	if err := root.Bucket(BY_ADDRESS).Delete([]byte(change.Address + ":" + outpoint)); err != nil {
+		return err
+	}
+	return root.Bucket(BY_HEIGHT).Delete(append(Height(change.Output.Height), outpoint...))
+}
+
+// putUpdated records the height scanned to, the hash of its block and
+// forgets blocks too old to be rolled back.
+func putUpdated(root *bolt.Bucket, updated Updated) error {
+	if updated.Hash != "" {
+		if err := root.Bucket(BLOCKS).Put(Height(updated.Height), []byte(updated.Hash)); err != nil {
+			return err
+		}
+	}
+
+	if updated.Height >= KEEP {
+		old := make([][]byte, 0)
+		c := root.Bucket(BLOCKS).Cursor()
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k) <= updated.Height-KEEP; k, _ = c.Next() {
+			old = append(old, k)
+		}
+		if err := deleteKeys(root.Bucket(BLOCKS), old); err != nil {
+			return err
+		}
+	}
+
+	return put(root.Bucket(META), UPDATED, updated)
+}
+
+// deleteKeys deletes keys found with a cursor, which skips entries when
+// deleting while iterating.
+func deleteKeys(b *bolt.Bucket, keys [][]byte) error {
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
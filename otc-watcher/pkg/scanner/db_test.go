@@ -0,0 +1,177 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func MockDB(t *testing.T) (*DB, string) {
+	dir, err := ioutil.TempDir("", "otc-watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(filepath.Join(dir, "otc-watcher.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, dir
+}
+
+func TestDBSave(t *testing.T) {
+	db, dir := MockDB(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	if storage, err := db.Load(otc.BTC); storage != nil || err != nil {
+		t.Fatalf("expected no storage, got %v %v", storage, err)
+	}
+
+	if err := db.Register(otc.BTC, "address", Updated{Height: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := []*Change{
+		{Address: "address", Hash: "transaction", Index: 1, Output: &otc.OutputVerbose{Amount: 1, Height: 11}},
+		{Address: "address", Hash: "transaction", Index: 2, Output: &otc.OutputVerbose{Amount: 2, Height: 12, Spent: "spender"}, Spend: true},
+	}
+	if err := db.Save(otc.BTC, Updated{Height: 14}, changes); err != nil {
+		t.Fatal(err)
+	}
+
+	storage, err := db.Load(otc.BTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := storage.Outputs("address")
+	if storage.Updated.Height != 14 || len(outputs["transaction"]) != 2 {
+		t.Fatalf("expected 2 outputs at 14, got %+v", storage)
+	}
+	if outputs["transaction"][1].Confirmations != 4 || outputs["transaction"][2].Spent != "spender" {
+		t.Fatalf("unexpected outputs %+v %+v", outputs["transaction"][1], outputs["transaction"][2])
+	}
+
+	outpoints, err := db.Outpoints(otc.BTC, "address")
+	if err != nil || len(outpoints) != 2 || outpoints[0] != "transaction:1" {
+		t.Fatalf("expected address index, got %v %v", outpoints, err)
+	}
+
+	above, err := db.Above(otc.BTC, 12)
+	if err != nil || len(above) != 1 || above[0] != "transaction:2" {
+		t.Fatalf("expected height index, got %v %v", above, err)
+	}
+}
+
+func TestDBMigrate(t *testing.T) {
+	db, dir := MockDB(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	filename := filepath.Join(dir, "BTC.json")
+	if storage, err := db.Migrate(otc.BTC, filename); storage != nil || err != nil {
+		t.Fatalf("expected nothing to migrate, got %v %v", storage, err)
+	}
+
+	old := NewStorage(otc.BTC)
+	old.Updated.Height = 20
+	old.Register("address")
+	old.Register("empty")
+	old.Addresses["address"].Outputs.Update("transaction", 1, &otc.OutputVerbose{Amount: 1, Height: 18})
+
+	data, err := json.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the old Save didn't truncate, leaving the end of longer versions
+	data = append(data, "\n  }\n}\n"...)
+	if err = ioutil.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.Migrate(otc.BTC, filename); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filename + ".migrated"); err != nil {
+		t.Fatal("expected file to be kept as .migrated")
+	}
+
+	storage, err := db.Load(otc.BTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storage.Updated.Height != 20 || storage.Addresses["empty"] == nil ||
+		storage.Outputs("address")["transaction"][1].Confirmations != 3 {
+		t.Fatalf("unexpected migrated storage %+v", storage)
+	}
+}
+
+func TestParseOutpoint(t *testing.T) {
+	hash, index, err := ParseOutpoint(Outpoint("transaction", 3))
+	if err != nil || hash != "transaction" || index != 3 {
+		t.Fatalf("got %s %d %v", hash, index, err)
+	}
+
+	if _, _, err = ParseOutpoint("transaction"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// MockWatcher is a chain that only finds the addresses it's told about.
+type MockWatcher struct {
+	MockChain
+	Watched []string
+	Err     error
+}
+
+func (w *MockWatcher) Watch(addr string) error {
+	if w.Err != nil {
+		return w.Err
+	}
+	w.Watched = append(w.Watched, addr)
+	return nil
+}
+
+func TestLoadWatch(t *testing.T) {
+	db, dir := MockDB(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	saved := NewStorage(otc.BTC)
+	saved.Register("saved")
+	if err := db.Import(otc.BTC, saved); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := &MockWatcher{MockChain: MockChain{Blocks: make(map[uint64]*otc.Block)}}
+	scnr := &Scanner{
+		Connections: currency.Connections{otc.BTC: watcher},
+		Scanning:    make(map[otc.Currency]*Storage),
+		DB:          db,
+	}
+	if err := scnr.Load(scnr.Connections); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scnr.Register(&otc.Drop{Address: "new", Currency: otc.BTC}); err != nil {
+		t.Fatal(err)
+	}
+	if len(watcher.Watched) != 2 || watcher.Watched[0] != "saved" || watcher.Watched[1] != "new" {
+		t.Fatalf("expected saved and new addresses watched, got %v", watcher.Watched)
+	}
+
+	watcher.Err = errors.New("unsupported")
+	if err := scnr.Register(&otc.Drop{Address: "bad", Currency: otc.BTC}); err != watcher.Err {
+		t.Fatalf("expected watch error, got %v", err)
+	}
+	if scnr.Scanning[otc.BTC].Watched("bad") {
+		t.Fatal("address that can't be watched shouldn't be registered")
+	}
+}
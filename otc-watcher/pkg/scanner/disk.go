@@ -1,70 +1,57 @@
 package scanner
 
 import (
-	"encoding/json"
-	"io"
-	"os"
+	"log"
 	"time"
 
-	"github.com/skycoin/services/otc/pkg/otc"
-
 	"github.com/skycoin/services/otc-watcher/pkg/currency"
 )
 
-func (s *Scanner) Save(cur otc.Currency) error {
-	file, err := os.OpenFile(string(cur)+".json", os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-
-	s.Scanning[cur].RLock()
-	defer s.Scanning[cur].RUnlock()
-
-	enc := json.NewEncoder(file)
-	enc.SetIndent("", "  ")
-
-	if err = enc.Encode(s.Scanning[cur]); err != nil {
-		return err
-	}
-
-	if err = file.Sync(); err != nil {
-		return err
-	}
-
-	return file.Close()
-}
-
+// Load reads the storage of every currency from the database, migrating a
+// JSON storage file on first start, or starts scanning from the latest
+// block.
 func (s *Scanner) Load(cons currency.Connections) error {
-	for cur, _ := range cons {
-		file, err := os.OpenFile(string(cur)+".json", os.O_CREATE|os.O_RDWR, 0644)
+	for cur := range cons {
+		storage, err := s.DB.Load(cur)
 		if err != nil {
 			return err
 		}
 
-		s.Scanning[cur] = NewStorage(cur)
+		if storage == nil {
+			if storage, err = s.DB.Migrate(cur, NewStorage(cur).Filename); err != nil {
+				return err
+			}
+		}
+
+		if storage == nil {
+			// get latest block height
+			height, err := cons.Height(cur)
+			if err != nil {
+				return err
+			}
 
-		if err = json.NewDecoder(file).Decode(s.Scanning[cur]); err != nil {
-			if err == io.EOF {
-				// get latest block height
-				height, err := cons.Height(cur)
-				if err != nil {
-					return err
-				}
+			// create and start scanning from latest block
+			storage = NewStorage(cur)
+			storage.Updated = &Updated{
+				Time:   time.Now().UTC().Unix(),
+				Height: height,
+			}
 
-				// create and start scanning from latest block
-				s.Scanning[cur].Updated = &Updated{
-					Time:   time.Now().UTC().Unix(),
-					Height: height,
-				}
-				s.Scanning[cur].Addresses = make(map[string]*Relevant, 0)
-			} else {
+			if err = s.DB.Import(cur, storage); err != nil {
 				return err
 			}
 		}
 
-		if err = file.Close(); err != nil {
-			return err
+		// backends that only look up watched addresses need to know them
+		if watcher, ok := cons[cur].(currency.Watcher); ok {
+			for addr := range storage.Addresses {
+				if err = watcher.Watch(addr); err != nil {
+					log.Printf("watching %s: %v\n", addr, err)
+				}
+			}
 		}
+
+		s.Scanning[cur] = storage
 	}
 
 	return nil
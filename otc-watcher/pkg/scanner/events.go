@@ -0,0 +1,232 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+	bolt "go.etcd.io/bbolt"
+)
+
+// event types
+const (
+	// an output to a watched address was mined
+	OUTPUT = "output"
+	// an output got another confirmation, up to DB.Confirmations
+	CONFIRMED = "confirmed"
+	// an output was spent
+	SPENT = "spent"
+	// blocks were rolled back, Reorg lists what was undone
+	REORG = "reorg"
+	// an unconfirmed transaction pays a watched address
+	PENDING = "pending"
+	// an unconfirmed transaction was replaced by another spending the same
+	// outputs, ReplacedBy
+	REPLACED = "replaced"
+	// an unconfirmed transaction left the mempool without being mined
+	EVICTED = "evicted"
+)
+
+// EVENTS_KEEP is how many events are kept for clients to catch up on.
+const EVENTS_KEEP = 100000
+
+// events of every currency, big endian id -> Event
+var EVENTS = []byte("events")
+
+// Event is something clients can subscribe to. Ids increase, so the last one
+// a client has seen is where it resumes from.
+type Event struct {
+	Id       uint64             `json:"id"`
+	Type     string             `json:"type"`
+	Currency otc.Currency       `json:"currency"`
+	Time     int64              `json:"time"`
+	Address  string             `json:"address,omitempty"`
+	Hash     string             `json:"hash,omitempty"`
+	Index    int                `json:"index"`
+	Output   *otc.OutputVerbose `json:"output,omitempty"`
+	Reorg    *Reorg             `json:"reorg,omitempty"`
+	// transaction that replaced an unconfirmed one
+	ReplacedBy string `json:"replaced_by,omitempty"`
+	// wallet and index the address was derived at
+	Derivation *Derivation `json:"derivation,omitempty"`
+}
+
+// Events returns up to limit events with ids above since.
+func (d *DB) Events(since uint64, limit int) ([]*Event, error) {
+	events := make([]*Event, 0)
+
+	err := d.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(EVENTS)
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		for k, v := c.Seek(Height(since + 1)); k != nil && len(events) < limit; k, v = c.Next() {
+			event := &Event{}
+			if err := json.Unmarshal(v, event); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+
+	return events, err
+}
+
+// LatestEvent returns the id of the last event added, 0 if none were.
+func (d *DB) LatestEvent() (uint64, error) {
+	var latest uint64
+
+	err := d.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(EVENTS); b != nil {
+			latest = b.Sequence()
+		}
+		return nil
+	})
+
+	return latest, err
+}
+
+// Publish adds events that aren't part of a block saved, e.g. of the
+// mempool.
+func (d *DB) Publish(events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	err := d.Update(func(tx *bolt.Tx) error {
+		for _, event := range events {
+			if err := addEvent(tx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notify()
+	return nil
+}
+
+// Added returns a channel closed when events are next added.
+func (d *DB) Added() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.added
+}
+
+func (d *DB) notify() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	close(d.added)
+	d.added = make(chan struct{})
+}
+
+// addEvents appends events for the changes of a block: outputs added and
+// spent, and confirmations of outputs in the last Confirmations blocks.
+func (d *DB) addEvents(tx *bolt.Tx, root *bolt.Bucket, cur otc.Currency, updated Updated, changes []*Change) error {
+	if err := addChanges(tx, cur, changes); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Unix()
+
+	if d.Confirmations < 2 || updated.Height < 1 {
+		return nil
+	}
+
+	// outputs below the block with at most Confirmations, those of the
+	// block itself got an output event
+	from := uint64(0)
+	if updated.Height+1 > d.Confirmations {
+		from = updated.Height + 1 - d.Confirmations
+	}
+
+	c := root.Bucket(BY_HEIGHT).Cursor()
+	for k, _ := c.Seek(Height(from)); k != nil && binary.BigEndian.Uint64(k) < updated.Height; k, _ = c.Next() {
+		stored := &Stored{}
+		if err := json.Unmarshal(root.Bucket(OUTPUTS).Get(k[8:]), stored); err != nil {
+			return err
+		}
+
+		hash, index, err := ParseOutpoint(string(k[8:]))
+		if err != nil {
+			return err
+		}
+
+		stored.Confirmations = updated.Height - stored.Height + 1
+		err = addEvent(tx, &Event{
+			Type:     CONFIRMED,
+			Currency: cur,
+			Time:     now,
+			Address:  stored.Address,
+			Hash:     hash,
+			Index:    index,
+			Output:   stored.OutputVerbose,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addChanges appends an output or spent event for each change.
+func addChanges(tx *bolt.Tx, cur otc.Currency, changes []*Change) error {
+	now := time.Now().UTC().Unix()
+
+	for _, change := range changes {
+		typ := OUTPUT
+		if change.Spend {
+			typ = SPENT
+		}
+
+		err := addEvent(tx, &Event{
+			Type:     typ,
+			Currency: cur,
+			Time:     now,
+			Address:  change.Address,
+			Hash:     change.Hash,
+			Index:    change.Index,
+			Output:   change.Output,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addEvent gives an event its id and appends it, dropping the oldest past
+// EVENTS_KEEP. Events of derived addresses tell where they were derived.
+func addEvent(tx *bolt.Tx, event *Event) error {
+	b, err := tx.CreateBucketIfNotExists(EVENTS)
+	if err != nil {
+		return err
+	}
+
+	if event.Address != "" && event.Derivation == nil {
+		event.Derivation = derivation(tx.Bucket([]byte(event.Currency)), event.Address)
+	}
+
+	if event.Id, err = b.NextSequence(); err != nil {
+		return err
+	}
+	if err = put(b, Height(event.Id), event); err != nil {
+		return err
+	}
+
+	if event.Id > EVENTS_KEEP {
+		return b.Delete(Height(event.Id - EVENTS_KEEP))
+	}
+	return nil
+}
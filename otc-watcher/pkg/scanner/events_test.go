@@ -0,0 +1,91 @@
+package scanner
+
+import (
+	"os"
+	"testing"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func TestEvents(t *testing.T) {
+	db, dir := MockDB(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+	db.Confirmations = 3
+
+	added := db.Added()
+
+	output := &otc.OutputVerbose{Amount: 1, Height: 10}
+	changes := []*Change{
+		{Address: "address", Hash: "transaction", Index: 1, Output: output},
+	}
+	if err := db.Save(otc.BTC, Updated{Height: 10}, changes); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-added:
+	default:
+		t.Fatal("expected waiting clients to be woken")
+	}
+
+	// confirmed at 11 and 12, not at 13 with 4
+	for height := uint64(11); height <= 13; height++ {
+		if err := db.Save(otc.BTC, Updated{Height: height}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spent := *output
+	spent.Spent, spent.SpentHeight = "spender", 14
+	changes = []*Change{
+		{Address: "address", Hash: "transaction", Index: 1, Output: &spent, Spend: true},
+	}
+	if err := db.Save(otc.BTC, Updated{Height: 14}, changes); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := db.Events(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	types := []string{OUTPUT, CONFIRMED, CONFIRMED, SPENT}
+	if len(events) != len(types) {
+		t.Fatalf("expected %d events, got %d", len(types), len(events))
+	}
+	for i, event := range events {
+		if event.Id != uint64(i+1) || event.Type != types[i] || event.Address != "address" {
+			t.Fatalf("unexpected event %d %+v", i, event)
+		}
+	}
+	if events[2].Output.Confirmations != 3 {
+		t.Fatalf("expected 3 confirmations, got %d", events[2].Output.Confirmations)
+	}
+
+	if events, _ = db.Events(2, 1); len(events) != 1 || events[0].Id != 3 {
+		t.Fatalf("expected event 3, got %+v", events)
+	}
+	if latest, _ := db.LatestEvent(); latest != 4 {
+		t.Fatalf("expected latest 4, got %d", latest)
+	}
+}
+
+func TestEventsReorg(t *testing.T) {
+	db, dir := MockDB(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	reorg := &Reorg{Currency: otc.BTC, Height: 9, Orphaned: []string{"a10"}}
+	if err := db.Rollback(otc.BTC, Updated{Height: 9}, nil, nil, reorg); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := db.Events(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != REORG || events[0].Reorg.Orphaned[0] != "a10" {
+		t.Fatalf("expected reorg event, got %+v", events)
+	}
+}
@@ -0,0 +1,359 @@
+package scanner
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc/pkg/otc"
+	bolt "go.etcd.io/bbolt"
+)
+
+var ErrNotFound = errors.New("nothing watched found")
+
+// Status is how far a currency is scanned, against its node's tip.
+type Status struct {
+	Currency otc.Currency `json:"currency"`
+	// block scanned last, and when
+	Height  uint64 `json:"height"`
+	Hash    string `json:"hash,omitempty"`
+	Updated int64  `json:"updated"`
+	// node's latest block, 0 if it couldn't be reached
+	Tip uint64 `json:"tip"`
+	// blocks left to scan
+	Lag       uint64 `json:"lag"`
+	Addresses int    `json:"addresses"`
+	// why the node couldn't be reached
+	Error string `json:"error,omitempty"`
+}
+
+// Watched is an output of a watched address.
+type Watched struct {
+	Address string             `json:"address"`
+	Hash    string             `json:"hash"`
+	Index   int                `json:"index"`
+	Output  *otc.OutputVerbose `json:"output"`
+}
+
+// TxSummary is what's known of a transaction for watched addresses.
+type TxSummary struct {
+	Currency otc.Currency `json:"currency"`
+	Hash     string       `json:"hash"`
+	// outputs it paid to watched addresses, and those it spent
+	Outputs []*Watched `json:"outputs"`
+	Spends  []*Watched `json:"spends"`
+	// its outputs to watched addresses if it isn't mined yet
+	Pending []*Pending `json:"pending,omitempty"`
+}
+
+// BlockSummary is a block as the node has it, with what it holds for
+// watched addresses.
+type BlockSummary struct {
+	Currency     otc.Currency `json:"currency"`
+	Height       uint64       `json:"height"`
+	Hash         string       `json:"hash"`
+	Previous     string       `json:"previous,omitempty"`
+	Time         int64        `json:"time"`
+	Transactions int          `json:"transactions"`
+	// whether the scanner got to it, and how many confirmations it has
+	Scanned       bool       `json:"scanned"`
+	Confirmations uint64     `json:"confirmations"`
+	Outputs       []*Watched `json:"outputs"`
+	Spends        []*Watched `json:"spends"`
+}
+
+// Unregister stops watching an address, forgetting its outputs.
+func (s *Scanner) Unregister(drop *otc.Drop) error {
+	storage := s.Scanning[drop.Currency]
+	if storage == nil {
+		return currency.ErrConnMissing
+	}
+
+	storage.Applying.Lock()
+	defer storage.Applying.Unlock()
+
+	return s.unregister(drop)
+}
+
+// unregister stops watching an address, with its storage's Applying held.
+func (s *Scanner) unregister(drop *otc.Drop) error {
+	storage := s.Scanning[drop.Currency]
+
+	removed := storage.Unregister(drop.Address)
+	if removed == nil {
+		return ErrAddressMissing
+	}
+
+	return s.DB.Unregister(drop.Currency, drop.Address, removed)
+}
+
+// Addresses returns the addresses watched in a currency, sorted.
+func (s *Scanner) Addresses(cur otc.Currency) ([]string, error) {
+	storage := s.Scanning[cur]
+	if storage == nil {
+		return nil, currency.ErrConnMissing
+	}
+	return storage.List(), nil
+}
+
+// Status returns how far a currency is scanned. A node that can't be
+// reached has its error set rather than returned.
+func (s *Scanner) Status(cur otc.Currency) (*Status, error) {
+	storage := s.Scanning[cur]
+	if storage == nil {
+		return nil, currency.ErrConnMissing
+	}
+
+	updated := storage.Status()
+	status := &Status{
+		Currency:  cur,
+		Height:    updated.Height,
+		Hash:      updated.Hash,
+		Updated:   updated.Time,
+		Addresses: len(storage.List()),
+	}
+
+	tip, err := s.Connections.Height(cur)
+	if err != nil {
+		status.Error = err.Error()
+		return status, nil
+	}
+
+	status.Tip = tip
+	if tip > updated.Height {
+		status.Lag = tip - updated.Height
+	}
+	return status, nil
+}
+
+// Statuses returns the status of every currency scanned, by name.
+func (s *Scanner) Statuses() []*Status {
+	currencies := make([]string, 0, len(s.Scanning))
+	for cur := range s.Scanning {
+		currencies = append(currencies, string(cur))
+	}
+	sort.Strings(currencies)
+
+	statuses := make([]*Status, 0, len(currencies))
+	for _, cur := range currencies {
+		status, _ := s.Status(otc.Currency(cur))
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Transaction returns the outputs a transaction paid to and spent from
+// watched addresses, and those pending if it isn't mined.
+func (s *Scanner) Transaction(cur otc.Currency, hash string) (*TxSummary, error) {
+	storage := s.Scanning[cur]
+	if storage == nil {
+		return nil, currency.ErrConnMissing
+	}
+
+	summary := &TxSummary{Currency: cur, Hash: hash}
+	summary.Outputs, summary.Spends = storage.Transaction(hash)
+
+	if pool := s.Pools[cur]; pool != nil {
+		summary.Pending = pool.Transaction(hash)
+	}
+
+	if len(summary.Outputs)+len(summary.Spends)+len(summary.Pending) == 0 {
+		return nil, ErrNotFound
+	}
+	return summary, nil
+}
+
+// Block fetches a block from the node and returns what it holds for
+// watched addresses.
+func (s *Scanner) Block(cur otc.Currency, height uint64) (*BlockSummary, error) {
+	storage := s.Scanning[cur]
+	if storage == nil {
+		return nil, currency.ErrConnMissing
+	}
+
+	block, err := s.Connections.Get(cur, height)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &BlockSummary{
+		Currency:     cur,
+		Height:       height,
+		Hash:         block.Hash,
+		Previous:     block.Previous,
+		Time:         block.Time,
+		Transactions: len(block.Transactions),
+	}
+
+	if scanned := storage.Status().Height; height <= scanned {
+		summary.Scanned = true
+		summary.Confirmations = scanned - height + 1
+	}
+
+	summary.Outputs, summary.Spends = storage.Find(block)
+	return summary, nil
+}
+
+// Unregister stops watching addr, returning the outputs it had, or nil if
+// it wasn't watched.
+func (s *Storage) Unregister(addr string) []*Change {
+	s.Lock()
+	defer s.Unlock()
+
+	rel := s.Addresses[addr]
+	if rel == nil {
+		return nil
+	}
+	delete(s.Addresses, addr)
+
+	rel.RLock()
+	defer rel.RUnlock()
+
+	removed := make([]*Change, 0)
+	for hash, outputs := range rel.Outputs {
+		for index, out := range outputs {
+			delete(s.byOutpoint, Outpoint(hash, index))
+			if out.Id != "" {
+				delete(s.byId, out.Id)
+			}
+			removed = append(removed, &Change{Address: addr, Hash: hash, Index: index, Output: out})
+		}
+	}
+
+	return removed
+}
+
+// List returns the watched addresses, sorted.
+func (s *Storage) List() []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	addrs := make([]string, 0, len(s.Addresses))
+	for addr := range s.Addresses {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// Transaction returns copies of the outputs a transaction paid to watched
+// addresses and of those it spent, going through every address.
+func (s *Storage) Transaction(hash string) ([]*Watched, []*Watched) {
+	s.RLock()
+	defer s.RUnlock()
+
+	outputs, spends := make([]*Watched, 0), make([]*Watched, 0)
+
+	for addr, rel := range s.Addresses {
+		rel.RLock()
+		for index, out := range rel.Outputs[hash] {
+			outputs = append(outputs, s.watched(addr, hash, index, out))
+		}
+		for outHash, outs := range rel.Outputs {
+			for index, out := range outs {
+				if out.Spent == hash {
+					spends = append(spends, s.watched(addr, outHash, index, out))
+				}
+			}
+		}
+		rel.RUnlock()
+	}
+
+	sortWatched(outputs)
+	sortWatched(spends)
+	return outputs, spends
+}
+
+// Find returns copies of the watched outputs a block added and spent, as
+// stored.
+func (s *Storage) Find(block *otc.Block) ([]*Watched, []*Watched) {
+	s.RLock()
+	defer s.RUnlock()
+
+	outputs, spends := make([]*Watched, 0), make([]*Watched, 0)
+
+	for hash, tx := range block.Transactions {
+		for index := range tx.Out {
+			if w := s.find(s.byOutpoint[Outpoint(hash, index)]); w != nil {
+				outputs = append(outputs, w)
+			}
+		}
+
+		for _, in := range tx.In {
+			loc := s.byOutpoint[Outpoint(in.Hash, in.Index)]
+			if in.Id != "" {
+				loc = s.byId[in.Id]
+			}
+			if w := s.find(loc); w != nil && w.Output.Spent == hash {
+				spends = append(spends, w)
+			}
+		}
+	}
+
+	sortWatched(outputs)
+	sortWatched(spends)
+	return outputs, spends
+}
+
+func (s *Storage) find(loc *Location) *Watched {
+	if loc == nil {
+		return nil
+	}
+
+	rel := s.Addresses[loc.Address]
+	rel.RLock()
+	defer rel.RUnlock()
+
+	return s.watched(loc.Address, loc.Hash, loc.Index, rel.Outputs[loc.Hash][loc.Index])
+}
+
+// watched copies an output with its confirmations at the height scanned.
+func (s *Storage) watched(addr, hash string, index int, out *otc.OutputVerbose) *Watched {
+	copied := *out
+	copied.Confirmations = 0
+	if out.Height <= s.Updated.Height {
+		copied.Confirmations = s.Updated.Height - out.Height + 1
+	}
+	return &Watched{Address: addr, Hash: hash, Index: index, Output: &copied}
+}
+
+func sortWatched(list []*Watched) {
+	sort.Slice(list, func(i, j int) bool {
+		return Outpoint(list[i].Hash, list[i].Index) < Outpoint(list[j].Hash, list[j].Index)
+	})
+}
+
+// Transaction returns copies of the pending outputs of a transaction.
+func (p *Pool) Transaction(hash string) []*Pending {
+	p.RLock()
+	defer p.RUnlock()
+
+	list := make([]*Pending, 0)
+	for _, pending := range p.Pending {
+		if pending.Hash == hash {
+			copied := *pending
+			list = append(list, &copied)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Index < list[j].Index })
+	return list
+}
+
+// Unregister forgets an address and its outputs.
+func (d *DB) Unregister(cur otc.Currency, addr string, removed []*Change) error {
+	return d.Update(func(tx *bolt.Tx) error {
+		root, err := buckets(tx, cur)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range removed {
+			if err = deleteOutput(root, change); err != nil {
+				return err
+			}
+		}
+
+		return root.Bucket(ADDRESSES).Delete([]byte(addr))
+	})
+}
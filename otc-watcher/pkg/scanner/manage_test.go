@@ -0,0 +1,192 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc/pkg/otc"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestManage(t *testing.T) {
+	db, dir := MockDB(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	chain := &MockChain{Blocks: map[uint64]*otc.Block{
+		1: MockBlock(1, "a1", "a0", "t1", 1),
+		2: MockBlock(2, "a2", "a1", "t2", 2),
+		3: MockBlock(3, "a3", "a2", "t3", 3, otc.Input{Hash: "t1", Index: 0}),
+	}}
+
+	storage := NewStorage(otc.BTC)
+	scnr := &Scanner{
+		Connections: currency.Connections{otc.BTC: chain},
+		Scanning:    map[otc.Currency]*Storage{otc.BTC: storage},
+		DB:          db,
+	}
+	for _, addr := range []string{"other", "address"} {
+		if err := scnr.Register(&otc.Drop{Address: addr, Currency: otc.BTC}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for height := uint64(1); height <= 3; height++ {
+		if err := scnr.Apply(otc.BTC, chain.Blocks[height]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if addrs, err := scnr.Addresses(otc.BTC); err != nil || len(addrs) != 2 || addrs[0] != "address" {
+		t.Fatalf("expected both addresses sorted, got %v %v", addrs, err)
+	}
+	if _, err := scnr.Addresses(otc.SKY); err != currency.ErrConnMissing {
+		t.Fatalf("expected missing connection, got %v", err)
+	}
+
+	chain.Blocks[4] = MockBlock(4, "a4", "a3", "t4", 4)
+	chain.Blocks[5] = MockBlock(5, "a5", "a4", "t5", 5)
+	status, err := scnr.Status(otc.BTC)
+	if err != nil || status.Height != 3 || status.Hash != "a3" || status.Tip != 5 || status.Lag != 2 || status.Addresses != 2 {
+		t.Fatalf("unexpected status %+v %v", status, err)
+	}
+	if statuses := scnr.Statuses(); len(statuses) != 1 || statuses[0].Currency != otc.BTC {
+		t.Fatalf("unexpected statuses %+v", statuses)
+	}
+
+	tx, err := scnr.Transaction(otc.BTC, "t3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Outputs) != 1 || tx.Outputs[0].Hash != "t3" || tx.Outputs[0].Output.Confirmations != 1 ||
+		len(tx.Spends) != 1 || tx.Spends[0].Hash != "t1" || tx.Spends[0].Output.Confirmations != 3 {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+	if _, err = scnr.Transaction(otc.BTC, "unknown"); err != ErrNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	block, err := scnr.Block(otc.BTC, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !block.Scanned || block.Confirmations != 1 || block.Hash != "a3" || block.Transactions != 1 ||
+		len(block.Outputs) != 1 || block.Outputs[0].Hash != "t3" || len(block.Spends) != 1 || block.Spends[0].Hash != "t1" {
+		t.Fatalf("unexpected block %+v", block)
+	}
+	if block, err = scnr.Block(otc.BTC, 5); err != nil || block.Scanned || len(block.Outputs) != 0 {
+		t.Fatalf("expected block 5 not scanned, got %+v %v", block, err)
+	}
+
+	// unregistering forgets the outputs, in memory and saved
+	if err = scnr.Unregister(&otc.Drop{Address: "address", Currency: otc.BTC}); err != nil {
+		t.Fatal(err)
+	}
+	if err = scnr.Unregister(&otc.Drop{Address: "address", Currency: otc.BTC}); err != ErrAddressMissing {
+		t.Fatalf("expected address missing, got %v", err)
+	}
+	if len(storage.byOutpoint) != 0 {
+		t.Fatalf("expected index emptied, got %v", storage.byOutpoint)
+	}
+	if _, err = scnr.Transaction(otc.BTC, "t3"); err != ErrNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	loaded, err := db.Load(otc.BTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Addresses["address"] != nil || loaded.Addresses["other"] == nil {
+		t.Fatalf("unexpected saved addresses %v", loaded.Addresses)
+	}
+	if outpoints, _ := db.Outpoints(otc.BTC, "address"); len(outpoints) != 0 {
+		t.Fatalf("expected saved outputs removed, got %v", outpoints)
+	}
+	if above, _ := db.Above(otc.BTC, 0); len(above) != 0 {
+		t.Fatalf("expected height index emptied, got %v", above)
+	}
+}
+
+func TestBackfillUnregistered(t *testing.T) {
+	scnr, _, dir := MockBackfill(t)
+	defer os.RemoveAll(dir)
+	defer scnr.DB.Close()
+
+	drop := &otc.Drop{Address: "address", Currency: otc.BTC}
+	if err := scnr.Register(drop); err != nil {
+		t.Fatal(err)
+	}
+	if err := scnr.Unregister(drop); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Backfill{Currency: otc.BTC, Address: "address", To: 200}
+	scnr.Rescan(b)
+
+	if b.Finished == 0 || b.Error != ErrAddressMissing.Error() || b.Found != 0 {
+		t.Fatalf("expected backfill to stop, got %+v", b)
+	}
+}
+
+func TestUnregisterApply(t *testing.T) {
+	db, dir := MockDB(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	scnr := &Scanner{
+		Connections: currency.Connections{otc.BTC: &MockChain{}},
+		Scanning:    map[otc.Currency]*Storage{otc.BTC: NewStorage(otc.BTC)},
+		DB:          db,
+	}
+	drop := &otc.Drop{Address: "address", Currency: otc.BTC}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			scnr.Register(drop)
+			scnr.Outputs(drop)
+			scnr.Unregister(drop)
+		}
+	}()
+
+	for height := uint64(1); height <= 200; height++ {
+		block := MockBlock(height, fmt.Sprintf("a%d", height), fmt.Sprintf("a%d", height-1), fmt.Sprintf("t%d", height), 1)
+		if err := scnr.Apply(otc.BTC, block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	// no output is saved for an address that isn't
+	err := db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(otc.BTC))
+		return root.Bucket(OUTPUTS).ForEach(func(k, v []byte) error {
+			stored := &Stored{}
+			if err := json.Unmarshal(v, stored); err != nil {
+				return err
+			}
+			if root.Bucket(ADDRESSES).Get([]byte(stored.Address)) == nil {
+				return fmt.Errorf("output %s of unregistered address %s", k, stored.Address)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one left behind anyway doesn't stop the watcher from starting
+	err = db.Update(func(tx *bolt.Tx) error {
+		output := &otc.OutputVerbose{Amount: 1, Height: 1}
+		return putOutput(tx.Bucket([]byte(otc.BTC)), &Change{Address: "gone", Hash: "orphan", Output: output})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := db.Load(otc.BTC); err != nil || loaded.Addresses["gone"] != nil {
+		t.Fatalf("expected the orphan output skipped, got %v", err)
+	}
+}
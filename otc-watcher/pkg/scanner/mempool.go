@@ -0,0 +1,354 @@
+package scanner
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// how often mempools are polled, and how long outputs replaced or evicted
+// are still listed
+var (
+	MEMPOOL_POLL = time.Second * 10
+	MEMPOOL_KEEP = time.Hour
+)
+
+var ErrNoMempool = errors.New("mempool not watched")
+
+// Pending is an output to a watched address of a transaction that isn't
+// mined yet. Once it is, it's one of the address's outputs instead.
+type Pending struct {
+	Address string             `json:"address"`
+	Hash    string             `json:"hash"`
+	Index   int                `json:"index"`
+	Output  *otc.OutputVerbose `json:"output"`
+	// PENDING, REPLACED or EVICTED
+	Status string `json:"status"`
+	// transaction spending the same outputs that replaced it
+	ReplacedBy string `json:"replaced_by,omitempty"`
+	Seen       int64  `json:"seen"`
+	Changed    int64  `json:"changed,omitempty"`
+}
+
+// Pool follows the unconfirmed transactions of a currency that pay watched
+// addresses. Only what's in memory is known, after a restart the first poll
+// finds them again.
+type Pool struct {
+	sync.RWMutex
+
+	Currency otc.Currency
+	// outpoint -> Pending
+	Pending map[string]*Pending
+
+	// ids of the transactions followed and what they spend, to tell when
+	// another replaces them
+	transactions map[string]*otc.Transaction
+	spends       map[string]string
+	// ids of mempool transactions already looked at
+	seen map[string]bool
+	// ids of transactions followed that left the mempool -> height scanned
+	// then, they're evicted if a later block doesn't have them
+	missing map[string]uint64
+}
+
+func NewPool(cur otc.Currency) *Pool {
+	return &Pool{
+		Currency:     cur,
+		Pending:      make(map[string]*Pending),
+		transactions: make(map[string]*otc.Transaction),
+		spends:       make(map[string]string),
+		seen:         make(map[string]bool),
+		missing:      make(map[string]uint64),
+	}
+}
+
+// Watch polls the mempool of a currency every MEMPOOL_POLL until the
+// scanner stops.
+func (s *Scanner) Watch(cur otc.Currency) {
+	for {
+		if err := s.Poll(cur); err != nil {
+			log.Printf("mempool %s: %v\n", cur, err)
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(MEMPOOL_POLL):
+		}
+	}
+}
+
+// Poll looks at the transactions added to a currency's mempool since the
+// last poll and those gone from it, and adds events for what changed.
+func (s *Scanner) Poll(cur otc.Currency) error {
+	pool, storage := s.Pools[cur], s.Scanning[cur]
+	mempool, ok := s.Connections[cur].(currency.Mempool)
+	if pool == nil || !ok {
+		return ErrNoMempool
+	}
+
+	ids, err := mempool.Mempool()
+	if err != nil {
+		return err
+	}
+
+	events := pool.Missing(ids, storage.Status().Height)
+
+	for _, id := range pool.Unseen(ids) {
+		tx, err := mempool.Unconfirmed(id)
+		if err != nil {
+			// mined or dropped meanwhile
+			continue
+		}
+		events = append(events, pool.Add(tx, storage.Watched)...)
+	}
+
+	return s.DB.Publish(events)
+}
+
+// Pending returns the unconfirmed outputs of an address, and those replaced
+// or evicted lately, oldest first.
+func (s *Scanner) Pending(drop *otc.Drop) ([]*Pending, error) {
+	if s.Scanning[drop.Currency] == nil {
+		return nil, currency.ErrConnMissing
+	}
+	pool := s.Pools[drop.Currency]
+	if pool == nil {
+		return nil, ErrNoMempool
+	}
+	if !s.Scanning[drop.Currency].Watched(drop.Address) {
+		return nil, ErrAddressMissing
+	}
+
+	return pool.List(drop.Address), nil
+}
+
+// Unseen returns the ids not looked at yet, forgetting those no longer in
+// the mempool.
+func (p *Pool) Unseen(ids []string) []string {
+	p.Lock()
+	defer p.Unlock()
+
+	unseen := make([]string, 0)
+	seen := make(map[string]bool, len(ids))
+
+	for _, id := range ids {
+		if !p.seen[id] {
+			unseen = append(unseen, id)
+		}
+		seen[id] = true
+	}
+
+	p.seen = seen
+	return unseen
+}
+
+// Add follows a transaction if it pays a watched address, replacing those
+// spending any of the same outputs.
+func (p *Pool) Add(tx *otc.Transaction, watched func(string) bool) []*Event {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.transactions[tx.Id] != nil {
+		// back in the mempool before it was evicted
+		return nil
+	}
+
+	events := p.conflicts(tx)
+	now := time.Now().UTC().Unix()
+
+	for index, out := range tx.Out {
+		for _, addr := range out.Addresses {
+			if !watched(addr) {
+				continue
+			}
+
+			pending := &Pending{
+				Address: addr,
+				Hash:    tx.Id,
+				Index:   index,
+				Output: &otc.OutputVerbose{
+					Id:     out.Id,
+					Amount: out.Amount,
+					Hours:  out.Hours,
+					TxHash: tx.Hash,
+				},
+				Status: PENDING,
+				Seen:   now,
+			}
+			p.Pending[Outpoint(tx.Id, index)] = pending
+			events = append(events, p.event(pending, now))
+
+			if p.transactions[tx.Id] == nil {
+				p.transactions[tx.Id] = tx
+				for _, in := range tx.In {
+					p.spends[spent(in)] = tx.Id
+				}
+			}
+		}
+	}
+
+	return events
+}
+
+// Missing evicts the transactions followed that left the mempool and
+// weren't in the block scanned next.
+func (p *Pool) Missing(ids []string, height uint64) []*Event {
+	p.Lock()
+	defer p.Unlock()
+
+	events := make([]*Event, 0)
+	now := time.Now().UTC().Unix()
+
+	in := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		in[id] = true
+	}
+
+	for id := range p.transactions {
+		if in[id] {
+			delete(p.missing, id)
+			continue
+		}
+
+		left, ok := p.missing[id]
+		if !ok {
+			// the node may have mined it in a block not scanned yet
+			p.missing[id] = height
+			continue
+		}
+
+		if height > left {
+			events = append(events, p.drop(id, EVICTED, "", now)...)
+		}
+	}
+
+	p.prune(now)
+	return events
+}
+
+// Mined stops following the transactions of a block, their outputs are
+// now the address's, and replaces those spending the same outputs.
+func (p *Pool) Mined(block *otc.Block) []*Event {
+	p.Lock()
+	defer p.Unlock()
+
+	events := make([]*Event, 0)
+
+	for _, tx := range block.Transactions {
+		if p.transactions[tx.Id] != nil {
+			p.forget(tx.Id)
+			for outpoint, pending := range p.Pending {
+				if pending.Hash == tx.Id {
+					delete(p.Pending, outpoint)
+				}
+			}
+			continue
+		}
+
+		events = append(events, p.conflicts(tx)...)
+	}
+
+	return events
+}
+
+// List returns an address's pending outputs, oldest first.
+func (p *Pool) List(addr string) []*Pending {
+	p.RLock()
+	defer p.RUnlock()
+
+	list := make([]*Pending, 0)
+	for _, pending := range p.Pending {
+		if pending.Address == addr {
+			copied := *pending
+			list = append(list, &copied)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Seen != list[j].Seen {
+			return list[i].Seen < list[j].Seen
+		}
+		return Outpoint(list[i].Hash, list[i].Index) < Outpoint(list[j].Hash, list[j].Index)
+	})
+	return list
+}
+
+// conflicts replaces the transactions followed that spend what tx spends.
+func (p *Pool) conflicts(tx *otc.Transaction) []*Event {
+	events := make([]*Event, 0)
+	now := time.Now().UTC().Unix()
+
+	for _, in := range tx.In {
+		if id := p.spends[spent(in)]; id != "" && id != tx.Id {
+			events = append(events, p.drop(id, REPLACED, tx.Id, now)...)
+		}
+	}
+
+	return events
+}
+
+// drop stops following a transaction, keeping its outputs listed with the
+// status they ended with.
+func (p *Pool) drop(id, status, by string, now int64) []*Event {
+	events := make([]*Event, 0)
+
+	for _, pending := range p.Pending {
+		if pending.Hash == id && pending.Status == PENDING {
+			pending.Status, pending.ReplacedBy, pending.Changed = status, by, now
+			events = append(events, p.event(pending, now))
+		}
+	}
+
+	p.forget(id)
+	return events
+}
+
+func (p *Pool) forget(id string) {
+	if tx := p.transactions[id]; tx != nil {
+		for _, in := range tx.In {
+			if p.spends[spent(in)] == id {
+				delete(p.spends, spent(in))
+			}
+		}
+	}
+
+	delete(p.transactions, id)
+	delete(p.missing, id)
+}
+
+// prune drops outputs replaced or evicted more than MEMPOOL_KEEP ago.
+func (p *Pool) prune(now int64) {
+	for outpoint, pending := range p.Pending {
+		if pending.Status != PENDING && now-pending.Changed > int64(MEMPOOL_KEEP/time.Second) {
+			delete(p.Pending, outpoint)
+		}
+	}
+}
+
+func (p *Pool) event(pending *Pending, now int64) *Event {
+	output := *pending.Output
+	return &Event{
+		Type:       pending.Status,
+		Currency:   p.Currency,
+		Time:       now,
+		Address:    pending.Address,
+		Hash:       pending.Hash,
+		Index:      pending.Index,
+		Output:     &output,
+		ReplacedBy: pending.ReplacedBy,
+	}
+}
+
+// spent is how an input refers to the output it spends.
+func spent(in otc.Input) string {
+	if in.Id != "" {
+		return in.Id
+	}
+	return Outpoint(in.Hash, in.Index)
+}
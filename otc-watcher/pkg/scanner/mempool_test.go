@@ -0,0 +1,200 @@
+package scanner
+
+import (
+	"os"
+	"testing"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// MockMempool is a chain with unconfirmed transactions by id.
+type MockMempool struct {
+	MockChain
+	Unmined map[string]*otc.Transaction
+}
+
+func (m *MockMempool) Mempool() ([]string, error) {
+	ids := make([]string, 0, len(m.Unmined))
+	for id := range m.Unmined {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (m *MockMempool) Unconfirmed(id string) (*otc.Transaction, error) {
+	return m.Unmined[id], nil
+}
+
+// MockUnconfirmed pays amount to addr, spending one output.
+func MockUnconfirmed(id, addr string, amount uint64, spends otc.Input) *otc.Transaction {
+	return &otc.Transaction{
+		Id:   id,
+		Hash: id,
+		In:   []otc.Input{spends},
+		Out: map[int]*otc.Output{
+			0: &otc.Output{Amount: amount, Addresses: []string{addr}},
+		},
+	}
+}
+
+func MockPool(t *testing.T) (*Scanner, *MockMempool, string) {
+	db, dir := MockDB(t)
+
+	mempool := &MockMempool{
+		MockChain: MockChain{Blocks: make(map[uint64]*otc.Block)},
+		Unmined:   make(map[string]*otc.Transaction),
+	}
+
+	storage := NewStorage(otc.BTC)
+	storage.Updated.Height = 10
+	scnr := &Scanner{
+		Connections: currency.Connections{otc.BTC: mempool},
+		Scanning:    map[otc.Currency]*Storage{otc.BTC: storage},
+		DB:          db,
+		Pools:       map[otc.Currency]*Pool{otc.BTC: NewPool(otc.BTC)},
+	}
+	if err := scnr.Register(&otc.Drop{Address: "address", Currency: otc.BTC}); err != nil {
+		t.Fatal(err)
+	}
+
+	return scnr, mempool, dir
+}
+
+func Types(t *testing.T, db *DB, since uint64) []string {
+	events, err := db.Events(since, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type+":"+event.Hash)
+	}
+	return types
+}
+
+func TestPoolReplaced(t *testing.T) {
+	scnr, mempool, dir := MockPool(t)
+	defer os.RemoveAll(dir)
+	defer scnr.DB.Close()
+
+	drop := &otc.Drop{Address: "address", Currency: otc.BTC}
+	mempool.Unmined["t1"] = MockUnconfirmed("t1", "address", 1, otc.Input{Hash: "funding", Index: 0})
+	mempool.Unmined["other"] = MockUnconfirmed("other", "unwatched", 1, otc.Input{Hash: "funding", Index: 1})
+
+	if err := scnr.Poll(otc.BTC); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := scnr.Pending(drop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Hash != "t1" || pending[0].Status != PENDING || pending[0].Output.Amount != 1 {
+		t.Fatalf("expected t1 pending, got %+v", pending)
+	}
+
+	// bumped fee, paying less
+	delete(mempool.Unmined, "t1")
+	mempool.Unmined["t2"] = MockUnconfirmed("t2", "address", 0, otc.Input{Hash: "funding", Index: 0})
+
+	if err = scnr.Poll(otc.BTC); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, _ = scnr.Pending(drop)
+	if len(pending) != 2 || pending[0].Status != REPLACED || pending[0].ReplacedBy != "t2" || pending[1].Status != PENDING {
+		t.Fatalf("expected t1 replaced by t2, got %+v %+v", pending[0], pending[1])
+	}
+
+	types := Types(t, scnr.DB, 0)
+	if len(types) != 3 || types[0] != "pending:t1" || types[1] != "replaced:t1" || types[2] != "pending:t2" {
+		t.Fatalf("unexpected events %v", types)
+	}
+}
+
+func TestPoolMined(t *testing.T) {
+	scnr, mempool, dir := MockPool(t)
+	defer os.RemoveAll(dir)
+	defer scnr.DB.Close()
+
+	drop := &otc.Drop{Address: "address", Currency: otc.BTC}
+	mempool.Unmined["t1"] = MockUnconfirmed("t1", "address", 1, otc.Input{Hash: "funding", Index: 0})
+
+	if err := scnr.Poll(otc.BTC); err != nil {
+		t.Fatal(err)
+	}
+
+	// the node mines it before the scanner gets the block
+	delete(mempool.Unmined, "t1")
+	if err := scnr.Poll(otc.BTC); err != nil {
+		t.Fatal(err)
+	}
+
+	block := MockBlock(11, "", "", "t1", 1)
+	if err := scnr.Apply(otc.BTC, block); err != nil {
+		t.Fatal(err)
+	}
+	if err := scnr.Poll(otc.BTC); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending, _ := scnr.Pending(drop); len(pending) != 0 {
+		t.Fatalf("expected nothing pending once mined, got %+v", pending)
+	}
+	if outputs, _ := scnr.Outputs(drop); outputs["t1"] == nil {
+		t.Fatal("expected t1 an output once mined")
+	}
+
+	types := Types(t, scnr.DB, 0)
+	if len(types) != 2 || types[0] != "pending:t1" || types[1] != "output:t1" {
+		t.Fatalf("unexpected events %v", types)
+	}
+}
+
+func TestPoolEvicted(t *testing.T) {
+	scnr, mempool, dir := MockPool(t)
+	defer os.RemoveAll(dir)
+	defer scnr.DB.Close()
+
+	drop := &otc.Drop{Address: "address", Currency: otc.BTC}
+	mempool.Unmined["t1"] = MockUnconfirmed("t1", "address", 1, otc.Input{Hash: "funding", Index: 0})
+
+	if err := scnr.Poll(otc.BTC); err != nil {
+		t.Fatal(err)
+	}
+
+	delete(mempool.Unmined, "t1")
+	if err := scnr.Poll(otc.BTC); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := scnr.Pending(drop); pending[0].Status != PENDING {
+		t.Fatal("expected t1 pending until a block without it is scanned")
+	}
+
+	if err := scnr.Apply(otc.BTC, MockBlock(11, "", "", "unrelated", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := scnr.Poll(otc.BTC); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending, _ := scnr.Pending(drop); len(pending) != 1 || pending[0].Status != EVICTED {
+		t.Fatalf("expected t1 evicted, got %+v", pending)
+	}
+}
+
+func TestPoolMissing(t *testing.T) {
+	scnr := &Scanner{
+		Connections: currency.Connections{otc.BTC: &MockChain{}},
+		Scanning:    map[otc.Currency]*Storage{otc.BTC: NewStorage(otc.BTC)},
+	}
+
+	if err := scnr.Poll(otc.BTC); err != ErrNoMempool {
+		t.Fatalf("expected %v, got %v", ErrNoMempool, err)
+	}
+	if _, err := scnr.Pending(&otc.Drop{Address: "address", Currency: otc.BTC}); err != ErrNoMempool {
+		t.Fatalf("expected %v, got %v", ErrNoMempool, err)
+	}
+}
@@ -0,0 +1,142 @@
+package scanner
+
+import (
+	"log"
+	"time"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// KEEP is how many recent block hashes are kept to detect reorganisations.
+// Deeper ones are rolled back to the oldest block kept.
+const KEEP = 100
+
+// Reorg is a chain reorganisation the scanner rolled back.
+type Reorg struct {
+	Id       uint64       `json:"id"`
+	Currency otc.Currency `json:"currency"`
+	Time     int64        `json:"time"`
+	// last block both branches have
+	Height uint64 `json:"height"`
+	// hashes of the orphaned blocks, lowest first
+	Orphaned []string `json:"orphaned"`
+	// outpoints (hash:index) of outputs removed and no longer spent
+	Removed []string `json:"removed"`
+	Unspent []string `json:"unspent"`
+}
+
+// Reorg rolls storage back if block isn't on the branch scanned so far,
+// then scans the new branch up to it.
+func (s *Scanner) Reorg(cur otc.Currency, block *otc.Block) error {
+	fork, found, err := s.Fork(cur, block)
+	if err != nil || !found {
+		return err
+	}
+
+	storage := s.Scanning[cur]
+	storage.Applying.Lock()
+	removed, unspent, orphaned := storage.Rollback(fork)
+
+	reorg := &Reorg{
+		Currency: cur,
+		Time:     time.Now().UTC().Unix(),
+		Height:   fork,
+		Orphaned: orphaned,
+		Removed:  outpoints(removed),
+		Unspent:  outpoints(unspent),
+	}
+	log.Printf("reorg: %s rolled back to block %d, %d blocks orphaned, %d outputs removed\n",
+		cur, fork, len(orphaned), len(removed))
+
+	err = s.DB.Rollback(cur, storage.Status(), removed, unspent, reorg)
+	storage.Applying.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for height := fork + 1; height < block.Height; height++ {
+		b, err := s.Connections.Get(cur, height)
+		if err != nil {
+			return err
+		}
+		if err = s.Apply(cur, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Fork compares the blocks scanned with the branch block is on, from the
+// top down, and returns the last block they have in common if any scanned
+// block isn't on it.
+func (s *Scanner) Fork(cur otc.Currency, block *otc.Block) (uint64, bool, error) {
+	storage := s.Scanning[cur]
+
+	// connections not reporting hashes can't be checked
+	if block.Hash == "" || block.Height == 0 {
+		return 0, false, nil
+	}
+
+	height := storage.Status().Height
+	if height > block.Height {
+		height = block.Height
+	}
+
+	differ := false
+	for ; height > 0; height-- {
+		scanned := storage.Block(height)
+		if scanned == "" {
+			if differ {
+				log.Printf("reorg: %s deeper than %d blocks\n", cur, KEEP)
+			}
+			// nothing older to compare
+			return height, differ, nil
+		}
+
+		hash, err := s.branch(cur, block, height)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if hash == scanned {
+			return height, differ, nil
+		}
+		differ = true
+	}
+
+	return 0, differ, nil
+}
+
+// branch returns the hash of the block at height on block's branch.
+func (s *Scanner) branch(cur otc.Currency, block *otc.Block, height uint64) (string, error) {
+	switch height {
+	case block.Height:
+		return block.Hash, nil
+	case block.Height - 1:
+		return block.Previous, nil
+	}
+
+	b, err := s.Connections.Get(cur, height)
+	if err != nil {
+		return "", err
+	}
+	return b.Hash, nil
+}
+
+// Reorgs returns the reorganisations of a currency with ids above since.
+func (s *Scanner) Reorgs(cur otc.Currency, since uint64) ([]*Reorg, error) {
+	if s.Scanning[cur] == nil {
+		return nil, currency.ErrConnMissing
+	}
+	return s.DB.Reorgs(cur, since)
+}
+
+func outpoints(changes []*Change) []string {
+	list := make([]string, 0, len(changes))
+	for _, change := range changes {
+		list = append(list, Outpoint(change.Hash, change.Index))
+	}
+	return list
+}
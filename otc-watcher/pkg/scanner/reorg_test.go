@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"os"
+	"testing"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// MockChain serves the blocks of its current branch by height.
+type MockChain struct {
+	Blocks map[uint64]*otc.Block
+}
+
+func (c *MockChain) Stop() error                            { return nil }
+func (c *MockChain) Scan(h uint64) (chan *otc.Block, error) { return nil, nil }
+func (c *MockChain) Get(h uint64) (*otc.Block, error)       { return c.Blocks[h], nil }
+func (c *MockChain) Height() (uint64, error)                { return uint64(len(c.Blocks)), nil }
+
+// MockBlock pays amount to address in transaction id, spending the given
+// outputs.
+func MockBlock(height uint64, hash, previous, id string, amount uint64, spends ...otc.Input) *otc.Block {
+	return &otc.Block{
+		Height:   height,
+		Hash:     hash,
+		Previous: previous,
+		Transactions: map[string]*otc.Transaction{
+			id: &otc.Transaction{
+				Id:   id,
+				Hash: id,
+				In:   spends,
+				Out: map[int]*otc.Output{
+					0: &otc.Output{Amount: amount, Addresses: []string{"address"}},
+				},
+			},
+		},
+	}
+}
+
+func TestReorg(t *testing.T) {
+	db, dir := MockDB(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	chain := &MockChain{Blocks: map[uint64]*otc.Block{
+		1: MockBlock(1, "a1", "a0", "t1", 1),
+		2: MockBlock(2, "a2", "a1", "t2", 2),
+		3: MockBlock(3, "a3", "a2", "t3", 3, otc.Input{Hash: "t1", Index: 0}),
+	}}
+
+	storage := NewStorage(otc.BTC)
+	scnr := &Scanner{
+		Connections: currency.Connections{otc.BTC: chain},
+		Scanning:    map[otc.Currency]*Storage{otc.BTC: storage},
+		DB:          db,
+	}
+	if err := scnr.Register(&otc.Drop{Address: "address", Currency: otc.BTC}); err != nil {
+		t.Fatal(err)
+	}
+
+	for height := uint64(1); height <= 3; height++ {
+		if err := scnr.Reorg(otc.BTC, chain.Blocks[height]); err != nil {
+			t.Fatal(err)
+		}
+		if err := scnr.Apply(otc.BTC, chain.Blocks[height]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if storage.Addresses["address"].Outputs["t1"][0].Spent != "t3" {
+		t.Fatal("expected t1 spent by t3")
+	}
+
+	// blocks 3 and 4 of another branch replace block 3
+	chain.Blocks[3] = MockBlock(3, "b3", "a2", "u3", 30)
+	chain.Blocks[4] = MockBlock(4, "b4", "b3", "u4", 40)
+
+	if err := scnr.Reorg(otc.BTC, chain.Blocks[4]); err != nil {
+		t.Fatal(err)
+	}
+	if err := scnr.Apply(otc.BTC, chain.Blocks[4]); err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := storage.Addresses["address"].Outputs
+	if outputs["t3"] != nil || outputs["u3"] == nil || outputs["u4"] == nil {
+		t.Fatalf("expected branch b outputs, got %v", outputs)
+	}
+	if outputs["t1"][0].Spent != "" {
+		t.Fatal("expected t1 no longer spent")
+	}
+	if storage.Block(3) != "b3" || storage.Status().Height != 4 {
+		t.Fatalf("expected b3 at 4, got %s at %d", storage.Block(3), storage.Status().Height)
+	}
+
+	reorgs, err := scnr.Reorgs(otc.BTC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reorgs) != 1 || reorgs[0].Id != 1 || reorgs[0].Height != 2 ||
+		len(reorgs[0].Orphaned) != 1 || reorgs[0].Orphaned[0] != "a3" ||
+		len(reorgs[0].Removed) != 1 || reorgs[0].Removed[0] != "t3:0" ||
+		len(reorgs[0].Unspent) != 1 || reorgs[0].Unspent[0] != "t1:0" {
+		t.Fatalf("unexpected reorgs %+v", reorgs)
+	}
+	if reorgs, _ = scnr.Reorgs(otc.BTC, 1); len(reorgs) != 0 {
+		t.Fatalf("expected none after 1, got %+v", reorgs)
+	}
+
+	// what was saved matches
+	loaded, err := db.Load(otc.BTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Addresses["address"].Outputs["t3"] != nil || loaded.Addresses["address"].Outputs["t1"][0].Spent != "" ||
+		loaded.Blocks[3] != "b3" || loaded.Blocks[4] != "b4" {
+		t.Fatalf("unexpected saved storage %+v", loaded)
+	}
+	if above, _ := db.Above(otc.BTC, 3); len(above) != 2 {
+		t.Fatalf("expected u3 and u4 above 3, got %v", above)
+	}
+}
+
+func TestForkUnchecked(t *testing.T) {
+	storage := NewStorage(otc.BTC)
+	storage.Updated.Height = 10
+	scnr := &Scanner{Scanning: map[otc.Currency]*Storage{otc.BTC: storage}}
+
+	// no hashes kept, e.g. after migrating
+	if _, found, err := scnr.Fork(otc.BTC, MockBlock(11, "b11", "b10", "t", 1)); found || err != nil {
+		t.Fatalf("expected no fork, got %v %v", found, err)
+	}
+
+	// connection without hashes
+	storage.Blocks[10] = "a10"
+	if _, found, err := scnr.Fork(otc.BTC, MockBlock(11, "", "", "t", 1)); found || err != nil {
+		t.Fatalf("expected no fork, got %v %v", found, err)
+	}
+
+	if _, found, _ := scnr.Fork(otc.BTC, MockBlock(11, "b11", "a10", "t", 1)); found {
+		t.Fatal("expected no fork on the same branch")
+	}
+}
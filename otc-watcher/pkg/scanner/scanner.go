@@ -2,8 +2,9 @@ package scanner
 
 import (
 	"errors"
-
 	"log"
+	"sync"
+	"time"
 
 	"github.com/skycoin/services/otc-watcher/pkg/currency"
 	"github.com/skycoin/services/otc/pkg/otc"
@@ -16,15 +17,41 @@ var (
 type Scanner struct {
 	Connections currency.Connections
 	Scanning    map[otc.Currency]*Storage
+	DB          *DB
+	// unconfirmed transactions of currencies whose connection sees them
+	Pools map[otc.Currency]*Pool
+	// extended keys and descriptors addresses are derived from
+	Wallets map[otc.Currency]*Wallets
+
+	// closed on Stop, which waits for the backfills and mempool watchers
+	// running
+	stop    chan struct{}
+	running sync.WaitGroup
 }
 
-func New(cons currency.Connections) (*Scanner, error) {
-	s := &Scanner{cons, make(map[otc.Currency]*Storage, 0)}
+// New scans with cons, keeping what it finds in db, which it closes on Stop.
+func New(cons currency.Connections, db *DB) (*Scanner, error) {
+	s := &Scanner{
+		Connections: cons,
+		Scanning:    make(map[otc.Currency]*Storage, 0),
+		DB:          db,
+		Pools:       make(map[otc.Currency]*Pool),
+		Wallets:     make(map[otc.Currency]*Wallets),
+		stop:        make(chan struct{}),
+	}
 
 	// load from disk or create
 	if err := s.Load(cons); err != nil {
 		return nil, err
 	}
+	if err := s.LoadWallets(); err != nil {
+		return nil, err
+	}
+
+	// carry on with backfills cut short
+	if err := s.Resume(); err != nil {
+		return nil, err
+	}
 
 	// for each connection (supported currency)
 	for cur, con := range cons {
@@ -35,6 +62,16 @@ func New(cons currency.Connections) (*Scanner, error) {
 		}
 		// start scanning
 		go s.Scan(cur, blocks)
+
+		if _, ok := con.(currency.Mempool); ok {
+			s.Pools[cur] = NewPool(cur)
+
+			s.running.Add(1)
+			go func(cur otc.Currency) {
+				defer s.running.Done()
+				s.Watch(cur)
+			}(cur)
+		}
 	}
 
 	return s, nil
@@ -43,16 +80,18 @@ func New(cons currency.Connections) (*Scanner, error) {
 func (s *Scanner) Stop() error {
 	var err error
 
-	for cur, con := range s.Connections {
+	if s.stop != nil {
+		close(s.stop)
+	}
+	s.running.Wait()
+
+	for _, con := range s.Connections {
 		if err = con.Stop(); err != nil {
 			return err
 		}
-		if err = s.Save(cur); err != nil {
-			return err
-		}
 	}
 
-	return nil
+	return s.DB.Close()
 }
 
 func (s *Scanner) Scan(cur otc.Currency, blocks chan *otc.Block) {
@@ -62,30 +101,62 @@ func (s *Scanner) Scan(cur otc.Currency, blocks chan *otc.Block) {
 		// TODO: use logger
 		log.Printf("scanning block %d\n", block.Height)
 
-		// update storage based on received block
-		s.Scanning[cur].Update(block)
+		// roll back blocks that aren't on its branch
+		if err := s.Reorg(cur, block); err != nil {
+			log.Printf("reorg at block %d: %v\n", block.Height, err)
+		}
 
-		// TODO: handle error better
-		//
-		// TODO: move Save function to *Storage so there's no need to pass
-		//       cur as a param
-		if err := s.Save(cur); err != nil {
-			println(err.Error())
+		// TODO: handle error better, the block is scanned again after a
+		//       restart as the saved height wasn't updated
+		if err := s.Apply(cur, block); err != nil {
+			log.Printf("saving block %d: %v\n", block.Height, err)
 		}
 	}
 }
 
+// Apply updates storage based on a block and saves what changed.
+func (s *Scanner) Apply(cur otc.Currency, block *otc.Block) error {
+	s.Scanning[cur].Applying.Lock()
+	defer s.Scanning[cur].Applying.Unlock()
+
+	changes := s.Scanning[cur].Update(block)
+	if err := s.DB.Save(cur, s.Scanning[cur].Status(), changes); err != nil {
+		return err
+	}
+
+	// keep watching past the addresses of wallets just used
+	if err := s.extend(cur, block, changes); err != nil {
+		return err
+	}
+
+	if pool := s.Pools[cur]; pool != nil {
+		return s.DB.Publish(pool.Mined(block))
+	}
+	return nil
+}
+
 func (s *Scanner) Register(drop *otc.Drop) error {
 	// check that connection exists
 	if s.Scanning[drop.Currency] == nil {
 		return currency.ErrConnMissing
 	}
 
+	if watcher, ok := s.Connections[drop.Currency].(currency.Watcher); ok {
+		if err := watcher.Watch(drop.Address); err != nil {
+			return err
+		}
+	}
+
 	// add to storage
-	s.Scanning[drop.Currency].Register(drop.Address)
+	storage := s.Scanning[drop.Currency]
+	if !storage.Register(drop.Address) {
+		return nil
+	}
 
-	// save to disk
-	return s.Save(drop.Currency)
+	// save to disk, from the height it's watched from
+	at := storage.Status()
+	at.Time = time.Now().UTC().Unix()
+	return s.DB.Register(drop.Currency, drop.Address, at)
 }
 
 func (s *Scanner) Outputs(drop *otc.Drop) (otc.Outputs, error) {
@@ -94,11 +165,10 @@ func (s *Scanner) Outputs(drop *otc.Drop) (otc.Outputs, error) {
 		return nil, currency.ErrConnMissing
 	}
 
-	// check that address is registered
-	if s.Scanning[drop.Currency].Addresses[drop.Address] == nil {
+	// get outputs from storage, checking that address is registered
+	outputs := s.Scanning[drop.Currency].Outputs(drop.Address)
+	if outputs == nil {
 		return nil, ErrAddressMissing
 	}
-
-	// get outputs from storage
-	return s.Scanning[drop.Currency].Outputs(drop.Address), nil
+	return outputs, nil
 }
@@ -16,15 +16,47 @@ type Relevant struct {
 type Updated struct {
 	Time   int64  `json:"time"`
 	Height uint64 `json:"height"`
+	// hash of the block at Height, if the connection reports it
+	Hash string `json:"hash,omitempty"`
 }
 
 type Storage struct {
 	sync.RWMutex
 
+	// JSON file the storage was kept in before the database, migrated on
+	// first start
 	Filename     string               `json:"filename"`
 	Updated      *Updated             `json:"updated"`
 	Addresses    map[string]*Relevant `json:"addresses"`
 	Transactions map[string]*Relevant `json:"transaction"`
+	// hashes of the last KEEP blocks by height, to detect reorganisations
+	Blocks map[uint64]string `json:"-"`
+	// held while what a block, rollback or backfill changed is worked out
+	// and saved, so addresses aren't unregistered in between
+	Applying sync.Mutex `json:"-"`
+
+	// where each output of a watched address is, by outpoint (hash:index)
+	// and by id for currencies spending by id, so spends are found without
+	// going through every address
+	byOutpoint map[string]*Location
+	byId       map[string]*Location
+}
+
+// Location is an output of a watched address.
+type Location struct {
+	Address string
+	Hash    string
+	Index   int
+}
+
+// Change is an output of a watched address that a block added or spent.
+type Change struct {
+	Address string
+	Hash    string
+	Index   int
+	Output  *otc.OutputVerbose
+	// the output was spent rather than added
+	Spend bool
 }
 
 func NewStorage(cur otc.Currency) *Storage {
@@ -32,76 +64,316 @@ func NewStorage(cur otc.Currency) *Storage {
 		Filename:  string(cur) + ".json",
 		Updated:   &Updated{},
 		Addresses: make(map[string]*Relevant, 0),
+		Blocks:    make(map[uint64]string),
+
+		byOutpoint: make(map[string]*Location),
+		byId:       make(map[string]*Location),
 	}
 }
 
-func (s *Storage) Register(addr string) {
+// Add adds an output to a registered address.
+func (s *Storage) Add(addr, hash string, index int, out *otc.OutputVerbose) {
 	s.Lock()
 	defer s.Unlock()
 
+	s.add(addr, hash, index, out)
+}
+
+func (s *Storage) add(addr, hash string, index int, out *otc.OutputVerbose) {
+	rel := s.Addresses[addr]
+
+	rel.Lock()
+	rel.Outputs.Update(hash, index, out)
+	rel.Unlock()
+
+	loc := &Location{Address: addr, Hash: hash, Index: index}
+	s.byOutpoint[Outpoint(hash, index)] = loc
+	if out.Id != "" {
+		s.byId[out.Id] = loc
+	}
+}
+
+// Reindex rebuilds the output indexes from Addresses, for storage decoded
+// from JSON.
+func (s *Storage) Reindex() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.byOutpoint = make(map[string]*Location)
+	s.byId = make(map[string]*Location)
+
+	for addr, rel := range s.Addresses {
+		for hash, outputs := range rel.Outputs {
+			for index, out := range outputs {
+				loc := &Location{Address: addr, Hash: hash, Index: index}
+				s.byOutpoint[Outpoint(hash, index)] = loc
+				if out.Id != "" {
+					s.byId[out.Id] = loc
+				}
+			}
+		}
+	}
+}
+
+// Register starts watching addr, returning false if it already was.
+func (s *Storage) Register(addr string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.Addresses[addr] != nil {
+		return false
+	}
+
 	s.Addresses[addr] = &Relevant{Outputs: make(otc.Outputs, 0)}
+	return true
 }
 
+// Watched returns whether addr is registered.
+func (s *Storage) Watched(addr string) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.Addresses[addr] != nil
+}
+
+// Status returns the height and time storage was last updated at.
+func (s *Storage) Status() Updated {
+	s.RLock()
+	defer s.RUnlock()
+
+	return *s.Updated
+}
+
+// Outputs returns copies of an address's outputs, with their confirmations
+// at the height scanned, or nil if it isn't watched.
 func (s *Storage) Outputs(addr string) otc.Outputs {
 	s.RLock()
 	defer s.RUnlock()
 
-	// TODO: is this needed?
-	s.Addresses[addr].RLock()
-	defer s.Addresses[addr].RUnlock()
+	rel := s.Addresses[addr]
+	if rel == nil {
+		return nil
+	}
+	rel.RLock()
+	defer rel.RUnlock()
+
+	outputs := make(otc.Outputs, len(rel.Outputs))
+	for hash, outs := range rel.Outputs {
+		for index, out := range outs {
+			copied := *out
+			copied.Confirmations = 0
+			if out.Height <= s.Updated.Height {
+				copied.Confirmations = s.Updated.Height - out.Height + 1
+			}
+			outputs.Update(hash, index, &copied)
+		}
+	}
 
-	return s.Addresses[addr].Outputs
+	return outputs
 }
 
-func (s *Storage) Update(block *otc.Block) {
+// Update adds the outputs and spends of watched addresses in block,
+// returning them so they can be saved.
+func (s *Storage) Update(block *otc.Block) []*Change {
 	s.Lock()
 	defer s.Unlock()
 
-	// iterate all transactions in block
+	changes := make([]*Change, 0)
+
+	for hash, tx := range block.Transactions {
+		for index, out := range tx.Out {
+			for _, addr := range out.Addresses {
+				if s.Addresses[addr] == nil {
+					continue
+				}
+
+				// confirmations are worked out when read, this is the
+				// block scanned last
+				output := &otc.OutputVerbose{
+					Id:            out.Id,
+					Amount:        out.Amount,
+					Hours:         out.Hours,
+					TxHash:        tx.Hash,
+					BlockHash:     tx.BlockHash,
+					Confirmations: 1,
+					Height:        block.Height,
+				}
+				s.add(addr, hash, index, output)
+
+				changes = append(changes, &Change{Address: addr, Hash: hash, Index: index, Output: output})
+			}
+		}
+	}
+
+	// inputs after all outputs, they can spend outputs of the same block
 	for hash, tx := range block.Transactions {
-		// Iterate over all inputs to keep track if transaction was spent or unspent.
 		for _, in := range tx.In {
-			for _, addr := range s.Addresses {
-				addr.Outputs.UpdateSpent(in.From, in.To)
+			loc := s.byOutpoint[Outpoint(in.Hash, in.Index)]
+			if in.Id != "" {
+				loc = s.byId[in.Id]
+			}
+			if loc == nil {
+				continue
+			}
+
+			rel := s.Addresses[loc.Address]
+			rel.Lock()
+			if rel.Outputs.UpdateSpent(loc.Hash, loc.Index, hash) {
+				out := rel.Outputs[loc.Hash][loc.Index]
+				out.SpentHeight = block.Height
+				changes = append(changes, &Change{Address: loc.Address, Hash: loc.Hash, Index: loc.Index, Output: out, Spend: true})
 			}
+			rel.Unlock()
 		}
+	}
+
+	// record that everything was updated to current block and time
+	s.Updated.Height = block.Height
+	s.Updated.Time = time.Now().UTC().Unix()
+	s.Updated.Hash = block.Hash
+
+	if block.Hash != "" {
+		s.Blocks[block.Height] = block.Hash
+		delete(s.Blocks, block.Height-KEEP)
+	}
 
-		// iterate all outputs in transaction
+	return changes
+}
+
+// Backfill adds the outputs and spends of addr in a block below the one
+// scanned, as Update would have had it been registered then. It returns
+// whether the block was applied, which it isn't if it's above the block
+// scanned, and whether the scanner has nothing more for a backfill to
+// catch up on.
+func (s *Storage) Backfill(addr string, block *otc.Block) ([]*Change, bool, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	changes := make([]*Change, 0)
+
+	if block.Height > s.Updated.Height {
+		// rolled back meanwhile, scanning carries on from there
+		return changes, false, true, nil
+	}
+	if hash := s.Blocks[block.Height]; hash != "" && block.Hash != "" && hash != block.Hash {
+		return nil, false, false, ErrStale
+	}
+
+	rel := s.Addresses[addr]
+	if rel == nil {
+		return nil, false, false, ErrAddressMissing
+	}
+
+	for hash, tx := range block.Transactions {
 		for index, out := range tx.Out {
 			for _, outAddr := range out.Addresses {
-				// iterate all registered addresses
-				for addr, rel := range s.Addresses {
-					// if registered address is in output addresses
-					if addr == outAddr {
-						rel.Lock()
-						rel.Outputs.Update(
-							hash,
-							index,
-							&otc.OutputVerbose{
-								Amount:        out.Amount,
-								TxHash:        tx.Hash,
-								BlockHash:     tx.BlockHash,
-								Confirmations: tx.Confirmations,
-								Height:        block.Height,
-							},
-						)
-						rel.Unlock()
-					}
+				if outAddr != addr {
+					continue
+				}
+
+				rel.RLock()
+				known := rel.Outputs[hash] != nil && rel.Outputs[hash][index] != nil
+				rel.RUnlock()
+				if known {
+					continue
 				}
+
+				output := &otc.OutputVerbose{
+					Id:        out.Id,
+					Amount:    out.Amount,
+					Hours:     out.Hours,
+					TxHash:    tx.Hash,
+					BlockHash: tx.BlockHash,
+					Height:    block.Height,
+				}
+				s.add(addr, hash, index, output)
+
+				changes = append(changes, &Change{Address: addr, Hash: hash, Index: index, Output: output})
 			}
 		}
 	}
 
-	// update confirmations number
-	for _, rel := range s.Addresses {
-		for _, outputs := range rel.Outputs {
-			for _, out := range outputs {
-				out.Confirmations = (block.Height - out.Height) + 1
+	for hash, tx := range block.Transactions {
+		for _, in := range tx.In {
+			loc := s.byOutpoint[Outpoint(in.Hash, in.Index)]
+			if in.Id != "" {
+				loc = s.byId[in.Id]
+			}
+			if loc == nil || loc.Address != addr {
+				continue
 			}
+
+			rel.Lock()
+			if rel.Outputs.UpdateSpent(loc.Hash, loc.Index, hash) {
+				out := rel.Outputs[loc.Hash][loc.Index]
+				out.SpentHeight = block.Height
+				changes = append(changes, &Change{Address: addr, Hash: loc.Hash, Index: loc.Index, Output: out, Spend: true})
+			}
+			rel.Unlock()
 		}
 	}
 
-	// record that everything was updated to current block and time
-	s.Updated.Height = block.Height
+	// confirmations as of the block scanned, for the events
+	for _, change := range changes {
+		change.Output.Confirmations = s.Updated.Height - change.Output.Height + 1
+	}
+
+	return changes, true, block.Height == s.Updated.Height, nil
+}
+
+// Block returns the hash of the block scanned at height, or empty if it's
+// not one of the last KEEP.
+func (s *Storage) Block(height uint64) string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.Blocks[height]
+}
+
+// Rollback undoes the blocks above height: their outputs are removed and
+// their spends undone. It returns the removed outputs, the outputs no longer
+// spent and the hashes of the undone blocks, lowest first.
+func (s *Storage) Rollback(height uint64) ([]*Change, []*Change, []string) {
+	s.Lock()
+	defer s.Unlock()
+
+	removed, unspent := make([]*Change, 0), make([]*Change, 0)
+
+	for addr, rel := range s.Addresses {
+		rel.Lock()
+		for hash, outputs := range rel.Outputs {
+			for index, out := range outputs {
+				if out.Height > height {
+					delete(outputs, index)
+					delete(s.byOutpoint, Outpoint(hash, index))
+					if out.Id != "" {
+						delete(s.byId, out.Id)
+					}
+					removed = append(removed, &Change{Address: addr, Hash: hash, Index: index, Output: out})
+				} else if out.Spent != "" && out.SpentHeight > height {
+					out.Spent, out.SpentHeight = "", 0
+					unspent = append(unspent, &Change{Address: addr, Hash: hash, Index: index, Output: out})
+				}
+			}
+
+			if len(outputs) == 0 {
+				delete(rel.Outputs, hash)
+			}
+		}
+		rel.Unlock()
+	}
+
+	orphaned := make([]string, 0)
+	for h := height + 1; h <= s.Updated.Height; h++ {
+		if s.Blocks[h] != "" {
+			orphaned = append(orphaned, s.Blocks[h])
+		}
+		delete(s.Blocks, h)
+	}
+
+	s.Updated.Height = height
 	s.Updated.Time = time.Now().UTC().Unix()
+	s.Updated.Hash = s.Blocks[height]
+
+	return removed, unspent, orphaned
 }
@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/skycoin/services/otc/pkg/otc"
@@ -30,20 +31,25 @@ func TestStorageRegister(t *testing.T) {
 func TestStorageOutputs(t *testing.T) {
 	storage := NewStorage(otc.BTC)
 	storage.Register("address")
-	storage.Addresses["address"].Outputs.Update(
+	storage.Add(
+		"address",
 		"hash",
 		1,
 		&otc.OutputVerbose{
-			Amount:        32,
-			Confirmations: 1,
-			Height:        40,
+			Amount: 32,
+			Height: 40,
 		},
 	)
+	storage.Updated.Height = 42
 	outputs := storage.Outputs("address")
 
 	if outputs["hash"][1].Amount != 32 {
 		t.Fatal("outputs failed")
 	}
+
+	if outputs["hash"][1].Confirmations != 3 {
+		t.Fatalf("expected 3 confirmations, got %d", outputs["hash"][1].Confirmations)
+	}
 }
 
 func TestStorageUpdate(t *testing.T) {
@@ -80,3 +86,163 @@ func TestStorageUpdate(t *testing.T) {
 		t.Fatal("update failed")
 	}
 }
+
+func TestStorageUpdateSpent(t *testing.T) {
+	storage := NewStorage(otc.BTC)
+	storage.Register("address")
+
+	// spent in the block it's created in
+	changes := storage.Update(&otc.Block{
+		Height: 32,
+		Transactions: map[string]*otc.Transaction{
+			"spender": &otc.Transaction{
+				Hash: "spender",
+				In:   []otc.Input{{Hash: "transaction", Index: 1}},
+				Out:  map[int]*otc.Output{},
+			},
+			"transaction": &otc.Transaction{
+				Hash: "transaction",
+				Out: map[int]*otc.Output{
+					1: &otc.Output{
+						Amount:    32000000,
+						Addresses: []string{"address"},
+					},
+				},
+			},
+		},
+	})
+
+	if storage.Addresses["address"].Outputs["transaction"][1].Spent != "spender" {
+		t.Fatal("output not spent")
+	}
+
+	if len(changes) != 2 || changes[0].Address != "address" || changes[1].Output.Spent != "spender" {
+		t.Fatalf("expected output and spend changes, got %+v", changes)
+	}
+}
+
+func TestStorageRegisterTwice(t *testing.T) {
+	storage := NewStorage(otc.BTC)
+	storage.Register("address")
+	storage.Add("address", "hash", 1, &otc.OutputVerbose{})
+
+	if storage.Register("address") || storage.Addresses["address"].Outputs["hash"] == nil {
+		t.Fatal("registering again dropped outputs")
+	}
+}
+
+func TestStorageUpdateSpentById(t *testing.T) {
+	storage := NewStorage(otc.SKY)
+	storage.Register("address")
+	storage.Add("address", "transaction", 0, &otc.OutputVerbose{Id: "uxid", Height: 10})
+
+	storage.Update(&otc.Block{
+		Height: 11,
+		Transactions: map[string]*otc.Transaction{
+			"spender": &otc.Transaction{
+				Hash: "spender",
+				In:   []otc.Input{{Id: "uxid"}, {Id: "unknown"}},
+				Out:  map[int]*otc.Output{},
+			},
+		},
+	})
+
+	if out := storage.Addresses["address"].Outputs["transaction"][0]; out.Spent != "spender" || out.SpentHeight != 11 {
+		t.Fatalf("expected output spent by id, got %+v", out)
+	}
+}
+
+func TestStorageReindex(t *testing.T) {
+	storage := NewStorage(otc.SKY)
+	storage.Register("address")
+	// as decoded from JSON, without indexes
+	storage.Addresses["address"].Outputs.Update("transaction", 0, &otc.OutputVerbose{Id: "uxid", Height: 10})
+	storage.Addresses["address"].Outputs.Update("transaction", 1, &otc.OutputVerbose{Height: 10})
+	storage.Reindex()
+
+	changes := storage.Update(&otc.Block{
+		Height: 11,
+		Transactions: map[string]*otc.Transaction{
+			"spender": &otc.Transaction{
+				Hash: "spender",
+				In:   []otc.Input{{Id: "uxid"}, {Hash: "transaction", Index: 1}},
+				Out:  map[int]*otc.Output{},
+			},
+		},
+	})
+
+	if len(changes) != 2 || !changes[0].Spend || !changes[1].Spend {
+		t.Fatalf("expected both outputs spent, got %+v", changes)
+	}
+}
+
+func TestStorageRollbackIndex(t *testing.T) {
+	storage := NewStorage(otc.BTC)
+	storage.Register("address")
+	storage.Add("address", "transaction", 1, &otc.OutputVerbose{Height: 12})
+	storage.Updated.Height = 12
+
+	storage.Rollback(11)
+
+	changes := storage.Update(&otc.Block{
+		Height: 12,
+		Transactions: map[string]*otc.Transaction{
+			"spender": &otc.Transaction{
+				Hash: "spender",
+				In:   []otc.Input{{Hash: "transaction", Index: 1}},
+				Out:  map[int]*otc.Output{},
+			},
+		},
+	})
+
+	if len(changes) != 0 || storage.Addresses["address"].Outputs["transaction"] != nil {
+		t.Fatalf("expected rolled back output gone, got %+v", changes)
+	}
+}
+
+// BenchmarkStorageUpdate scans blocks of 2000 transactions, each paying one
+// watched address and spending an output of an earlier block. The time per
+// block should stay the same however many addresses are watched.
+func BenchmarkStorageUpdate(b *testing.B) {
+	for _, watched := range []int{10, 1000, 100000} {
+		b.Run(fmt.Sprintf("addresses=%d", watched), func(b *testing.B) {
+			storage := NewStorage(otc.BTC)
+			for i := 0; i < watched; i++ {
+				storage.Register(fmt.Sprintf("address%d", i))
+			}
+
+			blocks := make([]*otc.Block, b.N)
+			for height := range blocks {
+				blocks[height] = benchmarkBlock(uint64(height+1), watched)
+			}
+
+			b.ResetTimer()
+			for _, block := range blocks {
+				storage.Update(block)
+			}
+		})
+	}
+}
+
+func benchmarkBlock(height uint64, watched int) *otc.Block {
+	const TRANSACTIONS = 2000
+
+	block := &otc.Block{
+		Height:       height,
+		Transactions: make(map[string]*otc.Transaction, TRANSACTIONS),
+	}
+
+	for i := 0; i < TRANSACTIONS; i++ {
+		hash := fmt.Sprintf("%d-%d", height, i)
+		block.Transactions[hash] = &otc.Transaction{
+			Hash: hash,
+			In:   []otc.Input{{Hash: fmt.Sprintf("%d-%d", height-1, i), Index: 0}},
+			Out: map[int]*otc.Output{
+				0: &otc.Output{Amount: 1, Addresses: []string{fmt.Sprintf("address%d", i%watched)}},
+				1: &otc.Output{Amount: 1, Addresses: []string{"unwatched"}},
+			},
+		}
+	}
+
+	return block
+}
@@ -0,0 +1,467 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc/pkg/otc"
+	bolt "go.etcd.io/bbolt"
+)
+
+// unused addresses watched past the last used one of a wallet by default,
+// and at most
+const (
+	GAP_LIMIT = 20
+	MAX_GAP   = 1000
+)
+
+var (
+	// big endian id -> Wallet
+	WALLETS = []byte("wallets")
+	// address -> Derivation
+	DERIVED = []byte("derived")
+)
+
+var (
+	ErrNoDerive      = errors.New("currency can't derive addresses")
+	ErrWalletMissing = errors.New("wallet not watched")
+	ErrGap           = errors.New("gap limit above 1000")
+)
+
+// Wallet is an extended public key or output descriptor whose addresses are
+// watched, Gap of them past the last one that received an output.
+type Wallet struct {
+	Id         uint64       `json:"id"`
+	Currency   otc.Currency `json:"currency"`
+	Descriptor string       `json:"descriptor"`
+	Gap        uint32       `json:"gap"`
+	// addresses derived and watched, at indexes 0 to Derived - 1
+	Derived uint32 `json:"derived"`
+	// highest index that received an output, -1 if none did
+	Used    int64 `json:"used"`
+	Created int64 `json:"created"`
+}
+
+// Derivation is the wallet and index an address was derived at.
+type Derivation struct {
+	Wallet uint64 `json:"wallet"`
+	Index  uint32 `json:"index"`
+}
+
+// Wallets are the wallets of a currency and the addresses derived for them.
+type Wallets struct {
+	sync.RWMutex
+
+	ById map[uint64]*Wallet
+	// address -> Derivation
+	Derivations map[string]*Derivation
+}
+
+// WalletSummary is a wallet with its addresses and their outputs.
+type WalletSummary struct {
+	*Wallet
+
+	Addresses []*DerivedAddress `json:"addresses"`
+}
+
+// DerivedAddress is an address of a wallet, by index.
+type DerivedAddress struct {
+	Address string      `json:"address"`
+	Index   uint32      `json:"index"`
+	Outputs otc.Outputs `json:"outputs"`
+}
+
+func NewWallets() *Wallets {
+	return &Wallets{
+		ById:        make(map[uint64]*Wallet),
+		Derivations: make(map[string]*Derivation),
+	}
+}
+
+// LoadWallets reads the wallets of every currency scanned from the database.
+func (s *Scanner) LoadWallets() error {
+	for cur := range s.Scanning {
+		wallets, err := s.DB.Wallets(cur)
+		if err != nil {
+			return err
+		}
+		s.Wallets[cur] = wallets
+	}
+	return nil
+}
+
+// AddWallet watches the first gap addresses of a descriptor, GAP_LIMIT if
+// gap is 0, and more as they're used. A descriptor already watched returns
+// its wallet.
+func (s *Scanner) AddWallet(cur otc.Currency, descriptor string, gap uint32) (*Wallet, error) {
+	wallets := s.Wallets[cur]
+	if s.Scanning[cur] == nil || wallets == nil {
+		return nil, currency.ErrConnMissing
+	}
+	deriver, ok := s.Connections[cur].(currency.Deriver)
+	if !ok {
+		return nil, ErrNoDerive
+	}
+
+	if gap == 0 {
+		gap = GAP_LIMIT
+	} else if gap > MAX_GAP {
+		return nil, ErrGap
+	}
+
+	wallets.Lock()
+	defer wallets.Unlock()
+
+	for _, w := range wallets.ById {
+		if w.Descriptor == descriptor {
+			copied := *w
+			return &copied, nil
+		}
+	}
+
+	addrs, err := deriver.Derive(descriptor, 0, gap)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Wallet{
+		Currency:   cur,
+		Descriptor: descriptor,
+		Gap:        gap,
+		Used:       -1,
+		Created:    time.Now().UTC().Unix(),
+	}
+	if err = s.derived(wallets, w, addrs); err != nil {
+		return nil, err
+	}
+
+	copied := *w
+	return &copied, nil
+}
+
+// ListWallets returns the wallets of a currency, oldest first.
+func (s *Scanner) ListWallets(cur otc.Currency) ([]*Wallet, error) {
+	wallets := s.Wallets[cur]
+	if wallets == nil {
+		return nil, currency.ErrConnMissing
+	}
+
+	wallets.RLock()
+	defer wallets.RUnlock()
+
+	list := make([]*Wallet, 0, len(wallets.ById))
+	for _, w := range wallets.ById {
+		copied := *w
+		list = append(list, &copied)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+	return list, nil
+}
+
+// Wallet returns a wallet with the outputs of each of its addresses.
+func (s *Scanner) Wallet(cur otc.Currency, id uint64) (*WalletSummary, error) {
+	wallets, storage := s.Wallets[cur], s.Scanning[cur]
+	if wallets == nil || storage == nil {
+		return nil, currency.ErrConnMissing
+	}
+
+	wallets.RLock()
+	defer wallets.RUnlock()
+
+	w := wallets.ById[id]
+	if w == nil {
+		return nil, ErrWalletMissing
+	}
+
+	copied := *w
+	summary := &WalletSummary{Wallet: &copied, Addresses: make([]*DerivedAddress, 0, w.Derived)}
+	for addr, d := range wallets.Derivations {
+		if d.Wallet != id || !storage.Watched(addr) {
+			continue
+		}
+		summary.Addresses = append(summary.Addresses, &DerivedAddress{
+			Address: addr,
+			Index:   d.Index,
+			Outputs: storage.Outputs(addr),
+		})
+	}
+
+	sort.Slice(summary.Addresses, func(i, j int) bool {
+		return summary.Addresses[i].Index < summary.Addresses[j].Index
+	})
+	return summary, nil
+}
+
+// RemoveWallet stops watching a wallet and unregisters its addresses.
+func (s *Scanner) RemoveWallet(cur otc.Currency, id uint64) error {
+	wallets, storage := s.Wallets[cur], s.Scanning[cur]
+	if wallets == nil || storage == nil {
+		return currency.ErrConnMissing
+	}
+
+	// before wallets, in the order Apply takes them
+	storage.Applying.Lock()
+	defer storage.Applying.Unlock()
+
+	wallets.Lock()
+	defer wallets.Unlock()
+
+	if wallets.ById[id] == nil {
+		return ErrWalletMissing
+	}
+
+	addrs := make([]string, 0)
+	for addr, d := range wallets.Derivations {
+		if d.Wallet != id {
+			continue
+		}
+		addrs = append(addrs, addr)
+
+		err := s.unregister(&otc.Drop{Address: addr, Currency: cur})
+		if err != nil && err != ErrAddressMissing {
+			return err
+		}
+	}
+
+	if err := s.DB.DeleteWallet(cur, id, addrs); err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		delete(wallets.Derivations, addr)
+	}
+	delete(wallets.ById, id)
+	return nil
+}
+
+// extend records which addresses of wallets the outputs of a block went to
+// and derives more to keep Gap unused ones watched. The new addresses are
+// looked for in the block as well, in case it paid them too.
+func (s *Scanner) extend(cur otc.Currency, block *otc.Block, changes []*Change) error {
+	wallets, storage := s.Wallets[cur], s.Scanning[cur]
+	if wallets == nil {
+		return nil
+	}
+
+	wallets.Lock()
+	defer wallets.Unlock()
+
+	for len(changes) > 0 {
+		used := make(map[uint64]*Wallet)
+		for _, change := range changes {
+			d := wallets.Derivations[change.Address]
+			if change.Spend || d == nil {
+				continue
+			}
+
+			w := wallets.ById[d.Wallet]
+			if int64(d.Index) > w.Used {
+				w.Used = int64(d.Index)
+				used[w.Id] = w
+			}
+		}
+
+		found := make([]*Change, 0)
+		for _, w := range used {
+			addrs := make([]string, 0)
+			if want := uint32(w.Used+1) + w.Gap; want > w.Derived {
+				var err error
+				if addrs, err = s.Connections[cur].(currency.Deriver).Derive(w.Descriptor, w.Derived, want); err != nil {
+					return err
+				}
+			}
+
+			if err := s.derived(wallets, w, addrs); err != nil {
+				return err
+			}
+
+			for _, addr := range addrs {
+				c, _, _, err := storage.Backfill(addr, block)
+				if err != nil {
+					return err
+				}
+				found = append(found, c...)
+			}
+		}
+
+		if err := s.DB.SaveChanges(cur, found); err != nil {
+			return err
+		}
+		changes = found
+	}
+
+	return nil
+}
+
+// derived watches the addresses derived for a wallet after those it had,
+// saving it. Wallets must be locked.
+func (s *Scanner) derived(wallets *Wallets, w *Wallet, addrs []string) error {
+	cur := w.Currency
+	storage := s.Scanning[cur]
+
+	if watcher, ok := s.Connections[cur].(currency.Watcher); ok {
+		for _, addr := range addrs {
+			if err := watcher.Watch(addr); err != nil {
+				return err
+			}
+		}
+	}
+
+	at := storage.Status()
+	at.Time = time.Now().UTC().Unix()
+
+	from := w.Derived
+	w.Derived += uint32(len(addrs))
+	if err := s.DB.SaveWallet(w, from, addrs, at); err != nil {
+		w.Derived = from
+		return err
+	}
+
+	wallets.ById[w.Id] = w
+	for i, addr := range addrs {
+		storage.Register(addr)
+		wallets.Derivations[addr] = &Derivation{Wallet: w.Id, Index: from + uint32(i)}
+	}
+	return nil
+}
+
+// SaveWallet writes a wallet along with the addresses derived for it from
+// index from, registering them as of at. The wallet gets its id here.
+func (d *DB) SaveWallet(w *Wallet, from uint32, addrs []string, at Updated) error {
+	return d.Update(func(tx *bolt.Tx) error {
+		root, err := buckets(tx, w.Currency)
+		if err != nil {
+			return err
+		}
+
+		if w.Id == 0 {
+			if w.Id, err = root.Bucket(WALLETS).NextSequence(); err != nil {
+				return err
+			}
+		}
+		if err = put(root.Bucket(WALLETS), Height(w.Id), w); err != nil {
+			return err
+		}
+
+		for i, addr := range addrs {
+			err = put(root.Bucket(DERIVED), []byte(addr), &Derivation{Wallet: w.Id, Index: from + uint32(i)})
+			if err != nil {
+				return err
+			}
+
+			if root.Bucket(ADDRESSES).Get([]byte(addr)) == nil {
+				if err = put(root.Bucket(ADDRESSES), []byte(addr), at); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Wallets reads the wallets of a currency and the addresses derived for
+// them.
+func (d *DB) Wallets(cur otc.Currency) (*Wallets, error) {
+	wallets := NewWallets()
+
+	err := d.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(cur))
+		if root == nil || root.Bucket(WALLETS) == nil {
+			return nil
+		}
+
+		err := root.Bucket(WALLETS).ForEach(func(k, v []byte) error {
+			w := &Wallet{}
+			if err := json.Unmarshal(v, w); err != nil {
+				return err
+			}
+			wallets.ById[w.Id] = w
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return root.Bucket(DERIVED).ForEach(func(k, v []byte) error {
+			derivation := &Derivation{}
+			if err := json.Unmarshal(v, derivation); err != nil {
+				return err
+			}
+			wallets.Derivations[string(k)] = derivation
+			return nil
+		})
+	})
+
+	return wallets, err
+}
+
+// DeleteWallet forgets a wallet and where its addresses came from.
+func (d *DB) DeleteWallet(cur otc.Currency, id uint64, addrs []string) error {
+	return d.Update(func(tx *bolt.Tx) error {
+		root, err := buckets(tx, cur)
+		if err != nil {
+			return err
+		}
+
+		for _, addr := range addrs {
+			if err = root.Bucket(DERIVED).Delete([]byte(addr)); err != nil {
+				return err
+			}
+		}
+
+		return root.Bucket(WALLETS).Delete(Height(id))
+	})
+}
+
+// SaveChanges writes outputs and spends found outside of a block's scan,
+// with their events.
+func (d *DB) SaveChanges(cur otc.Currency, changes []*Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	err := d.Update(func(tx *bolt.Tx) error {
+		root, err := buckets(tx, cur)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			if err = putOutput(root, change); err != nil {
+				return err
+			}
+		}
+
+		return addChanges(tx, cur, changes)
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notify()
+	return nil
+}
+
+// derivation returns where an address was derived from, nil if it wasn't.
+func derivation(root *bolt.Bucket, addr string) *Derivation {
+	if root == nil || root.Bucket(DERIVED) == nil {
+		return nil
+	}
+
+	data := root.Bucket(DERIVED).Get([]byte(addr))
+	if data == nil {
+		return nil
+	}
+
+	d := &Derivation{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil
+	}
+	return d
+}
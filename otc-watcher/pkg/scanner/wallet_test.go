@@ -0,0 +1,145 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/skycoin/services/otc-watcher/pkg/currency"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// MockDeriver derives descriptor/index as the address at index.
+type MockDeriver struct {
+	MockChain
+}
+
+func (d *MockDeriver) Derive(descriptor string, from, to uint32) ([]string, error) {
+	if descriptor == "invalid" {
+		return nil, errors.New("invalid descriptor")
+	}
+
+	addrs := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		addrs = append(addrs, fmt.Sprintf("%s/%d", descriptor, i))
+	}
+	return addrs, nil
+}
+
+// MockPayments pays 1 to each address in transaction id.
+func MockPayments(height uint64, hash, previous, id string, addrs ...string) *otc.Block {
+	tx := &otc.Transaction{Id: id, Hash: id, Out: make(map[int]*otc.Output)}
+	for i, addr := range addrs {
+		tx.Out[i] = &otc.Output{Amount: 1, Addresses: []string{addr}}
+	}
+
+	return &otc.Block{
+		Height:       height,
+		Hash:         hash,
+		Previous:     previous,
+		Transactions: map[string]*otc.Transaction{id: tx},
+	}
+}
+
+func TestWallet(t *testing.T) {
+	db, dir := MockDB(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	deriver := &MockDeriver{MockChain{Blocks: make(map[uint64]*otc.Block)}}
+	scnr := &Scanner{
+		Connections: currency.Connections{otc.BTC: deriver, otc.SKY: &MockChain{}},
+		Scanning:    map[otc.Currency]*Storage{otc.BTC: NewStorage(otc.BTC), otc.SKY: NewStorage(otc.SKY)},
+		DB:          db,
+		Wallets:     map[otc.Currency]*Wallets{otc.BTC: NewWallets(), otc.SKY: NewWallets()},
+	}
+
+	wallet, err := scnr.AddWallet(otc.BTC, "w", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wallet.Id == 0 || wallet.Derived != 3 || wallet.Used != -1 || !scnr.Scanning[otc.BTC].Watched("w/2") {
+		t.Fatalf("expected the first 3 addresses watched, got %+v", wallet)
+	}
+	if again, err := scnr.AddWallet(otc.BTC, "w", 3); err != nil || again.Id != wallet.Id {
+		t.Fatalf("expected the same wallet, got %+v %v", again, err)
+	}
+
+	for _, test := range []struct {
+		cur        otc.Currency
+		descriptor string
+		gap        uint32
+		err        error
+	}{
+		{otc.SKY, "w", 0, ErrNoDerive},
+		{otc.BTC, "w", MAX_GAP + 1, ErrGap},
+		{otc.Currency("ETH"), "w", 0, currency.ErrConnMissing},
+	} {
+		if _, err = scnr.AddWallet(test.cur, test.descriptor, test.gap); err != test.err {
+			t.Fatalf("%s %s: expected %v, got %v", test.cur, test.descriptor, test.err, err)
+		}
+	}
+	if _, err = scnr.AddWallet(otc.BTC, "invalid", 0); err == nil {
+		t.Fatal("expected the derivation error")
+	}
+
+	// index 4 isn't watched before index 2 is used, but is looked for in
+	// the block once derived
+	if err = scnr.Apply(otc.BTC, MockPayments(1, "a1", "a0", "t1", "w/2", "w/4")); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := scnr.Wallet(otc.BTC, wallet.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Used != 4 || summary.Derived != 8 || len(summary.Addresses) != 8 {
+		t.Fatalf("expected index 4 used and 3 ahead watched, got %+v", summary.Wallet)
+	}
+	for _, addr := range summary.Addresses {
+		paid := addr.Index == 2 || addr.Index == 4
+		if addr.Address != fmt.Sprintf("w/%d", addr.Index) || paid != (len(addr.Outputs) == 1) {
+			t.Fatalf("unexpected address %+v", addr)
+		}
+	}
+
+	events, err := db.Events(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexes := make(map[string]uint32)
+	for _, event := range events {
+		if event.Type == OUTPUT && event.Derivation != nil && event.Derivation.Wallet == wallet.Id {
+			indexes[event.Address] = event.Derivation.Index
+		}
+	}
+	if len(indexes) != 2 || indexes["w/2"] != 2 || indexes["w/4"] != 4 {
+		t.Fatalf("expected output events with their index, got %+v", events)
+	}
+
+	// saved for the next start
+	loaded, err := db.Wallets(otc.BTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := loaded.ById[wallet.Id]; w == nil || w.Used != 4 || w.Derived != 8 || len(loaded.Derivations) != 8 {
+		t.Fatalf("unexpected saved wallets %+v", loaded)
+	}
+	if list, err := scnr.ListWallets(otc.BTC); err != nil || len(list) != 1 {
+		t.Fatalf("expected one wallet, got %v %v", list, err)
+	}
+
+	if err = scnr.RemoveWallet(otc.BTC, wallet.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err = scnr.RemoveWallet(otc.BTC, wallet.Id); err != ErrWalletMissing {
+		t.Fatalf("expected wallet missing, got %v", err)
+	}
+	if scnr.Scanning[otc.BTC].Watched("w/0") {
+		t.Fatal("expected the wallet's addresses unregistered")
+	}
+	if loaded, _ = db.Wallets(otc.BTC); len(loaded.ById) != 0 || len(loaded.Derivations) != 0 {
+		t.Fatalf("expected saved wallet removed, got %+v", loaded)
+	}
+}
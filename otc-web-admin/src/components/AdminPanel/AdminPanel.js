@@ -24,6 +24,7 @@ import {
   setOctState,
   getHoldingBtc,
   getSkyAddresses,
+  watchEvents,
 } from './admin-api';
 
 const Panel = styled(Box) `
@@ -243,6 +244,15 @@ export default class extends React.Component {
     await this.refreshStatus();
     this.setState({ ...this.state, loaded: true });
   }
+  componentDidMount = () => {
+    // price and pause changes made elsewhere (otcctl, exchange updates)
+    this.stopEvents = watchEvents((event) => {
+      if (event.type === 'price' || event.type === 'pause') this.refreshStatus();
+    });
+  }
+  componentWillUnmount = () => {
+    if (this.stopEvents) this.stopEvents();
+  }
   setOctState = async pause => {
     this.setState({ ...this.state });
     await setOctState(pause);
@@ -1,12 +1,12 @@
 import axios from 'axios';
 
 export const getStatus = () =>
-  axios.get('/api/status')
+  axios.get('/api/v1/status')
     .then(response => response.data)
     .catch((error) => { throw new Error(error.response.data); });
 
 export const setPrice = price =>
-  axios.post('/api/price', { price }, {
+  axios.post('/api/v1/price', { price }, {
     headers: {
       'Content-Type': 'application/json',
     },
@@ -17,7 +17,7 @@ export const setPrice = price =>
     });
 
 export const setSource = source =>
-  axios.post('/api/source', { source }, {
+  axios.post('/api/v1/source', { source }, {
     headers: {
       'Content-Type': 'application/json',
     },
@@ -28,7 +28,7 @@ export const setSource = source =>
     });
 
 export const setOctState = pause =>
-  axios.post('/api/pause', { pause }, {
+  axios.post('/api/v1/pause', { pause }, {
     headers: {
       'Content-Type': 'application/json',
     },
@@ -37,7 +37,7 @@ export const setOctState = pause =>
     .catch((error) => { throw new Error(error.response.data); });
 
 export const getHoldingBtc = () =>
-  axios.get('/api/holding/btc', {
+  axios.get('/api/v1/holding/btc', {
     headers: {
       'Content-Type': 'application/json',
     },
@@ -46,10 +46,19 @@ export const getHoldingBtc = () =>
   .catch((error) => { throw new Error(error.response.data); });
 
 export const getSkyAddresses = () =>
-  axios.get('/api/addresses/sky', {
+  axios.get('/api/v1/addresses/sky', {
     headers: {
       'Content-Type': 'application/json',
     },
   })
   .then(response => response.data)
   .catch((error) => { throw new Error(error.response.data); });
+
+// calls onEvent with every order, price and pause change, returns a function
+// that stops listening
+export const watchEvents = (onEvent) => {
+  const source = new EventSource('/api/v1/events');
+  ['order', 'price', 'pause'].forEach(type =>
+    source.addEventListener(type, e => onEvent(JSON.parse(e.data))));
+  return () => source.close();
+};
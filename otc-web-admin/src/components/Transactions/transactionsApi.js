@@ -9,6 +9,6 @@ export const transactionFilters = {
 };
 
 export const getTransactions = (filter = { state: transactionFilters.byState.all.name }) =>
-  axios.get(`/api/transactions${transactionFilters.byState[filter.state].url}`)
+  axios.get(`/api/v1/transactions${transactionFilters.byState[filter.state].url}`)
     .then(response => response.data)
     .catch((error) => { throw new Error(error.response.data); });
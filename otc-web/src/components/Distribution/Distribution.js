@@ -22,7 +22,7 @@ import Text from 'components/Text';
 import media from '../../utils/media';
 import { getParameterByName } from '../../utils/window';
 
-import { checkStatus, getAddress, getConfig, } from '../../utils/distributionAPI';
+import { checkStatus, getAddress, getConfig, watchStatus } from '../../utils/distributionAPI';
 
 const Wrapper = styled.div`
   background-color: ${COLORS.gray[1]};
@@ -293,11 +293,30 @@ class Distribution extends React.Component {
   }
 
   closeModals = () => {
+    this.stopWatching();
     this.setState({
       statusIsOpen: false,
     });
   }
 
+  componentWillUnmount = () => {
+    this.stopWatching();
+  }
+
+  stopWatching = () => {
+    if (this.stopStatus) this.stopStatus();
+    this.stopStatus = null;
+  }
+
+  // refresh the open status as soon as the backend sees a deposit or payout
+  watchStatus = (drop) => {
+    this.stopWatching();
+    this.stopStatus = watchStatus(drop, () =>
+      checkStatus(drop)
+        .then(res => this.setState({ status: res }))
+        .catch(() => {}));
+  }
+
   checkStatus = () => {
     if (!this.state.status_address) {
       return alert(
@@ -311,13 +330,15 @@ class Distribution extends React.Component {
       statusLoading: true,
     });
 
-    return checkStatus({ drop_address: this.state.status_address, drop_currency: 'BTC' })
+    const drop = { drop_address: this.state.status_address, drop_currency: 'BTC' };
+    return checkStatus(drop)
       .then((res) => {
         this.setState({
           statusIsOpen: true,
           status: res,
           statusLoading: false,
         });
+        this.watchStatus(drop);
       })
       .catch((err) => {
         alert(err.message);
@@ -1,12 +1,20 @@
 import axios from 'axios';
 
 export const checkStatus = ({ drop_address, drop_currency }) =>
-  axios.post('/api/status', { drop_address, drop_currency })
+  axios.post('/api/v1/status', { drop_address, drop_currency })
     .then(response => [response.data])
     .catch((error) => { throw new Error(error.response.data); });
 
+// calls onOrder whenever an order for the drop address changes, returns a
+// function that stops listening
+export const watchStatus = ({ drop_address, drop_currency }, onOrder) => {
+  const source = new EventSource(`/api/v1/events?drop_address=${encodeURIComponent(drop_address)}&drop_currency=${encodeURIComponent(drop_currency)}`);
+  source.addEventListener('order', e => onOrder(JSON.parse(e.data).data));
+  return () => source.close();
+};
+
 export const getAddress = (skyAddress, affiliate) =>
-  axios.post('/api/bind', { address: skyAddress, drop_currency: 'BTC', affiliate }, {
+  axios.post('/api/v1/bind', { address: skyAddress, drop_currency: 'BTC', affiliate }, {
     headers: {
       'Content-Type': 'application/json',
     },
@@ -17,6 +25,6 @@ export const getAddress = (skyAddress, affiliate) =>
     });
 
 export const getConfig = () =>
-  axios.get('/api/config')
+  axios.get('/api/v1/config')
     .then(response => response.data)
     .catch((error) => { throw new Error(error.response.data); });
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/backup"
+)
+
+// exportCommand writes every desk's state to a signed archive.
+func exportCommand(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	out := flags.String("o", "", "archive path, - for stdout (default otc-<time>.tar.gz)")
+	flags.Parse(args)
+
+	now := time.Now()
+	if *out == "" {
+		*out = "otc-" + now.UTC().Format("20060102-150405") + ".tar.gz"
+	}
+
+	archive, err := backup.Snapshot(CONFIG, now)
+	if err != nil {
+		return err
+	}
+
+	_, sec := backup.Key(CONFIG.SKY.Seed)
+	if *out == "-" {
+		return archive.Write(os.Stdout, sec)
+	}
+
+	// written aside first so a failed export doesn't leave half an archive
+	file, err := os.OpenFile(*out+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err = archive.Write(file, sec); err != nil {
+		file.Close()
+		os.Remove(*out + ".tmp")
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(*out+".tmp", *out); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d files from %d desks to %s, signed by %s\n",
+		len(archive.Files), len(archive.Manifest.Desks), *out, archive.Manifest.Signer)
+	return nil
+}
+
+// importCommand restores an archive into empty desks.
+func importCommand(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	signer := flags.String("signer", backup.Signer(CONFIG.SKY.Seed),
+		"address the archive must be signed by (default the key of this config's SKY seed)")
+	configOut := flags.String("config-out", "", "also write the archived config (without secrets) here")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: otc import [-signer address] [-config-out path] <archive>")
+	}
+
+	var in io.Reader = os.Stdin
+	if flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	archive, err := backup.Read(in, *signer)
+	if err != nil {
+		return err
+	}
+
+	if *configOut != "" {
+		file, err := os.OpenFile(*configOut, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(archive.Files[backup.CONFIG])
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = archive.Restore(CONFIG); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "imported %d files into %d desks from an archive created %s\n",
+		len(archive.Files), len(archive.Manifest.Desks),
+		time.Unix(archive.Manifest.CreatedAt, 0).UTC().Format(time.RFC3339))
+	return nil
+}
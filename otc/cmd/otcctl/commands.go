@@ -0,0 +1,419 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/skycoin/services/otc/pkg/api/client"
+)
+
+var ErrArgs = errors.New("wrong number of arguments, see --help")
+
+// NewAdmin returns an admin api client for base (scheme and host) sending
+// token, if set, which selects the desk when otc runs more than one.
+func NewAdmin(base, token string) *client.Admin {
+	admin := client.NewAdmin(base)
+	admin.Token = token
+	return admin
+}
+
+// admin returns a client for the --url flag, or the selected profile.
+// --token overrides the profile's token.
+func admin(c *cli.Context) (*client.Admin, error) {
+	if u := c.GlobalString("url"); u != "" {
+		return NewAdmin(u, c.GlobalString("token")), nil
+	}
+
+	conf, err := LoadConfig(c.GlobalString("config"))
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := conf.Profile(c.GlobalString("profile"))
+	if err != nil {
+		return nil, err
+	}
+
+	token := profile.Token
+	if t := c.GlobalString("token"); t != "" {
+		token = t
+	}
+
+	return NewAdmin(profile.URL, token), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+func StatusCommand(c *cli.Context) error {
+	cl, err := admin(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cl.Status()
+	if err != nil {
+		return err
+	}
+
+	prices := res.Prices
+	if prices == nil {
+		prices = &client.StatusPrices{}
+	}
+
+	rows := [][]string{
+		{"paused", strconv.FormatBool(res.Paused)},
+		{"source", res.Source},
+		{"internal price", Coins(prices.Internal, 8) + " BTC (" + Time(prices.InternalUpdated) + ")"},
+		{"exchange price", Coins(prices.Exchange, 8) + " BTC (" + Time(prices.ExchangeUpdated) + ")"},
+	}
+	for _, p := range res.Pauses {
+		rows = append(rows, []string{"paused stage", PauseString(p.Currency, p.Stage)})
+	}
+	for _, w := range res.Windows {
+		rows = append(rows, []string{"maintenance", WindowString(w)})
+	}
+
+	return Print(c, res, nil, rows)
+}
+
+func PauseString(currency string, s client.Stage) string {
+	cur, stage := currency, string(s)
+	if cur == "" {
+		cur = "all currencies"
+	}
+	if stage == "" {
+		stage = "all stages"
+	}
+	return cur + " / " + stage
+}
+
+func WindowString(w client.Window) string {
+	return fmt.Sprintf("#%d %s %s - %s %s",
+		w.Id, PauseString(w.Currency, w.Stage), Time(w.Start), Time(w.End), w.Reason)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+func PauseCommand(pause bool) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		cl, err := admin(c)
+		if err != nil {
+			return err
+		}
+
+		return cl.Pause(&client.PauseRequest{
+			Pause:    pause,
+			Currency: c.String("currency"),
+			Stage:    c.String("stage"),
+		})
+	}
+}
+
+func PriceSetCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return ErrArgs
+	}
+
+	price, err := strconv.ParseUint(c.Args().First(), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	cl, err := admin(c)
+	if err != nil {
+		return err
+	}
+
+	return cl.SetPrice(&client.PriceRequest{Price: price})
+}
+
+func PriceHistoryCommand(c *cli.Context) error {
+	cl, err := admin(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cl.Prices(&client.PricesRequest{
+		Currency: c.String("currency"),
+		Source:   c.String("source"),
+		From:     c.Int64("from"),
+		To:       c.Int64("to"),
+		Interval: int64(c.Duration("interval") / time.Second),
+	})
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(res.Points))
+	for i, p := range res.Points {
+		rows[i] = []string{
+			Time(p.Time),
+			Coins(p.Open, 8),
+			Coins(p.High, 8),
+			Coins(p.Low, 8),
+			Coins(p.Close, 8),
+			strconv.Itoa(p.Count),
+		}
+	}
+
+	return Print(c, res, []string{"TIME", "OPEN", "HIGH", "LOW", "CLOSE", "COUNT"}, rows)
+}
+
+func PriceObservationCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return ErrArgs
+	}
+
+	id, err := strconv.ParseUint(c.Args().First(), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	cl, err := admin(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cl.Observation(&client.ObservationRequest{Id: id})
+	if err != nil {
+		return err
+	}
+
+	return Print(c, res, []string{"ID", "CURRENCY", "SOURCE", "VALUE", "TIME"}, [][]string{{
+		strconv.FormatUint(res.Id, 10),
+		res.Currency,
+		res.Source,
+		Coins(res.Value, 8),
+		Time(res.Time),
+	}})
+}
+
+func SourceCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return ErrArgs
+	}
+
+	cl, err := admin(c)
+	if err != nil {
+		return err
+	}
+
+	return cl.SetSource(&client.SourceRequest{Source: c.Args().First()})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+func MaintenanceListCommand(c *cli.Context) error {
+	cl, err := admin(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cl.Maintenance()
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(res))
+	for i, w := range res {
+		rows[i] = []string{
+			strconv.Itoa(w.Id),
+			PauseString(w.Currency, w.Stage),
+			Time(w.Start),
+			Time(w.End),
+			w.Reason,
+		}
+	}
+
+	return Print(c, res, []string{"ID", "PAUSES", "START", "END", "REASON"}, rows)
+}
+
+func MaintenanceScheduleCommand(c *cli.Context) error {
+	cl, err := admin(c)
+	if err != nil {
+		return err
+	}
+
+	start := c.Int64("start")
+	if start == 0 {
+		start = time.Now().Unix()
+	}
+
+	end := c.Int64("end")
+	if end == 0 {
+		end = start + int64(c.Duration("duration")/time.Second)
+	}
+
+	res, err := cl.Schedule(&client.MaintenanceRequest{
+		Currency: c.String("currency"),
+		Stage:    c.String("stage"),
+		Start:    start,
+		End:      end,
+		Reason:   c.String("reason"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return Print(c, res, nil, [][]string{{"scheduled", "#" + strconv.Itoa(res.Id)}})
+}
+
+func MaintenanceCancelCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return ErrArgs
+	}
+
+	id, err := strconv.Atoi(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	cl, err := admin(c)
+	if err != nil {
+		return err
+	}
+
+	return cl.Unschedule(&client.Id{Id: id})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+func TransactionsCommand(c *cli.Context) error {
+	cl, err := admin(c)
+	if err != nil {
+		return err
+	}
+
+	list := cl.Transactions
+	if c.Bool("pending") {
+		list = cl.TransactionsPending
+	} else if c.Bool("completed") {
+		list = cl.TransactionsCompleted
+	}
+
+	query := url.Values{}
+	for _, key := range []string{"status", "currency", "address"} {
+		if c.String(key) != "" {
+			query.Set(key, c.String(key))
+		}
+	}
+
+	res, err := list(query)
+	if err != nil {
+		return err
+	}
+
+	return Print(c, res, []string{"ID", "STATUS", "AMOUNT", "PAID", "TXID", "CREATED"}, OrderRows(res))
+}
+
+func OrderRows(orders []client.Order) [][]string {
+	rows := make([][]string, len(orders))
+	for i, order := range orders {
+		paid, txid, created := "-", "-", int64(0)
+		if order.Purchase != nil {
+			paid = Coins(order.Purchase.Amount, 6)
+			if order.Purchase.TxId != "" {
+				txid = order.Purchase.TxId
+			}
+		}
+		if order.Times != nil {
+			created = order.Times.CreatedAt
+		}
+
+		rows[i] = []string{
+			order.Id,
+			string(order.Status),
+			Coins(order.Amount, 8),
+			paid,
+			txid,
+			Time(created),
+		}
+	}
+	return rows
+}
+
+func AddressesCommand(c *cli.Context) error {
+	cl, err := admin(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cl.Addresses()
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(res))
+	for i, a := range res {
+		rows[i] = []string{a.Address, Coins(a.Balance, 6)}
+	}
+
+	return Print(c, res, []string{"ADDRESS", "BALANCE (SKY)"}, rows)
+}
+
+func HoldingCommand(c *cli.Context) error {
+	cl, err := admin(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := cl.Holding()
+	if err != nil {
+		return err
+	}
+
+	return Print(c, res, nil, [][]string{{"holding", Coins(res.Holding, 8) + " BTC"}})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// OrderCommand calls one of the /orders intervention endpoints, e.g.
+// (*client.Admin).Payout. args are the positional arguments after the order
+// id.
+func OrderCommand(intervene func(*client.Admin, *client.Intervention) (*client.Order, error), args ...string) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if c.NArg() != len(args)+1 {
+			return ErrArgs
+		}
+
+		req := &client.Intervention{
+			Id:   c.Args().Get(0),
+			Note: c.String("note"),
+		}
+
+		for i, arg := range args {
+			value := c.Args().Get(i + 1)
+
+			switch arg {
+			case "status":
+				req.Status = value
+			case "txid":
+				req.TxId = value
+			case "amount":
+				amount, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					return err
+				}
+				req.Amount = amount
+			}
+		}
+
+		cl, err := admin(c)
+		if err != nil {
+			return err
+		}
+
+		res, err := intervene(cl, req)
+		if err != nil {
+			return err
+		}
+
+		return Print(c, res, []string{"ID", "STATUS", "AMOUNT", "PAID", "TXID", "CREATED"},
+			OrderRows([]client.Order{*res}))
+	}
+}
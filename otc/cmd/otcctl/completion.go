@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli"
+)
+
+// completion scripts call otcctl with --generate-bash-completion, which
+// urfave/cli answers with the commands and flags valid at that point
+var COMPLETIONS = map[string]string{
+	"bash": `_otcctl_complete() {
+	local cur opts
+	COMPREPLY=()
+	cur="${COMP_WORDS[COMP_CWORD]}"
+	opts=$( "${COMP_WORDS[@]:0:$COMP_CWORD}" --generate-bash-completion )
+	COMPREPLY=( $(compgen -W "${opts}" -- "${cur}") )
+	return 0
+}
+complete -F _otcctl_complete otcctl
+`,
+	"zsh": `#compdef otcctl
+_otcctl() {
+	local -a opts
+	opts=("${(@f)$(${words[@]:0:#words[@]-1} --generate-bash-completion)}")
+	_describe 'values' opts
+}
+compdef _otcctl otcctl
+`,
+}
+
+func CompletionCommand(c *cli.Context) error {
+	script, ok := COMPLETIONS[c.Args().First()]
+	if !ok {
+		return fmt.Errorf("shell must be bash or zsh")
+	}
+
+	_, err := fmt.Fprint(c.App.Writer, script)
+	return err
+}
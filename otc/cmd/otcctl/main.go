@@ -0,0 +1,219 @@
+// otcctl is a command line client for the otc admin api.
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/skycoin/services/otc/pkg/api/client"
+)
+
+func main() {
+	if err := App().Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func App() *cli.App {
+	app := cli.NewApp()
+	app.Name = "otcctl"
+	app.Usage = "manage otc through its admin api"
+	app.EnableBashCompletion = true
+
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "config",
+			Usage:  "config file with profiles",
+			Value:  DefaultConfigPath(),
+			EnvVar: "OTCCTL_CONFIG",
+		},
+		cli.StringFlag{
+			Name:   "profile, p",
+			Usage:  "profile (deployment) to use, defaults to the config's default",
+			EnvVar: "OTCCTL_PROFILE",
+		},
+		cli.StringFlag{
+			Name:   "url",
+			Usage:  "admin api url, overrides the profile",
+			EnvVar: "OTCCTL_URL",
+		},
+		cli.StringFlag{
+			Name:   "token",
+			Usage:  "your operator token, or the token of the desk to manage, overrides the profile",
+			EnvVar: "OTCCTL_TOKEN",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print JSON instead of tables",
+		},
+	}
+
+	stageFlags := []cli.Flag{
+		cli.StringFlag{Name: "currency", Usage: "drop currency, empty for all"},
+		cli.StringFlag{Name: "stage", Usage: "bind, scan, send or monitor, empty for all"},
+	}
+
+	noteFlags := []cli.Flag{
+		cli.StringFlag{Name: "note", Usage: "reason for the change"},
+	}
+
+	app.Commands = []cli.Command{
+		{
+			Name:   "status",
+			Usage:  "show prices, price source, pauses and maintenance windows",
+			Action: StatusCommand,
+		},
+		{
+			Name:   "pause",
+			Usage:  "pause everything, or a stage and/or currency",
+			Flags:  stageFlags,
+			Action: PauseCommand(true),
+		},
+		{
+			Name:   "unpause",
+			Usage:  "undo pause",
+			Flags:  stageFlags,
+			Action: PauseCommand(false),
+		},
+		{
+			Name:  "price",
+			Usage: "internal price and price history",
+			Subcommands: []cli.Command{
+				{
+					Name:      "set",
+					Usage:     "set the internal BTC price of 1 SKY",
+					ArgsUsage: "<satoshis>",
+					Action:    PriceSetCommand,
+				},
+				{
+					Name:  "history",
+					Usage: "show downsampled price history",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "currency", Usage: "defaults to BTC"},
+						cli.StringFlag{Name: "source", Usage: "internal or exchange, empty for all"},
+						cli.Int64Flag{Name: "from", Usage: "unix time, defaults to a day before --to"},
+						cli.Int64Flag{Name: "to", Usage: "unix time, defaults to now"},
+						cli.DurationFlag{Name: "interval", Usage: "point interval"},
+					},
+					Action: PriceHistoryCommand,
+				},
+				{
+					Name:      "observation",
+					Usage:     "show a single price observation",
+					ArgsUsage: "<id>",
+					Action:    PriceObservationCommand,
+				},
+			},
+		},
+		{
+			Name:      "source",
+			Usage:     "set the price source",
+			ArgsUsage: "<exchange|internal>",
+			Action:    SourceCommand,
+		},
+		{
+			Name:  "maintenance",
+			Usage: "scheduled maintenance windows",
+			Subcommands: []cli.Command{
+				{
+					Name:   "list",
+					Usage:  "list current and upcoming windows",
+					Action: MaintenanceListCommand,
+				},
+				{
+					Name:  "schedule",
+					Usage: "schedule a window",
+					Flags: append([]cli.Flag{
+						cli.Int64Flag{Name: "start", Usage: "unix time, defaults to now"},
+						cli.Int64Flag{Name: "end", Usage: "unix time"},
+						cli.DurationFlag{Name: "duration", Usage: "used if --end isn't set", Value: time.Hour},
+						cli.StringFlag{Name: "reason"},
+					}, stageFlags...),
+					Action: MaintenanceScheduleCommand,
+				},
+				{
+					Name:      "cancel",
+					Usage:     "remove a window",
+					ArgsUsage: "<id>",
+					Action:    MaintenanceCancelCommand,
+				},
+			},
+		},
+		{
+			Name:    "transactions",
+			Aliases: []string{"tx"},
+			Usage:   "list orders",
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "pending", Usage: "only orders that aren't done"},
+				cli.BoolFlag{Name: "completed", Usage: "only done orders"},
+				cli.StringFlag{Name: "status"},
+				cli.StringFlag{Name: "currency", Usage: "drop currency"},
+				cli.StringFlag{Name: "address", Usage: "skycoin address"},
+			},
+			Action: TransactionsCommand,
+		},
+		{
+			Name:   "addresses",
+			Usage:  "list skycoin addresses and balances",
+			Action: AddressesCommand,
+		},
+		{
+			Name:   "holding",
+			Usage:  "show BTC held in deposit addresses",
+			Action: HoldingCommand,
+		},
+		{
+			Name:  "order",
+			Usage: "manual order intervention",
+			Subcommands: []cli.Command{
+				{
+					Name:      "annotate",
+					Usage:     "add a note",
+					ArgsUsage: "<id>",
+					Flags:     noteFlags,
+					Action:    OrderCommand((*client.Admin).Annotate),
+				},
+				{
+					Name:      "transition",
+					Usage:     "force a status",
+					ArgsUsage: "<id> <status>",
+					Flags:     noteFlags,
+					Action:    OrderCommand((*client.Admin).Transition, "status"),
+				},
+				{
+					Name:      "cancel",
+					Usage:     "cancel an order",
+					ArgsUsage: "<id>",
+					Flags:     noteFlags,
+					Action:    OrderCommand((*client.Admin).Cancel),
+				},
+				{
+					Name:      "payout",
+					Usage:     "record skycoin sent outside of otc",
+					ArgsUsage: "<id> <txid> <droplets>",
+					Flags:     noteFlags,
+					Action:    OrderCommand((*client.Admin).Payout, "txid", "amount"),
+				},
+				{
+					Name:      "requote",
+					Usage:     "fix the current price for an order waiting to be sent",
+					ArgsUsage: "<id>",
+					Flags:     noteFlags,
+					Action:    OrderCommand((*client.Admin).Requote),
+				},
+			},
+		},
+		{
+			Name:      "completion",
+			Usage:     "print a shell completion script",
+			ArgsUsage: "<bash|zsh>",
+			Action:    CompletionCommand,
+		},
+	}
+
+	return app
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Run runs otcctl against a fake admin api and returns what was printed
+// along with the last request received.
+func Run(t *testing.T, handler http.HandlerFunc, args ...string) (string, *http.Request, string) {
+	var (
+		last *http.Request
+		body string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		last, body = r, string(data)
+		handler(w, r)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	app := App()
+	app.Writer = &out
+
+	err := app.Run(append([]string{"otcctl", "--url", server.URL}, args...))
+	if err != nil {
+		return err.Error(), last, body
+	}
+
+	return out.String(), last, body
+}
+
+func Respond(res string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(res))
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	out, req, _ := Run(t, Respond(`[{"id":"tx:0","status":"done","amount":100000000,
+		"purchase":{"amount":500000000,"txid":"sky"},"times":{"created_at":0}}]`),
+		"transactions", "--pending", "--currency", "BTC")
+
+	if req.URL.Path != "/api/v1/transactions/pending" || req.URL.Query().Get("currency") != "BTC" {
+		t.Fatalf("unexpected request %s", req.URL)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "ID") {
+		t.Fatalf("unexpected table:\n%s", out)
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "tx:0 done 1.00000000 500.000000 sky -" {
+		t.Fatalf("unexpected row %q", lines[1])
+	}
+}
+
+func TestJSON(t *testing.T) {
+	out, _, _ := Run(t, Respond(`{"holding":150000000}`), "--json", "holding")
+
+	var res struct {
+		Holding uint64 `json:"holding"`
+	}
+	if err := json.Unmarshal([]byte(out), &res); err != nil || res.Holding != 150000000 {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestOrderPayout(t *testing.T) {
+	out, req, body := Run(t, Respond(`{"id":"tx:0","status":"waiting_confirm"}`),
+		"order", "payout", "tx:0", "sky", "1000000")
+
+	if req.URL.Path != "/api/v1/orders/payout" {
+		t.Fatalf("unexpected path %s", req.URL.Path)
+	}
+
+	expected := `{"amount":1000000,"id":"tx:0","txid":"sky"}`
+	if strings.TrimSpace(body) != expected {
+		t.Fatalf(`expected "%s", got "%s"`, expected, body)
+	}
+
+	if !strings.Contains(out, "waiting_confirm") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestError(t *testing.T) {
+	out, _, _ := Run(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "transition not allowed", http.StatusBadRequest)
+	}, "order", "cancel", "tx:0")
+
+	if out != "400: transition not allowed" {
+		t.Fatalf("unexpected error %q", out)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	file, err := ioutil.TempFile("", "otcctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	file.WriteString(`
+default = "local"
+
+[profiles.local]
+url = "http://localhost:8080"
+
+[profiles.production]
+url = "http://10.0.0.2:8080"
+`)
+	file.Close()
+
+	conf, err := LoadConfig(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"":           "http://localhost:8080",
+		"production": "http://10.0.0.2:8080",
+	}
+	for name, expected := range tests {
+		profile, err := conf.Profile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.URL != expected {
+			t.Fatalf(`expected "%s", got "%s"`, expected, profile.URL)
+		}
+	}
+
+	if _, err = conf.Profile("staging"); err == nil {
+		t.Fatal("expected missing profile error")
+	}
+
+	// no config file uses the default url
+	conf, err = LoadConfig(file.Name() + ".missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile, _ := conf.Profile(""); profile.URL != DEFAULT_URL {
+		t.Fatalf(`expected "%s", got "%s"`, DEFAULT_URL, profile.URL)
+	}
+}
+
+func TestToken(t *testing.T) {
+	_, req, _ := Run(t, Respond(`{"holding":0}`), "--token", "shop-token", "holding")
+
+	if auth := req.Header.Get("Authorization"); auth != "Bearer shop-token" {
+		t.Fatalf(`expected "Bearer shop-token", got "%s"`, auth)
+	}
+}
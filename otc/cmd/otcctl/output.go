@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// Print writes v as indented JSON if --json is set, otherwise as a table
+// with the given header and rows.
+func Print(c *cli.Context, v interface{}, header []string, rows [][]string) error {
+	if c.GlobalBool("json") {
+		enc := json.NewEncoder(c.App.Writer)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	return Table(c.App.Writer, header, rows)
+}
+
+func Table(w io.Writer, header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	if len(header) > 0 {
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// Time formats unix seconds, or "-" for zero.
+func Time(unix int64) string {
+	if unix == 0 {
+		return "-"
+	}
+	return time.Unix(unix, 0).UTC().Format("2006-01-02 15:04:05")
+}
+
+// Coins formats an amount in the smallest unit of a coin with the given
+// number of decimals (8 for satoshis, 6 for droplets).
+func Coins(amount uint64, decimals int) string {
+	s := fmt.Sprintf("%0*d", decimals+1, amount)
+	return s[:len(s)-decimals] + "." + s[len(s)-decimals:]
+}
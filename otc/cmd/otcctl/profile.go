@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// used without a config file or profile
+	DEFAULT_URL string = "http://localhost:8080"
+	// in the home directory
+	CONFIG_FILE string = ".otcctl.toml"
+)
+
+// Config holds a profile per otc deployment:
+//
+//	default = "local"
+//
+//	[profiles.local]
+//	url = "http://localhost:8080"
+//
+//	[profiles.production]
+//	url = "http://10.0.0.2:8080"
+//	token = "..."
+type Config struct {
+	Default  string
+	Profiles map[string]*Profile
+}
+
+type Profile struct {
+	URL string
+	// admin token of the desk, see otc's [Desk] config
+	Token string
+}
+
+// DefaultConfigPath is ~/.otcctl.toml, or empty without a home directory.
+func DefaultConfigPath() string {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, CONFIG_FILE)
+}
+
+// LoadConfig reads the config file. A missing file is an empty config.
+func LoadConfig(path string) (*Config, error) {
+	conf := &Config{Profiles: make(map[string]*Profile)}
+
+	if path == "" {
+		return conf, nil
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return conf, nil
+	}
+
+	if _, err := toml.DecodeFile(path, conf); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+// Profile returns the named profile, the default profile if name is empty,
+// or a profile for DEFAULT_URL if there's neither.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Default
+	}
+
+	if name == "" {
+		return &Profile{URL: DEFAULT_URL}, nil
+	}
+
+	profile := c.Profiles[name]
+	if profile == nil {
+		return nil, fmt.Errorf("profile %s missing", name)
+	}
+
+	return profile, nil
+}
@@ -1,71 +1,244 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"net/http"
 	"os"
 	"os/signal"
+	"time"
 
+	"github.com/skycoin/services/otc/pkg/alerts"
 	"github.com/skycoin/services/otc/pkg/api/admin"
 	"github.com/skycoin/services/otc/pkg/api/public"
 	"github.com/skycoin/services/otc/pkg/currencies"
 	"github.com/skycoin/services/otc/pkg/currencies/btc"
 	"github.com/skycoin/services/otc/pkg/currencies/sky"
+	"github.com/skycoin/services/otc/pkg/desk"
+	"github.com/skycoin/services/otc/pkg/limiter"
 	"github.com/skycoin/services/otc/pkg/model"
 	"github.com/skycoin/services/otc/pkg/otc"
+	"github.com/skycoin/services/otc/pkg/scanner"
+	"github.com/skycoin/services/otc/pkg/server"
 	"github.com/skycoin/services/otc/pkg/watcher"
 )
 
+var CONFIG *otc.Config
+
 var (
-	CURRENCIES = currencies.New()
-	CONFIG     *otc.Config
+	configPath  = flag.String("config", "config.toml", "path to the config file")
+	checkConfig = flag.Bool("check-config", false, "validate the config and exit")
 )
 
-func init() {
+func setup() {
 	var err error
 
-	CONFIG, err = otc.NewConfig("config.toml")
+	CONFIG, err = otc.NewConfig(*configPath)
+	if err == nil {
+		err = CONFIG.Validate()
+	}
 	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *checkConfig {
+		fmt.Printf("%s ok\n", *configPath)
+		os.Exit(0)
+	}
+}
+
+// start connects a desk's nodes and builds its model, apis and alerts.
+// Alerts stop when alertStop receives.
+func start(id string, conf *otc.Config, alertStop chan struct{}) *desk.Desk {
+	d := &desk.Desk{Id: id, Config: conf, Currencies: currencies.New()}
+
+	path := desk.Path(id, conf)
+	if err := os.MkdirAll(path, 0755); err != nil {
 		panic(err)
 	}
 
-	SKY, err := sky.New(CONFIG)
+	// every price seen is kept for charting and order disputes
+	retention := time.Duration(conf.Prices.Retention) * time.Second
+	history, err := currencies.NewHistory(path+"prices.json", retention)
 	if err != nil {
 		panic(err)
 	}
-	CURRENCIES.Add(otc.SKY, SKY)
+	d.Currencies.History = history
 
-	BTC, err := btc.New(CONFIG)
+	var SKY *sky.Connection
+	err = connect(d.Name()+" sky", func() (err error) {
+		SKY, err = sky.New(conf)
+		return
+	})
 	if err != nil {
 		panic(err)
 	}
-	CURRENCIES.Add(otc.BTC, BTC)
+	d.Currencies.Add(otc.SKY, SKY)
+
+	var BTC *btc.Connection
+	err = connect(d.Name()+" btc", func() (err error) {
+		BTC, err = btc.New(conf)
+		return
+	})
+	if err != nil {
+		panic(err)
+	}
+	d.Currencies.Add(otc.BTC, BTC)
+
+	if conf.Desk.Price > 0 {
+		if err = d.Currencies.Prices[otc.BTC].SetPrice(currencies.INTERNAL, conf.Desk.Price); err != nil {
+			panic(err)
+		}
+	}
+
+	watch, err := watcher.New(conf)
+	if err != nil {
+		panic(err)
+	}
+
+	deposits := &scanner.Config{
+		Minimum: make(map[otc.Currency]uint64),
+		Window:  time.Duration(conf.Deposits.Window) * time.Second,
+	}
+	for cur, min := range conf.Deposits.Minimum {
+		deposits.Minimum[otc.Currency(cur)] = min
+	}
+
+	d.Model, err = model.New(&model.Config{
+		Currencies: d.Currencies,
+		Watcher:    watch,
+		Deposits:   deposits,
+		Expiry:     time.Duration(conf.Bind.Expiry) * time.Second,
+		Path:       path,
+	})
+	if err != nil {
+		panic(err)
+	}
+	if id != "" {
+		d.Model.Logs.SetPrefix("    [OTC " + id + "] ")
+	}
+
+	// price changes go to the live feeds
+	d.Currencies.History.Events = d.Model.Events
+
+	// alerts keep running while otc is paused
+	if conf.Alerts.Interval > 0 {
+		alerter := alerts.New(d.Name(), conf, d.Currencies, d.Model, d.Model.Logs)
+		go alerter.Run(time.Duration(conf.Alerts.Interval)*time.Second, alertStop)
+	}
+
+	period := time.Duration(conf.Bind.Period) * time.Second
+	guard := &public.Guard{
+		IP:      limiter.New(conf.Bind.IPLimit, period),
+		Address: limiter.New(conf.Bind.AddressLimit, period),
+		Proxied: conf.Bind.Proxied,
+	}
+	if conf.Bind.Work > 0 {
+		guard.Challenge = &public.Work{Bits: conf.Bind.Work}
+	}
+
+	d.Admin = admin.New(d.Currencies, d.Model)
+	d.Public = public.New(d.Currencies, d.Model, guard)
+
+	return d
+}
+
+// connect retries f as configured by CONFIG.Connect, so otc can be started
+// before the nodes it depends on.
+func connect(name string, f func() error) error {
+	delay := time.Duration(CONFIG.Connect.Delay) * time.Second
+	if delay == 0 {
+		delay = time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		if CONFIG.Connect.Retries > 0 && attempt >= CONFIG.Connect.Retries {
+			return fmt.Errorf("%s: %v (after %d attempts)", name, err, attempt)
+		}
+
+		fmt.Printf("%s: %v, retrying in %s\n", name, err, delay)
+		<-time.After(delay)
+
+		if delay *= 2; delay > time.Minute {
+			delay = time.Minute
+		}
+	}
 }
 
 func main() {
+	flag.Parse()
+	setup()
+
+	// otc export and otc import work on storage without starting desks
+	var err error
+	switch flag.Arg(0) {
+	case "":
+	case "export":
+		err = exportCommand(flag.Args()[1:])
+	case "import":
+		err = importCommand(flag.Args()[1:])
+	default:
+		err = fmt.Errorf("unknown command %s, expected export or import", flag.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if flag.NArg() > 0 {
+		return
+	}
+
 	// for graceful shutdown / cleanup
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt)
 
-	watch, err := watcher.New(CONFIG)
+	// the main desk first, it gets public requests for unknown hosts
+	alertStop := make(chan struct{})
+	desks := []*desk.Desk{start("", CONFIG, alertStop)}
+	for _, id := range CONFIG.DeskIds() {
+		desks = append(desks, start(id, CONFIG.Desks[id], alertStop))
+		fmt.Printf("desk %s started\n", id)
+	}
+
+	// listener errors stop otc instead of being dropped
+	errs := make(chan error, 2)
+
+	adminServer, err := server.New("api.admin", &CONFIG.API.Admin, desk.Admin(desks...))
 	if err != nil {
 		panic(err)
 	}
+	go server.Serve(adminServer, errs)
+	fmt.Printf("api.admin listening at %s\n", CONFIG.API.Admin.Listen)
 
-	modl, err := model.New(&model.Config{CURRENCIES, watch})
+	publicServer, err := server.New("api.public", &CONFIG.API.Public, desk.Public(desks...))
 	if err != nil {
 		panic(err)
 	}
+	go server.Serve(publicServer, errs)
+	fmt.Printf("api.public listening at %s\n", CONFIG.API.Public.Listen)
 
-	admin := admin.New(CURRENCIES, modl)
-	go http.ListenAndServe(CONFIG.API.Admin.Listen, admin)
-	fmt.Printf("api.admin listening at %s\n", CONFIG.API.Admin.Listen)
+	select {
+	case <-stop:
+		println("stopping")
+	case err = <-errs:
+		println(err.Error())
+	}
 
-	public := public.New(CURRENCIES, modl)
-	go http.ListenAndServe(CONFIG.API.Public.Listen, public)
-	fmt.Printf("api.public listening at %s\n", CONFIG.API.Public.Listen)
+	// let in-flight requests finish
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	adminServer.Shutdown(ctx)
+	publicServer.Shutdown(ctx)
 
-	<-stop
-	println("stopping")
-	modl.Controller.Stop()
+	// closed rather than sent to so every desk's alerter sees it
+	close(alertStop)
+	for _, d := range desks {
+		d.Model.Controller.Stop()
+	}
 }
@@ -36,6 +36,17 @@ func (a *Actor) Delete(work *otc.Work) {
 	a.Work.Delete(work)
 }
 
+// Remove deletes any work for the order, or an earlier copy of it, without
+// returning it.
+func (a *Actor) Remove(order *otc.Order) {
+	a.Work.Range(func(k, v interface{}) bool {
+		if work := k.(*otc.Work); work.Order == order || work.Order.Id == order.Id {
+			a.Delete(work)
+		}
+		return true
+	})
+}
+
 func (a *Actor) Ranger(task Task) func(k, v interface{}) bool {
 	return func(k, v interface{}) bool {
 		var work *otc.Work = k.(*otc.Work)
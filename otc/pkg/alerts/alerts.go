@@ -0,0 +1,173 @@
+// Package alerts tells operators about problems (stale prices, low
+// holdings, stuck orders, disconnected nodes) through email and webhooks.
+package alerts
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Alert is a problem found by a rule. It's sent when it starts firing, again
+// every Repeat while it lasts, and once more when resolved.
+type Alert struct {
+	// name of the desk the alert is about
+	Desk     string `json:"desk"`
+	Rule     string `json:"rule"`
+	Key      string `json:"key"`
+	Message  string `json:"message"`
+	Since    int64  `json:"since"`
+	Time     int64  `json:"time"`
+	Resolved bool   `json:"resolved"`
+
+	sent time.Time
+}
+
+func (a *Alert) Subject() string {
+	state := "FIRING"
+	if a.Resolved {
+		state = "RESOLVED"
+	}
+	return fmt.Sprintf("[otc %s] %s %s: %s", a.Desk, state, a.Rule, a.Message)
+}
+
+// Rule checks for a problem. Check returns a message for every instance of
+// it (by key, e.g. an order id), or none when all is well.
+type Rule struct {
+	Name  string
+	Check func(now time.Time) map[string]string
+}
+
+type Sink interface {
+	Send(*Alert) error
+}
+
+type Alerter struct {
+	sync.Mutex
+
+	// name of the desk whose rules are checked, see desk.Desk.Name
+	Desk  string
+	Rules []*Rule
+	Sinks []Sink
+	// firing alerts are sent again after Repeat, 0 only sends them once
+	Repeat time.Duration
+	// defaults to time.Now, replaced by tests
+	Now  func() time.Time
+	Logs *log.Logger
+
+	firing map[string]*Alert
+}
+
+func (a *Alerter) now() time.Time {
+	if a.Now == nil {
+		return time.Now()
+	}
+	return a.Now()
+}
+
+func (a *Alerter) Log(s string) {
+	if a.Logs != nil {
+		a.Logs.Println(s)
+	}
+}
+
+// Run checks rules every d until stop receives. Unlike the order workers
+// it keeps running while otc is paused.
+func (a *Alerter) Run(d time.Duration, stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			a.Log("alerts stopping")
+			return
+		case <-time.After(d):
+			a.Tick()
+		}
+	}
+}
+
+// Tick runs every rule and sends new, repeated and resolved alerts.
+func (a *Alerter) Tick() {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.firing == nil {
+		a.firing = make(map[string]*Alert)
+	}
+
+	now := a.now()
+	found := make(map[string]bool)
+
+	for _, rule := range a.Rules {
+		for key, message := range rule.Check(now) {
+			id := rule.Name + ":" + key
+			found[id] = true
+
+			alert := a.firing[id]
+			if alert == nil {
+				alert = &Alert{
+					Desk:  a.Desk,
+					Rule:  rule.Name,
+					Key:   key,
+					Since: now.UTC().Unix(),
+				}
+				a.firing[id] = alert
+			} else if a.Repeat == 0 || now.Sub(alert.sent) < a.Repeat {
+				// messages change as problems age, that alone isn't news
+				alert.Message = message
+				continue
+			}
+
+			alert.Message = message
+			alert.Time = now.UTC().Unix()
+			alert.sent = now
+			a.Send(alert)
+		}
+	}
+
+	// sorted so resolved alerts go out in a stable order
+	ids := make([]string, 0)
+	for id := range a.firing {
+		if !found[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		alert := a.firing[id]
+		delete(a.firing, id)
+
+		alert.Resolved = true
+		alert.Time = now.UTC().Unix()
+		a.Send(alert)
+	}
+}
+
+// Firing returns the alerts currently firing.
+func (a *Alerter) Firing() []*Alert {
+	a.Lock()
+	defer a.Unlock()
+
+	firing := make([]*Alert, 0, len(a.firing))
+	for _, alert := range a.firing {
+		firing = append(firing, alert)
+	}
+	sort.Slice(firing, func(i, j int) bool {
+		return firing[i].Rule+firing[i].Key < firing[j].Rule+firing[j].Key
+	})
+	return firing
+}
+
+// Send delivers an alert to every sink, logging failures so one broken
+// sink doesn't stop the others.
+func (a *Alerter) Send(alert *Alert) {
+	a.Log(alert.Subject())
+
+	for _, sink := range a.Sinks {
+		if err := sink.Send(alert); err != nil {
+			a.Log(fmt.Sprintf("alert sink %T: %v", sink, err))
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package alerts
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+type MockSink struct {
+	Sent []Alert
+}
+
+func (s *MockSink) Send(alert *Alert) error {
+	s.Sent = append(s.Sent, *alert)
+	return nil
+}
+
+type MockConnection struct {
+	currencies.Connection
+	connected bool
+	holding   uint64
+	err       error
+}
+
+func (c *MockConnection) Connected() (bool, error) {
+	return c.connected, c.err
+}
+
+func (c *MockConnection) Holding() (uint64, error) {
+	return c.holding, c.err
+}
+
+func TestAlerterTick(t *testing.T) {
+	now := time.Unix(1000, 0)
+	found := map[string]string{"a": "first"}
+
+	sink := &MockSink{}
+	alerter := &Alerter{
+		Desk: "shop",
+		Rules: []*Rule{{"rule", func(time.Time) map[string]string {
+			return found
+		}}},
+		Sinks:  []Sink{sink},
+		Repeat: time.Minute,
+		Now:    func() time.Time { return now },
+	}
+
+	alerter.Tick()
+	if len(sink.Sent) != 1 || sink.Sent[0].Message != "first" || sink.Sent[0].Resolved || sink.Sent[0].Desk != "shop" {
+		t.Fatalf("expected firing alert, got %+v", sink.Sent)
+	}
+	if len(alerter.Firing()) != 1 {
+		t.Fatal("expected 1 firing")
+	}
+
+	// changed message within repeat isn't sent
+	now = now.Add(time.Second * 30)
+	found["a"] = "second"
+	alerter.Tick()
+	if len(sink.Sent) != 1 {
+		t.Fatalf("expected no repeat, got %+v", sink.Sent)
+	}
+
+	now = now.Add(time.Second * 31)
+	alerter.Tick()
+	if len(sink.Sent) != 2 || sink.Sent[1].Message != "second" || sink.Sent[1].Since != 1000 {
+		t.Fatalf("expected repeat, got %+v", sink.Sent)
+	}
+
+	delete(found, "a")
+	alerter.Tick()
+	if len(sink.Sent) != 3 || !sink.Sent[2].Resolved {
+		t.Fatalf("expected resolved, got %+v", sink.Sent)
+	}
+	if len(alerter.Firing()) != 0 {
+		t.Fatal("expected none firing")
+	}
+
+	alerter.Tick()
+	if len(sink.Sent) != 3 {
+		t.Fatal("expected resolved to be sent once")
+	}
+}
+
+func TestAlerterNoRepeat(t *testing.T) {
+	now := time.Unix(1000, 0)
+	sink := &MockSink{}
+	alerter := &Alerter{
+		Rules: []*Rule{{"rule", func(time.Time) map[string]string {
+			return map[string]string{"a": "message"}
+		}}},
+		Sinks: []Sink{sink},
+		Now:   func() time.Time { return now },
+	}
+
+	for i := 0; i < 3; i++ {
+		alerter.Tick()
+		now = now.Add(time.Hour)
+	}
+
+	if len(sink.Sent) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(sink.Sent))
+	}
+}
+
+func TestPriceStale(t *testing.T) {
+	now := time.Now()
+	curs := currencies.New()
+
+	rule := PriceStale(curs, otc.BTC, time.Minute)
+	if len(rule.Check(now)) != 1 {
+		t.Fatal("expected missing price to fire")
+	}
+
+	curs.Prices[otc.BTC] = &currencies.Pricer{
+		Sources: map[currencies.Source]*currencies.Price{
+			currencies.EXCHANGE: {Amount: 1, Updated: now.Add(-time.Second)},
+		},
+	}
+	if found := rule.Check(now); len(found) != 0 {
+		t.Fatalf("expected fresh price, got %v", found)
+	}
+
+	if found := rule.Check(now.Add(time.Hour)); found["BTC"] == "" {
+		t.Fatalf("expected stale price, got %v", found)
+	}
+}
+
+func TestHoldingBelow(t *testing.T) {
+	conn := &MockConnection{holding: 10}
+	curs := currencies.New()
+	curs.Connections[otc.SKY] = conn
+
+	rule := HoldingBelow(curs, otc.SKY, 10)
+	if found := rule.Check(time.Now()); len(found) != 0 {
+		t.Fatalf("expected no alert, got %v", found)
+	}
+
+	conn.holding = 9
+	if found := rule.Check(time.Now()); found["SKY"] == "" {
+		t.Fatalf("expected low holding, got %v", found)
+	}
+
+	conn.err = errors.New("unreachable")
+	if found := rule.Check(time.Now()); !strings.Contains(found["SKY"], "unreachable") {
+		t.Fatalf("expected error, got %v", found)
+	}
+}
+
+func TestNodeDown(t *testing.T) {
+	curs := currencies.New()
+	curs.Connections[otc.SKY] = &MockConnection{connected: true}
+	curs.Connections[otc.BTC] = &MockConnection{}
+
+	found := NodeDown(curs).Check(time.Now())
+	if len(found) != 1 || found["BTC"] == "" {
+		t.Fatalf("expected BTC down, got %v", found)
+	}
+}
+
+func MockModel(orders ...*otc.Order) *model.Model {
+	lookup := model.NewLookup()
+	for _, order := range orders {
+		lookup.AddOrder(order)
+	}
+	return &model.Model{Lookup: lookup}
+}
+
+func TestOrderStuck(t *testing.T) {
+	user := &otc.User{Drop: &otc.Drop{Address: "drop", Currency: otc.BTC}}
+	modl := MockModel(
+		&otc.Order{User: user, Id: "sending", Status: otc.SEND,
+			Times: &otc.Times{UpdatedAt: 100},
+			Events: []*otc.Event{
+				{Status: otc.COLLECT, Finished: 100},
+				{Status: otc.SEND, Finished: 200},
+				{Status: otc.SEND, Finished: 300, Err: "retry"},
+			}},
+		&otc.Order{User: user, Id: "new", Status: otc.CONFIRM,
+			Times: &otc.Times{UpdatedAt: 900}},
+		&otc.Order{User: user, Id: "done", Status: otc.DONE,
+			Times: &otc.Times{UpdatedAt: 0}},
+	)
+
+	found := OrderStuck(modl, time.Minute*10).Check(time.Unix(1000, 0))
+	if len(found) != 1 || found["sending"] == "" {
+		t.Fatalf("expected sending stuck, got %v", found)
+	}
+}
+
+func TestOrderFailed(t *testing.T) {
+	user := &otc.User{Drop: &otc.Drop{Address: "drop", Currency: otc.BTC}}
+	modl := MockModel(
+		&otc.Order{User: user, Id: "failed", Status: otc.SEND,
+			Events: []*otc.Event{{Status: otc.SEND, Err: "no funds"}}},
+		&otc.Order{User: user, Id: "recovered", Status: otc.CONFIRM,
+			Events: []*otc.Event{{Status: otc.SEND, Err: "no funds"}, {Status: otc.CONFIRM}}},
+		&otc.Order{User: user, Id: "cancelled", Status: otc.CANCELLED,
+			Events: []*otc.Event{{Status: otc.SEND, Err: "no funds"}}},
+	)
+
+	found := OrderFailed(modl).Check(time.Now())
+	if len(found) != 1 || !strings.Contains(found["failed"], "no funds") {
+		t.Fatalf("expected failed order, got %v", found)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var got Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	alert := &Alert{Desk: "main", Rule: "node_down", Key: "BTC", Message: "BTC node disconnected"}
+	if err := (&Webhook{URL: server.URL}).Send(alert); err != nil {
+		t.Fatal(err)
+	}
+	if got.Desk != alert.Desk || got.Rule != alert.Rule || got.Message != alert.Message {
+		t.Fatalf("expected %+v, got %+v", alert, got)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	var got struct{ Text string }
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	slack := &Slack{URL: server.URL}
+	alert := &Alert{Desk: "main", Rule: "node_down", Message: "BTC node disconnected", Resolved: true}
+	if err := slack.Send(alert); err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != ":white_check_mark: [otc main] RESOLVED node_down: BTC node disconnected" {
+		t.Fatalf("unexpected text %q", got.Text)
+	}
+
+	status = http.StatusNotFound
+	if err := slack.Send(alert); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// MockSMTP accepts a single message on a local port, speaking just enough
+// SMTP for net/smtp.
+func MockSMTP(t *testing.T) (string, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := make(chan string, 1)
+
+	go func() {
+		defer listener.Close()
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 localhost")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "DATA":
+				reply("354 go ahead")
+				var msg strings.Builder
+				for {
+					line, err = r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					msg.WriteString(line)
+				}
+				messages <- msg.String()
+				reply("250 ok")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+func TestSMTP(t *testing.T) {
+	addr, messages := MockSMTP(t)
+
+	sink := &SMTP{Addr: addr, From: "otc@localhost", To: []string{"ops@localhost"}}
+	alert := &Alert{Desk: "shop", Rule: "holding_low", Key: "SKY", Message: "SKY holding 1 below 10", Time: 1000}
+	if err := sink.Send(alert); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-messages
+	for _, want := range []string{
+		"To: ops@localhost",
+		"Subject: [otc shop] FIRING holding_low: SKY holding 1 below 10",
+		"desk: shop",
+		"key: SKY",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in message:\n%s", want, msg)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	conf := &otc.Config{}
+	conf.Alerts.PriceStale = 60
+	conf.Alerts.Nodes = true
+	conf.Alerts.Holding = map[string]uint64{"SKY": 1}
+	conf.Alerts.Webhook.URL = "http://localhost"
+	conf.Alerts.SMTP.Addr = "localhost:25"
+
+	curs := currencies.New()
+	curs.Prices[otc.BTC] = &currencies.Pricer{}
+
+	alerter := New("shop", conf, curs, MockModel(), nil)
+	if len(alerter.Rules) != 3 {
+		t.Fatalf("expected 3 rules, got %d", len(alerter.Rules))
+	}
+	if len(alerter.Sinks) != 2 {
+		t.Fatalf("expected 2 sinks, got %d", len(alerter.Sinks))
+	}
+	if alerter.Desk != "shop" {
+		t.Fatalf(`expected desk "shop", got "%s"`, alerter.Desk)
+	}
+}
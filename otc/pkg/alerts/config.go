@@ -0,0 +1,59 @@
+package alerts
+
+import (
+	"log"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// New builds an alerter from the [Alerts] config section. Rules with a zero
+// threshold and sinks without an address are left out.
+func New(desk string, conf *otc.Config, curs *currencies.Currencies, modl *model.Model, logs *log.Logger) *Alerter {
+	c := &conf.Alerts
+	a := &Alerter{
+		Desk:   desk,
+		Rules:  make([]*Rule, 0),
+		Sinks:  make([]Sink, 0),
+		Repeat: time.Duration(c.Repeat) * time.Second,
+		Logs:   logs,
+	}
+
+	if c.PriceStale > 0 {
+		for cur := range curs.Prices {
+			a.Rules = append(a.Rules, PriceStale(curs, cur, time.Duration(c.PriceStale)*time.Second))
+		}
+	}
+	for cur, min := range c.Holding {
+		a.Rules = append(a.Rules, HoldingBelow(curs, otc.Currency(cur), min))
+	}
+	if c.Nodes {
+		a.Rules = append(a.Rules, NodeDown(curs))
+	}
+	if c.OrderStuck > 0 {
+		a.Rules = append(a.Rules, OrderStuck(modl, time.Duration(c.OrderStuck)*time.Second))
+	}
+	if c.OrderFailed {
+		a.Rules = append(a.Rules, OrderFailed(modl))
+	}
+
+	if c.SMTP.Addr != "" {
+		a.Sinks = append(a.Sinks, &SMTP{
+			Addr: c.SMTP.Addr,
+			From: c.SMTP.From,
+			To:   c.SMTP.To,
+			User: c.SMTP.User,
+			Pass: c.SMTP.Pass,
+		})
+	}
+	if c.Webhook.URL != "" {
+		a.Sinks = append(a.Sinks, &Webhook{URL: c.Webhook.URL})
+	}
+	if c.Slack.URL != "" {
+		a.Sinks = append(a.Sinks, &Slack{URL: c.Slack.URL})
+	}
+
+	return a
+}
@@ -0,0 +1,143 @@
+package alerts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// PriceStale fires when the exchange price for cur hasn't updated within
+// max, meaning orders are filled at the internal price.
+func PriceStale(curs *currencies.Currencies, cur otc.Currency, max time.Duration) *Rule {
+	return &Rule{"price_stale", func(now time.Time) map[string]string {
+		missing := map[string]string{string(cur): fmt.Sprintf("no exchange price for %s", cur)}
+		pricer := curs.Prices[cur]
+		if pricer == nil {
+			return missing
+		}
+
+		updated, ok := pricer.Updated(currencies.EXCHANGE)
+		if !ok {
+			return missing
+		}
+
+		if age := now.Sub(updated); age > max {
+			return map[string]string{string(cur): fmt.Sprintf(
+				"%s exchange price not updated for %s", cur, age.Truncate(time.Second))}
+		}
+		return nil
+	}}
+}
+
+// HoldingBelow fires when the wallet for cur holds less than min (in
+// droplets or satoshis), or its holding can't be read.
+func HoldingBelow(curs *currencies.Currencies, cur otc.Currency, min uint64) *Rule {
+	return &Rule{"holding_low", func(now time.Time) map[string]string {
+		holding, err := curs.Holding(cur)
+		if err != nil {
+			return map[string]string{string(cur): fmt.Sprintf("%s holding unavailable: %v", cur, err)}
+		}
+
+		if holding < min {
+			return map[string]string{string(cur): fmt.Sprintf(
+				"%s holding %d below %d", cur, holding, min)}
+		}
+		return nil
+	}}
+}
+
+// NodeDown fires for every connection that reports itself disconnected.
+func NodeDown(curs *currencies.Currencies) *Rule {
+	return &Rule{"node_down", func(now time.Time) map[string]string {
+		found := make(map[string]string)
+
+		for cur, conn := range curs.Connections {
+			connected, err := conn.Connected()
+			if err != nil {
+				found[string(cur)] = fmt.Sprintf("%s node: %v", cur, err)
+			} else if !connected {
+				found[string(cur)] = fmt.Sprintf("%s node disconnected", cur)
+			}
+		}
+
+		return found
+	}}
+}
+
+// STUCK are the statuses an order is expected to leave on its own.
+var STUCK = []otc.Status{otc.COLLECT, otc.SEND, otc.CONFIRM}
+
+// OrderStuck fires for orders that have been in a processing status for
+// longer than max.
+func OrderStuck(modl *model.Model, max time.Duration) *Rule {
+	return &Rule{"order_stuck", func(now time.Time) map[string]string {
+		found := make(map[string]string)
+
+		for _, order := range modl.Orders() {
+			if !stuck(order.Status) {
+				continue
+			}
+
+			age := now.Sub(time.Unix(Entered(&order), 0))
+			if age > max {
+				found[order.Id] = fmt.Sprintf("order %s %s for %s",
+					order.Id, order.Status, age.Truncate(time.Second))
+			}
+		}
+
+		return found
+	}}
+}
+
+// OrderFailed fires for unfinished orders whose last attempt failed, until
+// an attempt succeeds.
+func OrderFailed(modl *model.Model) *Rule {
+	return &Rule{"order_failed", func(now time.Time) map[string]string {
+		found := make(map[string]string)
+
+		for _, order := range modl.Orders() {
+			if order.Status == otc.DONE || order.Status == otc.CANCELLED || len(order.Events) == 0 {
+				continue
+			}
+
+			if last := order.Events[len(order.Events)-1]; last.Err != "" {
+				found[order.Id] = fmt.Sprintf("order %s %s: %s", order.Id, order.Status, last.Err)
+			}
+		}
+
+		return found
+	}}
+}
+
+func stuck(status otc.Status) bool {
+	for _, s := range STUCK {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Entered returns when the order moved into its current status: the first
+// of the trailing events with that status, failed retries included.
+func Entered(order *otc.Order) int64 {
+	var at int64
+	if order.Times != nil {
+		at = order.Times.UpdatedAt
+		if at == 0 {
+			at = order.Times.CreatedAt
+		}
+	}
+
+	for i := len(order.Events) - 1; i >= 0; i-- {
+		if order.Events[i].Status != order.Status {
+			break
+		}
+		at = order.Events[i].Finished
+	}
+
+	return at
+}
@@ -0,0 +1,92 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP emails alerts. Auth is only used when User is set.
+type SMTP struct {
+	// host:port
+	Addr string
+	From string
+	To   []string
+	User string
+	Pass string
+}
+
+func (s *SMTP) Send(alert *Alert) error {
+	host := strings.Split(s.Addr, ":")[0]
+
+	var auth smtp.Auth
+	if s.User != "" {
+		auth = smtp.PlainAuth("", s.User, s.Pass, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", alert.Subject())
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Unix(alert.Time, 0).UTC().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n\r\ndesk: %s\r\nrule: %s\r\nkey: %s\r\nsince: %s\r\n",
+		alert.Message, alert.Desk, alert.Rule, alert.Key,
+		time.Unix(alert.Since, 0).UTC().Format(time.RFC3339))
+
+	return smtp.SendMail(s.Addr, auth, s.From, s.To, msg.Bytes())
+}
+
+// Webhook posts alerts as JSON.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *Webhook) Send(alert *Alert) error {
+	return post(w.Client, w.URL, alert)
+}
+
+// Slack posts alerts to an incoming webhook, or anything accepting the
+// same {"text": ...} payload (Mattermost, Rocket.Chat).
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *Slack) Send(alert *Alert) error {
+	icon := ":rotating_light:"
+	if alert.Resolved {
+		icon = ":white_check_mark:"
+	}
+
+	return post(s.Client, s.URL, &struct {
+		Text string `json:"text"`
+	}{icon + " " + alert.Subject()})
+}
+
+func post(client *http.Client, url string, body interface{}) error {
+	if client == nil {
+		client = &http.Client{Timeout: time.Second * 10}
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", &buf)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package admin
 import (
 	"net/http"
 
+	"github.com/skycoin/services/otc/pkg/api"
+	"github.com/skycoin/services/otc/pkg/api/spec"
 	"github.com/skycoin/services/otc/pkg/currencies"
 	"github.com/skycoin/services/otc/pkg/model"
 	"github.com/skycoin/services/otc/pkg/otc"
@@ -10,14 +12,25 @@ import (
 
 func New(curs *currencies.Currencies, modl *model.Model) *http.ServeMux {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/status", Status(curs, modl))
-	mux.HandleFunc("/api/pause", Pause(curs, modl))
-	mux.HandleFunc("/api/price", Price(curs, modl))
-	mux.HandleFunc("/api/source", Source(curs, modl))
-	mux.HandleFunc("/api/transactions", Transactions(curs, modl))
-	mux.HandleFunc("/api/transactions/pending", TransactionsPending(curs, modl))
-	mux.HandleFunc("/api/transactions/completed", TransactionsCompleted(curs, modl))
-	mux.HandleFunc("/api/addresses/sky", Addresses(otc.SKY, curs, modl))
-	mux.HandleFunc("/api/holding/btc", Holding(otc.BTC, curs, modl))
+	api.Handle(mux, "/status", Status(curs, modl))
+	api.Handle(mux, "/events", Events(curs, modl))
+	api.Handle(mux, "/pause", Pause(curs, modl))
+	api.Handle(mux, "/maintenance", Maintenance(curs, modl))
+	api.Handle(mux, "/maintenance/cancel", MaintenanceCancel(curs, modl))
+	api.Handle(mux, "/price", Price(curs, modl))
+	api.Handle(mux, "/prices", Prices(curs, modl))
+	api.Handle(mux, "/prices/observation", PricesObservation(curs, modl))
+	api.Handle(mux, "/source", Source(curs, modl))
+	api.Handle(mux, "/transactions", Transactions(curs, modl))
+	api.Handle(mux, "/transactions/pending", TransactionsPending(curs, modl))
+	api.Handle(mux, "/transactions/completed", TransactionsCompleted(curs, modl))
+	api.Handle(mux, "/orders/annotate", OrdersAnnotate(curs, modl))
+	api.Handle(mux, "/orders/transition", OrdersTransition(curs, modl))
+	api.Handle(mux, "/orders/cancel", OrdersCancel(curs, modl))
+	api.Handle(mux, "/orders/payout", OrdersPayout(curs, modl))
+	api.Handle(mux, "/orders/requote", OrdersRequote(curs, modl))
+	api.Handle(mux, "/addresses/sky", Addresses(otc.SKY, curs, modl))
+	api.Handle(mux, "/holding/btc", Holding(otc.BTC, curs, modl))
+	api.Spec(mux, spec.ADMIN)
 	return mux
 }
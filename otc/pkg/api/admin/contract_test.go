@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/services/otc/pkg/api"
+	"github.com/skycoin/services/otc/pkg/api/spec"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// TestContract checks handler responses against the OpenAPI document.
+func TestContract(t *testing.T) {
+	modl := MockOrderModel(t, otc.SEND)
+	defer os.RemoveAll(model.PATH)
+
+	doc := spec.MustParse(spec.ADMIN)
+	mux := New(MockHistory(), modl)
+
+	tests := []struct {
+		Method, Path, Body string
+		Status             int
+	}{
+		{"GET", "/status", ``, 200},
+		{"POST", "/pause", `{"pause":true,"currency":"BTC","stage":"send"}`, 200},
+		{"POST", "/pause", `{"pause":true,"stage":"never"}`, 400},
+		{"POST", "/maintenance", `{"start":100,"end":4000000000,"reason":"upgrade"}`, 200},
+		{"POST", "/maintenance", `{"start":100,"end":50}`, 400},
+		{"GET", "/maintenance", ``, 200},
+		{"POST", "/maintenance/cancel", `{"id":1}`, 200},
+		{"POST", "/price", `{"price":150}`, 200},
+		{"POST", "/prices", `{"from":900,"to":1200,"interval":60}`, 200},
+		{"POST", "/prices", `{"from":1200,"to":900}`, 400},
+		{"POST", "/prices/observation", `{"id":1}`, 200},
+		{"POST", "/prices/observation", `{"id":100}`, 404},
+		{"POST", "/source", `{"source":"exchange"}`, 200},
+		{"POST", "/source", `{"source":"moon"}`, 400},
+		{"GET", "/transactions?currency=BTC", ``, 200},
+		{"GET", "/transactions/pending", ``, 200},
+		{"GET", "/transactions/completed", ``, 200},
+		{"POST", "/orders/annotate", `{"id":"transaction:0","note":"checking"}`, 200},
+		{"POST", "/orders/requote", `{"id":"transaction:0"}`, 200},
+		{"POST", "/orders/transition", `{"id":"transaction:0","status":"done"}`, 400},
+		{"POST", "/orders/payout", `{"id":"transaction:0","txid":"tx","amount":5}`, 200},
+		{"POST", "/orders/cancel", `{"id":"transaction:0"}`, 400},
+		{"GET", "/addresses/sky", ``, 500},
+		{"GET", "/holding/btc", ``, 500},
+		{"GET", "/openapi.json", ``, 200},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.Method, "/api/v1"+test.Path, strings.NewReader(test.Body))
+		req = api.WithOperator(req, "karl")
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, req)
+
+		if res.Code != test.Status {
+			t.Fatalf("%s %s: expected %d, got %d (%s)", test.Method, test.Path,
+				test.Status, res.Code, res.Body.String())
+		}
+
+		path := strings.Split(test.Path, "?")[0]
+		err := doc.Check(test.Method, path, res.Code, res.Header().Get("Content-Type"), res.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestRoutes checks that every documented path is served, versioned and
+// unversioned.
+func TestRoutes(t *testing.T) {
+	mux := New(MockCurrencies(), MockModel())
+
+	for path := range spec.MustParse(spec.ADMIN).Paths {
+		prefixes := []string{"/api/v1"}
+		if path != "/openapi.json" {
+			prefixes = append(prefixes, "/api")
+		}
+
+		for _, prefix := range prefixes {
+			_, pattern := mux.Handler(httptest.NewRequest("GET", prefix+path, nil))
+			if pattern != prefix+path {
+				t.Fatalf("%s%s isn't routed", prefix, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/events"
+	"github.com/skycoin/services/otc/pkg/model"
+)
+
+// Events streams every order, price and pause change as server-sent
+// events.
+func Events(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		events.Serve(w, r, modl.Events, nil)
+	}
+}
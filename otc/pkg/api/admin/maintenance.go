@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func Maintenance(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			json.NewEncoder(w).Encode(modl.Controller.GetWindows())
+			return
+		}
+
+		var (
+			req = &struct {
+				Currency string `json:"currency"`
+				Stage    string `json:"stage"`
+				Start    int64  `json:"start"`
+				End      int64  `json:"end"`
+				Reason   string `json:"reason"`
+			}{}
+			err error
+		)
+
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if !ValidStage(req.Stage) {
+			http.Error(w, "invalid stage", http.StatusBadRequest)
+			return
+		}
+
+		id, err := modl.Controller.Schedule(&model.Window{
+			Pause: model.Pause{
+				Currency: otc.Currency(req.Currency),
+				Stage:    model.Stage(req.Stage),
+			},
+			Start:  req.Start,
+			End:    req.End,
+			Reason: req.Reason,
+		})
+		if err == model.ErrWindowInvalid {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(&struct {
+			Id int `json:"id"`
+		}{id})
+	}
+}
+
+func MaintenanceCancel(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			req = &struct {
+				Id int `json:"id"`
+			}{}
+			err error
+		)
+
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if err = modl.Controller.Unschedule(req.Id); err == model.ErrWindowMissing {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
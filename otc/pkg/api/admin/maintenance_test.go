@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func MockMaintenanceSend(modl *model.Model, data string) string {
+	res := httptest.NewRecorder()
+	req := MockRequest(data)
+	req.Method = "POST"
+
+	Maintenance(nil, modl)(res, req)
+
+	out, _ := ioutil.ReadAll(res.Body)
+	return strings.TrimSpace(string(out))
+}
+
+func TestMaintenanceInvalidJSON(t *testing.T) {
+	if res := MockMaintenanceSend(MockModel(), "bad json"); res != "invalid JSON" {
+		t.Fatalf(`expected "invalid JSON", got "%s"`, res)
+	}
+}
+
+func TestMaintenanceInvalidWindow(t *testing.T) {
+	res := MockMaintenanceSend(MockModel(), `{"start":20,"end":10}`)
+
+	if res != model.ErrWindowInvalid.Error() {
+		t.Fatalf(`expected "%s", got "%s"`, model.ErrWindowInvalid, res)
+	}
+}
+
+func TestMaintenanceSchedule(t *testing.T) {
+	modl := MockModel()
+	modl.Controller.Unpause()
+
+	now := time.Now().Unix()
+	res := MockMaintenanceSend(modl, fmt.Sprintf(
+		`{"currency":"BTC","stage":"bind","start":%d,"end":%d}`, now-10, now+60,
+	))
+
+	if res != `{"id":1}` {
+		t.Fatalf(`expected {"id":1}, got "%s"`, res)
+	}
+
+	if !modl.Controller.PausedFor(otc.BTC, model.BIND) {
+		t.Fatal("BTC binding should be paused")
+	}
+
+	if modl.Controller.PausedFor(otc.BTC, model.SEND) {
+		t.Fatal("BTC sending shouldn't be paused")
+	}
+
+	res = MockRequestBody(MaintenanceCancel(nil, modl), `{"id":1}`)
+	if res != "" {
+		t.Fatalf(`expected empty response, got "%s"`, res)
+	}
+
+	if modl.Controller.PausedFor(otc.BTC, model.BIND) {
+		t.Fatal("BTC binding should be unpaused")
+	}
+
+	res = MockRequestBody(MaintenanceCancel(nil, modl), `{"id":1}`)
+	if res != model.ErrWindowMissing.Error() {
+		t.Fatalf(`expected "%s", got "%s"`, model.ErrWindowMissing, res)
+	}
+}
+
+func MockRequestBody(handler http.HandlerFunc, data string) string {
+	res := httptest.NewRecorder()
+	handler(res, MockRequest(data))
+
+	out, _ := ioutil.ReadAll(res.Body)
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/skycoin/services/otc/pkg/api"
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// Intervention is the request body shared by the manual order endpoints.
+type Intervention struct {
+	Id string `json:"id"`
+	// from the admin token, see desk.Admin
+	Operator string `json:"-"`
+	Note     string `json:"note"`
+	// transition only
+	Status string `json:"status"`
+	// payout only
+	TxId   string `json:"txid"`
+	Amount uint64 `json:"amount"`
+}
+
+// Intervene decodes the request, applies it with fn and responds with the
+// changed order.
+func Intervene(w http.ResponseWriter, r *http.Request, fn func(*Intervention) (*otc.Order, error)) {
+	req := &Intervention{}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	req.Operator = api.Operator(r)
+
+	order, err := fn(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(order)
+}
+
+func OrdersAnnotate(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		Intervene(w, r, func(req *Intervention) (*otc.Order, error) {
+			return modl.Annotate(req.Id, req.Operator, req.Note)
+		})
+	}
+}
+
+func OrdersTransition(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		Intervene(w, r, func(req *Intervention) (*otc.Order, error) {
+			return modl.Transition(req.Id, req.Operator, req.Note, otc.Status(req.Status))
+		})
+	}
+}
+
+func OrdersCancel(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		Intervene(w, r, func(req *Intervention) (*otc.Order, error) {
+			return modl.Cancel(req.Id, req.Operator, req.Note)
+		})
+	}
+}
+
+func OrdersPayout(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		Intervene(w, r, func(req *Intervention) (*otc.Order, error) {
+			return modl.Payout(req.Id, req.Operator, req.Note, req.TxId, req.Amount)
+		})
+	}
+}
+
+// OrdersRequote fixes the current price for an order waiting to be sent.
+func OrdersRequote(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		Intervene(w, r, func(req *Intervention) (*otc.Order, error) {
+			order, err := modl.Lookup.GetOrder(req.Id)
+			if err != nil {
+				return nil, err
+			}
+
+			value, obs, err := curs.Quote(order.User.Drop.Currency, order.Amount)
+			if err != nil {
+				return nil, err
+			}
+
+			return modl.Requote(req.Id, req.Operator, req.Note, value, obs)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package admin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/services/otc/pkg/actor"
+	"github.com/skycoin/services/otc/pkg/api"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// MockOrderModel returns a model with a single order in the given status,
+// saved under a temporary model.PATH.
+func MockOrderModel(t *testing.T, status otc.Status) *model.Model {
+	dir, err := ioutil.TempDir("", "otc-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.PATH = dir + "/"
+
+	user := &otc.User{
+		Id:      "address:BTC:drop",
+		Address: "address",
+		Drop:    &otc.Drop{Address: "drop", Currency: otc.BTC},
+		Times:   &otc.Times{},
+	}
+	order := &otc.Order{
+		User:   user,
+		Id:     "transaction:0",
+		Status: status,
+		Amount: 100,
+		Times:  &otc.Times{},
+	}
+	user.Orders = []*otc.Order{order}
+
+	if err = os.MkdirAll(dir+"/"+model.ORDERS+user.Id, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	modl := MockModel()
+	modl.Lookup = model.NewLookup()
+	modl.Lookup.AddUser(user)
+	modl.Lookup.AddOrder(order)
+	modl.Router = actor.New(nil, nil)
+	modl.Workers = &model.Workers{
+		Sender:  actor.New(nil, nil),
+		Monitor: actor.New(nil, nil),
+	}
+
+	return modl
+}
+
+// MockIntervention calls handler as karl, as desk.Admin would for karl's
+// token.
+func MockIntervention(handler http.HandlerFunc, data string) string {
+	res := httptest.NewRecorder()
+	handler(res, api.WithOperator(MockRequest(data), "karl"))
+
+	out, _ := ioutil.ReadAll(res.Body)
+	return strings.TrimSpace(string(out))
+}
+
+func TestOrdersInvalid(t *testing.T) {
+	modl := MockOrderModel(t, otc.SEND)
+	defer os.RemoveAll(model.PATH)
+
+	// the operator comes from the token, not the body
+	res := MockRequestBody(OrdersAnnotate(nil, modl), `{"id":"transaction:0","operator":"karl"}`)
+	if res != model.ErrOperatorMissing.Error() {
+		t.Fatalf(`expected "%s", got "%s"`, model.ErrOperatorMissing, res)
+	}
+
+	tests := [][]string{
+		{`bad json`, `invalid JSON`},
+		{`{"id":"missing:0"}`, model.ErrMissing.Error()},
+		{`{"id":"transaction:0","status":"done"}`,
+			model.ErrTransition.Error()},
+	}
+
+	for _, test := range tests {
+		if res := MockIntervention(OrdersTransition(nil, modl), test[0]); res != test[1] {
+			t.Fatalf(`expected "%s", got "%s"`, test[1], res)
+		}
+	}
+
+	res = MockIntervention(OrdersPayout(nil, modl), `{"id":"transaction:0"}`)
+	if res != model.ErrTxIdMissing.Error() {
+		t.Fatalf(`expected "%s", got "%s"`, model.ErrTxIdMissing, res)
+	}
+}
+
+func TestOrdersTransition(t *testing.T) {
+	modl := MockOrderModel(t, otc.SEND)
+	defer os.RemoveAll(model.PATH)
+
+	MockIntervention(OrdersTransition(nil, modl),
+		`{"id":"transaction:0","status":"below_minimum","note":"hold"}`)
+
+	order, _ := modl.Lookup.GetOrder("transaction:0")
+	if order.Status != otc.BELOW {
+		t.Fatalf(`expected "%s", got "%s"`, otc.BELOW, order.Status)
+	}
+
+	event := order.Events[len(order.Events)-1]
+	if event.Operator != "karl" || event.Action != "transition" || event.Note != "hold" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	// saved so it survives a restart
+	saved, err := model.ReadOrder(model.PATH+model.ORDERS+order.User.Id+"/", order.Id+".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != otc.BELOW || len(saved.Events) != 1 {
+		t.Fatalf("unexpected saved order %+v", saved)
+	}
+}
+
+func TestOrdersPayout(t *testing.T) {
+	modl := MockOrderModel(t, otc.SEND)
+	defer os.RemoveAll(model.PATH)
+
+	MockIntervention(OrdersPayout(nil, modl),
+		`{"id":"transaction:0","txid":"abc","amount":1000000}`)
+
+	order, _ := modl.Lookup.GetOrder("transaction:0")
+	if order.Status != otc.CONFIRM || order.Purchase.TxId != "abc" {
+		t.Fatalf("unexpected order %+v", order)
+	}
+
+	// handed to the monitor to confirm
+	if modl.Workers.Monitor.Count() != 1 {
+		t.Fatalf("expected order to be monitored")
+	}
+}
+
+func TestOrdersRequote(t *testing.T) {
+	modl := MockOrderModel(t, otc.SEND)
+	defer os.RemoveAll(model.PATH)
+
+	MockIntervention(OrdersRequote(MockCurrencies(), modl),
+		`{"id":"transaction:0"}`)
+
+	order, _ := modl.Lookup.GetOrder("transaction:0")
+	if order.Purchase == nil || order.Purchase.Amount != 1e6 || order.Purchase.TxId != "" {
+		t.Fatalf("unexpected purchase %+v", order.Purchase)
+	}
+
+	if modl.Workers.Sender.Count() != 1 {
+		t.Fatalf("expected order to be sent")
+	}
+}
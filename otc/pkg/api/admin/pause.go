@@ -6,13 +6,16 @@ import (
 
 	"github.com/skycoin/services/otc/pkg/currencies"
 	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
 )
 
 func Pause(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var (
 			req = &struct {
-				Pause bool `json:"pause"`
+				Pause    bool   `json:"pause"`
+				Currency string `json:"currency"`
+				Stage    string `json:"stage"`
 			}{}
 			err error
 		)
@@ -22,10 +25,48 @@ func Pause(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 			return
 		}
 
+		// no currency or stage pauses everything
+		if req.Currency == "" && req.Stage == "" {
+			if req.Pause {
+				modl.Controller.Pause()
+			} else {
+				modl.Controller.Unpause()
+			}
+			return
+		}
+
+		if !ValidStage(req.Stage) {
+			http.Error(w, "invalid stage", http.StatusBadRequest)
+			return
+		}
+
+		pause := model.Pause{
+			Currency: otc.Currency(req.Currency),
+			Stage:    model.Stage(req.Stage),
+		}
+
 		if req.Pause {
-			modl.Controller.Pause()
+			err = modl.Controller.PauseStage(pause)
 		} else {
-			modl.Controller.Unpause()
+			err = modl.Controller.UnpauseStage(pause)
 		}
+		if err != nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// ValidStage accepts any of model.STAGES, or empty for all stages.
+func ValidStage(stage string) bool {
+	if stage == "" {
+		return true
 	}
+
+	for _, s := range model.STAGES {
+		if string(s) == stage {
+			return true
+		}
+	}
+
+	return false
 }
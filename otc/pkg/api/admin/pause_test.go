@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
 )
 
 func MockModel() *model.Model {
@@ -62,3 +63,33 @@ func TestPauseFalse(t *testing.T) {
 		t.Fatal("model shouldn't be paused")
 	}
 }
+
+func TestPauseStage(t *testing.T) {
+	modl, res := MockPauseSend(`{"pause":true,"currency":"BTC","stage":"send"}`)
+
+	if res != "" {
+		t.Fatalf(`expected empty response, got "%s"`, res)
+	}
+
+	modl.Controller.Unpause()
+
+	if !modl.Controller.PausedFor(otc.BTC, model.SEND) {
+		t.Fatal("BTC sending should be paused")
+	}
+
+	if modl.Controller.PausedFor(otc.BTC, model.SCAN) {
+		t.Fatal("BTC scanning shouldn't be paused")
+	}
+
+	if modl.Controller.PausedFor(otc.ETH, model.SEND) {
+		t.Fatal("ETH sending shouldn't be paused")
+	}
+}
+
+func TestPauseInvalidStage(t *testing.T) {
+	_, res := MockPauseSend(`{"pause":true,"stage":"bad"}`)
+
+	if res != "invalid stage" {
+		t.Fatalf(`expected "invalid stage", got "%s"`, res)
+	}
+}
@@ -23,6 +23,9 @@ func Price(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 			return
 		}
 
-		curs.Prices[otc.BTC].Sources[currencies.INTERNAL].Set(req.Price)
+		if err = curs.Prices[otc.BTC].SetPrice(currencies.INTERNAL, req.Price); err != nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
 	}
 }
@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// maximum number of points returned when no interval is requested
+const POINTS int64 = 300
+
+func Prices(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			req = &struct {
+				Currency string `json:"currency"`
+				Source   string `json:"source"`
+				From     int64  `json:"from"`
+				To       int64  `json:"to"`
+				Interval int64  `json:"interval"`
+			}{}
+			err error
+		)
+
+		// empty body returns the last day of the BTC price in use
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if req.Currency == "" {
+			req.Currency = string(otc.BTC)
+		}
+		if req.To == 0 {
+			req.To = time.Now().UTC().Unix()
+		}
+		if req.From == 0 {
+			req.From = req.To - 60*60*24
+		}
+		if req.From >= req.To {
+			http.Error(w, "invalid range", http.StatusBadRequest)
+			return
+		}
+		if req.Interval <= 0 {
+			req.Interval = (req.To-req.From)/POINTS + 1
+		}
+
+		if curs.History == nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+
+		obs := curs.History.Range(
+			otc.Currency(req.Currency),
+			currencies.Source(req.Source),
+			req.From,
+			req.To,
+		)
+
+		json.NewEncoder(w).Encode(&struct {
+			Currency string             `json:"currency"`
+			Source   string             `json:"source,omitempty"`
+			Interval int64              `json:"interval"`
+			Points   []currencies.Point `json:"points"`
+		}{req.Currency, req.Source, req.Interval, currencies.Downsample(obs, req.Interval)})
+	}
+}
+
+func PricesObservation(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			req = &struct {
+				Id uint64 `json:"id"`
+			}{}
+			err error
+		)
+
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if curs.History == nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+
+		obs, err := curs.History.Get(req.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		json.NewEncoder(w).Encode(&obs)
+	}
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func MockHistory() *currencies.Currencies {
+	curs := MockCurrencies()
+	curs.History, _ = currencies.NewHistory("", 0)
+	curs.History.Add(otc.BTC, currencies.EXCHANGE, 100, time.Unix(1000, 0))
+	curs.History.Add(otc.BTC, currencies.EXCHANGE, 300, time.Unix(1010, 0))
+	curs.History.Add(otc.BTC, currencies.INTERNAL, 200, time.Unix(1100, 0))
+	return curs
+}
+
+func TestPricesInvalidJSON(t *testing.T) {
+	if res := MockRequestBody(Prices(MockHistory(), nil), "bad json"); res != "invalid JSON" {
+		t.Fatalf(`expected "invalid JSON", got "%s"`, res)
+	}
+}
+
+func TestPrices(t *testing.T) {
+	res := MockRequestBody(Prices(MockHistory(), nil),
+		`{"currency":"BTC","from":900,"to":1200,"interval":60}`)
+
+	var out struct {
+		Points []currencies.Point `json:"points"`
+	}
+	if err := json.Unmarshal([]byte(res), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out.Points) != 2 || out.Points[0].High != 300 || out.Points[0].Count != 2 {
+		t.Fatalf("bad points: %s", res)
+	}
+}
+
+func TestPricesObservation(t *testing.T) {
+	res := MockRequestBody(PricesObservation(MockHistory(), nil), `{"id":2}`)
+
+	if res != `{"id":2,"currency":"BTC","source":"exchange","value":300,"time":1010}` {
+		t.Fatalf("bad observation: %s", res)
+	}
+
+	res = MockRequestBody(PricesObservation(MockHistory(), nil), `{"id":9}`)
+	if res != currencies.ErrObservationMissing.Error() {
+		t.Fatalf(`expected "%s", got "%s"`, currencies.ErrObservationMissing, res)
+	}
+}
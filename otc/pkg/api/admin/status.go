@@ -19,13 +19,17 @@ func Status(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 					Exchange        uint64 `json:"exchange"`
 					ExchangeUpdated int64  `json:"exchange_updated"`
 				} `json:"prices"`
-				Source currencies.Source `json:"source"`
-				Paused bool              `json:"paused"`
+				Source  currencies.Source `json:"source"`
+				Paused  bool              `json:"paused"`
+				Pauses  []model.Pause     `json:"pauses"`
+				Windows []model.Window    `json:"windows"`
 			}{}
 			err error
 		)
 
 		res.Paused = modl.Controller.Paused()
+		res.Pauses = modl.Controller.GetPauses()
+		res.Windows = modl.Controller.GetWindows()
 
 		// TODO: add other currency support
 		if res.Source, err = curs.Source(otc.BTC); err != nil {
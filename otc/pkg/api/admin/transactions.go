@@ -10,9 +10,36 @@ import (
 	"github.com/skycoin/services/otc/pkg/otc"
 )
 
+// Filter keeps orders matching the optional status, currency (drop) and
+// address (skycoin) query parameters.
+func Filter(orders []otc.Order, r *http.Request) []otc.Order {
+	var (
+		query    = r.URL.Query()
+		status   = otc.Status(query.Get("status"))
+		currency = otc.Currency(query.Get("currency"))
+		address  = query.Get("address")
+		filtered = make([]otc.Order, 0, len(orders))
+	)
+
+	for _, order := range orders {
+		if status != "" && order.Status != status {
+			continue
+		}
+		if currency != "" && (order.User == nil || order.User.Drop.Currency != currency) {
+			continue
+		}
+		if address != "" && (order.User == nil || order.User.Address != address) {
+			continue
+		}
+		filtered = append(filtered, order)
+	}
+
+	return filtered
+}
+
 func Transactions(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		all := modl.Orders()
+		all := Filter(modl.Orders(), r)
 
 		sort.Slice(all, func(i, j int) bool {
 			return all[i].Times.CreatedAt > all[j].Times.CreatedAt
@@ -24,11 +51,11 @@ func Transactions(curs *currencies.Currencies, modl *model.Model) http.HandlerFu
 
 func TransactionsPending(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		all := modl.Orders()
+		all := Filter(modl.Orders(), r)
 		pending := make([]otc.Order, 0)
 
 		for _, order := range all {
-			if order.Status != otc.DONE {
+			if order.Status != otc.DONE && order.Status != otc.CANCELLED {
 				pending = append(pending, order)
 			}
 		}
@@ -43,7 +70,7 @@ func TransactionsPending(curs *currencies.Currencies, modl *model.Model) http.Ha
 
 func TransactionsCompleted(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		all := modl.Orders()
+		all := Filter(modl.Orders(), r)
 		completed := make([]otc.Order, 0)
 
 		for _, order := range all {
@@ -0,0 +1,40 @@
+package admin
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func TestFilter(t *testing.T) {
+	alice := &otc.User{Address: "alice", Drop: &otc.Drop{Currency: otc.BTC}}
+	bob := &otc.User{Address: "bob", Drop: &otc.Drop{Currency: otc.ETH}}
+
+	orders := []otc.Order{
+		{User: alice, Id: "a", Status: otc.DONE},
+		{User: alice, Id: "b", Status: otc.SEND},
+		{User: bob, Id: "c", Status: otc.DONE},
+	}
+
+	tests := map[string][]string{
+		"/api/transactions":                           {"a", "b", "c"},
+		"/api/transactions?status=done":               {"a", "c"},
+		"/api/transactions?currency=ETH":              {"c"},
+		"/api/transactions?address=alice&status=done": {"a"},
+		"/api/transactions?address=carol":             {},
+	}
+
+	for url, expected := range tests {
+		filtered := Filter(orders, httptest.NewRequest("GET", url, nil))
+
+		if len(filtered) != len(expected) {
+			t.Fatalf("%s: expected %d orders, got %d", url, len(expected), len(filtered))
+		}
+		for i := range expected {
+			if filtered[i].Id != expected[i] {
+				t.Fatalf(`%s: expected "%s", got "%s"`, url, expected[i], filtered[i].Id)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Package api holds what the public and admin apis share: the versioned
+// path scheme and their OpenAPI documents (see spec).
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/skycoin/services/otc/pkg/api/spec"
+)
+
+// VERSION prefixes every route, /api/v1/bind. Routes are also served as
+// /api/bind for clients written before versioning.
+const VERSION = "v1"
+
+// Handle registers handler at the versioned and unversioned path. Responses
+// are JSON unless the handler says otherwise (http.Error, event streams).
+func Handle(mux *http.ServeMux, path string, handler http.HandlerFunc) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", spec.JSON)
+		handler(w, r)
+	}
+
+	mux.HandleFunc("/api/"+VERSION+path, h)
+	mux.HandleFunc("/api"+path, h)
+}
+
+// Spec serves an OpenAPI document at /api/v1/openapi.json.
+func Spec(mux *http.ServeMux, doc string) {
+	mux.HandleFunc("/api/"+VERSION+"/openapi.json", spec.Handler(doc))
+}
+
+type operatorKey struct{}
+
+// WithOperator returns r carrying the name of who is making it, as found
+// from its admin token.
+func WithOperator(r *http.Request, name string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), operatorKey{}, name))
+}
+
+// Operator returns the name set by WithOperator, or empty.
+func Operator(r *http.Request) string {
+	name, _ := r.Context().Value(operatorKey{}).(string)
+	return name
+}
@@ -0,0 +1,130 @@
+// Code generated by gen/main.go from pkg/api/spec. DO NOT EDIT.
+
+package client
+
+import "net/url"
+
+// Admin is a client for the otc admin api 1.0.0.
+type Admin struct {
+	*Client
+}
+
+func NewAdmin(base string) *Admin {
+	return &Admin{New(base)}
+}
+
+// Addresses calls GET /addresses/sky: SKY wallet addresses and their balances.
+func (c *Admin) Addresses() ([]Address, error) {
+	var res []Address
+	return res, c.Do("GET", "/addresses/sky", nil, nil, &res)
+}
+
+// Holding calls GET /holding/btc: BTC held by the wallet.
+func (c *Admin) Holding() (*Holding, error) {
+	res := &Holding{}
+	return res, c.Do("GET", "/holding/btc", nil, nil, res)
+}
+
+// Maintenance calls GET /maintenance: Maintenance windows that haven't ended.
+func (c *Admin) Maintenance() ([]Window, error) {
+	var res []Window
+	return res, c.Do("GET", "/maintenance", nil, nil, &res)
+}
+
+// OpenAPI calls GET /openapi.json: This document.
+func (c *Admin) OpenAPI() (map[string]interface{}, error) {
+	var res map[string]interface{}
+	return res, c.Do("GET", "/openapi.json", nil, nil, &res)
+}
+
+// Status calls GET /status: Prices, price source, pauses and maintenance windows.
+func (c *Admin) Status() (*Status, error) {
+	res := &Status{}
+	return res, c.Do("GET", "/status", nil, nil, res)
+}
+
+// Transactions calls GET /transactions: Every order, newest first.
+func (c *Admin) Transactions(query url.Values) ([]Order, error) {
+	var res []Order
+	return res, c.Do("GET", "/transactions", query, nil, &res)
+}
+
+// TransactionsCompleted calls GET /transactions/completed: Done orders, newest first.
+func (c *Admin) TransactionsCompleted(query url.Values) ([]Order, error) {
+	var res []Order
+	return res, c.Do("GET", "/transactions/completed", query, nil, &res)
+}
+
+// TransactionsPending calls GET /transactions/pending: Orders that aren't done or cancelled, newest first.
+func (c *Admin) TransactionsPending(query url.Values) ([]Order, error) {
+	var res []Order
+	return res, c.Do("GET", "/transactions/pending", query, nil, &res)
+}
+
+// Schedule calls POST /maintenance: Schedule a maintenance window.
+func (c *Admin) Schedule(req *MaintenanceRequest) (*Id, error) {
+	res := &Id{}
+	return res, c.Do("POST", "/maintenance", nil, req, res)
+}
+
+// Unschedule calls POST /maintenance/cancel: Cancel a maintenance window.
+func (c *Admin) Unschedule(req *Id) error {
+	return c.Do("POST", "/maintenance/cancel", nil, req, nil)
+}
+
+// Annotate calls POST /orders/annotate: Add a note to an order.
+func (c *Admin) Annotate(req *Intervention) (*Order, error) {
+	res := &Order{}
+	return res, c.Do("POST", "/orders/annotate", nil, req, res)
+}
+
+// Cancel calls POST /orders/cancel: Cancel an order.
+func (c *Admin) Cancel(req *Intervention) (*Order, error) {
+	res := &Order{}
+	return res, c.Do("POST", "/orders/cancel", nil, req, res)
+}
+
+// Payout calls POST /orders/payout: Record skycoin sent outside of otc.
+func (c *Admin) Payout(req *Intervention) (*Order, error) {
+	res := &Order{}
+	return res, c.Do("POST", "/orders/payout", nil, req, res)
+}
+
+// Requote calls POST /orders/requote: Fix the current price for an order waiting to be sent.
+func (c *Admin) Requote(req *Intervention) (*Order, error) {
+	res := &Order{}
+	return res, c.Do("POST", "/orders/requote", nil, req, res)
+}
+
+// Transition calls POST /orders/transition: Force an order into another status.
+func (c *Admin) Transition(req *Intervention) (*Order, error) {
+	res := &Order{}
+	return res, c.Do("POST", "/orders/transition", nil, req, res)
+}
+
+// Pause calls POST /pause: Pause or unpause everything, or a stage and/or currency.
+func (c *Admin) Pause(req *PauseRequest) error {
+	return c.Do("POST", "/pause", nil, req, nil)
+}
+
+// SetPrice calls POST /price: Set the internal BTC price.
+func (c *Admin) SetPrice(req *PriceRequest) error {
+	return c.Do("POST", "/price", nil, req, nil)
+}
+
+// Prices calls POST /prices: Price history as open/high/low/close points.
+func (c *Admin) Prices(req *PricesRequest) (*Prices, error) {
+	res := &Prices{}
+	return res, c.Do("POST", "/prices", nil, req, res)
+}
+
+// Observation calls POST /prices/observation: A single price observation, as linked from orders.
+func (c *Admin) Observation(req *ObservationRequest) (*Observation, error) {
+	res := &Observation{}
+	return res, c.Do("POST", "/prices/observation", nil, req, res)
+}
+
+// SetSource calls POST /source: Choose the BTC price source.
+func (c *Admin) SetSource(req *SourceRequest) error {
+	return c.Do("POST", "/source", nil, req, nil)
+}
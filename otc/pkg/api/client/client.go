@@ -0,0 +1,91 @@
+// Package client calls the otc public and admin apis. Types and methods
+// are generated from the OpenAPI documents in pkg/api/spec:
+//
+//	go generate ./pkg/api/client
+//
+// Event streams (/events) aren't covered, read them with an EventSource.
+package client
+
+//go:generate go run gen/main.go
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/api"
+)
+
+// Error is returned for responses other than 200, with the message the api
+// wrote.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Status, e.Message)
+}
+
+type Client struct {
+	// scheme and host, e.g. http://127.0.0.1:8080
+	Base string
+	HTTP *http.Client
+	// admin api token of the desk to manage, sent as a bearer token
+	Token string
+}
+
+func New(base string) *Client {
+	return &Client{
+		Base: strings.TrimRight(base, "/"),
+		HTTP: &http.Client{Timeout: time.Second * 30},
+	}
+}
+
+// Do sends req (if not nil) as JSON to the versioned path and decodes the
+// response into res (if not nil).
+func (c *Client) Do(method, path string, query url.Values, req, res interface{}) error {
+	var body bytes.Buffer
+	if req != nil {
+		if err := json.NewEncoder(&body).Encode(req); err != nil {
+			return err
+		}
+	}
+
+	u := c.Base + "/api/" + api.VERSION + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	r, err := http.NewRequest(method, u, &body)
+	if err != nil {
+		return err
+	}
+	if req != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		r.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTP.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return &Error{resp.StatusCode, strings.TrimSpace(string(msg))}
+	}
+
+	if res == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/skycoin/services/otc/pkg/api/spec"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/bind":
+			req := &BindRequest{}
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil || req.DropCurrency != "BTC" {
+				http.Error(w, "invalid JSON", http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(&Bound{"drop", "BTC", 100})
+		case "/api/v1/transactions":
+			json.NewEncoder(w).Encode([]Order{{Id: r.URL.Query().Get("status")}})
+		case "/api/v1/pause":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	public := NewPublic(server.URL + "/")
+
+	bound, err := public.Bind(&BindRequest{Address: "address", DropCurrency: "BTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bound.DropAddress != "drop" || bound.DropValue != 100 {
+		t.Fatalf("unexpected %+v", bound)
+	}
+
+	_, err = public.Bind(&BindRequest{Address: "address", DropCurrency: "ETH"})
+	if e, ok := err.(*Error); !ok || e.Status != 400 || e.Message != "invalid JSON" {
+		t.Fatalf(`expected 400 "invalid JSON", got %v`, err)
+	}
+
+	admin := NewAdmin(server.URL)
+
+	orders, err := admin.Transactions(map[string][]string{"status": {"done"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].Id != "done" {
+		t.Fatalf("unexpected %+v", orders)
+	}
+
+	if err = admin.Pause(&PauseRequest{Pause: true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGenerated fails when the documents change without go generate.
+func TestGenerated(t *testing.T) {
+	clients := map[string]interface{}{spec.PUBLIC: &Public{}, spec.ADMIN: &Admin{}}
+
+	for raw, client := range clients {
+		doc := spec.MustParse(raw)
+		typ := reflect.TypeOf(client)
+
+		for path, methods := range doc.Paths {
+			for _, op := range methods {
+				res, _ := doc.Response(op.Responses["200"])
+				if len(res.Content) > 0 && res.Content[spec.JSON] == nil {
+					continue
+				}
+
+				if _, ok := typ.MethodByName(op.OperationId); !ok {
+					t.Fatalf("%s has no %s method for %s", typ, op.OperationId, path)
+				}
+			}
+		}
+	}
+}
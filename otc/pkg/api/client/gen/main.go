@@ -0,0 +1,271 @@
+// gen writes the client's types and methods from the OpenAPI documents in
+// pkg/api/spec. Run with go generate from pkg/api/client.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/skycoin/services/otc/pkg/api/spec"
+)
+
+// field names that don't follow from capitalizing json names
+var NAMES = map[string]string{
+	"txid": "TxId",
+	"url":  "URL",
+	"ip":   "IP",
+}
+
+const HEADER = "// Code generated by gen/main.go from pkg/api/spec. DO NOT EDIT.\n\npackage client\n\n"
+
+func main() {
+	docs := []struct {
+		Client string
+		File   string
+		Raw    string
+	}{
+		{"Public", "public.go", spec.PUBLIC},
+		{"Admin", "admin.go", spec.ADMIN},
+	}
+
+	g := &Generator{Schemas: make(map[string]*spec.Schema)}
+
+	for _, d := range docs {
+		doc, err := spec.Parse(d.Raw)
+		if err != nil {
+			fail(err)
+		}
+
+		// both documents describe shared types (Order, Window) the same way
+		for name, s := range doc.Components.Schemas {
+			if g.Schemas[name] != nil && !reflect.DeepEqual(g.Schemas[name], s) {
+				fail(fmt.Errorf("schema %s differs between documents", name))
+			}
+			g.Schemas[name] = s
+		}
+
+		write(d.File, g.Operations(d.Client, doc))
+	}
+
+	write("types.go", g.Types())
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func write(file string, src []byte) {
+	out, err := format.Source(src)
+	if err != nil {
+		fail(fmt.Errorf("%s: %v\n%s", file, err, src))
+	}
+
+	if err = ioutil.WriteFile(file, out, 0644); err != nil {
+		fail(err)
+	}
+}
+
+type Generator struct {
+	Schemas map[string]*spec.Schema
+	// inline object types, written with the named ones
+	inline []string
+}
+
+func (g *Generator) Types() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(HEADER)
+
+	names := make([]string, 0, len(g.Schemas))
+	for name := range g.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		buf.WriteString(g.Type(name, g.Schemas[name]))
+	}
+
+	for i := 0; i < len(g.inline); i++ {
+		buf.WriteString(g.inline[i])
+	}
+
+	return buf.Bytes()
+}
+
+// Type declares a named type for a component schema.
+func (g *Generator) Type(name string, s *spec.Schema) string {
+	var buf bytes.Buffer
+
+	if s.Description != "" {
+		fmt.Fprintf(&buf, "// %s: %s\n", name, s.Description)
+	}
+
+	if s.Type != "object" {
+		fmt.Fprintf(&buf, "type %s %s\n\n", name, g.GoType(name, s, false))
+		return buf.String()
+	}
+
+	fmt.Fprintf(&buf, "type %s struct {\n", name)
+
+	props := make([]string, 0, len(s.Properties))
+	for prop := range s.Properties {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+
+	for _, prop := range props {
+		field := FieldName(prop)
+		p := s.Properties[prop]
+
+		tag := prop
+		if !contains(s.Required, prop) {
+			tag += ",omitempty"
+		}
+
+		if p.Description != "" {
+			fmt.Fprintf(&buf, "\t// %s\n", p.Description)
+		}
+		fmt.Fprintf(&buf, "\t%s %s `json:\"%s\"`\n", field, g.GoType(name+field, p, true), tag)
+	}
+
+	buf.WriteString("}\n\n")
+	return buf.String()
+}
+
+// GoType returns the go type for a schema. Objects referenced from fields
+// are pointers so they can be omitted, inline objects get a type named by
+// where they're used.
+func (g *Generator) GoType(name string, s *spec.Schema, field bool) string {
+	if s.Ref != "" {
+		ref := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		if field && g.Schemas[ref] != nil && g.Schemas[ref].Type == "object" {
+			return "*" + ref
+		}
+		return ref
+	}
+
+	switch s.Type {
+	case "object":
+		if len(s.Properties) == 0 {
+			return "map[string]interface{}"
+		}
+		g.inline = append(g.inline, g.Type(name, s))
+		if field {
+			return "*" + name
+		}
+		return name
+	case "array":
+		return "[]" + strings.TrimPrefix(g.GoType(name, s.Items, false), "*")
+	case "string":
+		return "string"
+	case "boolean":
+		return "bool"
+	case "number":
+		return "float64"
+	case "integer":
+		switch s.Format {
+		case "int", "int64", "uint64":
+			return s.Format
+		}
+		return "int64"
+	}
+	return "interface{}"
+}
+
+// Operations writes a client type with a method per JSON operation. Event
+// streams are left to an EventSource (or events.Serve's format).
+func (g *Generator) Operations(client string, doc *spec.Document) []byte {
+	var (
+		buf   bytes.Buffer
+		query bool
+	)
+
+	fmt.Fprintf(&buf, "// %s is a client for the %s %s.\n", client, doc.Info.Title, doc.Info.Version)
+	fmt.Fprintf(&buf, "type %s struct {\n\t*Client\n}\n\n", client)
+	fmt.Fprintf(&buf, "func New%s(base string) *%s {\n\treturn &%s{New(base)}\n}\n\n", client, client, client)
+
+	for _, key := range doc.Operations() {
+		parts := strings.SplitN(key, " ", 2)
+		method, path := parts[0], parts[1]
+		op := doc.Operation(method, path)
+
+		res, err := doc.Response(op.Responses["200"])
+		if err != nil {
+			fail(err)
+		}
+		if res != nil && len(res.Content) > 0 && res.Content[spec.JSON] == nil {
+			continue
+		}
+
+		args, pass := make([]string, 0), []string{`"` + method + `"`, `"` + path + `"`, "nil"}
+		if len(op.Parameters) > 0 {
+			args = append(args, "query url.Values")
+			pass[2] = "query"
+			query = true
+		}
+		if op.RequestBody != nil && op.RequestBody.Content[spec.JSON] != nil {
+			args = append(args, "req *"+g.GoType(op.OperationId+"Request", op.RequestBody.Content[spec.JSON].Schema, false))
+			pass = append(pass, "req")
+		} else {
+			pass = append(pass, "nil")
+		}
+
+		fmt.Fprintf(&buf, "// %s calls %s %s: %s.\n", op.OperationId, method, path, op.Summary)
+
+		if res == nil || len(res.Content) == 0 {
+			pass = append(pass, "nil")
+			fmt.Fprintf(&buf, "func (c *%s) %s(%s) error {\n", client, op.OperationId, strings.Join(args, ", "))
+			fmt.Fprintf(&buf, "\treturn c.Do(%s)\n}\n\n", strings.Join(pass, ", "))
+			continue
+		}
+
+		typ := g.GoType(op.OperationId+"Response", res.Content[spec.JSON].Schema, true)
+		fmt.Fprintf(&buf, "func (c *%s) %s(%s) (%s, error) {\n", client, op.OperationId, strings.Join(args, ", "), typ)
+
+		switch {
+		case strings.HasPrefix(typ, "*"):
+			fmt.Fprintf(&buf, "\tres := &%s{}\n", typ[1:])
+			pass = append(pass, "res")
+		default:
+			fmt.Fprintf(&buf, "\tvar res %s\n", typ)
+			pass = append(pass, "&res")
+		}
+		fmt.Fprintf(&buf, "\treturn res, c.Do(%s)\n}\n\n", strings.Join(pass, ", "))
+	}
+
+	header := HEADER
+	if query {
+		header += "import \"net/url\"\n\n"
+	}
+
+	return append([]byte(header), buf.Bytes()...)
+}
+
+// FieldName turns a json name (drop_address, otcStatus) into a go one.
+func FieldName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '_' })
+	for i, part := range parts {
+		if NAMES[part] != "" {
+			parts[i] = NAMES[part]
+		} else {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+// Code generated by gen/main.go from pkg/api/spec. DO NOT EDIT.
+
+package client
+
+// Public is a client for the otc public api 1.0.0.
+type Public struct {
+	*Client
+}
+
+func NewPublic(base string) *Public {
+	return &Public{New(base)}
+}
+
+// Config calls GET /config: Price, holding and the status of each currency pair.
+func (c *Public) Config() (*Config, error) {
+	res := &Config{}
+	return res, c.Do("GET", "/config", nil, nil, res)
+}
+
+// OpenAPI calls GET /openapi.json: This document.
+func (c *Public) OpenAPI() (map[string]interface{}, error) {
+	var res map[string]interface{}
+	return res, c.Do("GET", "/openapi.json", nil, nil, &res)
+}
+
+// Bind calls POST /bind: Get a drop address to deposit to for a skycoin address.
+func (c *Public) Bind(req *BindRequest) (*Bound, error) {
+	res := &Bound{}
+	return res, c.Do("POST", "/bind", nil, req, res)
+}
+
+// Order calls POST /order: A single order by id.
+func (c *Public) Order(req *OrderRequest) (*PublicOrder, error) {
+	res := &PublicOrder{}
+	return res, c.Do("POST", "/order", nil, req, res)
+}
+
+// Orders calls POST /orders: Every binding and order of a skycoin address, signed by its owner.
+func (c *Public) Orders(req *Proof) ([]Binding, error) {
+	var res []Binding
+	return res, c.Do("POST", "/orders", nil, req, &res)
+}
+
+// Status calls POST /status: Orders for a drop address.
+func (c *Public) Status(req *Drop) ([]PublicOrder, error) {
+	var res []PublicOrder
+	return res, c.Do("POST", "/status", nil, req, &res)
+}
@@ -11,13 +11,14 @@ import (
 	"github.com/skycoin/skycoin/src/cipher"
 )
 
-func Bind(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+func Bind(curs *currencies.Currencies, modl *model.Model, guard *Guard) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var (
 			data struct {
 				Affiliate    string `json:"affiliate"`
 				Address      string `json:"address"`
 				DropCurrency string `json:"drop_currency"`
+				Token        string `json:"token"`
 			}
 			err error
 		)
@@ -27,19 +28,53 @@ func Bind(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 			return
 		}
 
-		if modl.Controller.Paused() {
+		curr := otc.Currency(data.DropCurrency)
+
+		if modl.Controller.PausedFor(curr, model.BIND) {
 			http.Error(w, "paused", http.StatusInternalServerError)
 			return
 		}
 
-		curr := otc.Currency(data.DropCurrency)
-
 		addr, err := cipher.DecodeBase58Address(data.Address)
 		if err != nil {
 			http.Error(w, "invalid skycoin address", http.StatusBadRequest)
 			return
 		}
 
+		// hand out the same drop address until it receives a deposit, unless
+		// it expired meanwhile
+		user := modl.Lookup.GetUnfunded(addr.String(), curr)
+		if user != nil {
+			if err = modl.Rebind(user); err == model.ErrMissing {
+				user = nil
+			} else if err != nil {
+				println(err.Error())
+				http.Error(w, "server error", http.StatusInternalServerError)
+				return
+			}
+		}
+		if user != nil {
+			price, err := curs.Price(curr)
+			if err != nil {
+				println(err.Error())
+				http.Error(w, "server error", http.StatusInternalServerError)
+				return
+			}
+
+			json.NewEncoder(w).Encode(&Bound{user.Drop.Address, curr, price})
+			return
+		}
+
+		if err = guard.Verify(addr.String(), data.DropCurrency, data.Token); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !guard.Allow(r, addr.String()) {
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+
 		dropAddr, err := curs.Address(curr)
 		if err != nil {
 			if err == currencies.ErrConnMissing {
@@ -51,7 +86,7 @@ func Bind(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 			return
 		}
 
-		user := &otc.User{
+		user = &otc.User{
 			Orders:    make([]*otc.Order, 0),
 			Id:        addr.String() + ":" + string(curr) + ":" + dropAddr,
 			Address:   addr.String(),
@@ -74,11 +109,13 @@ func Bind(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 
 		modl.Add(user)
 
-		json.NewEncoder(w).Encode(&struct {
-			DropAddress  string       `json:"drop_address"`
-			DropCurrency otc.Currency `json:"drop_currency"`
-			// TODO: change to price
-			DropValue uint64 `json:"drop_value"`
-		}{dropAddr, curr, price})
+		json.NewEncoder(w).Encode(&Bound{dropAddr, curr, price})
 	}
 }
+
+type Bound struct {
+	DropAddress  string       `json:"drop_address"`
+	DropCurrency otc.Currency `json:"drop_currency"`
+	// TODO: change to price
+	DropValue uint64 `json:"drop_value"`
+}
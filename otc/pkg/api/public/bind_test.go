@@ -6,11 +6,15 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/skycoin/services/otc/pkg/actor"
 	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/generator"
+	"github.com/skycoin/services/otc/pkg/limiter"
 	"github.com/skycoin/services/otc/pkg/model"
 	"github.com/skycoin/services/otc/pkg/otc"
 )
@@ -124,7 +128,7 @@ func TestBind(t *testing.T) {
 		req := httptest.NewRequest("GET", "http:///", &buf)
 		res := httptest.NewRecorder()
 
-		Bind(curs, modl)(res, req)
+		Bind(curs, modl, nil)(res, req)
 
 		out, err := ioutil.ReadAll(res.Body)
 		if err != nil {
@@ -137,3 +141,95 @@ func TestBind(t *testing.T) {
 		}
 	}
 }
+
+func TestBindGuard(t *testing.T) {
+	curs := &currencies.Currencies{
+		Prices: map[otc.Currency]*currencies.Pricer{
+			otc.BTC: &currencies.Pricer{
+				Using: currencies.INTERNAL,
+				Sources: map[currencies.Source]*currencies.Price{
+					currencies.INTERNAL: currencies.NewPrice(100),
+				},
+			},
+		},
+		Connections: map[otc.Currency]currencies.Connection{
+			otc.BTC: &MockConnection{},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "otc-bind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = model.MakeDirs(dir + "/"); err != nil {
+		t.Fatal(err)
+	}
+
+	modl := &model.Model{
+		Controller: &model.Controller{
+			Running: true,
+		},
+		Lookup:  model.NewLookup(),
+		Router:  actor.New(nil, nil),
+		Logs:    log.New(ioutil.Discard, "", 0),
+		Path:    dir + "/",
+		Workers: &model.Workers{Scanner: generator.New(nil, nil, nil)},
+	}
+
+	guard := &Guard{
+		IP:        limiter.New(1, time.Hour),
+		Challenge: &Work{Bits: 4},
+	}
+
+	tests := [][]string{
+		{
+			`{"address":"2dvVgeKNU7UHdvvBUVZXbBaxoTkpemo1cmg",
+			  "drop_currency":"BTC"}`,
+			`invalid proof of work`,
+		},
+		{
+			`{"address":"2dvVgeKNU7UHdvvBUVZXbBaxoTkpemo1cmg",
+			  "drop_currency":"BTC",
+			  "token":"` + Solve("2dvVgeKNU7UHdvvBUVZXbBaxoTkpemo1cmg", "BTC", 4) + `"}`,
+			`{"drop_address":"mock","drop_currency":"BTC","drop_value":100}`,
+		},
+		// unfunded binding is handed out again without a token
+		{
+			`{"address":"2dvVgeKNU7UHdvvBUVZXbBaxoTkpemo1cmg",
+			  "drop_currency":"BTC"}`,
+			`{"drop_address":"mock","drop_currency":"BTC","drop_value":100}`,
+		},
+		{
+			`{"address":"2GgFvqoyk9RjwVzj8tqfcXVXB4orBwoc9qv",
+			  "drop_currency":"BTC",
+			  "token":"` + Solve("2GgFvqoyk9RjwVzj8tqfcXVXB4orBwoc9qv", "BTC", 4) + `"}`,
+			`too many requests`,
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		buf.WriteString(test[0])
+		req := httptest.NewRequest("GET", "http:///", &buf)
+		res := httptest.NewRecorder()
+
+		Bind(curs, modl, guard)(res, req)
+
+		out, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.TrimSpace(string(out)) != test[1] {
+			t.Fatalf(`expected "%s", got "%s"`, test[1],
+				strings.TrimSpace(string(out)))
+		}
+	}
+
+	// handing it out again restarts its expiry
+	user := modl.Lookup.GetUnfunded("2dvVgeKNU7UHdvvBUVZXbBaxoTkpemo1cmg", otc.BTC)
+	if user == nil || user.Times.BoundAt == 0 {
+		t.Fatalf("expected the rebinding recorded, got %+v", user)
+	}
+}
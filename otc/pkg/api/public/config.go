@@ -3,12 +3,20 @@ package public
 import (
 	"encoding/json"
 	"net/http"
+	"sort"
 
 	"github.com/skycoin/services/otc/pkg/currencies"
 	"github.com/skycoin/services/otc/pkg/model"
 	"github.com/skycoin/services/otc/pkg/otc"
 )
 
+type Pair struct {
+	Currency    otc.Currency   `json:"currency"`
+	Status      string         `json:"status"`
+	Paused      []model.Stage  `json:"paused,omitempty"`
+	Maintenance []model.Window `json:"maintenance,omitempty"`
+}
+
 func Config(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		holding, err := curs.Holding(otc.SKY)
@@ -36,6 +44,43 @@ func Config(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 			// TODO: change to holding
 			Holding uint64 `json:"balance"`
 			Price   uint64 `json:"price"`
-		}{status, holding, price})
+			Pairs   []Pair `json:"pairs"`
+		}{status, holding, price, Pairs(curs, modl)})
+	}
+}
+
+// Pairs reports, for each priced drop currency, whether orders can be
+// placed and processed, and any upcoming maintenance.
+func Pairs(curs *currencies.Currencies, modl *model.Model) []Pair {
+	pairs := make([]Pair, 0, len(curs.Prices))
+	windows := modl.Controller.GetWindows()
+
+	for cur := range curs.Prices {
+		pair := Pair{
+			Currency: cur,
+			Status:   "WORKING",
+			Paused:   modl.Controller.Stages(cur),
+		}
+
+		for _, stage := range pair.Paused {
+			// monitoring doesn't stop new orders from being filled
+			if stage != model.MONITOR {
+				pair.Status = "PAUSED"
+			}
+		}
+
+		for _, window := range windows {
+			if window.Currency == "" || window.Currency == cur {
+				pair.Maintenance = append(pair.Maintenance, window)
+			}
+		}
+
+		pairs = append(pairs, pair)
 	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Currency < pairs[j].Currency
+	})
+
+	return pairs
 }
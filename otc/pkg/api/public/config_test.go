@@ -46,7 +46,7 @@ func TestConfig(t *testing.T) {
 	tests := map[*currencies.Currencies]string{
 		curs_one:   "server error",
 		curs_two:   "server error",
-		curs_three: `{"otcStatus":"WORKING","balance":0,"price":100}`,
+		curs_three: `{"otcStatus":"WORKING","balance":0,"price":100,"pairs":[{"currency":"BTC","status":"WORKING"}]}`,
 	}
 
 	for curs, expected := range tests {
@@ -0,0 +1,111 @@
+package public
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/api/spec"
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// TestContract checks handler responses against the OpenAPI document.
+func TestContract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otc-public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	model.PATH = dir + "/"
+
+	pub, sec := cipher.GenerateDeterministicKeyPair([]byte("contract"))
+	address := cipher.AddressFromPubKey(pub).String()
+	now := time.Now().Unix()
+
+	modl := MockOrdersModel(address)
+	modl.Lookup.AddStatus(modl.Lookup.GetAddress(address)[0])
+
+	curs := &currencies.Currencies{
+		Prices: map[otc.Currency]*currencies.Pricer{
+			otc.BTC: &currencies.Pricer{
+				Using: currencies.INTERNAL,
+				Sources: map[currencies.Source]*currencies.Price{
+					currencies.INTERNAL: currencies.NewPrice(100),
+				},
+			},
+		},
+		Connections: map[otc.Currency]currencies.Connection{
+			otc.BTC: &MockConnection{},
+			otc.SKY: &MockConnection{},
+		},
+	}
+
+	doc := spec.MustParse(spec.PUBLIC)
+	mux := New(curs, modl, nil)
+
+	tests := []struct {
+		Method, Path, Body string
+		Status             int
+	}{
+		{"POST", "/bind", `{"address":"` + address + `","drop_currency":"BTC"}`, 200},
+		{"POST", "/bind", `bad json`, 400},
+		{"POST", "/status", `{"drop_address":"drop","drop_currency":"BTC"}`, 200},
+		{"POST", "/status", `{"drop_address":"missing","drop_currency":"BTC"}`, 400},
+		{"GET", "/config", ``, 200},
+		{"POST", "/orders", `{"address":"` + address + `","timestamp":` + Itoa(now) +
+			`,"signature":"` + Sign(sec, address, now) + `"}`, 200},
+		{"POST", "/orders", `{"address":"` + address + `","timestamp":` + Itoa(now) +
+			`,"signature":"00"}`, 401},
+		{"POST", "/order", `{"id":"transaction:index"}`, 200},
+		{"POST", "/order", `{"id":"missing"}`, 400},
+		{"GET", "/events", ``, 400},
+		{"GET", "/openapi.json", ``, 200},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.Method, "/api/v1"+test.Path, strings.NewReader(test.Body))
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, req)
+
+		if res.Code != test.Status {
+			t.Fatalf("%s %s: expected %d, got %d (%s)", test.Method, test.Path,
+				test.Status, res.Code, res.Body.String())
+		}
+
+		err := doc.Check(test.Method, test.Path, res.Code, res.Header().Get("Content-Type"), res.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestRoutes checks that every documented path is served, versioned and
+// unversioned.
+func TestRoutes(t *testing.T) {
+	mux := New(&currencies.Currencies{}, MockOrdersModel("address"), nil)
+
+	for path := range spec.MustParse(spec.PUBLIC).Paths {
+		prefixes := []string{"/api/v1"}
+		if path != "/openapi.json" {
+			prefixes = append(prefixes, "/api")
+		}
+
+		for _, prefix := range prefixes {
+			_, pattern := mux.Handler(httptest.NewRequest("GET", prefix+path, nil))
+			if pattern != prefix+path {
+				t.Fatalf("%s%s isn't routed", prefix, path)
+			}
+		}
+	}
+}
+
+func Itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
@@ -0,0 +1,52 @@
+package public
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/events"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// Events streams order updates for a single drop address as server-sent
+// events: /api/events?drop_address=...&drop_currency=BTC
+func Events(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			query = r.URL.Query()
+			drop  = otc.Drop{
+				Address:  query.Get("drop_address"),
+				Currency: otc.Currency(query.Get("drop_currency")),
+			}
+		)
+
+		if drop.Address == "" || drop.Currency == "" {
+			http.Error(w, "drop missing", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := modl.Lookup.GetStatus(string(drop.Currency) + ":" + drop.Address); err != nil {
+			http.Error(w, "user missing", http.StatusBadRequest)
+			return
+		}
+
+		events.Map(w, r, modl.Events, func(e *events.Event) *events.Event {
+			if e.Type != events.ORDER || e.Drop == nil || *e.Drop != drop {
+				return nil
+			}
+
+			// without the admin's notes on interventions
+			order := &otc.Order{}
+			if err := json.Unmarshal(e.Data, order); err != nil {
+				return nil
+			}
+			public, err := e.WithData(order.Public())
+			if err != nil {
+				return nil
+			}
+			return public
+		})
+	}
+}
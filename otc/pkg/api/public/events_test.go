@@ -0,0 +1,30 @@
+package public
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/services/otc/pkg/model"
+)
+
+func TestEvents(t *testing.T) {
+	modl := &model.Model{Lookup: model.NewLookup()}
+
+	tests := [][]string{
+		{"/api/events", "drop missing"},
+		{"/api/events?drop_address=addr", "drop missing"},
+		{"/api/events?drop_address=addr&drop_currency=BTC", "user missing"},
+	}
+
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		Events(nil, modl)(res, httptest.NewRequest("GET", test[0], nil))
+
+		out, _ := ioutil.ReadAll(res.Body)
+		if strings.TrimSpace(string(out)) != test[1] {
+			t.Fatalf(`%s: expected "%s", got "%s"`, test[0], test[1], out)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package public
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/skycoin/services/otc/pkg/limiter"
+)
+
+var ErrWorkInvalid = errors.New("invalid proof of work")
+
+// Guard protects binding against abuse. A nil guard (or nil fields)
+// disables the matching protection.
+type Guard struct {
+	// binds per client IP
+	IP *limiter.Limiter
+	// binds per skycoin address
+	Address *limiter.Limiter
+	// checked before a new drop address is generated
+	Challenge Challenge
+	// trust the last X-Forwarded-For entry (set by nginx) for the client IP
+	Proxied bool
+}
+
+// Challenge verifies a token sent along with a bind request, such as a
+// proof of work or a captcha response.
+type Challenge interface {
+	Verify(address, currency, token string) error
+}
+
+// Work requires sha256("address:currency:token") to start with Bits zero
+// bits.
+type Work struct {
+	Bits int
+}
+
+func (w *Work) Verify(address, currency, token string) error {
+	if token == "" {
+		return ErrWorkInvalid
+	}
+
+	sum := sha256.Sum256([]byte(address + ":" + currency + ":" + token))
+
+	for i := 0; i < w.Bits && i < len(sum)*8; i++ {
+		if sum[i/8]&(0x80>>uint(i%8)) != 0 {
+			return ErrWorkInvalid
+		}
+	}
+
+	return nil
+}
+
+func (g *Guard) ClientIP(r *http.Request) string {
+	if g != nil && g.Proxied {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (g *Guard) Verify(address, currency, token string) error {
+	if g == nil || g.Challenge == nil {
+		return nil
+	}
+	return g.Challenge.Verify(address, currency, token)
+}
+
+func (g *Guard) Allow(r *http.Request, address string) bool {
+	if g == nil {
+		return true
+	}
+	return g.IP.Allow(g.ClientIP(r)) && g.Address.Allow(address)
+}
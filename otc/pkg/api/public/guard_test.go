@@ -0,0 +1,58 @@
+package public
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// Solve finds a proof of work token, like the web frontend does.
+func Solve(address, currency string, bits int) string {
+	work := &Work{Bits: bits}
+	for i := 0; ; i++ {
+		token := strconv.Itoa(i)
+		if work.Verify(address, currency, token) == nil {
+			return token
+		}
+	}
+}
+
+func TestWork(t *testing.T) {
+	work := &Work{Bits: 8}
+
+	if err := work.Verify("addr", "BTC", ""); err != ErrWorkInvalid {
+		t.Fatal("expected empty token to be rejected")
+	}
+
+	token := Solve("addr", "BTC", 8)
+	if err := work.Verify("addr", "BTC", token); err != nil {
+		t.Fatal(err)
+	}
+
+	// token is tied to the address and currency
+	if work.Verify("other", "BTC", token) == nil &&
+		work.Verify("addr", "SKY", token) == nil {
+		t.Fatal("expected token to be bound to address and currency")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/bind", nil)
+	req.RemoteAddr = "10.0.0.1:4000"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+
+	tests := []struct {
+		Guard    *Guard
+		Expected string
+	}{
+		{nil, "10.0.0.1"},
+		{&Guard{}, "10.0.0.1"},
+		{&Guard{Proxied: true}, "2.2.2.2"},
+	}
+
+	for _, test := range tests {
+		if ip := test.Guard.ClientIP(req); ip != test.Expected {
+			t.Fatalf(`expected "%s", got "%s"`, test.Expected, ip)
+		}
+	}
+}
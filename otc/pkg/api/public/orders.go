@@ -0,0 +1,142 @@
+package public
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// how far a signed timestamp may be from the server's time
+const PROOF_AGE time.Duration = time.Minute * 10
+
+var (
+	ErrProofExpired = errors.New("signature expired")
+	ErrProofInvalid = errors.New("invalid signature")
+)
+
+// ProofMessage is what the owner of a skycoin address signs (as a sha256
+// hash) to list its bindings and orders.
+func ProofMessage(address string, timestamp int64) string {
+	return fmt.Sprintf("otc:%s:%d", address, timestamp)
+}
+
+// Prove checks that the signature was made by the address's key over
+// ProofMessage, within PROOF_AGE of now.
+func Prove(address string, timestamp int64, signature string, now time.Time) error {
+	at := time.Unix(timestamp, 0)
+	if at.Before(now.Add(-PROOF_AGE)) || at.After(now.Add(PROOF_AGE)) {
+		return ErrProofExpired
+	}
+
+	addr, err := cipher.DecodeBase58Address(address)
+	if err != nil {
+		return err
+	}
+
+	sig, err := cipher.SigFromHex(signature)
+	if err != nil {
+		return ErrProofInvalid
+	}
+
+	hash := cipher.SumSHA256([]byte(ProofMessage(address, timestamp)))
+	if err = cipher.ChkSig(addr, hash, sig); err != nil {
+		return ErrProofInvalid
+	}
+
+	return nil
+}
+
+type Binding struct {
+	DropAddress  string       `json:"drop_address"`
+	DropCurrency otc.Currency `json:"drop_currency"`
+	CreatedAt    int64        `json:"created_at"`
+	Orders       []*otc.Order `json:"orders"`
+}
+
+// Orders lists every binding of a skycoin address, along with its orders.
+func Orders(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			data struct {
+				Address   string `json:"address"`
+				Timestamp int64  `json:"timestamp"`
+				Signature string `json:"signature"`
+			}
+			err error
+		)
+
+		if err = json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		err = Prove(data.Address, data.Timestamp, data.Signature, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		bindings := make([]*Binding, 0)
+		for _, user := range modl.Lookup.GetAddress(data.Address) {
+			binding := &Binding{
+				DropAddress:  user.Drop.Address,
+				DropCurrency: user.Drop.Currency,
+				Orders:       Public(user.Orders),
+			}
+			if user.Times != nil {
+				binding.CreatedAt = user.Times.CreatedAt
+			}
+			if binding.Orders == nil {
+				binding.Orders = make([]*otc.Order, 0)
+			}
+			bindings = append(bindings, binding)
+		}
+
+		json.NewEncoder(w).Encode(bindings)
+	}
+}
+
+// Order returns a single order by its id ("transaction:output index").
+func Order(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			data struct {
+				Id string `json:"id"`
+			}
+			err error
+		)
+
+		if err = json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		order, err := modl.Lookup.GetOrder(data.Id)
+		if err != nil {
+			http.Error(w, "order missing", http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(order.Public())
+	}
+}
+
+// Public returns the orders as customers see them, nil if there are none.
+func Public(orders []*otc.Order) []*otc.Order {
+	if orders == nil {
+		return nil
+	}
+
+	public := make([]*otc.Order, 0, len(orders))
+	for _, order := range orders {
+		public = append(public, order.Public())
+	}
+	return public
+}
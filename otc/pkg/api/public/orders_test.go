@@ -0,0 +1,206 @@
+package public
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/actor"
+	"github.com/skycoin/services/otc/pkg/events"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+func MockOrdersModel(address string) *model.Model {
+	user := &otc.User{
+		Id:      address + ":BTC:drop",
+		Address: address,
+		Drop:    &otc.Drop{Address: "drop", Currency: otc.BTC},
+		Times:   &otc.Times{CreatedAt: 1},
+	}
+	user.Orders = []*otc.Order{
+		{
+			User:   user,
+			Id:     "transaction:index",
+			Status: otc.DONE,
+			Amount: 1,
+		},
+	}
+
+	lookup := model.NewLookup()
+	lookup.AddUser(user)
+	lookup.AddOrder(user.Orders[0])
+
+	return &model.Model{
+		Controller: &model.Controller{
+			Running: true,
+		},
+		Lookup: lookup,
+		Router: actor.New(nil, nil),
+		Logs:   log.New(ioutil.Discard, "", 0),
+	}
+}
+
+func Sign(sec cipher.SecKey, address string, timestamp int64) string {
+	hash := cipher.SumSHA256([]byte(ProofMessage(address, timestamp)))
+	return cipher.SignHash(hash, sec).Hex()
+}
+
+func TestOrders(t *testing.T) {
+	pub, sec := cipher.GenerateDeterministicKeyPair([]byte("orders"))
+	address := cipher.AddressFromPubKey(pub).String()
+	_, other := cipher.GenerateDeterministicKeyPair([]byte("other"))
+	now := time.Now().Unix()
+
+	modl := MockOrdersModel(address)
+
+	request := func(timestamp int64, signature string) string {
+		return fmt.Sprintf(`{"address":"%s","timestamp":%d,"signature":"%s"}`,
+			address, timestamp, signature)
+	}
+
+	tests := [][]string{
+		{
+			`bad json`,
+			`invalid JSON`,
+		},
+		{
+			request(now, "bad"),
+			`invalid signature`,
+		},
+		{
+			request(now, Sign(other, address, now)),
+			`invalid signature`,
+		},
+		{
+			request(now-3600, Sign(sec, address, now-3600)),
+			`signature expired`,
+		},
+		{
+			request(now, Sign(sec, address, now)),
+			`[{"drop_address":"drop","drop_currency":"BTC","created_at":1,"orders":[{"id":"transaction:index","status":"done","amount":1}]}]`,
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		buf.WriteString(test[0])
+		req := httptest.NewRequest("GET", "http:///", &buf)
+		res := httptest.NewRecorder()
+
+		Orders(nil, modl)(res, req)
+
+		out, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.TrimSpace(string(out)) != test[1] {
+			t.Fatalf(`expected "%s", got "%s"`, test[1],
+				strings.TrimSpace(string(out)))
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	modl := MockOrdersModel("address")
+
+	tests := [][]string{
+		{
+			`bad json`,
+			`invalid JSON`,
+		},
+		{
+			`{"id":"missing:0"}`,
+			`order missing`,
+		},
+		{
+			`{"id":"transaction:index"}`,
+			`{"id":"transaction:index","status":"done","amount":1}`,
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		buf.WriteString(test[0])
+		req := httptest.NewRequest("GET", "http:///", &buf)
+		res := httptest.NewRecorder()
+
+		Order(nil, modl)(res, req)
+
+		out, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.TrimSpace(string(out)) != test[1] {
+			t.Fatalf(`expected "%s", got "%s"`, test[1],
+				strings.TrimSpace(string(out)))
+		}
+	}
+}
+
+func TestOrderPublic(t *testing.T) {
+	modl := MockOrdersModel("address")
+	modl.Events = events.New()
+
+	order := modl.Lookup.Orders["transaction:index"]
+	modl.Lookup.AddStatus(order.User)
+	order.Events = []*otc.Event{
+		{Status: otc.CANCELLED, Finished: 1, Operator: "alice", Action: "requote", Note: "internal note", TxId: "txid"},
+	}
+
+	hidden := func(name, out string) {
+		for _, field := range []string{"alice", "requote", "internal note", "txid"} {
+			if strings.Contains(out, field) {
+				t.Fatalf("%s: %q shown to customers in %s", name, field, out)
+			}
+		}
+		if !strings.Contains(out, `"status":"cancelled"`) {
+			t.Fatalf("%s: expected the event itself, got %s", name, out)
+		}
+	}
+
+	res := httptest.NewRecorder()
+	Order(nil, modl)(res, httptest.NewRequest("GET", "http:///", strings.NewReader(`{"id":"transaction:index"}`)))
+	hidden("order", res.Body.String())
+
+	res = httptest.NewRecorder()
+	Status(nil, modl)(res, httptest.NewRequest("GET", "http:///", strings.NewReader(`{"drop_address":"drop","drop_currency":"BTC"}`)))
+	hidden("status", res.Body.String())
+
+	server := httptest.NewServer(Events(nil, modl))
+	defer server.Close()
+
+	stream, err := http.Get(server.URL + "?drop_address=drop&drop_currency=BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+
+	for modl.Events.Count() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	modl.Events.Publish(events.ORDER, order.User.Drop, order)
+
+	scanner := bufio.NewScanner(stream.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "data: ") {
+			hidden("events", line)
+			break
+		}
+	}
+
+	// the order itself keeps them for the admin api
+	if order.Events[0].Operator != "alice" {
+		t.Fatal("expected the stored order left as it was")
+	}
+}
@@ -3,14 +3,20 @@ package public
 import (
 	"net/http"
 
+	"github.com/skycoin/services/otc/pkg/api"
+	"github.com/skycoin/services/otc/pkg/api/spec"
 	"github.com/skycoin/services/otc/pkg/currencies"
 	"github.com/skycoin/services/otc/pkg/model"
 )
 
-func New(curs *currencies.Currencies, modl *model.Model) *http.ServeMux {
+func New(curs *currencies.Currencies, modl *model.Model, guard *Guard) *http.ServeMux {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/bind", Bind(curs, modl))
-	mux.HandleFunc("/api/status", Status(curs, modl))
-	mux.HandleFunc("/api/config", Config(curs, modl))
+	api.Handle(mux, "/bind", Bind(curs, modl, guard))
+	api.Handle(mux, "/status", Status(curs, modl))
+	api.Handle(mux, "/config", Config(curs, modl))
+	api.Handle(mux, "/orders", Orders(curs, modl))
+	api.Handle(mux, "/order", Order(curs, modl))
+	api.Handle(mux, "/events", Events(curs, modl))
+	api.Spec(mux, spec.PUBLIC)
 	return mux
 }
@@ -21,7 +21,7 @@ func TestAdminNew(t *testing.T) {
 				},
 			},
 		},
-	}, nil)
+	}, nil, nil)
 
 	paths := []string{
 		"/api/bind",
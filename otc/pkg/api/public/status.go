@@ -31,6 +31,6 @@ func Status(curs *currencies.Currencies, modl *model.Model) http.HandlerFunc {
 			return
 		}
 
-		json.NewEncoder(w).Encode(user.Orders)
+		json.NewEncoder(w).Encode(Public(user.Orders))
 	}
 }
@@ -0,0 +1,554 @@
+package spec
+
+// ADMIN describes the api used by otc-web-admin and otcctl.
+const ADMIN = `{
+  "openapi": "3.0.0",
+  "info": {
+    "title": "otc admin api",
+    "version": "1.0.0"
+  },
+  "servers": [{"url": "/api/v1"}],
+  "security": [{"token": []}],
+  "paths": {
+    "/status": {
+      "get": {
+        "operationId": "Status",
+        "summary": "Prices, price source, pauses and maintenance windows",
+        "responses": {
+          "200": {
+            "description": "status",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Status"}}}
+          },
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/events": {
+      "get": {
+        "operationId": "Events",
+        "summary": "Server-sent order, price and pause events",
+        "responses": {
+          "200": {
+            "description": "event stream, resumed with the Last-Event-ID header",
+            "content": {"text/event-stream": {"schema": {"type": "string"}}}
+          }
+        }
+      }
+    },
+    "/pause": {
+      "post": {
+        "operationId": "Pause",
+        "summary": "Pause or unpause everything, or a stage and/or currency",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/PauseRequest"}}}
+        },
+        "responses": {
+          "200": {"description": "done"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/maintenance": {
+      "get": {
+        "operationId": "Maintenance",
+        "summary": "Maintenance windows that haven't ended",
+        "responses": {
+          "200": {
+            "description": "windows",
+            "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Window"}}}}
+          }
+        }
+      },
+      "post": {
+        "operationId": "Schedule",
+        "summary": "Schedule a maintenance window",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/MaintenanceRequest"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "scheduled window",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Id"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/maintenance/cancel": {
+      "post": {
+        "operationId": "Unschedule",
+        "summary": "Cancel a maintenance window",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Id"}}}
+        },
+        "responses": {
+          "200": {"description": "done"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/price": {
+      "post": {
+        "operationId": "SetPrice",
+        "summary": "Set the internal BTC price",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/PriceRequest"}}}
+        },
+        "responses": {
+          "200": {"description": "done"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/prices": {
+      "post": {
+        "operationId": "Prices",
+        "summary": "Price history as open/high/low/close points",
+        "requestBody": {
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/PricesRequest"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "points",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Prices"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/prices/observation": {
+      "post": {
+        "operationId": "Observation",
+        "summary": "A single price observation, as linked from orders",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ObservationRequest"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "observation",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Observation"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/source": {
+      "post": {
+        "operationId": "SetSource",
+        "summary": "Choose the BTC price source",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/SourceRequest"}}}
+        },
+        "responses": {
+          "200": {"description": "done"},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/transactions": {
+      "get": {
+        "operationId": "Transactions",
+        "summary": "Every order, newest first",
+        "parameters": [
+          {"name": "status", "in": "query", "schema": {"type": "string"}},
+          {"name": "currency", "in": "query", "schema": {"type": "string"}},
+          {"name": "address", "in": "query", "schema": {"type": "string"}}
+        ],
+        "responses": {"200": {"$ref": "#/components/responses/Orders"}}
+      }
+    },
+    "/transactions/pending": {
+      "get": {
+        "operationId": "TransactionsPending",
+        "summary": "Orders that aren't done or cancelled, newest first",
+        "parameters": [
+          {"name": "status", "in": "query", "schema": {"type": "string"}},
+          {"name": "currency", "in": "query", "schema": {"type": "string"}},
+          {"name": "address", "in": "query", "schema": {"type": "string"}}
+        ],
+        "responses": {"200": {"$ref": "#/components/responses/Orders"}}
+      }
+    },
+    "/transactions/completed": {
+      "get": {
+        "operationId": "TransactionsCompleted",
+        "summary": "Done orders, newest first",
+        "parameters": [
+          {"name": "status", "in": "query", "schema": {"type": "string"}},
+          {"name": "currency", "in": "query", "schema": {"type": "string"}},
+          {"name": "address", "in": "query", "schema": {"type": "string"}}
+        ],
+        "responses": {"200": {"$ref": "#/components/responses/Orders"}}
+      }
+    },
+    "/orders/annotate": {
+      "post": {
+        "operationId": "Annotate",
+        "summary": "Add a note to an order",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Intervention"}}}
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/Order"},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/orders/transition": {
+      "post": {
+        "operationId": "Transition",
+        "summary": "Force an order into another status",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Intervention"}}}
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/Order"},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/orders/cancel": {
+      "post": {
+        "operationId": "Cancel",
+        "summary": "Cancel an order",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Intervention"}}}
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/Order"},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/orders/payout": {
+      "post": {
+        "operationId": "Payout",
+        "summary": "Record skycoin sent outside of otc",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Intervention"}}}
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/Order"},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/orders/requote": {
+      "post": {
+        "operationId": "Requote",
+        "summary": "Fix the current price for an order waiting to be sent",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Intervention"}}}
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/Order"},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/addresses/sky": {
+      "get": {
+        "operationId": "Addresses",
+        "summary": "SKY wallet addresses and their balances",
+        "responses": {
+          "200": {
+            "description": "addresses",
+            "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Address"}}}}
+          },
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/holding/btc": {
+      "get": {
+        "operationId": "Holding",
+        "summary": "BTC held by the wallet",
+        "responses": {
+          "200": {
+            "description": "holding",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Holding"}}}
+          },
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "operationId": "OpenAPI",
+        "summary": "This document",
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "OpenAPI 3 document",
+            "content": {"application/json": {"schema": {"type": "object", "additionalProperties": {}}}}
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "token": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "the desk's [Desk] token, or one of its [Desk.Operators] tokens which order changes are recorded under, optional when the main desk runs alone without one; event streams may pass it as access_token instead"
+      }
+    },
+    "responses": {
+      "Error": {
+        "description": "error message",
+        "content": {"text/plain": {"schema": {"type": "string"}}}
+      },
+      "Order": {
+        "description": "the changed order",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Order"}}}
+      },
+      "Orders": {
+        "description": "orders, filtered by the status, currency and address query parameters",
+        "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Order"}}}}
+      }
+    },
+    "schemas": {
+      "Status": {
+        "type": "object",
+        "required": ["prices", "source", "paused", "pauses", "windows"],
+        "properties": {
+          "prices": {
+            "type": "object",
+            "required": ["internal", "internal_updated", "exchange", "exchange_updated"],
+            "properties": {
+              "internal": {"type": "integer", "format": "uint64"},
+              "internal_updated": {"type": "integer", "format": "int64"},
+              "exchange": {"type": "integer", "format": "uint64"},
+              "exchange_updated": {"type": "integer", "format": "int64"}
+            }
+          },
+          "source": {"type": "string", "enum": ["internal", "exchange"]},
+          "paused": {"type": "boolean"},
+          "pauses": {"type": "array", "items": {"$ref": "#/components/schemas/Pause"}},
+          "windows": {"type": "array", "items": {"$ref": "#/components/schemas/Window"}}
+        }
+      },
+      "Stage": {
+        "type": "string",
+        "enum": ["bind", "scan", "send", "monitor"]
+      },
+      "Pause": {
+        "type": "object",
+        "properties": {
+          "currency": {"type": "string"},
+          "stage": {"$ref": "#/components/schemas/Stage"}
+        }
+      },
+      "PauseRequest": {
+        "type": "object",
+        "required": ["pause"],
+        "properties": {
+          "pause": {"type": "boolean"},
+          "currency": {"type": "string", "description": "empty for every currency"},
+          "stage": {"type": "string", "description": "empty for every stage"}
+        }
+      },
+      "Window": {
+        "type": "object",
+        "required": ["id", "start", "end"],
+        "properties": {
+          "id": {"type": "integer", "format": "int"},
+          "currency": {"type": "string"},
+          "stage": {"$ref": "#/components/schemas/Stage"},
+          "start": {"type": "integer", "format": "int64"},
+          "end": {"type": "integer", "format": "int64"},
+          "reason": {"type": "string"}
+        }
+      },
+      "MaintenanceRequest": {
+        "type": "object",
+        "required": ["start", "end"],
+        "properties": {
+          "currency": {"type": "string"},
+          "stage": {"type": "string"},
+          "start": {"type": "integer", "format": "int64"},
+          "end": {"type": "integer", "format": "int64"},
+          "reason": {"type": "string"}
+        }
+      },
+      "Id": {
+        "type": "object",
+        "required": ["id"],
+        "properties": {
+          "id": {"type": "integer", "format": "int"}
+        }
+      },
+      "PriceRequest": {
+        "type": "object",
+        "required": ["price"],
+        "properties": {
+          "price": {"type": "integer", "format": "uint64", "description": "price of 1 SKY in satoshis"}
+        }
+      },
+      "PricesRequest": {
+        "type": "object",
+        "properties": {
+          "currency": {"type": "string", "description": "defaults to BTC"},
+          "source": {"type": "string", "description": "empty for every source"},
+          "from": {"type": "integer", "format": "int64", "description": "defaults to a day before to"},
+          "to": {"type": "integer", "format": "int64", "description": "defaults to now"},
+          "interval": {"type": "integer", "format": "int64", "description": "seconds per point, defaults to 300 points"}
+        }
+      },
+      "Prices": {
+        "type": "object",
+        "required": ["currency", "interval", "points"],
+        "properties": {
+          "currency": {"type": "string"},
+          "source": {"type": "string"},
+          "interval": {"type": "integer", "format": "int64"},
+          "points": {"type": "array", "items": {"$ref": "#/components/schemas/Point"}}
+        }
+      },
+      "Point": {
+        "type": "object",
+        "required": ["time", "open", "high", "low", "close", "count"],
+        "properties": {
+          "time": {"type": "integer", "format": "int64"},
+          "open": {"type": "integer", "format": "uint64"},
+          "high": {"type": "integer", "format": "uint64"},
+          "low": {"type": "integer", "format": "uint64"},
+          "close": {"type": "integer", "format": "uint64"},
+          "count": {"type": "integer", "format": "int"}
+        }
+      },
+      "ObservationRequest": {
+        "type": "object",
+        "required": ["id"],
+        "properties": {
+          "id": {"type": "integer", "format": "uint64"}
+        }
+      },
+      "Observation": {
+        "type": "object",
+        "required": ["id", "currency", "source", "value", "time"],
+        "properties": {
+          "id": {"type": "integer", "format": "uint64"},
+          "currency": {"type": "string"},
+          "source": {"type": "string"},
+          "value": {"type": "integer", "format": "uint64"},
+          "time": {"type": "integer", "format": "int64"}
+        }
+      },
+      "SourceRequest": {
+        "type": "object",
+        "required": ["source"],
+        "properties": {
+          "source": {"type": "string", "enum": ["internal", "exchange"]}
+        }
+      },
+      "Intervention": {
+        "type": "object",
+        "required": ["id"],
+        "properties": {
+          "id": {"type": "string"},
+          "note": {"type": "string"},
+          "status": {"type": "string", "description": "transition only"},
+          "txid": {"type": "string", "description": "payout only"},
+          "amount": {"type": "integer", "format": "uint64", "description": "payout only, in droplets"}
+        }
+      },
+      "Address": {
+        "type": "object",
+        "required": ["address", "balance"],
+        "properties": {
+          "address": {"type": "string"},
+          "balance": {"type": "integer", "format": "uint64"}
+        }
+      },
+      "Holding": {
+        "type": "object",
+        "required": ["holding"],
+        "properties": {
+          "holding": {"type": "integer", "format": "uint64"}
+        }
+      },
+      "OrderStatus": {
+        "type": "string",
+        "enum": ["waiting_deposit", "below_minimum", "collecting", "waiting_send", "waiting_confirm", "done", "cancelled"]
+      },
+      "Order": {
+        "type": "object",
+        "required": ["id", "status", "amount"],
+        "properties": {
+          "id": {"type": "string", "description": "transaction:output index of the first deposit"},
+          "status": {"$ref": "#/components/schemas/OrderStatus"},
+          "amount": {"type": "integer", "format": "uint64", "description": "deposited, in satoshis"},
+          "outputs": {"type": "array", "items": {"type": "string"}},
+          "purchase": {"$ref": "#/components/schemas/Purchase"},
+          "times": {"$ref": "#/components/schemas/Times"},
+          "events": {"type": "array", "items": {"$ref": "#/components/schemas/Event"}}
+        }
+      },
+      "Purchase": {
+        "type": "object",
+        "required": ["source", "price", "amount", "txid"],
+        "properties": {
+          "source": {"type": "string"},
+          "price": {"$ref": "#/components/schemas/Price", "nullable": true},
+          "amount": {"type": "integer", "format": "uint64", "description": "sent, in droplets"},
+          "txid": {"type": "string"}
+        }
+      },
+      "Price": {
+        "type": "object",
+        "required": ["source", "executed"],
+        "properties": {
+          "source": {"type": "string"},
+          "executed": {"type": "integer", "format": "uint64"},
+          "observation": {"type": "integer", "format": "uint64"},
+          "observed_at": {"type": "integer", "format": "int64"}
+        }
+      },
+      "Times": {
+        "type": "object",
+        "required": ["created_at", "updated_at"],
+        "properties": {
+          "created_at": {"type": "integer", "format": "int64"},
+          "updated_at": {"type": "integer", "format": "int64"},
+          "deposited_at": {"type": "integer", "format": "int64"},
+          "sent_at": {"type": "integer", "format": "int64"},
+          "confirmed_at": {"type": "integer", "format": "int64"}
+        }
+      },
+      "Event": {
+        "type": "object",
+        "required": ["status", "finished"],
+        "properties": {
+          "id": {"type": "string"},
+          "status": {"$ref": "#/components/schemas/OrderStatus"},
+          "finished": {"type": "integer", "format": "int64"},
+          "error": {"type": "string"},
+          "operator": {"type": "string"},
+          "action": {"type": "string"},
+          "note": {"type": "string"},
+          "txid": {"type": "string"}
+        }
+      }
+    }
+  }
+}`
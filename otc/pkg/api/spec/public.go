@@ -0,0 +1,283 @@
+package spec
+
+// PUBLIC describes the api used by otc-web.
+const PUBLIC = `{
+  "openapi": "3.0.0",
+  "info": {
+    "title": "otc public api",
+    "version": "1.0.0"
+  },
+  "servers": [{"url": "/api/v1"}],
+  "paths": {
+    "/bind": {
+      "post": {
+        "operationId": "Bind",
+        "summary": "Get a drop address to deposit to for a skycoin address",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BindRequest"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "drop address, new or reused while it's unfunded",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Bound"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "429": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/status": {
+      "post": {
+        "operationId": "Status",
+        "summary": "Orders for a drop address",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Drop"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "orders, oldest first",
+            "content": {"application/json": {"schema": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/PublicOrder"}}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/config": {
+      "get": {
+        "operationId": "Config",
+        "summary": "Price, holding and the status of each currency pair",
+        "responses": {
+          "200": {
+            "description": "current config",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Config"}}}
+          },
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/orders": {
+      "post": {
+        "operationId": "Orders",
+        "summary": "Every binding and order of a skycoin address, signed by its owner",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Proof"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "bindings",
+            "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Binding"}}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/order": {
+      "post": {
+        "operationId": "Order",
+        "summary": "A single order by id",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/OrderRequest"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "order",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/PublicOrder"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/events": {
+      "get": {
+        "operationId": "Events",
+        "summary": "Server-sent order events for a drop address",
+        "parameters": [
+          {"name": "drop_address", "in": "query", "required": true, "schema": {"type": "string"}},
+          {"name": "drop_currency", "in": "query", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "event stream, resumed with the Last-Event-ID header",
+            "content": {"text/event-stream": {"schema": {"type": "string"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "operationId": "OpenAPI",
+        "summary": "This document",
+        "responses": {
+          "200": {
+            "description": "OpenAPI 3 document",
+            "content": {"application/json": {"schema": {"type": "object", "additionalProperties": {}}}}
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "responses": {
+      "Error": {
+        "description": "error message",
+        "content": {"text/plain": {"schema": {"type": "string"}}}
+      }
+    },
+    "schemas": {
+      "BindRequest": {
+        "type": "object",
+        "required": ["address", "drop_currency"],
+        "properties": {
+          "address": {"type": "string", "description": "skycoin address to send to"},
+          "drop_currency": {"type": "string", "enum": ["BTC"]},
+          "affiliate": {"type": "string"},
+          "token": {"type": "string", "description": "proof of work solution, when required"}
+        }
+      },
+      "Bound": {
+        "type": "object",
+        "required": ["drop_address", "drop_currency", "drop_value"],
+        "properties": {
+          "drop_address": {"type": "string"},
+          "drop_currency": {"type": "string"},
+          "drop_value": {"type": "integer", "format": "uint64", "description": "price of 1 SKY in satoshis"}
+        }
+      },
+      "Drop": {
+        "type": "object",
+        "required": ["drop_address", "drop_currency"],
+        "properties": {
+          "drop_address": {"type": "string"},
+          "drop_currency": {"type": "string"}
+        }
+      },
+      "Proof": {
+        "type": "object",
+        "required": ["address", "timestamp", "signature"],
+        "properties": {
+          "address": {"type": "string"},
+          "timestamp": {"type": "integer", "format": "int64"},
+          "signature": {"type": "string", "description": "hex signature of sha256(\"otc:address:timestamp\")"}
+        }
+      },
+      "OrderRequest": {
+        "type": "object",
+        "required": ["id"],
+        "properties": {
+          "id": {"type": "string"}
+        }
+      },
+      "Binding": {
+        "type": "object",
+        "required": ["drop_address", "drop_currency", "created_at", "orders"],
+        "properties": {
+          "drop_address": {"type": "string"},
+          "drop_currency": {"type": "string"},
+          "created_at": {"type": "integer", "format": "int64"},
+          "orders": {"type": "array", "items": {"$ref": "#/components/schemas/PublicOrder"}}
+        }
+      },
+      "Config": {
+        "type": "object",
+        "required": ["otcStatus", "balance", "price", "pairs"],
+        "properties": {
+          "otcStatus": {"type": "string", "enum": ["WORKING", "PAUSED"]},
+          "balance": {"type": "integer", "format": "uint64", "description": "SKY holding in droplets"},
+          "price": {"type": "integer", "format": "uint64", "description": "price of 1 SKY in satoshis"},
+          "pairs": {"type": "array", "items": {"$ref": "#/components/schemas/Pair"}}
+        }
+      },
+      "Pair": {
+        "type": "object",
+        "required": ["currency", "status"],
+        "properties": {
+          "currency": {"type": "string"},
+          "status": {"type": "string", "enum": ["WORKING", "PAUSED"]},
+          "paused": {"type": "array", "items": {"$ref": "#/components/schemas/Stage"}},
+          "maintenance": {"type": "array", "items": {"$ref": "#/components/schemas/Window"}}
+        }
+      },
+      "Stage": {
+        "type": "string",
+        "enum": ["bind", "scan", "send", "monitor"]
+      },
+      "Window": {
+        "type": "object",
+        "required": ["id", "start", "end"],
+        "properties": {
+          "id": {"type": "integer", "format": "int"},
+          "currency": {"type": "string"},
+          "stage": {"$ref": "#/components/schemas/Stage"},
+          "start": {"type": "integer", "format": "int64"},
+          "end": {"type": "integer", "format": "int64"},
+          "reason": {"type": "string"}
+        }
+      },
+      "OrderStatus": {
+        "type": "string",
+        "enum": ["waiting_deposit", "below_minimum", "collecting", "waiting_send", "waiting_confirm", "done", "cancelled"]
+      },
+      "PublicOrder": {
+        "type": "object",
+        "required": ["id", "status", "amount"],
+        "properties": {
+          "id": {"type": "string", "description": "transaction:output index of the first deposit"},
+          "status": {"$ref": "#/components/schemas/OrderStatus"},
+          "amount": {"type": "integer", "format": "uint64", "description": "deposited, in satoshis"},
+          "outputs": {"type": "array", "items": {"type": "string"}},
+          "purchase": {"$ref": "#/components/schemas/Purchase"},
+          "times": {"$ref": "#/components/schemas/Times"},
+          "events": {"type": "array", "items": {"$ref": "#/components/schemas/PublicEvent"}}
+        }
+      },
+      "Purchase": {
+        "type": "object",
+        "required": ["source", "price", "amount", "txid"],
+        "properties": {
+          "source": {"type": "string"},
+          "price": {"$ref": "#/components/schemas/Price", "nullable": true},
+          "amount": {"type": "integer", "format": "uint64", "description": "sent, in droplets"},
+          "txid": {"type": "string"}
+        }
+      },
+      "Price": {
+        "type": "object",
+        "required": ["source", "executed"],
+        "properties": {
+          "source": {"type": "string"},
+          "executed": {"type": "integer", "format": "uint64"},
+          "observation": {"type": "integer", "format": "uint64"},
+          "observed_at": {"type": "integer", "format": "int64"}
+        }
+      },
+      "Times": {
+        "type": "object",
+        "required": ["created_at", "updated_at"],
+        "properties": {
+          "created_at": {"type": "integer", "format": "int64"},
+          "updated_at": {"type": "integer", "format": "int64"},
+          "deposited_at": {"type": "integer", "format": "int64"},
+          "sent_at": {"type": "integer", "format": "int64"},
+          "confirmed_at": {"type": "integer", "format": "int64"}
+        }
+      },
+      "PublicEvent": {
+        "type": "object",
+        "required": ["status", "finished"],
+        "properties": {
+          "id": {"type": "string"},
+          "status": {"$ref": "#/components/schemas/OrderStatus"},
+          "finished": {"type": "integer", "format": "int64"},
+          "error": {"type": "string"}
+        }
+      }
+    }
+  }
+}`
@@ -0,0 +1,356 @@
+// Package spec holds the OpenAPI 3 descriptions of the public and admin
+// apis, served at /api/v1/openapi.json, and the subset of JSON schema
+// validation used to check handlers against them.
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Document struct {
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	// path -> lowercase method -> operation
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas   map[string]*Schema   `json:"schemas"`
+		Responses map[string]*Response `json:"responses"`
+	} `json:"components"`
+}
+
+type Operation struct {
+	OperationId string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *Body                `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type Body struct {
+	Required bool              `json:"required"`
+	Content  map[string]*Media `json:"content"`
+}
+
+type Response struct {
+	Ref         string            `json:"$ref"`
+	Description string            `json:"description"`
+	Content     map[string]*Media `json:"content"`
+}
+
+type Media struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref         string             `json:"$ref"`
+	Type        string             `json:"type"`
+	Format      string             `json:"format"`
+	Description string             `json:"description"`
+	Enum        []string           `json:"enum"`
+	Nullable    bool               `json:"nullable"`
+	Properties  map[string]*Schema `json:"properties"`
+	Required    []string           `json:"required"`
+	Items       *Schema            `json:"items"`
+	// map values, objects without properties only
+	AdditionalProperties *Schema `json:"additionalProperties"`
+}
+
+// JSON is the media type of request and response bodies.
+const JSON = "application/json"
+
+// Parse decodes a document and checks that every $ref resolves.
+func Parse(raw string) (*Document, error) {
+	doc := &Document{}
+	if err := json.Unmarshal([]byte(raw), doc); err != nil {
+		return nil, err
+	}
+
+	var check func(string, *Schema) error
+	check = func(at string, s *Schema) error {
+		if s == nil {
+			return nil
+		}
+		if s.Ref != "" {
+			if _, err := doc.Resolve(s); err != nil {
+				return fmt.Errorf("%s: %v", at, err)
+			}
+			return nil
+		}
+		for name, prop := range s.Properties {
+			if err := check(at+"."+name, prop); err != nil {
+				return err
+			}
+		}
+		if err := check(at+"[]", s.Items); err != nil {
+			return err
+		}
+		return check(at+"{}", s.AdditionalProperties)
+	}
+
+	for name, s := range doc.Components.Schemas {
+		if err := check(name, s); err != nil {
+			return nil, err
+		}
+	}
+
+	for path, methods := range doc.Paths {
+		for method, op := range methods {
+			at := strings.ToUpper(method) + " " + path
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					if err := check(at+" request", media.Schema); err != nil {
+						return nil, err
+					}
+				}
+			}
+			for status, res := range op.Responses {
+				res, err := doc.Response(res)
+				if err != nil {
+					return nil, fmt.Errorf("%s %s: %v", at, status, err)
+				}
+				for _, media := range res.Content {
+					if err := check(at+" "+status, media.Schema); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+// MustParse is Parse for the documents in this package, which are covered
+// by tests.
+func MustParse(raw string) *Document {
+	doc, err := Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+// Handler serves a document as is.
+func Handler(raw string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", JSON)
+		w.Write([]byte(raw))
+	}
+}
+
+// Resolve follows a local $ref ("#/components/schemas/Order").
+func (d *Document) Resolve(s *Schema) (*Schema, error) {
+	for s != nil && s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		if d.Components.Schemas[name] == nil {
+			return nil, fmt.Errorf("unknown schema %s", s.Ref)
+		}
+		s = d.Components.Schemas[name]
+	}
+	return s, nil
+}
+
+// Response follows a local $ref ("#/components/responses/Error").
+func (d *Document) Response(res *Response) (*Response, error) {
+	if res == nil || res.Ref == "" {
+		return res, nil
+	}
+
+	name := strings.TrimPrefix(res.Ref, "#/components/responses/")
+	if d.Components.Responses[name] == nil {
+		return nil, fmt.Errorf("unknown response %s", res.Ref)
+	}
+	return d.Components.Responses[name], nil
+}
+
+// Operation returns the operation for method (any case) and path (without
+// the server prefix), or nil.
+func (d *Document) Operation(method, path string) *Operation {
+	return d.Paths[path][strings.ToLower(method)]
+}
+
+// Operations lists "METHOD path" for every operation, sorted.
+func (d *Document) Operations() []string {
+	ops := make([]string, 0)
+	for path, methods := range d.Paths {
+		for method := range methods {
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// Check validates a response body against the document: the status must
+// be listed for the operation, and JSON bodies must match its schema.
+func (d *Document) Check(method, path string, status int, contentType string, body []byte) error {
+	op := d.Operation(method, path)
+	if op == nil {
+		return fmt.Errorf("%s %s isn't documented", method, path)
+	}
+
+	res, err := d.Response(op.Responses[strconv.Itoa(status)])
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return fmt.Errorf("%s %s: status %d isn't documented", method, path, status)
+	}
+
+	if len(res.Content) == 0 {
+		if len(bytes.TrimSpace(body)) != 0 {
+			return fmt.Errorf("%s %s: %d has no documented body", method, path, status)
+		}
+		return nil
+	}
+
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	media := res.Content[mediaType]
+	if media == nil {
+		return fmt.Errorf("%s %s: %d isn't documented as %s", method, path, status, mediaType)
+	}
+
+	if mediaType != JSON {
+		return nil
+	}
+
+	return d.Validate(media.Schema, body)
+}
+
+// Validate checks JSON data against a schema. Objects may not have
+// properties the schema doesn't describe.
+func (d *Document) Validate(s *Schema, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return err
+	}
+
+	return d.validate("$", s, value)
+}
+
+func (d *Document) validate(at string, s *Schema, value interface{}) error {
+	// nullable may sit next to a $ref
+	if value == nil && s != nil && s.Nullable {
+		return nil
+	}
+
+	s, err := d.Resolve(s)
+	if err != nil {
+		return fmt.Errorf("%s: %v", at, err)
+	}
+	if s == nil {
+		return nil
+	}
+
+	if value == nil {
+		if s.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: null isn't allowed", at)
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object", at)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: %s is required", at, name)
+			}
+		}
+		for name, v := range obj {
+			prop := s.Properties[name]
+			if prop == nil {
+				prop = s.AdditionalProperties
+			}
+			if prop == nil {
+				return fmt.Errorf("%s: %s isn't documented", at, name)
+			}
+			if err := d.validate(at+"."+name, prop, v); err != nil {
+				return err
+			}
+		}
+
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array", at)
+		}
+		for i, v := range arr {
+			if err := d.validate(fmt.Sprintf("%s[%d]", at, i), s.Items, v); err != nil {
+				return err
+			}
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string", at)
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			return fmt.Errorf("%s: %q isn't one of %s", at, str, strings.Join(s.Enum, ", "))
+		}
+
+	case "integer":
+		num, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: expected integer", at)
+		}
+		if _, err := strconv.ParseInt(string(num), 10, 64); err != nil {
+			if _, err = strconv.ParseUint(string(num), 10, 64); err != nil {
+				return fmt.Errorf("%s: expected integer, got %s", at, num)
+			}
+		}
+
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s: expected number", at)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean", at)
+		}
+
+	case "":
+		// any value
+
+	default:
+		return fmt.Errorf("%s: unknown type %q", at, s.Type)
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package spec
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for name, raw := range map[string]string{"public": PUBLIC, "admin": ADMIN} {
+		doc, err := Parse(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		for _, op := range doc.Operations() {
+			if len(op) == 0 {
+				t.Fatalf("%s: empty operation", name)
+			}
+		}
+	}
+
+	if _, err := Parse(`{"components": {"schemas": {"A": {"$ref": "#/components/schemas/B"}}}}`); err == nil {
+		t.Fatal("expected unknown schema error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	doc := MustParse(PUBLIC)
+	order := doc.Components.Schemas["PublicOrder"]
+
+	tests := [][]string{
+		{`{"id": "tx:0", "status": "done", "amount": 100}`, ""},
+		{`{"id": "tx:0", "status": "done", "amount": 100, "purchase": {"source": "manual", "price": null, "amount": 1, "txid": "t"}}`, ""},
+		{`{"id": "tx:0", "status": "done"}`, "$: amount is required"},
+		{`{"id": "tx:0", "status": "lost", "amount": 100}`, `$.status: "lost" isn't one of waiting_deposit, below_minimum, collecting, waiting_send, waiting_confirm, done, cancelled`},
+		{`{"id": "tx:0", "status": "done", "amount": 1.5}`, "$.amount: expected integer, got 1.5"},
+		{`{"id": "tx:0", "status": "done", "amount": 100, "user": {}}`, "$: user isn't documented"},
+		{`{"id": "tx:0", "status": "done", "amount": 100, "outputs": [1]}`, "$.outputs[0]: expected string"},
+		{`{"id": "tx:0", "status": "done", "amount": 100, "times": null}`, "$.times: null isn't allowed"},
+		{`[]`, "$: expected object"},
+	}
+
+	for _, test := range tests {
+		err := doc.Validate(order, []byte(test[0]))
+
+		if (err == nil && test[1] != "") || (err != nil && err.Error() != test[1]) {
+			t.Fatalf(`%s: expected "%s", got "%v"`, test[0], test[1], err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	doc := MustParse(ADMIN)
+
+	tests := []struct {
+		Method, Path string
+		Status       int
+		Type, Body   string
+		Ok           bool
+	}{
+		{"GET", "/holding/btc", 200, JSON, `{"holding": 5}`, true},
+		{"GET", "/holding/btc", 200, "text/plain", `{"holding": 5}`, false},
+		{"GET", "/holding/btc", 500, "text/plain; charset=utf-8", "server error", true},
+		{"GET", "/holding/btc", 404, "text/plain", "not found", false},
+		{"POST", "/holding/btc", 200, JSON, `{"holding": 5}`, false},
+		{"POST", "/pause", 200, JSON, "", true},
+		{"POST", "/pause", 200, JSON, "{}", false},
+		{"GET", "/events", 200, "text/event-stream", ": connected\n\n", true},
+	}
+
+	for _, test := range tests {
+		err := doc.Check(test.Method, test.Path, test.Status, test.Type, []byte(test.Body))
+
+		if (err == nil) != test.Ok {
+			t.Fatalf("%s %s %d: expected ok %v, got %v", test.Method, test.Path, test.Status, test.Ok, err)
+		}
+	}
+}
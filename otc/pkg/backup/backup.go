@@ -0,0 +1,494 @@
+// Package backup writes the state of every desk (users, orders and their
+// events, price observations and the config without secrets) to a single
+// signed archive, and restores it into an empty store.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/skycoin/services/otc/pkg/desk"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// VERSION of the archive layout and the JSON inside it. Older archives are
+// brought up to date by UPGRADES when imported.
+const VERSION = 1
+
+const (
+	MANIFEST  string = "manifest.json"
+	SIGNATURE string = "manifest.sig"
+	CONFIG    string = "config.toml"
+	PRICES    string = "prices.json"
+)
+
+var (
+	ErrSignature = errors.New("archive signature is invalid")
+	ErrNewer     = errors.New("archive is newer than this otc, upgrade otc first")
+)
+
+// UPGRADES converts archives from the version they're keyed by to the next
+// one, e.g. UPGRADES[1] turns a version 1 archive into version 2.
+var UPGRADES = map[int]func(*Archive) error{}
+
+type Manifest struct {
+	Version   int   `json:"version"`
+	CreatedAt int64 `json:"created_at"`
+	// address of the key that signed the manifest, see Key
+	Signer string `json:"signer"`
+	// ids of the desks included, empty for the main desk
+	Desks []string `json:"desks"`
+	Files []*File  `json:"files"`
+}
+
+type File struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Archive is the contents of a backup, file name to data. Desk files are
+// under Dir(id).
+type Archive struct {
+	Manifest *Manifest
+	Files    map[string][]byte
+}
+
+// Key is the key archives are signed with, derived from the main desk's
+// SKY seed so that the same config can verify them after a migration.
+func Key(seed string) (cipher.PubKey, cipher.SecKey) {
+	return cipher.GenerateDeterministicKeyPair([]byte("otc backup:" + seed))
+}
+
+// Signer returns the address of Key(seed).
+func Signer(seed string) string {
+	pub, _ := Key(seed)
+	return cipher.AddressFromPubKey(pub).String()
+}
+
+// Dir is where a desk's files are kept in the archive.
+func Dir(id string) string {
+	if id == "" {
+		return "main/"
+	}
+	return "desks/" + id + "/"
+}
+
+// Snapshot reads the storage of every desk in conf. Files are checked to
+// decode so a corrupt store isn't backed up unnoticed.
+func Snapshot(conf *otc.Config, now time.Time) (*Archive, error) {
+	a := &Archive{
+		Manifest: &Manifest{
+			Version:   VERSION,
+			CreatedAt: now.UTC().Unix(),
+			Signer:    Signer(conf.SKY.Seed),
+			Desks:     append([]string{""}, conf.DeskIds()...),
+		},
+		Files: make(map[string][]byte),
+	}
+
+	for _, id := range a.Manifest.Desks {
+		c := conf
+		if id != "" {
+			c = conf.Desks[id]
+		}
+
+		if err := a.snapshot(Dir(id), desk.Path(id, c)); err != nil {
+			return nil, fmt.Errorf("desk %s: %v", (&desk.Desk{Id: id}).Name(), err)
+		}
+	}
+
+	config, err := Config(conf)
+	if err != nil {
+		return nil, err
+	}
+	a.Files[CONFIG] = config
+
+	return a, nil
+}
+
+func (a *Archive) snapshot(dir, path string) error {
+	users, err := visible(path + model.USERS)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range users {
+		data, err := ioutil.ReadFile(path + model.USERS + name)
+		if err != nil {
+			return err
+		}
+		if err = check(name, data, &otc.User{}); err != nil {
+			return err
+		}
+		a.Files[dir+model.USERS+name] = data
+
+		user := strings.TrimSuffix(name, ".json")
+		orders, err := visible(path + model.ORDERS + user)
+		if err != nil {
+			return err
+		}
+
+		for _, oname := range orders {
+			file := model.ORDERS + user + "/" + oname
+			data, err := ioutil.ReadFile(path + file)
+			if err != nil {
+				return err
+			}
+			if err = check(file, data, &otc.Order{}); err != nil {
+				return err
+			}
+			a.Files[dir+file] = data
+		}
+	}
+
+	// appended to while otc runs, so a partial last line is left out
+	for _, name := range []string{PRICES, model.EXPIRED} {
+		data, err := ioutil.ReadFile(path + name)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+			data = data[:i+1]
+		} else {
+			data = nil
+		}
+		if err = lines(name, data); err != nil {
+			return err
+		}
+		a.Files[dir+name] = data
+	}
+
+	// stage pauses and maintenance windows, replaced as a whole
+	data, err := ioutil.ReadFile(path + model.PAUSES)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err = check(model.PAUSES, data, &json.RawMessage{}); err != nil {
+		return err
+	}
+	a.Files[dir+model.PAUSES] = data
+
+	return nil
+}
+
+// visible lists the files of a directory that aren't hidden (temporary
+// files are), or none if it doesn't exist.
+func visible(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Name(), ".") {
+			names = append(names, info.Name())
+		}
+	}
+	return names, nil
+}
+
+func check(name string, data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// lines checks a file of JSON lines.
+func lines(name string, data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if err := check(name, scanner.Bytes(), &json.RawMessage{}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Config encodes conf and its desks as toml without secrets (seeds,
+// passwords, tokens, and alert webhook URLs, which carry their own). Secret
+// file paths are kept.
+func Config(conf *otc.Config) ([]byte, error) {
+	desks := make(map[string]*otc.Config)
+	for id, d := range conf.Desks {
+		desks[id] = redact(d)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# exported by otc, secrets removed\n\n")
+	err := toml.NewEncoder(&buf).Encode(struct {
+		*otc.Config
+		Desks map[string]*otc.Config
+	}{redact(conf), desks})
+
+	return buf.Bytes(), err
+}
+
+func redact(conf *otc.Config) *otc.Config {
+	c := *conf
+	c.SKY.Seed = ""
+	c.BTC.Pass = ""
+	c.Alerts.SMTP.Pass = ""
+	c.Alerts.Webhook.URL = ""
+	c.Alerts.Slack.URL = ""
+	c.Desk.Token = ""
+	c.Desk.Operators = nil
+	c.Watcher.Key = ""
+	c.Desks = nil
+	return &c
+}
+
+// Write signs the manifest with sec and writes the archive as a gzipped
+// tar: manifest, signature, then every file sorted by name.
+func (a *Archive) Write(w io.Writer, sec cipher.SecKey) error {
+	names := make([]string, 0, len(a.Files))
+	for name := range a.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	a.Manifest.Files = make([]*File, 0, len(names))
+	for _, name := range names {
+		sum := sha256.Sum256(a.Files[name])
+		a.Manifest.Files = append(a.Manifest.Files, &File{
+			Name:   name,
+			Size:   len(a.Files[name]),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	manifest, err := json.MarshalIndent(a.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	sig := cipher.SignHash(cipher.SumSHA256(manifest), sec)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	modified := time.Unix(a.Manifest.CreatedAt, 0)
+
+	add := func(name string, data []byte) error {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: modified,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	}
+
+	if err = add(MANIFEST, manifest); err != nil {
+		return err
+	}
+	if err = add(SIGNATURE, []byte(sig.Hex())); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err = add(name, a.Files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read reads an archive, checking that the manifest was signed by signer,
+// that every file matches it, and upgrading it to VERSION.
+func Read(r io.Reader, signer string) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if files[header.Name], err = ioutil.ReadAll(tr); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest, sig := files[MANIFEST], files[SIGNATURE]
+	delete(files, MANIFEST)
+	delete(files, SIGNATURE)
+	if manifest == nil || sig == nil {
+		return nil, errors.New("archive has no signed manifest")
+	}
+
+	a := &Archive{Manifest: &Manifest{}, Files: files}
+	if err = json.Unmarshal(manifest, a.Manifest); err != nil {
+		return nil, err
+	}
+
+	if a.Manifest.Signer != signer {
+		return nil, fmt.Errorf("archive is signed by %s, expected %s", a.Manifest.Signer, signer)
+	}
+	addr, err := cipher.DecodeBase58Address(signer)
+	if err != nil {
+		return nil, err
+	}
+	s, err := cipher.SigFromHex(string(sig))
+	if err != nil {
+		return nil, ErrSignature
+	}
+	if err = cipher.ChkSig(addr, cipher.SumSHA256(manifest), s); err != nil {
+		return nil, ErrSignature
+	}
+
+	if len(a.Manifest.Files) != len(files) {
+		return nil, fmt.Errorf("archive has %d files, manifest lists %d", len(files), len(a.Manifest.Files))
+	}
+	for _, f := range a.Manifest.Files {
+		data, ok := files[f.Name]
+		sum := sha256.Sum256(data)
+		if !ok || len(data) != f.Size || hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, fmt.Errorf("%s doesn't match the manifest", f.Name)
+		}
+	}
+
+	return a, a.Upgrade()
+}
+
+// Upgrade runs UPGRADES until the archive is at VERSION.
+func (a *Archive) Upgrade() error {
+	if a.Manifest.Version > VERSION {
+		return ErrNewer
+	}
+
+	for a.Manifest.Version < VERSION {
+		upgrade := UPGRADES[a.Manifest.Version]
+		if upgrade == nil {
+			return fmt.Errorf("no upgrade from archive version %d", a.Manifest.Version)
+		}
+		if err := upgrade(a); err != nil {
+			return fmt.Errorf("upgrading from version %d: %v", a.Manifest.Version, err)
+		}
+		a.Manifest.Version++
+	}
+
+	return nil
+}
+
+// Restore writes every desk in the archive to its storage in conf. Desks
+// must be configured and their storage empty, nothing is written otherwise.
+func (a *Archive) Restore(conf *otc.Config) error {
+	paths := make(map[string]string)
+
+	for _, id := range a.Manifest.Desks {
+		c := conf
+		if id != "" {
+			if c = conf.Desks[id]; c == nil {
+				return fmt.Errorf("desk %s isn't configured", id)
+			}
+		}
+
+		path := desk.Path(id, c)
+		if err := empty(path); err != nil {
+			return fmt.Errorf("desk %s: %v", (&desk.Desk{Id: id}).Name(), err)
+		}
+		paths[Dir(id)] = path
+	}
+
+	// checked before writing anything
+	targets := make(map[string]string)
+	for name := range a.Files {
+		if name == CONFIG {
+			continue
+		}
+
+		dir := "main/"
+		if strings.HasPrefix(name, "desks/") {
+			parts := strings.SplitN(name, "/", 3)
+			if len(parts) < 3 {
+				return fmt.Errorf("unexpected file %s", name)
+			}
+			dir = parts[0] + "/" + parts[1] + "/"
+		}
+
+		rest := strings.TrimPrefix(name, dir)
+		if paths[dir] == "" || rest == name || strings.Contains(name, "..") {
+			return fmt.Errorf("unexpected file %s", name)
+		}
+		targets[name] = paths[dir] + rest
+	}
+
+	for _, path := range paths {
+		if err := model.MakeDirs(path); err != nil {
+			return err
+		}
+	}
+
+	for name, target := range targets {
+		dir := filepath.Dir(target)
+		if filepath.Base(dir)+"/" == model.USERS {
+			// users without orders still have a folder for them
+			dir = filepath.Join(filepath.Dir(dir), model.ORDERS, strings.TrimSuffix(filepath.Base(target), ".json"))
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := model.WriteFile(target, a.Files[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// empty checks that a desk's storage has no users or prices yet.
+func empty(path string) error {
+	users, err := visible(path + model.USERS)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		return fmt.Errorf("%s already has %d users", path, len(users))
+	}
+
+	if info, err := os.Stat(path + PRICES); err == nil && info.Size() > 0 {
+		return fmt.Errorf("%s already has prices", path)
+	}
+
+	return nil
+}
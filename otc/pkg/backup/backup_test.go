@@ -0,0 +1,324 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const SEED = "backup seed"
+
+// MockConfig returns a config with a main desk and a "shop" desk stored
+// under dir.
+func MockConfig(t *testing.T, dir string) *otc.Config {
+	conf := &otc.Config{}
+	conf.SKY.Seed, conf.BTC.Pass = SEED, "btc pass"
+	conf.Desk.Path = filepath.Join(dir, "main")
+	conf.Desk.Token = "main token"
+	conf.Desk.Operators = map[string]string{"karl": "karl token"}
+	conf.Watcher.Key = "watcher key"
+	conf.Alerts.Webhook.URL = "https://alerts.example/webhook key"
+	conf.Alerts.Slack.URL = "https://hooks.slack.com/services/slack key"
+
+	shop := &otc.Config{}
+	shop.SKY.Seed = "shop seed"
+	shop.Desk.Path = filepath.Join(dir, "shop")
+	shop.Desk.Token = "shop token"
+	shop.Watcher.Key = "shop watcher key"
+	conf.Desks = map[string]*otc.Config{"shop": shop}
+
+	return conf
+}
+
+func MockStore(t *testing.T, path string) *otc.User {
+	if err := model.MakeDirs(path); err != nil {
+		t.Fatal(err)
+	}
+
+	_, sec := cipher.GenerateDeterministicKeyPair([]byte(path))
+	user := &otc.User{
+		Address: cipher.AddressFromSecKey(sec).String(),
+		Drop:    &otc.Drop{Address: "drop", Currency: otc.BTC},
+	}
+	user.Id = user.Address + ":" + string(user.Drop.Currency) + ":" + user.Drop.Address
+	if err := model.SaveUser(path, user); err != nil {
+		t.Fatal(err)
+	}
+
+	order := &otc.Order{User: user, Id: "drop:1", Status: otc.DEPOSIT, Times: &otc.Times{}}
+	if err := model.WriteOrder(path, order); err != nil {
+		t.Fatal(err)
+	}
+
+	prices := "{\"price\":1}\n{\"price\":2}\n{\"pri"
+	if err := ioutil.WriteFile(path+PRICES, []byte(prices), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := model.NewController(nil)
+	c.Path = path
+	if err := c.PauseStage(model.Pause{Currency: otc.BTC, Stage: model.SEND}); err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+func TempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "otc-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func Export(t *testing.T, a *Archive) []byte {
+	var buf bytes.Buffer
+	_, sec := Key(SEED)
+	if err := a.Write(&buf, sec); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	from, to := TempDir(t), TempDir(t)
+	defer os.RemoveAll(from)
+	defer os.RemoveAll(to)
+
+	conf := MockConfig(t, from)
+	user := MockStore(t, conf.Desk.Path+"/")
+	MockStore(t, conf.Desks["shop"].Desk.Path+"/")
+
+	a, err := Snapshot(conf, time.Unix(1000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2 desks with a user, an order, prices and pauses, and the config
+	if len(a.Files) != 9 {
+		t.Fatalf("expected 9 files, got %d", len(a.Files))
+	}
+	if prices := string(a.Files["main/"+PRICES]); prices != "{\"price\":1}\n{\"price\":2}\n" {
+		t.Fatalf("expected partial line to be left out, got %q", prices)
+	}
+
+	config := string(a.Files[CONFIG])
+	for _, secret := range []string{SEED, "shop seed", "btc pass", "main token", "shop token", "watcher key", "karl token", "webhook key", "slack key"} {
+		if strings.Contains(config, secret) {
+			t.Fatalf("config contains %q:\n%s", secret, config)
+		}
+	}
+	if conf.SKY.Seed != SEED || conf.Desk.Operators["karl"] != "karl token" {
+		t.Fatal("redacting changed the running config")
+	}
+
+	read, err := Read(bytes.NewReader(Export(t, a)), Signer(SEED))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restore := MockConfig(t, to)
+	if err = read.Restore(restore); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := model.Load(restore.Desk.Path + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Id != user.Id || len(users[0].Orders) != 1 {
+		t.Fatalf("expected restored user with 1 order, got %+v", users)
+	}
+	if _, err = model.Load(restore.Desks["shop"].Desk.Path + "/"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := model.NewController(nil)
+	c.Path = restore.Desk.Path + "/"
+	if err = c.Load(); err != nil || len(c.GetPauses()) != 1 {
+		t.Fatalf("expected restored pause, got %v %v", c.GetPauses(), err)
+	}
+
+	// restoring twice would mix stores
+	if err = read.Restore(restore); err == nil || !strings.Contains(err.Error(), "already has 1 users") {
+		t.Fatalf("expected non-empty store error, got %v", err)
+	}
+}
+
+func TestRestoreUnknownDesk(t *testing.T) {
+	from, to := TempDir(t), TempDir(t)
+	defer os.RemoveAll(from)
+	defer os.RemoveAll(to)
+
+	a, err := Snapshot(MockConfig(t, from), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := MockConfig(t, to)
+	conf.Desks = nil
+	if err = a.Restore(conf); err == nil || err.Error() != "desk shop isn't configured" {
+		t.Fatalf("expected unconfigured desk error, got %v", err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	a := &Archive{
+		Manifest: &Manifest{Version: VERSION, Signer: Signer(SEED)},
+		Files:    map[string][]byte{"main/" + PRICES: []byte("{}\n")},
+	}
+
+	tests := []struct {
+		Name   string
+		Change func(*Archive)
+		Signer string
+		Err    string
+	}{
+		{"valid", func(*Archive) {}, Signer(SEED), ""},
+		{"other signer", func(*Archive) {}, Signer("other"), "archive is signed by"},
+		{"newer", func(a *Archive) { a.Manifest.Version = VERSION + 1 }, Signer(SEED), ErrNewer.Error()},
+	}
+
+	for _, test := range tests {
+		c := &Archive{Manifest: &Manifest{}, Files: a.Files}
+		*c.Manifest = *a.Manifest
+		test.Change(c)
+
+		_, err := Read(bytes.NewReader(Export(t, c)), test.Signer)
+		if test.Err == "" && err != nil {
+			t.Fatalf("%s: %v", test.Name, err)
+		}
+		if test.Err != "" && (err == nil || !strings.Contains(err.Error(), test.Err)) {
+			t.Fatalf("%s: expected %q, got %v", test.Name, test.Err, err)
+		}
+	}
+}
+
+// Replace returns the archive in data with the contents of one entry
+// replaced, as someone editing it by hand would.
+func Replace(t *testing.T, data []byte, name string, contents []byte) []byte {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		entry, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name == name {
+			entry = contents
+			header.Size = int64(len(entry))
+		}
+
+		if err = tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tw.Write(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
+
+func TestReadTampered(t *testing.T) {
+	a := &Archive{
+		Manifest: &Manifest{Version: VERSION, Signer: Signer(SEED)},
+		Files:    map[string][]byte{"main/" + PRICES: []byte("{\"price\":1}\n")},
+	}
+	data := Export(t, a)
+
+	changed := Replace(t, data, "main/"+PRICES, []byte("{\"price\":9}\n"))
+	if _, err := Read(bytes.NewReader(changed), Signer(SEED)); err == nil || !strings.Contains(err.Error(), "doesn't match the manifest") {
+		t.Fatalf("expected changed file to be found, got %v", err)
+	}
+
+	// changing the manifest to match breaks the signature
+	a.Files["main/"+PRICES] = []byte("{\"price\":9}\n")
+	a.Manifest.CreatedAt = 1
+	var buf bytes.Buffer
+	_, sec := Key("other")
+	if err := a.Write(&buf, sec); err != nil {
+		t.Fatal(err)
+	}
+	manifest := Entry(t, buf.Bytes(), MANIFEST)
+	if _, err := Read(bytes.NewReader(Replace(t, changed, MANIFEST, manifest)), Signer(SEED)); err != ErrSignature {
+		t.Fatalf("expected %v, got %v", ErrSignature, err)
+	}
+}
+
+func Entry(t *testing.T, data []byte, name string) []byte {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name == name {
+			entry, err := ioutil.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return entry
+		}
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	defer delete(UPGRADES, VERSION-1)
+	UPGRADES[VERSION-1] = func(a *Archive) error {
+		a.Files["main/upgraded"] = []byte("yes")
+		return nil
+	}
+
+	a := &Archive{
+		Manifest: &Manifest{Version: VERSION - 1, Signer: Signer(SEED)},
+		Files:    map[string][]byte{},
+	}
+
+	read, err := Read(bytes.NewReader(Export(t, a)), Signer(SEED))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Manifest.Version != VERSION || string(read.Files["main/upgraded"]) != "yes" {
+		t.Fatalf("expected upgraded archive, got version %d", read.Manifest.Version)
+	}
+
+	a.Manifest.Version = VERSION - 2
+	if _, err = Read(bytes.NewReader(Export(t, a)), Signer(SEED)); err == nil || !strings.Contains(err.Error(), "no upgrade") {
+		t.Fatalf("expected missing upgrade error, got %v", err)
+	}
+}
@@ -29,12 +29,16 @@ type Connection interface {
 type Currencies struct {
 	Prices      map[otc.Currency]*Pricer
 	Connections map[otc.Currency]Connection
+	History     *History
 }
 
 func New() *Currencies {
+	history, _ := NewHistory("", 0)
+
 	return &Currencies{
 		Prices:      make(map[otc.Currency]*Pricer),
 		Connections: make(map[otc.Currency]Connection),
+		History:     history,
 	}
 }
 
@@ -47,10 +51,14 @@ func (c *Currencies) Add(curr otc.Currency, conn Connection) error {
 
 	if curr == otc.BTC {
 		c.Prices[curr] = &Pricer{
-			Using: INTERNAL,
-			Sources: map[Source]*Price{
-				INTERNAL: NewPrice(200000),
-			},
+			Using:    INTERNAL,
+			Sources:  make(map[Source]*Price),
+			Currency: curr,
+			History:  c.History,
+		}
+
+		if err := c.Prices[curr].SetPrice(INTERNAL, 200000); err != nil {
+			return err
 		}
 
 		go func() {
@@ -58,8 +66,9 @@ func (c *Currencies) Add(curr otc.Currency, conn Connection) error {
 				price, err := exchange.GetBTCValue()
 				if err != nil {
 					c.Prices[curr].SetSource(INTERNAL)
+				} else if err = c.Prices[curr].SetPrice(EXCHANGE, price); err != nil {
+					println(err.Error())
 				} else {
-					c.Prices[curr].SetPrice(EXCHANGE, price)
 					c.Prices[curr].SetSource(EXCHANGE)
 				}
 
@@ -96,16 +105,23 @@ func (c *Currencies) Balance(drop *otc.Drop) (uint64, error) {
 }
 
 func (c *Currencies) Value(curr otc.Currency, amount uint64) (uint64, string, uint64, error) {
+	value, obs, err := c.Quote(curr, amount)
+	return value, string(obs.Source), obs.Value, err
+}
+
+// Quote returns the SKY value of amount along with the price observation it
+// was calculated from.
+func (c *Currencies) Quote(curr otc.Currency, amount uint64) (uint64, Observation, error) {
 	if c.Prices[curr] == nil {
-		return 0, "", 0, ErrPriceMissing
+		return 0, Observation{}, ErrPriceMissing
 	}
 
 	if amount == 0 {
-		return 0, "", 0, ErrZeroAmount
+		return 0, Observation{}, ErrZeroAmount
 	}
 
-	price, source, _ := c.Prices[curr].GetPrice()
-	return uint64(float64(float64(amount)/float64(price)*1e2)) * 1e4, string(source), price, nil
+	obs := c.Prices[curr].Current()
+	return uint64(float64(float64(amount)/float64(obs.Value)*1e2)) * 1e4, obs, nil
 }
 
 func (c *Currencies) Send(curr otc.Currency, addr string, amount uint64) (string, error) {
@@ -0,0 +1,311 @@
+package currencies
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/events"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+var ErrObservationMissing error = errors.New("observation missing")
+
+// Observation is a single price seen from a source.
+type Observation struct {
+	Id       uint64       `json:"id"`
+	Currency otc.Currency `json:"currency"`
+	Source   Source       `json:"source"`
+	Value    uint64       `json:"value"`
+	Time     int64        `json:"time"`
+}
+
+// Point is a downsampled interval of observations, starting at Time.
+type Point struct {
+	Time  int64  `json:"time"`
+	Open  uint64 `json:"open"`
+	High  uint64 `json:"high"`
+	Low   uint64 `json:"low"`
+	Close uint64 `json:"close"`
+	Count int    `json:"count"`
+}
+
+// History keeps every price observation. If Path is set, observations are
+// appended to it as JSON lines so they survive restarts, and only the last
+// Retention of them are kept in memory.
+type History struct {
+	sync.RWMutex
+
+	Path string
+	// observations in time order, ids count up from the first one
+	Observations []*Observation
+	// how long observations stay in memory, measured back from the latest
+	// one. 0 keeps all of them, older ones are read from Path when needed.
+	Retention time.Duration
+	// new observations are published here
+	Events *events.Broker
+
+	// observations ever added, the id of the last one
+	count uint64
+}
+
+func NewHistory(path string, retention time.Duration) (*History, error) {
+	h := &History{
+		Path:         path,
+		Observations: make([]*Observation, 0),
+		Retention:    retention,
+	}
+
+	if path == "" {
+		return h, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	err = complete(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.stored(func(obs *Observation) bool {
+		obs.Time = h.clamp(obs.Time)
+		h.add(obs)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// stored calls fn with each observation saved in Path, in order, until it
+// returns false.
+func (h *History) stored(fn func(*Observation) bool) error {
+	file, err := os.Open(h.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	for {
+		obs := new(Observation)
+		if err = dec.Decode(obs); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !fn(obs) {
+			return nil
+		}
+	}
+}
+
+// complete truncates a partial last line, left by a crash while an
+// observation was being appended, so the file decodes and new observations
+// start on their own line.
+func complete(file *os.File) error {
+	end, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4096)
+	for off := end; off > 0; {
+		n := int64(len(buf))
+		if off < n {
+			n = off
+		}
+		off -= n
+
+		if _, err = file.ReadAt(buf[:n], off); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = off + int64(i) + 1
+			break
+		}
+		end = off
+	}
+
+	return file.Truncate(end)
+}
+
+// clamp keeps observations in time order: one timed before the latest
+// gets the latest's time.
+func (h *History) clamp(t int64) int64 {
+	if n := len(h.Observations); n > 0 && t < h.Observations[n-1].Time {
+		return h.Observations[n-1].Time
+	}
+	return t
+}
+
+// add keeps obs in memory, dropping observations older than Retention
+// before it.
+func (h *History) add(obs *Observation) {
+	h.count = obs.Id
+	h.Observations = append(h.Observations, obs)
+
+	if h.Retention > 0 {
+		cutoff := obs.Time - int64(h.Retention/time.Second)
+		h.Observations = h.Observations[h.search(cutoff):]
+	}
+}
+
+// search returns the index of the first observation in memory at or after
+// t.
+func (h *History) search(t int64) int {
+	return sort.Search(len(h.Observations), func(i int) bool {
+		return h.Observations[i].Time >= t
+	})
+}
+
+// first returns the id of the oldest observation in memory.
+func (h *History) first() uint64 {
+	return h.count - uint64(len(h.Observations)) + 1
+}
+
+func (h *History) Add(cur otc.Currency, source Source, value uint64, at time.Time) (*Observation, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	obs := &Observation{
+		Id:       h.count + 1,
+		Currency: cur,
+		Source:   source,
+		Value:    value,
+		Time:     h.clamp(at.UTC().Unix()),
+	}
+
+	if h.Path != "" {
+		file, err := os.OpenFile(h.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = json.NewEncoder(file).Encode(obs); err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		if err = file.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	h.add(obs)
+	h.Events.Publish(events.PRICE, nil, obs)
+	return obs, nil
+}
+
+// Get returns an observation by id, reading it from Path if it's no longer
+// kept in memory.
+func (h *History) Get(id uint64) (Observation, error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	// ids are sequential, starting at 1
+	if id == 0 || id > h.count {
+		return Observation{}, ErrObservationMissing
+	}
+
+	if first := h.first(); id >= first {
+		return *h.Observations[id-first], nil
+	}
+
+	var found *Observation
+	err := h.stored(func(obs *Observation) bool {
+		if obs.Id == id {
+			found = obs
+		}
+		return found == nil
+	})
+	if err != nil || found == nil {
+		return Observation{}, ErrObservationMissing
+	}
+
+	return *found, nil
+}
+
+// Range returns observations of a currency and source with from <= time <
+// to. An empty source matches all sources. Observations no longer kept in
+// memory are read from Path.
+func (h *History) Range(cur otc.Currency, source Source, from, to int64) []Observation {
+	h.RLock()
+	defer h.RUnlock()
+
+	out := make([]Observation, 0)
+	matches := func(obs *Observation) bool {
+		return obs.Currency == cur && (source == "" || obs.Source == source) &&
+			obs.Time >= from && obs.Time < to
+	}
+
+	// older than what's in memory
+	first := h.first()
+	if first > 1 && h.Path != "" && (len(h.Observations) == 0 || from < h.Observations[0].Time) {
+		h.stored(func(obs *Observation) bool {
+			if obs.Id >= first || obs.Time >= to {
+				return false
+			}
+			if matches(obs) {
+				out = append(out, *obs)
+			}
+			return true
+		})
+	}
+
+	// binary searched, the rest only need the currency and source checked
+	for i, end := h.search(from), h.search(to); i < end; i++ {
+		if matches(h.Observations[i]) {
+			out = append(out, *h.Observations[i])
+		}
+	}
+
+	return out
+}
+
+// Downsample groups time ordered observations into intervals of the given
+// number of seconds. Intervals without observations are left out.
+func Downsample(obs []Observation, interval int64) []Point {
+	points := make([]Point, 0)
+
+	if interval <= 0 {
+		interval = 1
+	}
+
+	for _, o := range obs {
+		start := o.Time - (o.Time % interval)
+
+		if len(points) == 0 || points[len(points)-1].Time != start {
+			points = append(points, Point{
+				Time:  start,
+				Open:  o.Value,
+				High:  o.Value,
+				Low:   o.Value,
+				Close: o.Value,
+			})
+		}
+
+		p := &points[len(points)-1]
+		if o.Value > p.High {
+			p.High = o.Value
+		}
+		if o.Value < p.Low {
+			p.Low = o.Value
+		}
+		p.Close = o.Value
+		p.Count++
+	}
+
+	return points
+}
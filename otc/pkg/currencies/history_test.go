@@ -0,0 +1,245 @@
+package currencies
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func TestHistoryPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "prices.json")
+
+	history, err := NewHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []uint64{100, 200, 300} {
+		if _, err = history.Add(otc.BTC, EXCHANGE, value, time.Unix(10, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err = NewHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.Observations) != 3 {
+		t.Fatalf("expected 3 observations, got %d", len(history.Observations))
+	}
+
+	obs, err := history.Get(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if obs.Value != 200 || obs.Source != EXCHANGE || obs.Time != 10 {
+		t.Fatal("bad observation")
+	}
+
+	if _, err = history.Get(4); err != ErrObservationMissing {
+		t.Fatal("observation should be missing")
+	}
+}
+
+func TestHistoryPartialLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "prices.json")
+
+	history, err := NewHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []uint64{100, 200} {
+		if _, err = history.Add(otc.BTC, EXCHANGE, value, time.Unix(10, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// crashed while appending the third
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"id":3,"currency":"BT`)
+	file.Close()
+
+	history, err = NewHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.Observations) != 2 {
+		t.Fatalf("expected 2 observations, got %d", len(history.Observations))
+	}
+
+	if _, err = history.Add(otc.BTC, EXCHANGE, 300, time.Unix(20, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err = NewHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obs, err := history.Get(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if obs.Value != 300 {
+		t.Fatal("bad observation after partial line")
+	}
+}
+
+func TestHistoryRange(t *testing.T) {
+	history, _ := NewHistory("", 0)
+	history.Add(otc.BTC, EXCHANGE, 100, time.Unix(10, 0))
+	history.Add(otc.BTC, INTERNAL, 200, time.Unix(20, 0))
+	history.Add(otc.ETH, EXCHANGE, 300, time.Unix(20, 0))
+	history.Add(otc.BTC, EXCHANGE, 400, time.Unix(30, 0))
+
+	if obs := history.Range(otc.BTC, "", 0, 100); len(obs) != 3 {
+		t.Fatalf("expected 3 observations, got %d", len(obs))
+	}
+
+	if obs := history.Range(otc.BTC, EXCHANGE, 0, 30); len(obs) != 1 {
+		t.Fatalf("expected 1 observation, got %d", len(obs))
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "prices.json")
+	history, err := NewHistory(path, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, at := range []int64{0, 30, 60, 100, 130} {
+		if _, err = history.Add(otc.BTC, EXCHANGE, uint64(i+1), time.Unix(at, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a clock going backwards doesn't break the order
+	obs, err := history.Add(otc.BTC, INTERNAL, 6, time.Unix(120, 0))
+	if err != nil || obs.Id != 6 || obs.Time != 130 {
+		t.Fatalf("expected observation 6 at 130, got %+v %v", obs, err)
+	}
+
+	for _, h := range []*History{history, MustHistory(t, path)} {
+		// 60 seconds before the latest
+		if len(h.Observations) != 3 {
+			t.Fatalf("expected 3 observations in memory, got %d", len(h.Observations))
+		}
+
+		for _, id := range []uint64{1, 4, 6} {
+			if obs, err := h.Get(id); err != nil || obs.Value != id {
+				t.Fatalf("expected observation %d, got %+v %v", id, obs, err)
+			}
+		}
+		if _, err := h.Get(7); err != ErrObservationMissing {
+			t.Fatalf("expected missing observation, got %v", err)
+		}
+
+		tests := []struct {
+			Source   Source
+			From, To int64
+			Values   []uint64
+		}{
+			{"", 0, 200, []uint64{1, 2, 3, 4, 5, 6}},
+			{EXCHANGE, 0, 200, []uint64{1, 2, 3, 4, 5}},
+			{"", 30, 61, []uint64{2, 3}},
+			{"", 60, 131, []uint64{3, 4, 5, 6}},
+			{"", 200, 0, nil},
+		}
+		for _, test := range tests {
+			found := h.Range(otc.BTC, test.Source, test.From, test.To)
+			if len(found) != len(test.Values) {
+				t.Fatalf("%d-%d: expected %v, got %+v", test.From, test.To, test.Values, found)
+			}
+			for i, value := range test.Values {
+				if found[i].Value != value {
+					t.Fatalf("%d-%d: expected %v, got %+v", test.From, test.To, test.Values, found)
+				}
+			}
+		}
+	}
+
+	// ids carry on after a restart
+	history = MustHistory(t, path)
+	if obs, err = history.Add(otc.BTC, EXCHANGE, 7, time.Unix(200, 0)); err != nil || obs.Id != 7 {
+		t.Fatalf("expected observation 7, got %+v %v", obs, err)
+	}
+}
+
+func MustHistory(t *testing.T, path string) *History {
+	history, err := NewHistory(path, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return history
+}
+
+func TestDownsample(t *testing.T) {
+	points := Downsample([]Observation{
+		{Value: 5, Time: 0},
+		{Value: 9, Time: 30},
+		{Value: 2, Time: 45},
+		{Value: 4, Time: 59},
+		{Value: 7, Time: 120},
+	}, 60)
+
+	if len(points) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(points))
+	}
+
+	if points[0] != (Point{Time: 0, Open: 5, High: 9, Low: 2, Close: 4, Count: 4}) {
+		t.Fatalf("bad point %+v", points[0])
+	}
+
+	if points[1] != (Point{Time: 120, Open: 7, High: 7, Low: 7, Close: 7, Count: 1}) {
+		t.Fatalf("bad point %+v", points[1])
+	}
+}
+
+func TestPricerHistory(t *testing.T) {
+	history, _ := NewHistory("", 0)
+	pricer := &Pricer{
+		Using:    EXCHANGE,
+		Sources:  make(map[Source]*Price),
+		Currency: otc.BTC,
+		History:  history,
+	}
+
+	pricer.SetPrice(EXCHANGE, 100)
+	pricer.SetPrice(EXCHANGE, 200)
+
+	current := pricer.Current()
+	if current.Id != 2 || current.Value != 200 || current.Source != EXCHANGE {
+		t.Fatalf("bad current observation %+v", current)
+	}
+}
@@ -10,6 +10,8 @@ type Price struct {
 
 	Updated time.Time
 	Amount  uint64
+	// id of the history observation for Amount, 0 if not recorded
+	Observation uint64
 }
 
 func NewPrice(amount uint64) *Price {
@@ -26,10 +28,22 @@ func (p *Price) Get() (uint64, time.Time) {
 	return p.Amount, p.Updated
 }
 
+func (p *Price) GetObserved() (uint64, time.Time, uint64) {
+	p.RLock()
+	defer p.RUnlock()
+
+	return p.Amount, p.Updated, p.Observation
+}
+
 func (p *Price) Set(amount uint64) {
+	p.SetObserved(amount, 0)
+}
+
+func (p *Price) SetObserved(amount, observation uint64) {
 	p.Lock()
 	defer p.Unlock()
 
 	p.Amount = amount
 	p.Updated = time.Now()
+	p.Observation = observation
 }
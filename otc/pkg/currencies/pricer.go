@@ -3,6 +3,8 @@ package currencies
 import (
 	"sync"
 	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
 )
 
 type Source string
@@ -17,6 +19,9 @@ type Pricer struct {
 
 	Using   Source
 	Sources map[Source]*Price
+	// if set, every price is recorded
+	Currency otc.Currency
+	History  *History
 }
 
 func (p *Pricer) SetSource(s Source) {
@@ -45,14 +50,57 @@ func (p *Pricer) GetPrice() (uint64, Source, time.Time) {
 	return price, p.Using, updated
 }
 
-func (p *Pricer) SetPrice(s Source, a uint64) {
+// Updated returns when source s last had a price set, or false if it
+// never had one.
+func (p *Pricer) Updated(s Source) (time.Time, bool) {
+	p.RLock()
+	defer p.RUnlock()
+
+	if p.Sources[s] == nil {
+		return time.Time{}, false
+	}
+
+	_, updated := p.Sources[s].Get()
+	return updated, true
+}
+
+// Current returns the price of the source in use as an observation, so it
+// can be referenced by orders.
+func (p *Pricer) Current() Observation {
+	p.RLock()
+	defer p.RUnlock()
+
+	if p.Sources[p.Using] == nil {
+		return Observation{Currency: p.Currency}
+	}
+
+	price, updated, id := p.Sources[p.Using].GetObserved()
+	return Observation{
+		Id:       id,
+		Currency: p.Currency,
+		Source:   p.Using,
+		Value:    price,
+		Time:     updated.UTC().Unix(),
+	}
+}
+
+func (p *Pricer) SetPrice(s Source, a uint64) error {
 	p.Lock()
 	defer p.Unlock()
 
+	var id uint64
+	if p.History != nil {
+		obs, err := p.History.Add(p.Currency, s, a, time.Now())
+		if err != nil {
+			return err
+		}
+		id = obs.Id
+	}
+
 	if p.Sources[s] == nil {
 		p.Sources[s] = NewPrice(a)
-		return
 	}
 
-	p.Sources[s].Set(a)
+	p.Sources[s].SetObserved(a, id)
+	return nil
 }
@@ -1,7 +1,9 @@
 package currencies
 
 import (
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestPricerGet(t *testing.T) {
@@ -33,3 +35,28 @@ func TestPricerGet(t *testing.T) {
 		t.Fatal("set price new")
 	}
 }
+
+func TestPricerUpdated(t *testing.T) {
+	pricer := &Pricer{Sources: make(map[Source]*Price)}
+
+	if _, ok := pricer.Updated(EXCHANGE); ok {
+		t.Fatal("expected no exchange price")
+	}
+
+	// read while the first exchange price is set, run with -race
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			pricer.Updated(EXCHANGE)
+		}
+	}()
+	pricer.SetPrice(EXCHANGE, 500)
+	wg.Wait()
+
+	updated, ok := pricer.Updated(EXCHANGE)
+	if !ok || time.Since(updated) > time.Minute {
+		t.Fatalf("expected exchange price just set, got %v %v", updated, ok)
+	}
+}
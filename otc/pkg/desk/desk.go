@@ -0,0 +1,136 @@
+// Package desk lets one otc process serve several desks (storefronts), each
+// with its own wallets, prices, orders and admin token, behind the shared
+// public and admin listeners.
+package desk
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/skycoin/services/otc/pkg/api"
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+type Desk struct {
+	// empty for the main desk
+	Id         string
+	Config     *otc.Config
+	Currencies *currencies.Currencies
+	Model      *model.Model
+	Public     http.Handler
+	Admin      http.Handler
+}
+
+// Name is how the desk appears in logs.
+func (d *Desk) Name() string {
+	if d.Id == "" {
+		return "main"
+	}
+	return d.Id
+}
+
+// Operator returns who token identifies at the desk: the operator it
+// belongs to, or the desk itself for the shared desk token. ok is false if
+// it isn't one of the desk's tokens.
+func (d *Desk) Operator(token string) (name string, ok bool) {
+	for _, name := range d.Config.Desk.OperatorNames() {
+		op := d.Config.Desk.Operators[name]
+		if op != "" && subtle.ConstantTimeCompare([]byte(op), []byte(token)) == 1 {
+			return name, true
+		}
+	}
+
+	// an empty desk token only opens a desk that has no operators
+	desk := d.Config.Desk.Token
+	if desk == "" && len(d.Config.Desk.Operators) > 0 {
+		return "", false
+	}
+	if subtle.ConstantTimeCompare([]byte(desk), []byte(token)) == 1 {
+		return d.Name() + " desk", true
+	}
+	return "", false
+}
+
+// Path returns the storage directory of a desk, ending in a slash:
+// Desk.path if set, model.PATH for the main desk and model.PATH/desks/<id>/
+// for the others.
+func Path(id string, conf *otc.Config) string {
+	path := conf.Desk.Path
+	if path == "" {
+		path = model.PATH
+		if id != "" {
+			path += "desks/" + id + "/"
+		}
+	}
+
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return path
+}
+
+// Public routes public api requests to the desk serving their host name,
+// or to the first (main) desk.
+func Public(desks ...*Desk) http.Handler {
+	hosts := make(map[string]*Desk)
+	for _, d := range desks {
+		for _, host := range d.Config.Desk.Hosts {
+			hosts[strings.ToLower(host)] = d
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d := hosts[Host(r)]; d != nil {
+			d.Public.ServeHTTP(w, r)
+			return
+		}
+		desks[0].Public.ServeHTTP(w, r)
+	})
+}
+
+// Admin routes admin api requests to the desk whose token they carry, as
+// "Authorization: Bearer <token>" or an access_token query parameter for
+// event streams, along with who the token identifies (see Desk.Operator).
+// A desk without a token (only valid when the main desk runs alone) takes
+// requests without one. The OpenAPI document is served to anyone.
+func Admin(desks ...*Desk) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/openapi.json") {
+			desks[0].Admin.ServeHTTP(w, r)
+			return
+		}
+
+		token := Token(r)
+		for _, d := range desks {
+			if name, ok := d.Operator(token); ok {
+				d.Admin.ServeHTTP(w, api.WithOperator(r, name))
+				return
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", `Bearer realm="otc"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// Host returns the request's host name, lowercased and without a port.
+func Host(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// Token returns the bearer token of a request, or empty.
+func Token(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return r.URL.Query().Get("access_token")
+}
@@ -0,0 +1,166 @@
+package desk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skycoin/services/otc/pkg/api"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func MockDesk(id, token string, hosts ...string) *Desk {
+	conf := &otc.Config{}
+	conf.Desk.Token = token
+	conf.Desk.Hosts = hosts
+
+	// handlers answer with the desk's name so routing can be checked
+	name := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte((&Desk{Id: id}).Name()))
+	}
+
+	return &Desk{
+		Id:     id,
+		Config: conf,
+		Public: http.HandlerFunc(name),
+		Admin:  http.HandlerFunc(name),
+	}
+}
+
+func TestPublic(t *testing.T) {
+	handler := Public(
+		MockDesk("", "main-token"),
+		MockDesk("shop", "shop-token", "shop.example.com"),
+	)
+
+	tests := []struct {
+		Host     string
+		Expected string
+	}{
+		{"otc.skycoin.net", "main"},
+		{"shop.example.com", "shop"},
+		{"SHOP.example.com:8081", "shop"},
+		{"127.0.0.1:8081", "main"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/api/v1/config", nil)
+		req.Host = test.Host
+
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		if res.Body.String() != test.Expected {
+			t.Fatalf(`%s: expected "%s", got "%s"`, test.Host, test.Expected, res.Body.String())
+		}
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	handler := Admin(
+		MockDesk("", "main-token"),
+		MockDesk("shop", "shop-token"),
+	)
+
+	tests := []struct {
+		Path     string
+		Auth     string
+		Status   int
+		Expected string
+	}{
+		{"/api/v1/status", "Bearer main-token", http.StatusOK, "main"},
+		{"/api/v1/status", "bearer shop-token", http.StatusOK, "shop"},
+		{"/api/v1/events?access_token=shop-token", "", http.StatusOK, "shop"},
+		{"/api/v1/status", "Bearer wrong", http.StatusUnauthorized, "unauthorized\n"},
+		{"/api/v1/status", "", http.StatusUnauthorized, "unauthorized\n"},
+		{"/api/v1/openapi.json", "", http.StatusOK, "main"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.Path, nil)
+		if test.Auth != "" {
+			req.Header.Set("Authorization", test.Auth)
+		}
+
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		if res.Code != test.Status || res.Body.String() != test.Expected {
+			t.Fatalf(`%s %s: expected %d "%s", got %d "%s"`, test.Path, test.Auth,
+				test.Status, test.Expected, res.Code, res.Body.String())
+		}
+	}
+}
+
+func TestAdminOperator(t *testing.T) {
+	shop := MockDesk("shop", "shop-token")
+	shop.Config.Desk.Operators = map[string]string{"karl": "karl-token", "nobody": ""}
+	shop.Admin = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(api.Operator(r)))
+	})
+	handler := Admin(MockDesk("", "main-token"), shop)
+
+	tests := []struct {
+		Auth     string
+		Status   int
+		Expected string
+	}{
+		{"Bearer karl-token", http.StatusOK, "karl"},
+		{"Bearer shop-token", http.StatusOK, "shop desk"},
+		// an operator without a token doesn't take requests without one
+		{"", http.StatusUnauthorized, "unauthorized\n"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/orders/annotate", nil)
+		if test.Auth != "" {
+			req.Header.Set("Authorization", test.Auth)
+		}
+
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		if res.Code != test.Status || res.Body.String() != test.Expected {
+			t.Fatalf(`%s: expected %d "%s", got %d "%s"`, test.Auth,
+				test.Status, test.Expected, res.Code, res.Body.String())
+		}
+	}
+}
+
+func TestAdminWithoutToken(t *testing.T) {
+	handler := Admin(MockDesk("", ""))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/api/v1/status", nil))
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+
+	// with operators, requests without a token aren't the desk's
+	main := MockDesk("", "")
+	main.Config.Desk.Operators = map[string]string{"karl": "karl-token"}
+	handler = Admin(main)
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/api/v1/status", nil))
+
+	if res.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", res.Code)
+	}
+}
+
+func TestPath(t *testing.T) {
+	conf := &otc.Config{}
+	if path := Path("", conf); path != ".otc/" {
+		t.Fatalf(`expected ".otc/", got "%s"`, path)
+	}
+	if path := Path("shop", conf); path != ".otc/desks/shop/" {
+		t.Fatalf(`expected ".otc/desks/shop/", got "%s"`, path)
+	}
+
+	conf.Desk.Path = "/var/lib/otc/shop"
+	if path := Path("shop", conf); path != "/var/lib/otc/shop/" {
+		t.Fatalf(`expected "/var/lib/otc/shop/", got "%s"`, path)
+	}
+}
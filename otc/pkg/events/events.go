@@ -0,0 +1,166 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+const (
+	// events kept for clients reconnecting with Last-Event-ID
+	RECENT int = 256
+	// events buffered per subscriber before it's dropped as too slow
+	BUFFER int = 64
+)
+
+type Type string
+
+const (
+	ORDER Type = "order"
+	PRICE Type = "price"
+	PAUSE Type = "pause"
+)
+
+type Event struct {
+	Id   uint64 `json:"id"`
+	Type Type   `json:"type"`
+	Time int64  `json:"time"`
+	// drop the event is about, for feeds scoped to a single user
+	Drop *otc.Drop `json:"drop,omitempty"`
+	// encoded when published, so later changes to the source don't race
+	// with subscribers
+	Data json.RawMessage `json:"data"`
+}
+
+// Broker fans published events out to subscribers. A nil broker ignores
+// everything published to it.
+type Broker struct {
+	sync.RWMutex
+
+	Subscribers map[*Subscription]bool
+	Recent      []*Event
+	// defaults to time.Now, replaced by the simulator
+	Now func() time.Time
+
+	next uint64
+}
+
+// WithData returns a copy of the event with data encoded instead.
+func (e *Event) WithData(data interface{}) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *e
+	copied.Data = raw
+	return &copied, nil
+}
+
+func New() *Broker {
+	return &Broker{
+		Subscribers: make(map[*Subscription]bool),
+		Recent:      make([]*Event, 0, RECENT),
+	}
+}
+
+func (b *Broker) Publish(typ Type, drop *otc.Drop, data interface{}) error {
+	if b == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.next++
+	event := &Event{
+		Id:   b.next,
+		Type: typ,
+		Time: b.now().UTC().Unix(),
+		Data: raw,
+	}
+	if drop != nil {
+		event.Drop = &otc.Drop{Address: drop.Address, Currency: drop.Currency}
+	}
+
+	if len(b.Recent) == RECENT {
+		b.Recent = append(b.Recent[:0], b.Recent[1:]...)
+	}
+	b.Recent = append(b.Recent, event)
+
+	for sub := range b.Subscribers {
+		select {
+		case sub.C <- event:
+		default:
+			// too slow, it can reconnect and catch up from Recent
+			delete(b.Subscribers, sub)
+			close(sub.C)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe returns a subscription receiving events published from now on,
+// preceded by recent events after the since id (if not 0).
+func (b *Broker) Subscribe(since uint64) *Subscription {
+	b.Lock()
+	defer b.Unlock()
+
+	backlog := make([]*Event, 0)
+	if since > 0 {
+		for _, event := range b.Recent {
+			if event.Id > since {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	sub := &Subscription{
+		C:      make(chan *Event, BUFFER+len(backlog)),
+		broker: b,
+	}
+	for _, event := range backlog {
+		sub.C <- event
+	}
+	b.Subscribers[sub] = true
+
+	return sub
+}
+
+func (b *Broker) Count() int {
+	b.RLock()
+	defer b.RUnlock()
+	return len(b.Subscribers)
+}
+
+func (b *Broker) now() time.Time {
+	if b.Now == nil {
+		return time.Now()
+	}
+	return b.Now()
+}
+
+type Subscription struct {
+	// closed when the subscription is closed or dropped
+	C chan *Event
+
+	broker *Broker
+}
+
+func (s *Subscription) Close() {
+	s.broker.Lock()
+	defer s.broker.Unlock()
+
+	if s.broker.Subscribers[s] {
+		delete(s.broker.Subscribers, s)
+		close(s.C)
+	}
+}
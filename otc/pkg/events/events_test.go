@@ -0,0 +1,128 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func TestPublish(t *testing.T) {
+	b := New()
+	sub := b.Subscribe(0)
+
+	drop := &otc.Drop{Address: "addr", Currency: otc.BTC}
+	if err := b.Publish(ORDER, drop, &otc.Order{Id: "tx:0", Status: otc.SEND}); err != nil {
+		t.Fatal(err)
+	}
+
+	event := <-sub.C
+	if event.Id != 1 || event.Type != ORDER || *event.Drop != *drop {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if string(event.Data) != `{"id":"tx:0","status":"waiting_send","amount":0}` {
+		t.Fatalf("unexpected data %s", event.Data)
+	}
+
+	sub.Close()
+	if b.Count() != 0 {
+		t.Fatal("expected subscription to be removed")
+	}
+
+	// closing twice is fine
+	sub.Close()
+}
+
+func TestNil(t *testing.T) {
+	var b *Broker
+	if err := b.Publish(PRICE, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBacklog(t *testing.T) {
+	b := New()
+	for i := 0; i < 3; i++ {
+		b.Publish(PRICE, nil, i)
+	}
+
+	sub := b.Subscribe(1)
+	defer sub.Close()
+
+	for _, id := range []uint64{2, 3} {
+		if event := <-sub.C; event.Id != id {
+			t.Fatalf("expected event %d, got %d", id, event.Id)
+		}
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	b := New()
+	sub := b.Subscribe(0)
+
+	for i := 0; i <= BUFFER; i++ {
+		b.Publish(PRICE, nil, i)
+	}
+
+	if b.Count() != 0 {
+		t.Fatal("expected slow subscriber to be dropped")
+	}
+
+	// buffered events are still delivered before the channel closes
+	n := 0
+	for range sub.C {
+		n++
+	}
+	if n != BUFFER {
+		t.Fatalf("expected %d events, got %d", BUFFER, n)
+	}
+}
+
+func TestServe(t *testing.T) {
+	b := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Serve(w, r, b, func(e *Event) bool { return e.Type == ORDER })
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected content type %s", res.Header.Get("Content-Type"))
+	}
+
+	// wait for the handler to subscribe
+	for b.Count() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	b.Publish(PRICE, nil, 1)
+	b.Publish(ORDER, nil, 2)
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(res.Body)
+	for len(lines) < 3 && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	expected := []string{"id: 2", "event: order", `data: {"id":2,"type":"order",`}
+	for i := range expected {
+		if !strings.HasPrefix(lines[i], expected[i]) {
+			t.Fatalf(`expected "%s", got "%s"`, expected[i], lines[i])
+		}
+	}
+}
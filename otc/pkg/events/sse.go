@@ -0,0 +1,74 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// comment sent to keep idle connections (and proxies) open
+const HEARTBEAT time.Duration = time.Second * 15
+
+// Serve streams events matching filter (nil for all) to the client as
+// server-sent events until it disconnects. Clients reconnecting with a
+// Last-Event-ID header receive the recent events they missed.
+func Serve(w http.ResponseWriter, r *http.Request, b *Broker, filter func(*Event) bool) {
+	Map(w, r, b, func(event *Event) *Event {
+		if filter != nil && !filter(event) {
+			return nil
+		}
+		return event
+	})
+}
+
+// Map streams what fn returns for each event like Serve, skipping those it
+// returns nil for, so a feed can send its clients changed copies.
+func Map(w http.ResponseWriter, r *http.Request, b *Broker, fn func(*Event) *Event) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || b == nil {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	since, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	sub := b.Subscribe(since)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// stop nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(HEARTBEAT)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if event = fn(event); event == nil {
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
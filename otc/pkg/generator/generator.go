@@ -64,10 +64,14 @@ func (g *Generator) Ranger(task Task) func(k, v interface{}) bool {
 			g.Logs.Println(err)
 		}
 
-		// if new order created, send to model
+		// if order created or updated, send to model
 		if order != nil {
-			// add to user
-			user.Orders = append(user.Orders, order)
+			// add to user if new, replacing the earlier copy if updated
+			if i := Index(user, order); i < 0 {
+				user.Orders = append(user.Orders, order)
+			} else {
+				user.Orders[i] = order
+			}
 
 			// create work from order
 			work := &otc.Work{
@@ -83,6 +87,17 @@ func (g *Generator) Ranger(task Task) func(k, v interface{}) bool {
 		}
 
 		// don't stop iterating over items
-		return false
+		return true
+	}
+}
+
+// Index returns the position of the order, or an earlier copy of it, in
+// the user's orders, or -1.
+func Index(user *otc.User, order *otc.Order) int {
+	for i, o := range user.Orders {
+		if o == order || o.Id == order.Id {
+			return i
+		}
 	}
+	return -1
 }
@@ -60,6 +60,24 @@ func TestTick(t *testing.T) {
 	}
 }
 
+func TestTickEveryUser(t *testing.T) {
+	ran := make(map[string]bool)
+	gen := New(nil, func(u *otc.User) (*otc.Order, error) {
+		ran[u.Id] = true
+		return nil, nil
+	}, nil)
+
+	for _, id := range []string{"1", "2", "3"} {
+		gen.Add(&otc.User{Id: id})
+	}
+	gen.Tick()
+
+	// ranging used to stop after the first user
+	if len(ran) != 3 {
+		t.Fatalf("expected task run for 3 users, got %v", ran)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	gen := New(nil, nil, nil)
 	user := &otc.User{}
@@ -73,10 +91,12 @@ func TestDelete(t *testing.T) {
 }
 
 func TestTaskGood(t *testing.T) {
+	n := 0
 	task := func(u *otc.User) (*otc.Order, error) {
+		n++
 		return &otc.Order{
 			User:   u,
-			Id:     "orderId",
+			Id:     fmt.Sprintf("orderId%d", n),
 			Status: otc.SEND,
 		}, nil
 	}
@@ -102,6 +122,30 @@ func TestTaskGood(t *testing.T) {
 	}
 }
 
+func TestTaskUpdated(t *testing.T) {
+	task := func(u *otc.User) (*otc.Order, error) {
+		return &otc.Order{
+			User:   u,
+			Id:     "orderId",
+			Status: otc.SEND,
+		}, nil
+	}
+
+	work := make(chan *otc.Work, 2)
+	gen := New(nil, task, work)
+	user := &otc.User{}
+	gen.Add(user)
+
+	gen.Tick()
+	gen.Tick()
+	<-work
+	latest := <-work
+
+	if len(user.Orders) != 1 || user.Orders[0] != latest.Order {
+		t.Fatal("updated order didn't replace earlier copy")
+	}
+}
+
 func TestTaskBad(t *testing.T) {
 	task := func(u *otc.User) (*otc.Order, error) {
 		return nil, fmt.Errorf("bad!")
@@ -0,0 +1,82 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows at most Limit hits per key in any Period (sliding window).
+type Limiter struct {
+	sync.Mutex
+
+	Limit  int
+	Period time.Duration
+	Hits   map[string][]time.Time
+	// defaults to time.Now
+	Now func() time.Time
+
+	swept time.Time
+}
+
+func New(limit int, period time.Duration) *Limiter {
+	return &Limiter{
+		Limit:  limit,
+		Period: period,
+		Hits:   make(map[string][]time.Time),
+	}
+}
+
+// Allow records a hit for key and reports whether it's within the limit.
+// A nil limiter or a limit <= 0 allows everything.
+func (l *Limiter) Allow(key string) bool {
+	if l == nil || l.Limit <= 0 {
+		return true
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	hits := recent(l.Hits[key], now.Add(-l.Period))
+	if len(hits) >= l.Limit {
+		l.Hits[key] = hits
+		return false
+	}
+
+	l.Hits[key] = append(hits, now)
+	return true
+}
+
+// sweep forgets keys without recent hits, at most once per period.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.Period {
+		return
+	}
+
+	for key, hits := range l.Hits {
+		if len(recent(hits, now.Add(-l.Period))) == 0 {
+			delete(l.Hits, key)
+		}
+	}
+
+	l.swept = now
+}
+
+func (l *Limiter) now() time.Time {
+	if l.Now == nil {
+		return time.Now()
+	}
+	return l.Now()
+}
+
+// recent drops hits at or before since, hits are in time order.
+func recent(hits []time.Time, since time.Time) []time.Time {
+	for i, hit := range hits {
+		if hit.After(since) {
+			return hits[i:]
+		}
+	}
+	return hits[:0]
+}
@@ -0,0 +1,50 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Now()
+
+	limiter := New(2, time.Minute)
+	limiter.Now = func() time.Time { return now }
+
+	if !limiter.Allow("a") || !limiter.Allow("a") {
+		t.Fatal("should allow first two hits")
+	}
+
+	if limiter.Allow("a") {
+		t.Fatal("shouldn't allow third hit")
+	}
+
+	if !limiter.Allow("b") {
+		t.Fatal("keys should be limited separately")
+	}
+
+	now = now.Add(time.Minute)
+
+	if !limiter.Allow("a") {
+		t.Fatal("should allow after period")
+	}
+
+	if len(limiter.Hits) != 1 {
+		t.Fatal("stale keys should be swept")
+	}
+}
+
+func TestAllowDisabled(t *testing.T) {
+	var limiter *Limiter
+
+	if !limiter.Allow("a") {
+		t.Fatal("nil limiter should allow")
+	}
+
+	limiter = New(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		if !limiter.Allow("a") {
+			t.Fatal("zero limit should allow")
+		}
+	}
+}
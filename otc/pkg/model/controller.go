@@ -1,30 +1,164 @@
 package model
 
 import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
 	"sync"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/actor"
+	"github.com/skycoin/services/otc/pkg/events"
+	"github.com/skycoin/services/otc/pkg/generator"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+var (
+	ErrWindowInvalid = errors.New("window must end after it starts")
+	ErrWindowMissing = errors.New("window missing")
+)
+
+// PAUSES holds the stage pauses and maintenance windows, so they survive
+// a restart. The global pause isn't kept, otc always starts paused.
+const PAUSES string = "pauses.json"
+
+type Stage string
+
+const (
+	BIND    Stage = "bind"
+	SCAN    Stage = "scan"
+	SEND    Stage = "send"
+	MONITOR Stage = "monitor"
 )
 
+var STAGES = []Stage{BIND, SCAN, SEND, MONITOR}
+
+// Pause stops a stage for a drop currency. An empty currency or stage
+// matches all of them.
+type Pause struct {
+	Currency otc.Currency `json:"currency,omitempty"`
+	Stage    Stage        `json:"stage,omitempty"`
+}
+
+func (p Pause) Matches(cur otc.Currency, stage Stage) bool {
+	return (p.Currency == "" || p.Currency == cur) &&
+		(p.Stage == "" || p.Stage == stage)
+}
+
+// Window is a pause that is only active between Start and End (unix
+// seconds).
+type Window struct {
+	Pause
+
+	Id     int    `json:"id"`
+	Start  int64  `json:"start"`
+	End    int64  `json:"end"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (w *Window) Active(now time.Time) bool {
+	return now.Unix() >= w.Start && now.Unix() < w.End
+}
+
 type Controller struct {
 	sync.RWMutex
 
 	Running  bool
 	Stoppers []chan struct{}
+	Pauses   map[Pause]bool
+	Windows  []*Window
+	// defaults to time.Now, replaced by the simulator
+	Now func() time.Time
+	// pause changes are published here
+	Events *events.Broker
+	// storage directory ending in a slash, pauses and windows aren't saved
+	// if empty
+	Path string
+
+	windows int
+}
+
+// saved is what's kept in PAUSES.
+type saved struct {
+	Pauses  []Pause   `json:"pauses"`
+	Windows []*Window `json:"windows"`
+	// last window id handed out, so ids aren't reused
+	LastWindow int `json:"last_window"`
+}
+
+// Load restores the pauses and windows saved under Path, if any.
+func (c *Controller) Load() error {
+	if c.Path == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(c.Path + PAUSES)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	s := &saved{}
+	if err = json.Unmarshal(data, s); err != nil {
+		return err
+	}
+
+	c.Lock()
+	c.Pauses = make(map[Pause]bool)
+	for _, pause := range s.Pauses {
+		c.Pauses[pause] = true
+	}
+	c.Windows = s.Windows
+	if c.Windows == nil {
+		c.Windows = make([]*Window, 0)
+	}
+	c.windows = s.LastWindow
+	c.Unlock()
+
+	return nil
+}
+
+// save writes the pauses and windows to Path, it must be called with the
+// lock held.
+func (c *Controller) save() error {
+	if c.Path == "" {
+		return nil
+	}
+
+	s := &saved{
+		Pauses:     make([]Pause, 0, len(c.Pauses)),
+		Windows:    c.Windows,
+		LastWindow: c.windows,
+	}
+	for pause := range c.Pauses {
+		s.Pauses = append(s.Pauses, pause)
+	}
+
+	return WriteJSON(c.Path+PAUSES, s)
 }
 
 func NewController(stoppers []chan struct{}) *Controller {
-	return &Controller{Stoppers: stoppers}
+	return &Controller{
+		Stoppers: stoppers,
+		Pauses:   make(map[Pause]bool),
+		Windows:  make([]*Window, 0),
+	}
 }
 
 func (c *Controller) Pause() {
 	c.Lock()
-	defer c.Unlock()
 	c.Running = false
+	c.Unlock()
+	c.notify()
 }
 
 func (c *Controller) Unpause() {
 	c.Lock()
-	defer c.Unlock()
 	c.Running = true
+	c.Unlock()
+	c.notify()
 }
 
 func (c *Controller) Paused() bool {
@@ -33,8 +167,183 @@ func (c *Controller) Paused() bool {
 	return !c.Running
 }
 
+// PauseStage pauses a stage. The pause applies even if saving it fails.
+func (c *Controller) PauseStage(p Pause) error {
+	c.Lock()
+	if c.Pauses == nil {
+		c.Pauses = make(map[Pause]bool)
+	}
+	c.Pauses[p] = true
+	err := c.save()
+	c.Unlock()
+
+	c.notify()
+	return err
+}
+
+func (c *Controller) UnpauseStage(p Pause) error {
+	c.Lock()
+	delete(c.Pauses, p)
+	err := c.save()
+	c.Unlock()
+
+	c.notify()
+	return err
+}
+
+// Schedule adds a maintenance window and returns its id. Windows that have
+// already ended are dropped. The window applies even if saving it fails.
+func (c *Controller) Schedule(w *Window) (int, error) {
+	if w.End <= w.Start {
+		return 0, ErrWindowInvalid
+	}
+
+	c.Lock()
+	now := c.now().Unix()
+	windows := make([]*Window, 0, len(c.Windows)+1)
+	for _, window := range c.Windows {
+		if window.End > now {
+			windows = append(windows, window)
+		}
+	}
+
+	c.windows++
+	w.Id = c.windows
+	c.Windows = append(windows, w)
+	err := c.save()
+	c.Unlock()
+
+	c.notify()
+	return w.Id, err
+}
+
+func (c *Controller) Unschedule(id int) error {
+	c.Lock()
+	for i, window := range c.Windows {
+		if window.Id == id {
+			c.Windows = append(c.Windows[:i], c.Windows[i+1:]...)
+			err := c.save()
+			c.Unlock()
+
+			c.notify()
+			return err
+		}
+	}
+	c.Unlock()
+
+	return ErrWindowMissing
+}
+
+// notify publishes the current pauses, it must be called without the lock
+// held.
+func (c *Controller) notify() {
+	if c.Events == nil {
+		return
+	}
+
+	c.Events.Publish(events.PAUSE, nil, &struct {
+		Paused  bool     `json:"paused"`
+		Pauses  []Pause  `json:"pauses"`
+		Windows []Window `json:"windows"`
+	}{c.Paused(), c.GetPauses(), c.GetWindows()})
+}
+
+// PausedFor reports whether a stage is stopped for the drop currency, either
+// globally, by a pause, or by an active maintenance window.
+func (c *Controller) PausedFor(cur otc.Currency, stage Stage) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	if !c.Running {
+		return true
+	}
+
+	for pause := range c.Pauses {
+		if pause.Matches(cur, stage) {
+			return true
+		}
+	}
+
+	now := c.now()
+	for _, window := range c.Windows {
+		if window.Active(now) && window.Matches(cur, stage) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Stages returns the stages currently stopped for the drop currency.
+func (c *Controller) Stages(cur otc.Currency) []Stage {
+	stages := make([]Stage, 0)
+
+	for _, stage := range STAGES {
+		if c.PausedFor(cur, stage) {
+			stages = append(stages, stage)
+		}
+	}
+
+	return stages
+}
+
+func (c *Controller) GetPauses() []Pause {
+	c.RLock()
+	defer c.RUnlock()
+
+	pauses := make([]Pause, 0, len(c.Pauses))
+	for pause := range c.Pauses {
+		pauses = append(pauses, pause)
+	}
+
+	return pauses
+}
+
+// GetWindows returns windows that haven't ended yet.
+func (c *Controller) GetWindows() []Window {
+	c.RLock()
+	defer c.RUnlock()
+
+	now := c.now().Unix()
+	windows := make([]Window, 0, len(c.Windows))
+	for _, window := range c.Windows {
+		if window.End > now {
+			windows = append(windows, *window)
+		}
+	}
+
+	return windows
+}
+
 func (c *Controller) Stop() {
 	for _, s := range c.Stoppers {
 		s <- struct{}{}
 	}
 }
+
+// Generator skips users whose drop currency has the stage paused.
+func (c *Controller) Generator(stage Stage, task generator.Task) generator.Task {
+	return func(user *otc.User) (*otc.Order, error) {
+		if c.PausedFor(user.Drop.Currency, stage) {
+			return nil, nil
+		}
+		return task(user)
+	}
+}
+
+// Actor leaves work in place while its drop currency has the stage paused.
+func (c *Controller) Actor(stage Stage, task actor.Task) actor.Task {
+	return func(work *otc.Work) (bool, error) {
+		if c.PausedFor(work.Order.User.Drop.Currency, stage) {
+			return false, nil
+		}
+		return task(work)
+	}
+}
+
+func (c *Controller) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
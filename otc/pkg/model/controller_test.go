@@ -0,0 +1,76 @@
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func TestControllerLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otc-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Unix(1000, 0)
+	c := NewController(nil)
+	c.Path = dir + "/"
+	c.Now = func() time.Time { return now }
+
+	pause := Pause{Currency: otc.BTC, Stage: SEND}
+	if err = c.PauseStage(pause); err != nil {
+		t.Fatal(err)
+	}
+	first, err := c.Schedule(&Window{
+		Pause:  Pause{Currency: otc.BTC, Stage: BIND},
+		Start:  900,
+		End:    2000,
+		Reason: "upgrade",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Schedule(&Window{Start: 3000, End: 4000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Unschedule(second); err != nil {
+		t.Fatal(err)
+	}
+
+	// as after a restart
+	loaded := NewController(nil)
+	loaded.Path = c.Path
+	loaded.Now = c.Now
+	if err = loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the global pause isn't kept
+	loaded.Unpause()
+	if !loaded.PausedFor(otc.BTC, SEND) || !loaded.PausedFor(otc.BTC, BIND) || loaded.PausedFor(otc.BTC, SCAN) {
+		t.Fatalf("expected the pause and window restored, got %v %v", loaded.GetPauses(), loaded.GetWindows())
+	}
+
+	windows := loaded.GetWindows()
+	if len(windows) != 1 || windows[0].Id != first || windows[0].Reason != "upgrade" {
+		t.Fatalf("expected window %d restored, got %+v", first, windows)
+	}
+
+	// ids aren't reused
+	id, err := loaded.Schedule(&Window{Start: 3000, End: 4000})
+	if err != nil || id != second+1 {
+		t.Fatalf("expected window %d, got %d %v", second+1, id, err)
+	}
+
+	// nothing saved yet
+	empty := NewController(nil)
+	empty.Path = dir + "/missing/"
+	if err = empty.Load(); err != nil {
+		t.Fatal(err)
+	}
+}
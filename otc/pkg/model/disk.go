@@ -1,10 +1,12 @@
 package model
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/skycoin/services/otc/pkg/otc"
@@ -12,51 +14,35 @@ import (
 )
 
 const (
-	PATH   string = ".otc/"
 	USERS  string = "users/"
 	ORDERS string = "orders/"
 )
 
-func SaveUser(user *otc.User) error {
-	file, err := os.OpenFile(
-		PATH+USERS+user.Id+".json",
-		os.O_CREATE|os.O_RDWR, 0644,
-	)
-	if err != nil {
-		return err
-	}
-
-	// empty file if exists
-	file.Truncate(0)
-	file.Seek(0, 0)
-
-	// format json output
-	enc := json.NewEncoder(file)
-	enc.SetIndent("", "  ")
+// PATH is the default root directory of on-disk storage, used when a
+// model isn't given its own (see Config.Path). It's a variable so that
+// tests can point models at a temporary directory.
+var PATH string = ".otc/"
 
-	if err = enc.Encode(user); err != nil {
-		return err
-	}
-	if err = file.Sync(); err != nil {
-		return err
-	}
-	if err = file.Close(); err != nil {
-		return err
+// MakeDirs creates the users and orders directories under path.
+func MakeDirs(path string) error {
+	for _, dir := range []string{USERS, ORDERS} {
+		if err := os.MkdirAll(path+dir, 0755); err != nil {
+			return err
+		}
 	}
-
-	// create orders folder
-	return os.Mkdir(PATH+ORDERS+user.Id, os.ModeDir)
+	return nil
 }
 
-func SaveOrder(order *otc.Order, result *otc.Result) error {
-	file, err := os.OpenFile(
-		PATH+ORDERS+order.User.Id+"/"+order.Id+".json",
-		os.O_CREATE|os.O_RDWR, 0644,
-	)
-	if err != nil {
+func SaveUser(path string, user *otc.User) error {
+	if err := WriteJSON(path+USERS+user.Id+".json", user); err != nil {
 		return err
 	}
 
+	// create orders folder (exists already if user was loaded from disk)
+	return os.MkdirAll(path+ORDERS+user.Id, 0755)
+}
+
+func SaveOrder(path string, order *otc.Order, result *otc.Result) error {
 	// append to order events
 	event := &otc.Event{
 		Status:   order.Status,
@@ -67,26 +53,56 @@ func SaveOrder(order *otc.Order, result *otc.Result) error {
 	}
 	order.Events = append(order.Events, event)
 
-	// empty file
-	file.Truncate(0)
-	file.Seek(0, 0)
+	return WriteOrder(path, order)
+}
+
+// WriteOrder saves the order as is, without adding an event.
+func WriteOrder(path string, order *otc.Order) error {
+	return WriteJSON(path+ORDERS+order.User.Id+"/"+order.Id+".json", order)
+}
+
+// WriteJSON saves v as indented JSON with WriteFile.
+func WriteJSON(path string, v interface{}) error {
+	var buf bytes.Buffer
 
-	// indent json output
-	enc := json.NewEncoder(file)
+	enc := json.NewEncoder(&buf)
 	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	return WriteFile(path, buf.Bytes())
+}
+
+// WriteFile replaces the file at path through a hidden temporary file in
+// the same directory, so readers (and backups) never see a partial write.
+func WriteFile(path string, data []byte) error {
+	dir, name := filepath.Split(path)
+	tmp := filepath.Join(dir, "."+name+".tmp")
 
-	if err = enc.Encode(order); err != nil {
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(data); err != nil {
+		file.Close()
 		return err
 	}
 	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
 		return err
 	}
-	return file.Close()
+
+	return os.Rename(tmp, path)
 }
 
-func Load() ([]*otc.User, error) {
+func Load(path string) ([]*otc.User, error) {
 	// get list of users
-	files, err := ioutil.ReadDir(PATH + USERS)
+	files, err := ioutil.ReadDir(path + USERS)
 	if err != nil {
 		return nil, err
 	}
@@ -101,13 +117,13 @@ func Load() ([]*otc.User, error) {
 		}
 
 		// get user struct from disk
-		user, err := ReadUser(PATH+USERS, file.Name())
+		user, err := ReadUser(path+USERS, file.Name())
 		if err != nil {
 			return nil, err
 		}
 
 		// get list of orders in user's dir
-		ofiles, err := ioutil.ReadDir(PATH + ORDERS + user.Id)
+		ofiles, err := ioutil.ReadDir(path + ORDERS + user.Id)
 		if err != nil {
 			return nil, err
 		}
@@ -120,12 +136,13 @@ func Load() ([]*otc.User, error) {
 			}
 
 			// read order from disk
-			order, err := ReadOrder(PATH+ORDERS+user.Id, ofile.Name())
+			order, err := ReadOrder(path+ORDERS+user.Id+"/", ofile.Name())
 			if err != nil {
 				return nil, err
 			}
 
 			// add order to user
+			order.User = user
 			user.Orders = append(user.Orders, order)
 		}
 
@@ -163,6 +180,9 @@ func ReadUser(path, filename string) (*otc.User, error) {
 		return nil, err
 	}
 
+	// id isn't stored in the file, it's the filename
+	user.Id = strings.TrimSuffix(filename, ".json")
+
 	return user, file.Close()
 }
 
@@ -189,3 +209,17 @@ func ReadOrder(path, filename string) (*otc.Order, error) {
 
 	return order, file.Close()
 }
+
+// RemoveUser deletes a user without orders from disk.
+func RemoveUser(path string, user *otc.User) error {
+	if err := os.Remove(path + USERS + user.Id + ".json"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// only removes the orders folder if it's empty
+	if err := os.Remove(path + ORDERS + user.Id); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func TestSaveUser(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otc-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := dir + "/"
+	if err = MakeDirs(path); err != nil {
+		t.Fatal(err)
+	}
+
+	user := &otc.User{Id: "address:BTC:drop"}
+	if err = SaveUser(path, user); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path + ORDERS + user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Fatalf("expected orders directory with 0755, got %v", info.Mode())
+	}
+
+	// users loaded from disk are saved again, with their orders directory
+	// already there
+	if err = SaveUser(path, user); err != nil {
+		t.Fatalf("saving a user again: %v", err)
+	}
+}
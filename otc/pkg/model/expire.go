@@ -0,0 +1,101 @@
+package model
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+const EXPIRED string = "expired.json"
+
+// Expirer removes users that haven't received a deposit within TTL of
+// binding, so unused drop addresses don't pile up in the scanner.
+type Expirer struct {
+	Model *Model
+	TTL   time.Duration
+}
+
+func (e *Expirer) Log(s string) {
+	e.Model.Logs.Println(s)
+}
+
+func (e *Expirer) Tick() {
+	expired, err := e.Model.Expire(e.TTL, e.Model.Controller.now())
+	if err != nil {
+		e.Model.Logs.Println(err)
+	}
+	if len(expired) > 0 {
+		e.Model.Logs.Printf("expired %d unfunded users\n", len(expired))
+	}
+}
+
+// Expire removes users without orders that were created, or last had their
+// drop address handed out, more than ttl before now. Removed users are
+// appended to Path+EXPIRED as JSON lines. The model must be locked for
+// writing, as the scanner adds orders to users.
+func (m *Model) Expire(ttl time.Duration, now time.Time) ([]*otc.User, error) {
+	cutoff := now.Add(-ttl).UTC().Unix()
+	expired := make([]*otc.User, 0)
+
+	for _, user := range m.Lookup.GetUsers() {
+		if user == nil || len(user.Orders) > 0 {
+			continue
+		}
+		if user.Times.CreatedAt > cutoff || user.Times.BoundAt > cutoff {
+			continue
+		}
+
+		// stop watching first so no order is generated while removing
+		m.Workers.Scanner.Delete(user)
+		m.Lookup.RemoveUser(user)
+
+		if err := RemoveUser(m.path(), user); err != nil {
+			return expired, err
+		}
+
+		expired = append(expired, user)
+	}
+
+	if len(expired) == 0 {
+		return expired, nil
+	}
+
+	file, err := os.OpenFile(
+		m.path()+EXPIRED,
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644,
+	)
+	if err != nil {
+		return expired, err
+	}
+
+	enc := json.NewEncoder(file)
+	for _, user := range expired {
+		if err = enc.Encode(&struct {
+			Id        string `json:"id"`
+			ExpiredAt int64  `json:"expired_at"`
+			*otc.User
+		}{user.Id, now.UTC().Unix(), user}); err != nil {
+			file.Close()
+			return expired, err
+		}
+	}
+
+	return expired, file.Close()
+}
+
+// Rebind records that an unfunded user's drop address was handed out again,
+// so it expires TTL after that rather than after the user was created.
+// ErrMissing is returned if the user expired meanwhile.
+func (m *Model) Rebind(user *otc.User) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, err := m.Lookup.GetUser(user.Id); err != nil {
+		return err
+	}
+
+	user.Times.BoundAt = m.Controller.now().UTC().Unix()
+	return SaveUser(m.path(), user)
+}
@@ -0,0 +1,222 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/events"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+var (
+	ErrOperatorMissing = errors.New("operator missing")
+	ErrTransition      = errors.New("transition not allowed")
+	ErrTxIdMissing     = errors.New("txid missing")
+	ErrAmountMissing   = errors.New("amount missing")
+)
+
+// TRANSITIONS are the statuses an operator can force an order into, by its
+// current status. Moving to waiting_confirm requires a txid, so that's only
+// done through Payout.
+var TRANSITIONS = map[otc.Status][]otc.Status{
+	otc.BELOW:   {otc.COLLECT, otc.SEND, otc.CANCELLED},
+	otc.COLLECT: {otc.BELOW, otc.SEND, otc.CANCELLED},
+	otc.SEND:    {otc.BELOW, otc.CANCELLED},
+	// payout never confirmed (dropped) and needs to be sent again, or was
+	// confirmed some other way
+	otc.CONFIRM: {otc.SEND, otc.DONE},
+}
+
+func CanTransition(from, to otc.Status) bool {
+	for _, status := range TRANSITIONS[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Intervene applies a manual change to an order. Workers are stopped while
+// it runs and the order is taken out of the pipeline, changed, saved with
+// an event recording the operator, and routed again according to its new
+// status.
+func (m *Model) Intervene(id string, event *otc.Event, change func(*otc.Order) error) (*otc.Order, error) {
+	if event.Operator == "" {
+		return nil, ErrOperatorMissing
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	order, err := m.Lookup.GetOrder(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// changes validate before modifying the order
+	if change != nil {
+		if err = change(order); err != nil {
+			return nil, err
+		}
+	}
+
+	// stop any work in progress for the order
+	m.Router.Remove(order)
+	m.Workers.Sender.Remove(order)
+	m.Workers.Monitor.Remove(order)
+
+	// a newer copy from the scanner may have been waiting in the router, the
+	// user's orders point at this one again so the scanner picks up from it
+	for i, o := range order.User.Orders {
+		if o.Id == order.Id {
+			order.User.Orders[i] = order
+		}
+	}
+
+	event.Status = order.Status
+	event.Finished = time.Now().UTC().Unix()
+	order.Events = append(order.Events, event)
+	order.Times.UpdatedAt = event.Finished
+
+	if err = WriteOrder(m.path(), order); err != nil {
+		return nil, err
+	}
+	m.Events.Publish(events.ORDER, order.User.Drop, order)
+
+	// below_minimum and collecting orders are picked up by the scanner
+	if order.Status == otc.SEND || order.Status == otc.CONFIRM {
+		work := &otc.Work{Order: order, Done: make(chan *otc.Result, 1)}
+		m.Router.Add(work)
+		m.Workers.Route(work)
+	}
+
+	return order, nil
+}
+
+func (m *Model) Annotate(id, operator, note string) (*otc.Order, error) {
+	return m.Intervene(id, &otc.Event{
+		Operator: operator,
+		Action:   "annotate",
+		Note:     note,
+	}, nil)
+}
+
+func (m *Model) Transition(id, operator, note string, status otc.Status) (*otc.Order, error) {
+	event := &otc.Event{
+		Operator: operator,
+		Action:   "transition",
+		Note:     note,
+	}
+
+	return m.Intervene(id, event, func(order *otc.Order) error {
+		if !CanTransition(order.Status, status) {
+			return ErrTransition
+		}
+
+		// sending again replaces the previous payout
+		if order.Status == otc.CONFIRM && status == otc.SEND && order.Purchase != nil {
+			event.TxId = order.Purchase.TxId
+			order.Purchase = nil
+		}
+		unquote(order, event, status)
+
+		order.Status = status
+		return nil
+	})
+}
+
+func (m *Model) Cancel(id, operator, note string) (*otc.Order, error) {
+	event := &otc.Event{
+		Operator: operator,
+		Action:   "cancel",
+		Note:     note,
+	}
+
+	return m.Intervene(id, event, func(order *otc.Order) error {
+		if !CanTransition(order.Status, otc.CANCELLED) {
+			return ErrTransition
+		}
+		unquote(order, event, otc.CANCELLED)
+
+		order.Status = otc.CANCELLED
+		return nil
+	})
+}
+
+// unquote drops the price fixed by Requote when an order leaves
+// waiting_send, so it isn't used if the order is sent again later. The
+// dropped price is noted on the event.
+func unquote(order *otc.Order, event *otc.Event, status otc.Status) {
+	p := order.Purchase
+	if order.Status != otc.SEND || status == otc.SEND || p == nil || p.TxId != "" {
+		return
+	}
+
+	dropped := fmt.Sprintf("requote of %d droplets dropped", p.Amount)
+	if event.Note != "" {
+		dropped = event.Note + " (" + dropped + ")"
+	}
+	event.Note = dropped
+	order.Purchase = nil
+}
+
+// Payout records skycoin sent outside of otc (amount in droplets). The
+// order is then monitored until the transaction confirms.
+func (m *Model) Payout(id, operator, note, txid string, amount uint64) (*otc.Order, error) {
+	if txid == "" {
+		return nil, ErrTxIdMissing
+	}
+	if amount == 0 {
+		return nil, ErrAmountMissing
+	}
+
+	return m.Intervene(id, &otc.Event{
+		Operator: operator,
+		Action:   "payout",
+		Note:     note,
+		TxId:     txid,
+	}, func(order *otc.Order) error {
+		switch order.Status {
+		case otc.BELOW, otc.COLLECT, otc.SEND, otc.CONFIRM:
+		default:
+			return ErrTransition
+		}
+
+		order.Purchase = &otc.Purchase{
+			Source: "manual",
+			Amount: amount,
+			TxId:   txid,
+		}
+		order.Times.SentAt = time.Now().UTC().Unix()
+		order.Status = otc.CONFIRM
+		return nil
+	})
+}
+
+// Requote fixes the price of an order waiting to be sent, which the sender
+// uses instead of the price at the time of sending.
+func (m *Model) Requote(id, operator, note string, value uint64, obs currencies.Observation) (*otc.Order, error) {
+	return m.Intervene(id, &otc.Event{
+		Operator: operator,
+		Action:   "requote",
+		Note:     note,
+	}, func(order *otc.Order) error {
+		if order.Status != otc.SEND {
+			return ErrTransition
+		}
+
+		order.Purchase = &otc.Purchase{
+			Source: "internal",
+			Amount: value,
+			Price: &otc.Price{
+				Source:      string(obs.Source),
+				Executed:    obs.Value,
+				Observation: obs.Id,
+				ObservedAt:  obs.Time,
+			},
+		}
+		return nil
+	})
+}
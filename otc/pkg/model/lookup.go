@@ -15,13 +15,16 @@ type Lookup struct {
 	Orders   map[string]*otc.Order
 	Users    map[string]*otc.User
 	Statuses map[string]*otc.User
+	// users by their skycoin address
+	Addresses map[string][]*otc.User
 }
 
 func NewLookup() *Lookup {
 	return &Lookup{
-		Orders:   make(map[string]*otc.Order),
-		Users:    make(map[string]*otc.User),
-		Statuses: make(map[string]*otc.User),
+		Orders:    make(map[string]*otc.Order),
+		Users:     make(map[string]*otc.User),
+		Statuses:  make(map[string]*otc.User),
+		Addresses: make(map[string][]*otc.User),
 	}
 }
 
@@ -41,6 +44,26 @@ func (l *Lookup) AddUser(user *otc.User) {
 	l.Lock()
 	defer l.Unlock()
 	l.Users[user.Id] = user
+	l.Addresses[user.Address] = append(l.Addresses[user.Address], user)
+}
+
+func (l *Lookup) RemoveUser(user *otc.User) {
+	l.Lock()
+	defer l.Unlock()
+
+	delete(l.Users, user.Id)
+	delete(l.Statuses, string(user.Drop.Currency)+":"+user.Drop.Address)
+
+	users := l.Addresses[user.Address]
+	for i, u := range users {
+		if u.Id == user.Id {
+			l.Addresses[user.Address] = append(users[:i:i], users[i+1:]...)
+			break
+		}
+	}
+	if len(l.Addresses[user.Address]) == 0 {
+		delete(l.Addresses, user.Address)
+	}
 }
 
 func (l *Lookup) GetStatus(id string) (*otc.User, error) {
@@ -69,7 +92,7 @@ func (l *Lookup) GetOrders() []*otc.Order {
 	l.RLock()
 	defer l.RUnlock()
 
-	orders := make([]*otc.Order, len(l.Orders))
+	orders := make([]*otc.Order, 0, len(l.Orders))
 	for _, order := range l.Orders {
 		orders = append(orders, order)
 	}
@@ -77,6 +100,21 @@ func (l *Lookup) GetOrders() []*otc.Order {
 	return orders
 }
 
+// GetUnfunded returns a user for the skycoin address and drop currency that
+// hasn't received a deposit yet, or nil.
+func (l *Lookup) GetUnfunded(address string, cur otc.Currency) *otc.User {
+	l.RLock()
+	defer l.RUnlock()
+
+	for _, user := range l.Addresses[address] {
+		if user.Drop.Currency == cur && len(user.Orders) == 0 {
+			return user
+		}
+	}
+
+	return nil
+}
+
 func (l *Lookup) GetUser(id string) (*otc.User, error) {
 	l.RLock()
 	defer l.RUnlock()
@@ -88,11 +126,22 @@ func (l *Lookup) GetUser(id string) (*otc.User, error) {
 	return l.Users[id], nil
 }
 
+// GetAddress returns all users (bindings) of a skycoin address.
+func (l *Lookup) GetAddress(address string) []*otc.User {
+	l.RLock()
+	defer l.RUnlock()
+
+	users := make([]*otc.User, len(l.Addresses[address]))
+	copy(users, l.Addresses[address])
+
+	return users
+}
+
 func (l *Lookup) GetUsers() []*otc.User {
 	l.RLock()
 	defer l.RUnlock()
 
-	users := make([]*otc.User, len(l.Users))
+	users := make([]*otc.User, 0, len(l.Users))
 	for _, user := range l.Users {
 		users = append(users, user)
 	}
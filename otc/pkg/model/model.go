@@ -3,46 +3,88 @@ package model
 import (
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/skycoin/services/otc/pkg/actor"
 	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/events"
 	"github.com/skycoin/services/otc/pkg/otc"
+	"github.com/skycoin/services/otc/pkg/scanner"
 	"github.com/skycoin/services/otc/pkg/watcher"
 )
 
 type Config struct {
 	Currencies *currencies.Currencies
 	Watcher    *watcher.Watcher
+	Deposits   *scanner.Config
+	// unfunded users are removed after this long, 0 keeps them forever
+	Expiry time.Duration
+	// storage directory ending in a slash, PATH if empty
+	Path string
 }
 
 type Model struct {
+	// read locked while workers tick, write locked during manual
+	// interventions so orders aren't changed while being processed
+	sync.RWMutex
+
 	Controller *Controller
 	Lookup     *Lookup
 	Workers    *Workers
 	Router     *actor.Actor
 	Work       chan *otc.Work
 	Logs       *log.Logger
+	// nil if unfunded users never expire
+	Expirer *Expirer
+	// order, price and pause changes for live feeds
+	Events *events.Broker
+	// storage directory ending in a slash, PATH if empty
+	Path string
 }
 
 func New(conf *Config) (*Model, error) {
-	workers, work := NewWorkers(conf)
-	stoppers := make([]chan struct{}, 4, 4)
+	stoppers := make([]chan struct{}, 5, 5)
+	broker := events.New()
+	path := conf.Path
+	if path == "" {
+		path = PATH
+	}
+	if err := MakeDirs(path); err != nil {
+		return nil, err
+	}
+
+	// stage pauses and maintenance windows from before a restart
+	controller := NewController(stoppers)
+	controller.Events = broker
+	controller.Path = path
+	if err := controller.Load(); err != nil {
+		return nil, err
+	}
+
+	workers, work := NewWorkers(conf, controller)
+	lookup := NewLookup()
 
 	model := &Model{
-		Controller: NewController(stoppers),
-		Lookup:     NewLookup(),
+		Controller: controller,
+		Lookup:     lookup,
 		Workers:    workers,
 		Router: actor.New(
 			log.New(os.Stdout, "  [MODEL] ", log.LstdFlags),
-			Task(workers),
+			Task(workers, lookup, broker, path),
 		),
-		Work: work,
-		Logs: log.New(os.Stdout, "    [OTC] ", log.LstdFlags),
+		Work:   work,
+		Logs:   log.New(os.Stdout, "    [OTC] ", log.LstdFlags),
+		Events: broker,
+		Path:   path,
+	}
+
+	if conf.Expiry > 0 {
+		model.Expirer = &Expirer{model, conf.Expiry}
 	}
 
 	// load all users from disk
-	users, err := Load()
+	users, err := Load(path)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +100,25 @@ func New(conf *Config) (*Model, error) {
 	return model, nil
 }
 
+func (m *Model) path() string {
+	if m.Path == "" {
+		return PATH
+	}
+	return m.Path
+}
+
+// Run ticks w every d until s is closed, with the model read locked.
 func (m *Model) Run(d time.Duration, s chan struct{}, w Worker) {
+	m.run(d, s, w, m.RLocker())
+}
+
+// RunLocked is Run for workers changing what the others read, with the
+// model locked for writing.
+func (m *Model) RunLocked(d time.Duration, s chan struct{}, w Worker) {
+	m.run(d, s, w, &m.RWMutex)
+}
+
+func (m *Model) run(d time.Duration, s chan struct{}, w Worker, lock sync.Locker) {
 	for {
 		<-time.After(d)
 
@@ -68,7 +128,9 @@ func (m *Model) Run(d time.Duration, s chan struct{}, w Worker) {
 			return
 		default:
 			if !m.Controller.Paused() {
+				lock.Lock()
 				w.Tick()
+				lock.Unlock()
 			}
 		}
 	}
@@ -83,7 +145,7 @@ func (m *Model) Start() {
 	// saved and routed accordingly
 	go func() {
 		for {
-			m.Router.Add(<-m.Work)
+			m.Receive(<-m.Work)
 		}
 	}()
 
@@ -97,6 +159,11 @@ func (m *Model) Start() {
 	// start order generator
 	go m.Run(wait, m.Controller.Stoppers[1], m.Workers.Scanner)
 
+	// remove unfunded users
+	if m.Expirer != nil {
+		go m.RunLocked(wait, m.Controller.Stoppers[4], m.Expirer)
+	}
+
 	// logging
 	go func() {
 		for {
@@ -112,27 +179,37 @@ func (m *Model) Start() {
 	}()
 }
 
+// Receive routes work from the scanner. The scanner sends a new copy of an
+// order every time it changes, so work for an earlier copy still waiting in
+// the router is dropped rather than routed (and paid) as well.
+func (m *Model) Receive(work *otc.Work) {
+	m.Router.Remove(work.Order)
+	m.Router.Add(work)
+}
+
 func (m *Model) Add(user *otc.User) error {
 	// add user to lookup map for later access
 	m.Lookup.AddUser(user)
 	m.Lookup.AddStatus(user)
 
 	// save user to disk
-	if err := SaveUser(user); err != nil {
+	if err := SaveUser(m.path(), user); err != nil {
 		return err
 	}
 
 	// route existing orders
 	for _, order := range user.Orders {
+		m.Lookup.AddOrder(order)
+
 		result := &otc.Result{time.Now().UTC().Unix(), nil}
 
 		// save to disk
-		if err := SaveOrder(order, result); err != nil {
+		if err := SaveOrder(m.path(), order, result); err != nil {
 			return err
 		}
 
 		// create work
-		work := &otc.Work{order, make(chan *otc.Result, 1)}
+		work := &otc.Work{Order: order, Done: make(chan *otc.Result, 1)}
 		work.Done <- result
 
 		// route work
@@ -3,19 +3,25 @@ package model
 import (
 	"time"
 
+	"github.com/skycoin/services/otc/pkg/events"
 	"github.com/skycoin/services/otc/pkg/otc"
 )
 
-func Task(workers *Workers) func(*otc.Work) (bool, error) {
+// Task saves finished work under path and routes orders to their next
+// worker.
+func Task(workers *Workers, lookup *Lookup, broker *events.Broker, path string) func(*otc.Work) (bool, error) {
 	return func(work *otc.Work) (bool, error) {
 		select {
 		case res := <-work.Done:
+			// new orders from the scanner aren't indexed yet
+			lookup.AddOrder(work.Order)
 			work.Order.Times.UpdatedAt = time.Now().UTC().Unix()
 
 			// save to disk
-			if err := SaveOrder(work.Order, res); err != nil {
+			if err := SaveOrder(path, work.Order, res); err != nil {
 				return true, err
 			}
+			broker.Publish(events.ORDER, work.Order.User.Drop, work.Order)
 
 			// check result
 			if res.Err != nil {
@@ -23,7 +29,13 @@ func Task(workers *Workers) func(*otc.Work) (bool, error) {
 			}
 
 			// if done, stop routing
-			if work.Order.Status == otc.DONE {
+			if work.Order.Status == otc.DONE || work.Order.Status == otc.CANCELLED {
+				return true, nil
+			}
+
+			// still accumulating deposits, the scanner sends it again
+			// once it changes
+			if work.Order.Status == otc.BELOW || work.Order.Status == otc.COLLECT {
 				return true, nil
 			}
 
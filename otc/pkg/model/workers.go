@@ -23,22 +23,22 @@ type Workers struct {
 	Monitor *actor.Actor
 }
 
-func NewWorkers(conf *Config) (*Workers, chan *otc.Work) {
+func NewWorkers(conf *Config, ctrl *Controller) (*Workers, chan *otc.Work) {
 	work := make(chan *otc.Work, 0)
 
 	return &Workers{
 		Scanner: generator.New(
 			log.New(os.Stdout, "[SCANNER] ", log.LstdFlags),
-			scanner.Task(conf.Watcher),
+			ctrl.Generator(SCAN, scanner.Task(conf.Watcher, conf.Deposits)),
 			work,
 		),
 		Sender: actor.New(
 			log.New(os.Stdout, " [SENDER] ", log.LstdFlags),
-			sender.Task(conf.Currencies),
+			ctrl.Actor(SEND, sender.Task(conf.Currencies)),
 		),
 		Monitor: actor.New(
 			log.New(os.Stdout, "[MONITOR] ", log.LstdFlags),
-			monitor.Task(conf.Currencies),
+			ctrl.Actor(MONITOR, monitor.Task(conf.Currencies)),
 		),
 	}, work
 }
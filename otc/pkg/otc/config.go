@@ -1,37 +1,399 @@
 package otc
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/BurntSushi/toml"
 )
 
+// ENV_PREFIX starts environment variables overriding config values, e.g.
+// OTC_SKY_NODE or OTC_API_PUBLIC_LISTEN.
+const ENV_PREFIX = "OTC"
+
 type Config struct {
 	SKY struct {
 		Node string
 		Seed string
-		Name string
+		// file holding the seed instead of the config, must not be readable
+		// by group or others
+		SeedFile string `toml:"seed_file"`
+		Name     string
 	}
 	BTC struct {
-		Node    string
-		User    string
-		Pass    string
-		Account string
-		Testnet bool
+		Node string
+		User string
+		Pass string
+		// file holding the pass, same permissions as SKY.SeedFile
+		PassFile string `toml:"pass_file"`
+		Account  string
+		Testnet  bool
 	}
 	API struct {
-		Public struct {
-			Listen string
-		}
-		Admin struct {
-			Listen string
-		}
+		Public Listener
+		Admin  Listener
 	}
 	Watcher struct {
 		Node string
+		// api key sent as a bearer token, if otc-watcher requires one
+		Key string
+		// file holding the key, same permissions as SKY.SeedFile
+		KeyFile string `toml:"key_file"`
+	}
+	// node connections are retried at startup instead of failing
+	Connect struct {
+		// attempts per node, 0 retries forever
+		Retries int
+		// seconds before the first retry, doubling up to a minute
+		Delay int64
+	}
+	Bind struct {
+		// new drop addresses per client IP and per skycoin address within
+		// Period seconds, 0 disables the limit
+		IPLimit      int `toml:"ip_limit"`
+		AddressLimit int `toml:"address_limit"`
+		Period       int64
+		// seconds before an unfunded drop address is dropped, 0 keeps it
+		Expiry int64
+		// leading zero bits of proof of work required, 0 disables it
+		Work int
+		// client IP is read from X-Forwarded-For (behind nginx)
+		Proxied bool
+	}
+	Alerts struct {
+		// seconds between checks
+		Interval int64
+		// seconds before a firing alert is sent again, 0 sends it once
+		Repeat int64
+		// seconds without an exchange price update, 0 disables
+		PriceStale int64 `toml:"price_stale"`
+		// seconds an order may stay collecting, waiting_send or
+		// waiting_confirm, 0 disables
+		OrderStuck int64 `toml:"order_stuck"`
+		// alert on orders whose last attempt failed
+		OrderFailed bool `toml:"order_failed"`
+		// alert on disconnected nodes
+		Nodes bool
+		// lowest holding per currency before alerting
+		Holding map[string]uint64
+		SMTP    struct {
+			// host:port, empty disables email
+			Addr string
+			From string
+			To   []string
+			User string
+			Pass string
+			// file holding the pass, same permissions as SKY.SeedFile
+			PassFile string `toml:"pass_file"`
+		}
+		Webhook struct {
+			URL string
+		}
+		// slack compatible incoming webhook
+		Slack struct {
+			URL string
+		}
+	}
+	Deposits struct {
+		// seconds to wait for further outputs before paying out
+		Window int64
+		// smallest deposit paid out, keyed by drop currency
+		Minimum map[string]uint64
 	}
+	Prices struct {
+		// seconds of price history kept in memory, older prices are read
+		// from prices.json when asked for, 0 keeps all of them
+		Retention int64
+	}
+	// what sets this desk apart from the others run by the process, not
+	// inherited by Desks
+	Desk Desk
+	// other desks served by this process, keyed by id. Each is this config
+	// with its [Desks.<id>] tables decoded on top, e.g. [Desks.shop.SKY].
+	Desks map[string]*Config `toml:"-"`
+}
+
+// Desk configures what's particular to one desk when several share a
+// process.
+type Desk struct {
+	// storage directory, .otc/ for the main desk and .otc/desks/<id>/ for
+	// the others
+	Path string
+	// host names whose public api requests go to this desk, the main desk
+	// gets the rest
+	Hosts []string
+	// starting internal price of 1 SKY in satoshis, 0 for the default
+	Price uint64
+	// bearer token for the admin api, required with Desks or Operators
+	Token string
+	// file holding the token, same permissions as SKY.SeedFile
+	TokenFile string `toml:"token_file"`
+	// admin api tokens of the people running the desk, by name. Order
+	// changes made with one are recorded under that name, those made with
+	// Token under the desk's.
+	Operators map[string]string
+	// files holding operator tokens, by name
+	OperatorFiles map[string]string `toml:"operator_files"`
+}
+
+// Listener configures one of the http apis.
+type Listener struct {
+	Listen string
+	TLS    struct {
+		// certificate and key files, reloaded when they change so an acme
+		// client (certbot, lego, step) can renew them in place
+		Cert string
+		Key  string
+	}
+	// seconds, 0 disables the timeout
+	ReadTimeout  int64 `toml:"read_timeout"`
+	WriteTimeout int64 `toml:"write_timeout"`
+	IdleTimeout  int64 `toml:"idle_timeout"`
+	// maximum request body in bytes, 0 for no limit
+	MaxBody int64 `toml:"max_body"`
+	// origins allowed to make cross-origin requests, "*" for any
+	CORS []string
+	// file that access logs are appended to (JSON lines), "-" for stdout
+	AccessLog string `toml:"access_log"`
 }
 
+// NewConfig decodes the config at path, applies environment overrides and
+// reads secret files, for the main desk and every one in Desks. It isn't
+// validated, see Validate.
 func NewConfig(path string) (*Config, error) {
+	c, err := decodeConfig(path, ENV_PREFIX)
+	if err != nil {
+		return c, err
+	}
+
+	var raw struct {
+		Desks map[string]toml.Primitive
+	}
+	meta, err := toml.DecodeFile(path, &raw)
+	if err != nil {
+		return c, err
+	}
+
+	for id, prim := range raw.Desks {
+		// decoded again rather than copied so maps aren't shared
+		desk, err := decodeConfig(path, ENV_PREFIX)
+		if err != nil {
+			return c, err
+		}
+		desk.Desk = Desk{}
+
+		if err = meta.PrimitiveDecode(prim, desk); err != nil {
+			return c, fmt.Errorf("Desks.%s: %v", id, err)
+		}
+
+		// OTC_DESKS_SHOP_SKY_SEED
+		prefix := ENV_PREFIX + "_DESKS_" + strings.ToUpper(id)
+		if err = Override(desk, prefix, os.LookupEnv); err != nil {
+			return c, err
+		}
+		if err = desk.ReadSecrets(); err != nil {
+			return c, fmt.Errorf("Desks.%s: %v", id, err)
+		}
+
+		if c.Desks == nil {
+			c.Desks = make(map[string]*Config)
+		}
+		c.Desks[id] = desk
+	}
+
+	return c, c.ReadSecrets()
+}
+
+func decodeConfig(path, prefix string) (*Config, error) {
 	c := &Config{}
-	_, err := toml.DecodeFile(path, &c)
-	return c, err
+	if _, err := toml.DecodeFile(path, &c); err != nil {
+		return c, err
+	}
+
+	return c, Override(c, prefix, os.LookupEnv)
+}
+
+// DeskIds returns the ids of Desks, sorted.
+func (c *Config) DeskIds() []string {
+	ids := make([]string, 0, len(c.Desks))
+	for id := range c.Desks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// OperatorNames returns the names of Operators, sorted.
+func (d *Desk) OperatorNames() []string {
+	names := make([]string, 0, len(d.Operators))
+	for name := range d.Operators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadSecrets replaces the SKY seed, BTC pass, SMTP pass, watcher key,
+// desk token and operator tokens with the contents of their files, if set.
+func (c *Config) ReadSecrets() error {
+	var err error
+
+	if c.SKY.SeedFile != "" {
+		if c.SKY.Seed, err = ReadSecret(c.SKY.SeedFile); err != nil {
+			return err
+		}
+	}
+
+	if c.BTC.PassFile != "" {
+		if c.BTC.Pass, err = ReadSecret(c.BTC.PassFile); err != nil {
+			return err
+		}
+	}
+
+	if c.Alerts.SMTP.PassFile != "" {
+		if c.Alerts.SMTP.Pass, err = ReadSecret(c.Alerts.SMTP.PassFile); err != nil {
+			return err
+		}
+	}
+
+	if c.Watcher.KeyFile != "" {
+		if c.Watcher.Key, err = ReadSecret(c.Watcher.KeyFile); err != nil {
+			return err
+		}
+	}
+
+	if c.Desk.TokenFile != "" {
+		if c.Desk.Token, err = ReadSecret(c.Desk.TokenFile); err != nil {
+			return err
+		}
+	}
+
+	for name, file := range c.Desk.OperatorFiles {
+		if c.Desk.Operators == nil {
+			c.Desk.Operators = make(map[string]string)
+		}
+		if c.Desk.Operators[name], err = ReadSecret(file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadSecret returns the trimmed contents of a file only its owner can read.
+func ReadSecret(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("secret file %s is accessible by group or others (%04o), chmod 600 it",
+			path, info.Mode().Perm())
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Override sets config fields from environment variables named by prefix and
+// the toml path of the field, uppercased and joined by underscores. Lists
+// are comma separated. lookup is os.LookupEnv outside of tests.
+func Override(c *Config, prefix string, lookup func(string) (string, bool)) error {
+	return override(reflect.ValueOf(c).Elem(), prefix, lookup)
+}
+
+func override(v reflect.Value, name string, lookup func(string) (string, bool)) error {
+	if v.Kind() == reflect.Struct {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+
+			key := field.Tag.Get("toml")
+			if key == "-" {
+				continue
+			}
+			if key == "" {
+				key = field.Name
+			}
+
+			err := override(v.Field(i), name+"_"+strings.ToUpper(key), lookup)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value, ok := lookup(name)
+	if !ok {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		v.SetInt(n)
+	case reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		v.SetUint(n)
+	case reflect.Slice:
+		list := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		// KEY=VALUE pairs, comma separated
+		m := reflect.MakeMap(v.Type())
+		for _, pair := range strings.Split(value, ",") {
+			parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("%s: expected KEY=VALUE pairs", name)
+			}
+			item := reflect.New(v.Type().Elem()).Elem()
+			switch item.Kind() {
+			case reflect.String:
+				item.SetString(parts[1])
+			case reflect.Uint64:
+				n, err := strconv.ParseUint(parts[1], 10, 64)
+				if err != nil {
+					return fmt.Errorf("%s: %v", name, err)
+				}
+				item.SetUint(n)
+			default:
+				return fmt.Errorf("%s: can't override map of %s", name, item.Kind())
+			}
+			m.SetMapIndex(reflect.ValueOf(parts[0]).Convert(v.Type().Key()), item)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("%s: can't override %s", name, v.Kind())
+	}
+
+	return nil
 }
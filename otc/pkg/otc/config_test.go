@@ -1,6 +1,9 @@
 package otc
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -21,3 +24,280 @@ func TestConfigNewBad(t *testing.T) {
 		t.Fatal("should return error")
 	}
 }
+
+func ValidConfig() *Config {
+	c := &Config{}
+	c.SKY.Node, c.SKY.Seed, c.SKY.Name = "localhost:6430", "seed", "otc"
+	c.BTC.Node, c.BTC.User, c.BTC.Pass, c.BTC.Account = "localhost:18332", "otc", "pass", "otc"
+	c.Watcher.Node = "http://localhost:8888"
+	c.API.Public.Listen = ":8081"
+	c.API.Admin.Listen = "127.0.0.1:8080"
+	return c
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := ValidConfig().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Change func(*Config)
+		Err    string
+	}{
+		{func(c *Config) { c.SKY.Seed = "" }, "SKY.seed (or SKY.seed_file) is required"},
+		{func(c *Config) { c.SKY.Node = "localhost" }, `SKY.node must be host:port, got "localhost"`},
+		{func(c *Config) { c.Watcher.Node = "localhost:8888" }, `Watcher.node must be an http(s) URL, got "localhost:8888"`},
+		{func(c *Config) { c.API.Admin.Listen = ":8081" }, "API.Public and API.Admin listen on the same address"},
+		{func(c *Config) { c.API.Public.TLS.Cert = "cert.pem" }, "API.Public.TLS needs both cert and key"},
+		{func(c *Config) { c.API.Public.CORS = []string{"otc.skycoin.net"} }, `API.Public.cors must be an http(s) URL, got "otc.skycoin.net"`},
+		{func(c *Config) { c.Bind.IPLimit = 5 }, "Bind.period is required with a bind limit"},
+		{func(c *Config) { c.Deposits.Minimum = map[string]uint64{"SKY": 1} }, "Deposits.Minimum: SKY isn't a drop currency"},
+		{func(c *Config) { c.Alerts.SMTP.Addr, c.Alerts.SMTP.To = "mail:25", []string{"ops@otc"} }, "Alerts.SMTP.from is required"},
+		{func(c *Config) { c.Alerts.Slack.URL = "hooks.slack.com" }, `Alerts.Slack.url must be an http(s) URL, got "hooks.slack.com"`},
+		{func(c *Config) { c.Alerts.Holding = map[string]uint64{"ETH": 1} }, "Alerts.Holding: unknown currency ETH"},
+	}
+
+	for _, test := range tests {
+		c := ValidConfig()
+		test.Change(c)
+
+		errs, ok := c.Validate().(ConfigErrors)
+		if !ok || len(errs) != 1 || errs[0] != test.Err {
+			t.Fatalf(`expected "%s", got %v`, test.Err, errs)
+		}
+	}
+
+	// every problem is reported
+	if errs := (&Config{}).Validate().(ConfigErrors); len(errs) < 10 {
+		t.Fatalf("expected all errors, got %v", errs)
+	}
+}
+
+func TestConfigOverride(t *testing.T) {
+	env := map[string]string{
+		"OTC_SKY_NODE":              "sky:6430",
+		"OTC_BTC_TESTNET":           "true",
+		"OTC_API_PUBLIC_LISTEN":     ":9000",
+		"OTC_API_PUBLIC_CORS":       "https://a.net, https://b.net",
+		"OTC_BIND_IP_LIMIT":         "3",
+		"OTC_DEPOSITS_MINIMUM":      "BTC=500",
+		"OTC_API_ADMIN_TLS_CERT":    "cert.pem",
+		"OTC_API_ADMIN_MAX_BODY":    "1024",
+		"OTC_SOMETHING_ELSE_ENTIRE": "ignored",
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	c := ValidConfig()
+	if err := Override(c, ENV_PREFIX, lookup); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.SKY.Node != "sky:6430" || !c.BTC.Testnet || c.API.Public.Listen != ":9000" ||
+		len(c.API.Public.CORS) != 2 || c.API.Public.CORS[1] != "https://b.net" ||
+		c.Bind.IPLimit != 3 || c.Deposits.Minimum["BTC"] != 500 ||
+		c.API.Admin.TLS.Cert != "cert.pem" || c.API.Admin.MaxBody != 1024 {
+		t.Fatalf("overrides not applied: %+v", c)
+	}
+
+	// untouched
+	if c.SKY.Seed != "seed" {
+		t.Fatal("expected seed unchanged")
+	}
+
+	env["OTC_BIND_WORK"] = "lots"
+	if err := Override(c, ENV_PREFIX, lookup); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestConfigOverrideOperators(t *testing.T) {
+	for _, value := range []string{"karl=s3cret", "karl=1234"} {
+		lookup := func(key string) (string, bool) {
+			return value, key == "OTC_DESK_OPERATORS"
+		}
+
+		c := ValidConfig()
+		if err := Override(c, ENV_PREFIX, lookup); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(c.Desk.Operators) != 1 || c.Desk.Operators["karl"] != value[5:] {
+			t.Fatalf("operators not applied from %q: %v", value, c.Desk.Operators)
+		}
+	}
+}
+
+func TestConfigSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otc-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "sky.seed")
+	if err = ioutil.WriteFile(path, []byte("secret seed\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := ValidConfig()
+	c.SKY.SeedFile = path
+	if err = c.ReadSecrets(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.SKY.Seed != "secret seed" {
+		t.Fatalf(`expected "secret seed", got "%s"`, c.SKY.Seed)
+	}
+
+	// readable by others
+	os.Chmod(path, 0644)
+	if err = c.ReadSecrets(); err == nil {
+		t.Fatal("expected permissions error")
+	}
+
+	c.SKY.SeedFile, c.BTC.PassFile = "", filepath.Join(dir, "missing")
+	if err = c.ReadSecrets(); err == nil {
+		t.Fatal("expected missing file error")
+	}
+}
+
+func TestConfigDesks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otc-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.toml")
+	err = ioutil.WriteFile(path, []byte(`
+[SKY]
+node = "localhost:6430"
+seed = "main seed"
+name = "otc"
+
+[Bind]
+work = 16
+
+[Deposits.Minimum]
+BTC = 1000
+
+[Desk]
+token = "main"
+
+[Desks.shop.SKY]
+seed = "shop seed"
+
+[Desks.shop.Bind]
+work = 0
+
+[Desks.shop.Deposits.Minimum]
+BTC = 5000
+
+[Desks.shop.Desk]
+hosts = ["shop.example.com"]
+token = "shop"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("OTC_DESKS_SHOP_SKY_NAME", "shop")
+	defer os.Unsetenv("OTC_DESKS_SHOP_SKY_NAME")
+
+	c, err := NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shop := c.Desks["shop"]
+	if shop == nil || len(c.DeskIds()) != 1 {
+		t.Fatalf("expected desk shop, got %v", c.DeskIds())
+	}
+
+	// inherited unless set
+	if shop.SKY.Node != "localhost:6430" || shop.SKY.Seed != "shop seed" || shop.SKY.Name != "shop" {
+		t.Fatalf("unexpected SKY %+v", shop.SKY)
+	}
+	if shop.Bind.Work != 0 || c.Bind.Work != 16 {
+		t.Fatalf("expected work 0 and 16, got %d and %d", shop.Bind.Work, c.Bind.Work)
+	}
+	if shop.Deposits.Minimum["BTC"] != 5000 || c.Deposits.Minimum["BTC"] != 1000 {
+		t.Fatal("expected separate deposit minimums")
+	}
+
+	// [Desk] isn't
+	if shop.Desk.Token != "shop" || len(shop.Desk.Hosts) != 1 || len(c.Desk.Hosts) != 0 {
+		t.Fatalf("unexpected desks %+v %+v", c.Desk, shop.Desk)
+	}
+	if shop.Desks != nil {
+		t.Fatal("expected desks not to nest")
+	}
+}
+
+func TestConfigValidateDesks(t *testing.T) {
+	addDesk := func(c *Config) *Config {
+		c.Desk.Token = "main"
+		shop := ValidConfig()
+		shop.SKY.Seed, shop.BTC.Account, shop.Desk.Token = "shop", "shop", "shop"
+		c.Desks = map[string]*Config{"shop": shop}
+		return shop
+	}
+
+	c := ValidConfig()
+	addDesk(c)
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Change func(c, shop *Config)
+		Err    string
+	}{
+		{func(c, shop *Config) { c.Desk.Token = "" }, "Desk.token (or Desk.token_file) is required"},
+		{func(c, shop *Config) { shop.Desk.Token = "main" }, "Desks.shop.Desk.token is also used by the main desk"},
+		{func(c, shop *Config) { shop.Desk.Operators = map[string]string{"karl": "main"} },
+			"Desks.shop.Desk.Operators.karl is also used by the main desk"},
+		{func(c, shop *Config) { c.Desk.Operators = map[string]string{"karl": "k", "olga": "k"} },
+			"Desk.Operators.olga is also used by Desk.Operators.karl"},
+		{func(c, shop *Config) { c.Desk.Operators = map[string]string{"karl": ""} },
+			"Desk.Operators.karl (or Desk.operator_files.karl) is required"},
+		{func(c, shop *Config) { shop.SKY.Seed = "seed" }, "Desks.shop.SKY.seed is also used by the main desk"},
+		{func(c, shop *Config) { shop.BTC.Account = "otc" }, "Desks.shop.BTC.account is also used by the main desk"},
+		{func(c, shop *Config) { c.Desk.Hosts, shop.Desk.Hosts = []string{"a.net"}, []string{"A.net"} },
+			"Desks.shop.Desk.hosts: a.net is also served by the main desk"},
+		{func(c, shop *Config) { shop.Bind.Work = 300 }, "Desks.shop: Bind.work must be between 0 and 256, got 300"},
+		{func(c, shop *Config) { c.Desks["Shop-2"] = c.Desks["shop"]; delete(c.Desks, "shop") },
+			"Desks.Shop-2: ids may only use a-z, 0-9 and _"},
+	}
+
+	for _, test := range tests {
+		c := ValidConfig()
+		shop := addDesk(c)
+		test.Change(c, shop)
+
+		errs, ok := c.Validate().(ConfigErrors)
+		if !ok || len(errs) != 1 || errs[0] != test.Err {
+			t.Fatalf(`expected "%s", got %v`, test.Err, errs)
+		}
+	}
+
+	// operators need a desk token, or requests without one would be the desk's
+	c = ValidConfig()
+	c.Desk.Operators = map[string]string{"karl": "k"}
+	errs, ok := c.Validate().(ConfigErrors)
+	if expected := "Desk.token (or Desk.token_file) is required with Desk.operators"; !ok || len(errs) != 1 || errs[0] != expected {
+		t.Fatalf(`expected "%s", got %v`, expected, errs)
+	}
+
+	// problems of the main config aren't repeated for every desk
+	c = ValidConfig()
+	addDesk(c)
+	c.Watcher.Node = ""
+	c.Desks["shop"].Watcher.Node = ""
+	if errs := c.Validate().(ConfigErrors); len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+}
@@ -13,6 +13,8 @@ type Order struct {
 	Status Status `json:"status"`
 	// bitcoin amount in satoshis
 	Amount uint64 `json:"amount"`
+	// deposit outputs ("transaction : output index") included in amount
+	Outputs []string `json:"outputs,omitempty"`
 	// purchase information
 	Purchase *Purchase `json:"purchase,omitempty"`
 	// timestamps for order
@@ -21,6 +23,39 @@ type Order struct {
 	Events []*Event `json:"events,omitempty"`
 }
 
+// Includes reports whether a deposit output is part of the order. Orders
+// saved before aggregation only have their id.
+func (o *Order) Includes(output string) bool {
+	if o.Id == output {
+		return true
+	}
+
+	for _, id := range o.Outputs {
+		if id == output {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Public returns a copy of the order for customers, without who intervened
+// in it, how or why.
+func (o *Order) Public() *Order {
+	copied := *o
+	if o.Events == nil {
+		return &copied
+	}
+
+	copied.Events = make([]*Event, 0, len(o.Events))
+	for _, event := range o.Events {
+		public := *event
+		public.Operator, public.Action, public.Note, public.TxId = "", "", "", ""
+		copied.Events = append(copied.Events, &public)
+	}
+	return &copied
+}
+
 type Purchase struct {
 	// coin source
 	Source string `json:"source"`
@@ -37,6 +72,10 @@ type Price struct {
 	Source string `json:"source"`
 	// price when executed (and sent)
 	Executed uint64 `json:"executed"`
+	// id of the price history observation used
+	Observation uint64 `json:"observation,omitempty"`
+	// unix time of the observation
+	ObservedAt int64 `json:"observed_at,omitempty"`
 }
 
 type User struct {
@@ -71,9 +110,13 @@ type Status string
 
 const (
 	DEPOSIT Status = "waiting_deposit"
+	BELOW   Status = "below_minimum"
+	COLLECT Status = "collecting"
 	SEND    Status = "waiting_send"
 	CONFIRM Status = "waiting_confirm"
 	DONE    Status = "done"
+	// stopped by an operator, never processed further
+	CANCELLED Status = "cancelled"
 )
 
 type Times struct {
@@ -82,6 +125,8 @@ type Times struct {
 	DepositedAt int64 `json:"deposited_at,omitempty"`
 	SentAt      int64 `json:"sent_at,omitempty"`
 	ConfirmedAt int64 `json:"confirmed_at,omitempty"`
+	// when an unfunded user's drop address was last handed out again
+	BoundAt int64 `json:"bound_at,omitempty"`
 }
 
 type Work struct {
@@ -103,31 +148,62 @@ type Event struct {
 	Status   Status `json:"status"`
 	Finished int64  `json:"finished"`
 	Err      string `json:"error,omitempty"`
+	// set for manual interventions through the admin api
+	Operator string `json:"operator,omitempty"`
+	Action   string `json:"action,omitempty"`
+	Note     string `json:"note,omitempty"`
+	TxId     string `json:"txid,omitempty"`
 }
 
 type Output struct {
+	// the chain's own id for chains identifying outputs by id rather than
+	// transaction and index (Skycoin uxids)
+	Id        string   `json:"id,omitempty"`
 	Amount    uint64   `json:"amount"`
 	Addresses []string `json:"addresses"`
+	// coin hours of Skycoin outputs
+	Hours uint64 `json:"hours,omitempty"`
 }
 
 type OutputVerbose struct {
+	Id            string   `json:"id,omitempty"`
 	Amount        uint64   `json:"amount"`
+	Hours         uint64   `json:"hours,omitempty"`
 	Confirmations uint64   `json:"confirmations"`
 	BlockHash     string   `json:"block_hash"`
 	TxHash        string   `json:"tx_hash"`
 	Addresses     []string `json:"addresses,omitempty"`
 	Height        uint64   `json:"height,omitempty"`
+	// id of the transaction spending the output, if any, and its height
+	Spent       string `json:"spent,omitempty"`
+	SpentHeight uint64 `json:"spent_height,omitempty"`
+}
+
+// Input spends output Index of the transaction with id Hash, or the output
+// Id on chains identifying outputs by id.
+type Input struct {
+	Hash  string `json:"hash,omitempty"`
+	Index int    `json:"index"`
+	Id    string `json:"id,omitempty"`
 }
 
 type Transaction struct {
+	// transaction id, differs from Hash for segwit transactions
+	Id            string          `json:"id"`
 	BlockHash     string          `json:"block_hash"`
 	Hash          string          `json:"hash"`
 	Confirmations uint64          `json:"confirmations"`
+	In            []Input         `json:"in"`
 	Out           map[int]*Output `json:"out"`
 }
 
 type Block struct {
-	Height       uint64                  `json:"height"`
+	Height uint64 `json:"height"`
+	Hash   string `json:"hash,omitempty"`
+	// hash of the parent block
+	Previous string `json:"previous,omitempty"`
+	// unix time the block was mined, as reported by its header
+	Time         int64                   `json:"time,omitempty"`
 	Transactions map[string]*Transaction `json:"transactions"`
 }
 
@@ -140,3 +216,26 @@ func (o Outputs) Update(hash string, index int, output *OutputVerbose) {
 
 	o[hash][index] = output
 }
+
+// UpdateSpent marks output index of transaction hash as spent by transaction
+// by, returning false if it isn't one of the outputs.
+func (o Outputs) UpdateSpent(hash string, index int, by string) bool {
+	if o[hash] == nil || o[hash][index] == nil {
+		return false
+	}
+
+	o[hash][index].Spent = by
+	return true
+}
+
+// Find returns the transaction and index of the output with the given id.
+func (o Outputs) Find(id string) (string, int, bool) {
+	for hash, outputs := range o {
+		for index, output := range outputs {
+			if output.Id == id {
+				return hash, index, true
+			}
+		}
+	}
+	return "", 0, false
+}
@@ -46,3 +46,42 @@ func TestOutputsUpdate(t *testing.T) {
 		t.Fatal("update failed")
 	}
 }
+
+func TestOutputsUpdateSpent(t *testing.T) {
+	outputs := Outputs(map[string]map[int]*OutputVerbose{
+		"transaction": {
+			1: {
+				Amount: 1,
+			},
+		},
+	})
+
+	if !outputs.UpdateSpent("transaction", 1, "spender") {
+		t.Fatal("output not found")
+	}
+
+	if outputs["transaction"][1].Spent != "spender" {
+		t.Fatal("spent not set")
+	}
+
+	if outputs.UpdateSpent("transaction", 2, "spender") || outputs.UpdateSpent("other", 1, "spender") {
+		t.Fatal("unknown outputs marked spent")
+	}
+}
+
+func TestOutputsFind(t *testing.T) {
+	outputs := Outputs(map[string]map[int]*OutputVerbose{
+		"transaction": {
+			0: {Id: "uxid_zero"},
+			1: {Id: "uxid_one"},
+		},
+	})
+
+	if hash, index, found := outputs.Find("uxid_one"); !found || hash != "transaction" || index != 1 {
+		t.Fatalf("got %s %d %v", hash, index, found)
+	}
+
+	if _, _, found := outputs.Find("missing"); found {
+		t.Fatal("found missing output")
+	}
+}
@@ -0,0 +1,266 @@
+package otc
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ConfigErrors lists every problem found in a config, so they can all be
+// fixed at once.
+type ConfigErrors []string
+
+func (e ConfigErrors) Error() string {
+	return "invalid config:\n  " + strings.Join(e, "\n  ")
+}
+
+func (e *ConfigErrors) add(format string, args ...interface{}) {
+	*e = append(*e, fmt.Sprintf(format, args...))
+}
+
+// Validate checks required fields and the format of addresses and URLs.
+// It returns ConfigErrors, or nil if the config is usable.
+func (c *Config) Validate() error {
+	errs := make(ConfigErrors, 0)
+
+	errs.required("SKY.node", c.SKY.Node)
+	errs.hostPort("SKY.node", c.SKY.Node)
+	errs.required("SKY.seed (or SKY.seed_file)", c.SKY.Seed)
+	errs.required("SKY.name", c.SKY.Name)
+
+	errs.required("BTC.node", c.BTC.Node)
+	errs.hostPort("BTC.node", c.BTC.Node)
+	errs.required("BTC.user", c.BTC.User)
+	errs.required("BTC.pass (or BTC.pass_file)", c.BTC.Pass)
+	errs.required("BTC.account", c.BTC.Account)
+
+	errs.required("Watcher.node", c.Watcher.Node)
+	errs.url("Watcher.node", c.Watcher.Node)
+
+	errs.listener("API.Public", &c.API.Public)
+	errs.listener("API.Admin", &c.API.Admin)
+	if c.API.Public.Listen != "" && c.API.Public.Listen == c.API.Admin.Listen {
+		errs.add("API.Public and API.Admin listen on the same address")
+	}
+
+	errs.positive("Bind.ip_limit", int64(c.Bind.IPLimit))
+	errs.positive("Bind.address_limit", int64(c.Bind.AddressLimit))
+	errs.positive("Bind.period", c.Bind.Period)
+	if (c.Bind.IPLimit > 0 || c.Bind.AddressLimit > 0) && c.Bind.Period == 0 {
+		errs.add("Bind.period is required with a bind limit")
+	}
+	errs.positive("Bind.expiry", c.Bind.Expiry)
+	if c.Bind.Work < 0 || c.Bind.Work > 256 {
+		errs.add("Bind.work must be between 0 and 256, got %d", c.Bind.Work)
+	}
+
+	errs.positive("Deposits.window", c.Deposits.Window)
+	errs.positive("Prices.retention", c.Prices.Retention)
+	for cur := range c.Deposits.Minimum {
+		if Currency(cur) != BTC {
+			errs.add("Deposits.Minimum: %s isn't a drop currency", cur)
+		}
+	}
+
+	errs.alerts(c)
+
+	errs.positive("Connect.retries", int64(c.Connect.Retries))
+	errs.positive("Connect.delay", c.Connect.Delay)
+
+	errs.operators(c)
+	errs.desks(c)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (e *ConfigErrors) required(name, value string) {
+	if value == "" {
+		e.add("%s is required", name)
+	}
+}
+
+func (e *ConfigErrors) positive(name string, value int64) {
+	if value < 0 {
+		e.add("%s can't be negative, got %d", name, value)
+	}
+}
+
+// hostPort checks a node address, e.g. localhost:6430
+func (e *ConfigErrors) hostPort(name, value string) {
+	if value == "" {
+		return
+	}
+	if _, port, err := net.SplitHostPort(value); err != nil || port == "" {
+		e.add("%s must be host:port, got %q", name, value)
+	}
+}
+
+// url checks an http(s) base URL, e.g. http://localhost:8888
+func (e *ConfigErrors) url(name, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		e.add("%s must be an http(s) URL, got %q", name, value)
+	}
+}
+
+func (e *ConfigErrors) listener(name string, l *Listener) {
+	e.required(name+".listen", l.Listen)
+	if l.Listen != "" {
+		if _, _, err := net.SplitHostPort(l.Listen); err != nil {
+			e.add("%s.listen must be [host]:port, got %q", name, l.Listen)
+		}
+	}
+
+	if (l.TLS.Cert == "") != (l.TLS.Key == "") {
+		e.add("%s.TLS needs both cert and key", name)
+	}
+
+	e.positive(name+".read_timeout", l.ReadTimeout)
+	e.positive(name+".write_timeout", l.WriteTimeout)
+	e.positive(name+".idle_timeout", l.IdleTimeout)
+	e.positive(name+".max_body", l.MaxBody)
+
+	for _, origin := range l.CORS {
+		if origin != "*" {
+			e.url(name+".cors", origin)
+		}
+	}
+}
+
+func (e *ConfigErrors) alerts(c *Config) {
+	a := &c.Alerts
+
+	e.positive("Alerts.interval", a.Interval)
+	e.positive("Alerts.repeat", a.Repeat)
+	e.positive("Alerts.price_stale", a.PriceStale)
+	e.positive("Alerts.order_stuck", a.OrderStuck)
+	for cur := range a.Holding {
+		if Currency(cur) != SKY && Currency(cur) != BTC {
+			e.add("Alerts.Holding: unknown currency %s", cur)
+		}
+	}
+
+	if a.SMTP.Addr != "" {
+		e.hostPort("Alerts.SMTP.addr", a.SMTP.Addr)
+		e.required("Alerts.SMTP.from", a.SMTP.From)
+		if len(a.SMTP.To) == 0 {
+			e.add("Alerts.SMTP.to is required")
+		}
+	}
+	e.url("Alerts.Webhook.url", a.Webhook.URL)
+	e.url("Alerts.Slack.url", a.Slack.URL)
+}
+
+// DESK_ID matches desk ids, which end up in storage paths and environment
+// variable names.
+var DESK_ID = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// desks validates every desk, reporting only the problems it doesn't share
+// with the main config, and checks that desks don't share tokens, hosts,
+// storage or wallets.
+func (e *ConfigErrors) desks(c *Config) {
+	if len(c.Desks) == 0 {
+		return
+	}
+
+	inherited := make(map[string]bool)
+	for _, msg := range *e {
+		inherited[msg] = true
+	}
+
+	const MAIN = "the main desk"
+	tokens := make(map[string]string)
+	hosts := make(map[string]string)
+	paths := make(map[string]string)
+	seeds := make(map[string]string)
+	accounts := make(map[string]string)
+
+	claim := func(name string, desk *Config, owner string) {
+		e.required(name+"Desk.token (or Desk.token_file)", desk.Desk.Token)
+		if other := tokens[desk.Desk.Token]; desk.Desk.Token != "" && other != "" {
+			e.add("%sDesk.token is also used by %s", name, other)
+		}
+		tokens[desk.Desk.Token] = owner
+
+		// duplicates within a desk are found by operators
+		for _, op := range desk.Desk.OperatorNames() {
+			token := desk.Desk.Operators[op]
+			if other := tokens[token]; token != "" && other != "" && other != owner {
+				e.add("%sDesk.Operators.%s is also used by %s", name, op, other)
+			}
+			tokens[token] = owner
+		}
+
+		for _, host := range desk.Desk.Hosts {
+			host = strings.ToLower(host)
+			if other := hosts[host]; other != "" {
+				e.add("%sDesk.hosts: %s is also served by %s", name, host, other)
+			}
+			hosts[host] = owner
+		}
+
+		if other := paths[desk.Desk.Path]; desk.Desk.Path != "" && other != "" {
+			e.add("%sDesk.path is also used by %s", name, other)
+		}
+		paths[desk.Desk.Path] = owner
+
+		// a shared wallet would mix the desks' inventory
+		if other := seeds[desk.SKY.Seed]; desk.SKY.Seed != "" && other != "" {
+			e.add("%sSKY.seed is also used by %s", name, other)
+		}
+		seeds[desk.SKY.Seed] = owner
+
+		if other := accounts[desk.BTC.Account]; desk.BTC.Account != "" && other != "" {
+			e.add("%sBTC.account is also used by %s", name, other)
+		}
+		accounts[desk.BTC.Account] = owner
+	}
+
+	claim("", c, MAIN)
+
+	for _, id := range c.DeskIds() {
+		desk, name := c.Desks[id], "Desks."+id+"."
+
+		if !DESK_ID.MatchString(id) {
+			e.add("Desks.%s: ids may only use a-z, 0-9 and _", id)
+		}
+
+		if errs, ok := desk.Validate().(ConfigErrors); ok {
+			for _, msg := range errs {
+				if !inherited[msg] {
+					e.add("Desks.%s: %s", id, msg)
+				}
+			}
+		}
+
+		claim(name, desk, "desk "+id)
+	}
+}
+
+// operators checks that every operator has a token of their own, as it's
+// what their changes are recorded under.
+func (e *ConfigErrors) operators(c *Config) {
+	// without a desk token, an empty one would match any request
+	if len(c.Desk.Operators) > 0 && c.Desk.Token == "" {
+		e.add("Desk.token (or Desk.token_file) is required with Desk.operators")
+	}
+
+	tokens := map[string]string{c.Desk.Token: "Desk.token"}
+
+	for _, name := range c.Desk.OperatorNames() {
+		token, field := c.Desk.Operators[name], "Desk.Operators."+name
+		e.required(field+" (or Desk.operator_files."+name+")", token)
+		if other := tokens[token]; token != "" && other != "" {
+			e.add("%s is also used by %s", field, other)
+		}
+		tokens[token] = field
+	}
+}
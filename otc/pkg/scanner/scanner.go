@@ -2,13 +2,51 @@ package scanner
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/skycoin/services/otc/pkg/otc"
 	"github.com/skycoin/services/otc/pkg/watcher"
 )
 
-func Task(watch *watcher.Watcher) func(*otc.User) (*otc.Order, error) {
+type Config struct {
+	// smallest total deposit (per drop currency) that is paid out, smaller
+	// deposits accumulate in a below_minimum order
+	Minimum map[otc.Currency]uint64
+	// outputs seen within Window of the first one are combined into a
+	// single order
+	Window time.Duration
+	// defaults to time.Now, replaced by the simulator
+	Now func() time.Time
+}
+
+func (c *Config) minimum(cur otc.Currency) uint64 {
+	if c == nil || c.Minimum == nil {
+		return 0
+	}
+	return c.Minimum[cur]
+}
+
+func (c *Config) window() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.Window
+}
+
+func (c *Config) now() time.Time {
+	if c == nil || c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// Task returns a new or updated order for the user when deposits change.
+// New outputs are added to a copy of the user's open (below_minimum or
+// collecting) order, which is moved on to waiting_send once it reaches the
+// minimum and its window has passed. The open order itself isn't changed,
+// it may still be read by the router and the apis.
+func Task(watch *watcher.Watcher, conf *Config) func(*otc.User) (*otc.Order, error) {
 	return func(user *otc.User) (*otc.Order, error) {
 		// get deposits from otc-watcher
 		deposits, err := watch.Outputs(user.Drop)
@@ -16,35 +54,122 @@ func Task(watch *watcher.Watcher) func(*otc.User) (*otc.Order, error) {
 			return nil, err
 		}
 
-		for transaction, outputs := range deposits {
-		indexing:
-			for index, output := range outputs {
-				id := fmt.Sprintf("%s:%d", transaction, index)
-
-				// check if order already exists
-				for _, order := range user.Orders {
-					if order.Id == id {
-						continue indexing
-					}
-				}
-
-				now := time.Now().UTC().Unix()
+		var (
+			outputs = New(user, deposits)
+			order   = Copy(Open(user))
+			minimum = conf.minimum(user.Drop.Currency)
+			window  = conf.window()
+			now     = conf.now().UTC().Unix()
+			changed = false
+		)
 
-				// generate new order
-				return &otc.Order{
-					User:   user,
-					Id:     id,
-					Status: otc.SEND,
-					Amount: output.Amount,
+		for _, output := range outputs {
+			if order == nil {
+				order = &otc.Order{
+					User:    user,
+					Id:      output.Id,
+					Status:  otc.BELOW,
+					Outputs: make([]string, 0),
 					Times: &otc.Times{
 						CreatedAt:   now,
 						DepositedAt: now,
 					},
 					Events: make([]*otc.Event, 0),
-				}, nil
+				}
+			}
+
+			order.Amount += output.Amount
+			order.Outputs = append(order.Outputs, output.Id)
+			changed = true
+
+			// without a window, leave remaining outputs for the next order
+			if window == 0 && order.Amount >= minimum {
+				break
+			}
+		}
+
+		if order == nil {
+			return nil, nil
+		}
+
+		if order.Amount >= minimum {
+			if now >= order.Times.DepositedAt+int64(window/time.Second) {
+				order.Status = otc.SEND
+				changed = true
+			} else if order.Status != otc.COLLECT {
+				order.Status = otc.COLLECT
+				changed = true
+			}
+		}
+
+		if !changed {
+			return nil, nil
+		}
+
+		return order, nil
+	}
+}
+
+type Output struct {
+	Id     string
+	Amount uint64
+	Height uint64
+}
+
+// New returns outputs that aren't part of any of the user's orders, oldest
+// first.
+func New(user *otc.User, deposits otc.Outputs) []*Output {
+	outputs := make([]*Output, 0)
+
+	for transaction, indexes := range deposits {
+	indexing:
+		for index, output := range indexes {
+			id := fmt.Sprintf("%s:%d", transaction, index)
+
+			// check if order already exists
+			for _, order := range user.Orders {
+				if order.Includes(id) {
+					continue indexing
+				}
 			}
+
+			outputs = append(outputs, &Output{id, output.Amount, output.Height})
+		}
+	}
+
+	sort.Slice(outputs, func(i, j int) bool {
+		if outputs[i].Height != outputs[j].Height {
+			return outputs[i].Height < outputs[j].Height
 		}
+		return outputs[i].Id < outputs[j].Id
+	})
+
+	return outputs
+}
 
-		return nil, nil
+// Copy returns a copy of order that can be changed without changing the
+// original, or nil.
+func Copy(order *otc.Order) *otc.Order {
+	if order == nil {
+		return nil
+	}
+
+	c := *order
+	c.Outputs = append(make([]string, 0, len(order.Outputs)), order.Outputs...)
+	c.Events = append(make([]*otc.Event, 0, len(order.Events)), order.Events...)
+	if order.Times != nil {
+		times := *order.Times
+		c.Times = &times
+	}
+	return &c
+}
+
+// Open returns the user's order that is still accumulating deposits.
+func Open(user *otc.User) *otc.Order {
+	for _, order := range user.Orders {
+		if order.Status == otc.BELOW || order.Status == otc.COLLECT {
+			return order
+		}
 	}
+	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/skycoin/services/otc/pkg/otc"
 	"github.com/skycoin/services/otc/pkg/watcher"
@@ -54,7 +55,7 @@ func MockWatcher(kind string) *watcher.Watcher {
 }
 
 func TestTaskGood(t *testing.T) {
-	order, err := Task(MockWatcher(""))(&otc.User{
+	order, err := Task(MockWatcher(""), nil)(&otc.User{
 		Drop: &otc.Drop{
 			Address:  "address",
 			Currency: otc.BTC,
@@ -67,7 +68,7 @@ func TestTaskGood(t *testing.T) {
 }
 
 func TestTaskBad(t *testing.T) {
-	order, err := Task(MockWatcher("error"))(&otc.User{
+	order, err := Task(MockWatcher("error"), nil)(&otc.User{
 		Drop: &otc.Drop{
 			Address:  "address",
 			Currency: otc.BTC,
@@ -83,7 +84,7 @@ func TestTaskBad(t *testing.T) {
 }
 
 func TestTaskExists(t *testing.T) {
-	order, err := Task(MockWatcher(""))(&otc.User{
+	order, err := Task(MockWatcher(""), nil)(&otc.User{
 		Drop: &otc.Drop{
 			Address:  "address",
 			Currency: otc.BTC,
@@ -99,3 +100,69 @@ func TestTaskExists(t *testing.T) {
 		t.Fatal("order should be empty")
 	}
 }
+
+func TestTaskBelowMinimum(t *testing.T) {
+	user := &otc.User{
+		Drop: &otc.Drop{
+			Address:  "address",
+			Currency: otc.BTC,
+		},
+	}
+
+	order, err := Task(MockWatcher(""), &Config{
+		Minimum: map[otc.Currency]uint64{otc.BTC: 200000},
+	})(user)
+
+	if order == nil || err != nil {
+		t.Fatal("bad scan")
+	}
+
+	if order.Status != otc.BELOW {
+		t.Fatalf("expected %s, got %s", otc.BELOW, order.Status)
+	}
+
+	// nothing new, so nothing to update
+	user.Orders = append(user.Orders, order)
+	order, err = Task(MockWatcher(""), &Config{
+		Minimum: map[otc.Currency]uint64{otc.BTC: 200000},
+	})(user)
+
+	if order != nil || err != nil {
+		t.Fatal("order should be empty")
+	}
+}
+
+func TestTaskWindow(t *testing.T) {
+	now := time.Now()
+	conf := &Config{
+		Window: time.Minute,
+		Now:    func() time.Time { return now },
+	}
+	user := &otc.User{
+		Drop: &otc.Drop{
+			Address:  "address",
+			Currency: otc.BTC,
+		},
+	}
+
+	order, err := Task(MockWatcher(""), conf)(user)
+	if order == nil || err != nil {
+		t.Fatal("bad scan")
+	}
+
+	if order.Status != otc.COLLECT || order.Outputs[0] != "transaction:1" {
+		t.Fatalf("expected %s, got %s", otc.COLLECT, order.Status)
+	}
+
+	user.Orders = append(user.Orders, order)
+	now = now.Add(time.Minute)
+
+	order, err = Task(MockWatcher(""), conf)(user)
+	if order == nil || err != nil {
+		t.Fatal("bad scan")
+	}
+
+	if order.Status != otc.SEND {
+		t.Fatalf("expected %s, got %s", otc.SEND, order.Status)
+	}
+}
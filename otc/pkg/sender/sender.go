@@ -9,10 +9,7 @@ import (
 
 func Task(curs *currencies.Currencies) func(*otc.Work) (bool, error) {
 	return func(work *otc.Work) (bool, error) {
-		value, source, price, err := curs.Value(
-			work.Order.User.Drop.Currency,
-			work.Order.Amount,
-		)
+		value, obs, err := Quote(curs, work.Order)
 		if err != nil {
 			return true, err
 		}
@@ -27,10 +24,32 @@ func Task(curs *currencies.Currencies) func(*otc.Work) (bool, error) {
 			Source: "internal",
 			Amount: value,
 			TxId:   txid,
-			Price:  &otc.Price{source, price},
+			Price: &otc.Price{
+				Source:      string(obs.Source),
+				Executed:    obs.Value,
+				Observation: obs.Id,
+				ObservedAt:  obs.Time,
+			},
 		}
 		work.Order.Times.SentAt = time.Now().UTC().Unix()
 		work.Order.Status = otc.CONFIRM
 		return true, nil
 	}
 }
+
+// Quote returns the amount of skycoin to send for an order. A price fixed
+// by an operator (re-quote) is used if there is one, otherwise the current
+// price.
+func Quote(curs *currencies.Currencies, order *otc.Order) (uint64, currencies.Observation, error) {
+	if p := order.Purchase; p != nil && p.TxId == "" && p.Price != nil {
+		return p.Amount, currencies.Observation{
+			Id:       p.Price.Observation,
+			Currency: order.User.Drop.Currency,
+			Source:   currencies.Source(p.Price.Source),
+			Value:    p.Price.Executed,
+			Time:     p.Price.ObservedAt,
+		}, nil
+	}
+
+	return curs.Quote(order.User.Drop.Currency, order.Amount)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// Certificates loads a certificate from files and reloads it when either
+// file changes, so renewals don't need a restart.
+type Certificates struct {
+	sync.Mutex
+
+	Cert string
+	Key  string
+
+	current  *tls.Certificate
+	modified time.Time
+}
+
+func NewCertificates(cert, key string) (*Certificates, error) {
+	c := &Certificates{Cert: cert, Key: key}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Certificates) Get(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	// keep serving the old certificate if a renewal is half written
+	c.load()
+
+	return c.current, nil
+}
+
+func (c *Certificates) load() error {
+	modified, err := lastModified(c.Cert, c.Key)
+	if err != nil {
+		return err
+	}
+
+	if c.current != nil && !modified.After(c.modified) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+	if err != nil {
+		return err
+	}
+
+	c.current, c.modified = &cert, modified
+	return nil
+}
+
+func lastModified(paths ...string) (time.Time, error) {
+	var last time.Time
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return last, err
+		}
+		if info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+
+	return last, nil
+}
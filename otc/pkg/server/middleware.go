@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timeout limits how long handlers take to respond. Event streams are left
+// alone since they're meant to stay open.
+func Timeout(d time.Duration, next http.Handler) http.Handler {
+	if d <= 0 {
+		return next
+	}
+
+	limited := http.TimeoutHandler(next, d, "timeout")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/events") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limited.ServeHTTP(w, r)
+	})
+}
+
+// Limit rejects request bodies larger than max bytes.
+func Limit(max int64, next http.Handler) http.Handler {
+	if max <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > max {
+			http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CORS allows cross-origin requests from the listed origins ("*" for any).
+// Without origins no CORS headers are sent, so browsers only allow same
+// origin requests.
+func CORS(origins []string, next http.Handler) http.Handler {
+	if len(origins) == 0 {
+		return next
+	}
+
+	allowed := make(map[string]bool)
+	for _, origin := range origins {
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+
+		if origin != "" && (allowed["*"] || allowed[origin]) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			// Authorization carries the desk token to the admin api
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Last-Event-ID")
+			w.Header().Set("Access-Control-Max-Age", "600")
+		}
+
+		// preflight
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Headers sets security headers on every response.
+func Headers(secure bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		h.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+		if secure {
+			h.Set("Strict-Transport-Security", "max-age=31536000")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Access is a single access log line.
+type Access struct {
+	Time      string  `json:"time"`
+	Listener  string  `json:"listener"`
+	Remote    string  `json:"remote"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	Bytes     int64   `json:"bytes"`
+	Duration  float64 `json:"duration_ms"`
+	UserAgent string  `json:"user_agent,omitempty"`
+	Forwarded string  `json:"forwarded_for,omitempty"`
+}
+
+// Log writes an access log line as JSON for every request, nothing if w is
+// nil.
+func Log(name string, w io.Writer, next http.Handler) http.Handler {
+	if w == nil {
+		return next
+	}
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &Recorder{ResponseWriter: rw, Status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		remote, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			remote = r.RemoteAddr
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		enc.Encode(&Access{
+			Time:      start.UTC().Format(time.RFC3339),
+			Listener:  name,
+			Remote:    remote,
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Status:    rec.Status,
+			Bytes:     rec.Bytes,
+			Duration:  float64(time.Since(start)) / float64(time.Millisecond),
+			UserAgent: r.UserAgent(),
+			Forwarded: r.Header.Get("X-Forwarded-For"),
+		})
+	})
+}
+
+// Recorder keeps the status and size of a response for access logs.
+type Recorder struct {
+	http.ResponseWriter
+
+	Status int
+	Bytes  int64
+}
+
+func (r *Recorder) WriteHeader(status int) {
+	r.Status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.Bytes += int64(n)
+	return n, err
+}
+
+// Flush passes through so event streams work behind the logger.
+func (r *Recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
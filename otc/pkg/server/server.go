@@ -0,0 +1,79 @@
+package server
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// New returns a server for one of the apis with timeouts, TLS, CORS, body
+// limits, security headers and access logs applied from the config.
+func New(name string, conf *otc.Listener, handler http.Handler) (*http.Server, error) {
+	var logs io.Writer
+	switch conf.AccessLog {
+	case "":
+	case "-":
+		logs = os.Stdout
+	default:
+		file, err := os.OpenFile(conf.AccessLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		logs = file
+	}
+
+	secure := conf.TLS.Cert != "" && conf.TLS.Key != ""
+
+	// innermost first, so Log ends up outermost and logs everything,
+	// including requests rejected by the others
+	handler = Timeout(seconds(conf.WriteTimeout), handler)
+	handler = Limit(conf.MaxBody, handler)
+	handler = CORS(conf.CORS, handler)
+	handler = Headers(secure, handler)
+	handler = Log(name, logs, handler)
+
+	server := &http.Server{
+		Addr:              conf.Listen,
+		Handler:           handler,
+		ReadTimeout:       seconds(conf.ReadTimeout),
+		ReadHeaderTimeout: seconds(conf.ReadTimeout),
+		IdleTimeout:       seconds(conf.IdleTimeout),
+	}
+
+	if secure {
+		certs, err := NewCertificates(conf.TLS.Cert, conf.TLS.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.Get,
+		}
+	}
+
+	return server, nil
+}
+
+// Serve runs the server until it's shut down, sending the error it stopped
+// with (if any) to errs.
+func Serve(server *http.Server, errs chan<- error) {
+	var err error
+	if server.TLSConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if err != nil && err != http.ErrServerClosed {
+		errs <- err
+	}
+}
+
+func seconds(s int64) time.Duration {
+	return time.Duration(s) * time.Second
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func Echo() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write(body)
+	})
+}
+
+func TestCORS(t *testing.T) {
+	handler := CORS([]string{"https://otc.skycoin.net/"}, Echo())
+
+	tests := []struct {
+		Origin  string
+		Allowed bool
+	}{
+		{"https://otc.skycoin.net", true},
+		{"https://evil.example", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/api/bind", nil)
+		req.Header.Set("Origin", test.Origin)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		allowed := res.Header().Get("Access-Control-Allow-Origin") == test.Origin
+		if test.Origin != "" && allowed != test.Allowed {
+			t.Fatalf("%s: expected allowed %v", test.Origin, test.Allowed)
+		}
+	}
+
+	// preflight doesn't reach the handler
+	req := httptest.NewRequest("OPTIONS", "/api/bind", nil)
+	req.Header.Set("Origin", "https://otc.skycoin.net")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", res.Code)
+	}
+
+	// admin requests carry their token
+	if headers := res.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(headers, "Authorization") {
+		t.Fatalf("expected Authorization allowed, got %q", headers)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	handler := Limit(8, Echo())
+
+	for body, status := range map[string]int{
+		"small":             http.StatusOK,
+		"much too large...": http.StatusRequestEntityTooLarge,
+	} {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+
+		if res.Code != status {
+			t.Fatalf("%q: expected %d, got %d", body, status, res.Code)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 50)
+		w.Write([]byte("done"))
+	})
+	handler := Timeout(time.Millisecond*10, slow)
+
+	for path, status := range map[string]int{
+		"/api/status": http.StatusServiceUnavailable,
+		"/api/events": http.StatusOK,
+	} {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest("GET", path, nil))
+
+		if res.Code != status {
+			t.Fatalf("%s: expected %d, got %d", path, status, res.Code)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	handler := Log("api.public", &buf, Headers(false, Echo()))
+
+	req := httptest.NewRequest("POST", "/api/bind", strings.NewReader("hello"))
+	req.RemoteAddr = "10.0.0.1:4000"
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatal("expected security headers")
+	}
+
+	var access Access
+	if err := json.Unmarshal(buf.Bytes(), &access); err != nil {
+		t.Fatal(err)
+	}
+
+	if access.Listener != "api.public" || access.Remote != "10.0.0.1" ||
+		access.Path != "/api/bind" || access.Status != 200 || access.Bytes != 5 {
+		t.Fatalf("unexpected access log %+v", access)
+	}
+}
+
+// WriteCertificate writes a self signed certificate for name to dir.
+func WriteCertificate(t *testing.T, dir, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+
+	return cert, keyPath
+}
+
+func TestCertificatesReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otc-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cert, key := WriteCertificate(t, dir, "first")
+	certs, err := NewCertificates(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := func() string {
+		c, _ := certs.Get(&tls.ClientHelloInfo{})
+		parsed, _ := x509.ParseCertificate(c.Certificate[0])
+		return parsed.Subject.CommonName
+	}
+
+	if name() != "first" {
+		t.Fatalf(`expected "first", got "%s"`, name())
+	}
+
+	// renewed in place
+	WriteCertificate(t, dir, "second")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(cert, later, later)
+
+	if name() != "second" {
+		t.Fatalf(`expected "second", got "%s"`, name())
+	}
+}
+
+func TestNew(t *testing.T) {
+	conf := &otc.Listener{Listen: ":0", ReadTimeout: 5, IdleTimeout: 60}
+
+	server, err := New("api.admin", conf, Echo())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if server.ReadTimeout != time.Second*5 || server.IdleTimeout != time.Minute || server.TLSConfig != nil {
+		t.Fatalf("unexpected server %+v", server)
+	}
+
+	conf.TLS.Cert, conf.TLS.Key = "missing.pem", "missing.pem"
+	if _, err = New("api.admin", conf, Echo()); err == nil {
+		t.Fatal("expected missing certificate error")
+	}
+}
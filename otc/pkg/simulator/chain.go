@@ -0,0 +1,151 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+var (
+	ErrNodeDown     = errors.New("node down")
+	ErrInsufficient = errors.New("insufficient holding")
+	ErrTxMissing    = errors.New("transaction missing")
+)
+
+type Sent struct {
+	Address string
+	Amount  uint64
+	At      time.Time
+}
+
+// Chain is an in-memory currencies.Connection. Sent transactions confirm
+// once Delay has passed on the simulator clock.
+type Chain struct {
+	sync.Mutex
+
+	Currency  otc.Currency
+	Clock     *Clock
+	Delay     time.Duration
+	Down      bool
+	Held      uint64
+	Addresses []string
+	Balances  map[string]uint64
+	Sent      map[string]*Sent
+}
+
+func NewChain(cur otc.Currency, clock *Clock, held uint64, delay time.Duration) *Chain {
+	return &Chain{
+		Currency:  cur,
+		Clock:     clock,
+		Delay:     delay,
+		Held:      held,
+		Addresses: make([]string, 0),
+		Balances:  make(map[string]uint64),
+		Sent:      make(map[string]*Sent),
+	}
+}
+
+func (c *Chain) SetDown(down bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.Down = down
+}
+
+func (c *Chain) Balance(addr string) (uint64, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Down {
+		return 0, ErrNodeDown
+	}
+
+	return c.Balances[addr], nil
+}
+
+func (c *Chain) Confirmed(txid string) (bool, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Down {
+		return false, ErrNodeDown
+	}
+
+	if c.Sent[txid] == nil {
+		return false, ErrTxMissing
+	}
+
+	return !c.Clock.Now().Before(c.Sent[txid].At.Add(c.Delay)), nil
+}
+
+func (c *Chain) Send(addr string, amount uint64) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Down {
+		return "", ErrNodeDown
+	}
+
+	if amount > c.Held {
+		return "", ErrInsufficient
+	}
+
+	txid := fmt.Sprintf("%s-tx-%d", strings.ToLower(string(c.Currency)), len(c.Sent))
+	c.Sent[txid] = &Sent{addr, amount, c.Clock.Now()}
+	c.Held -= amount
+	c.Balances[addr] += amount
+
+	return txid, nil
+}
+
+func (c *Chain) Address() (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Down {
+		return "", ErrNodeDown
+	}
+
+	addr := fmt.Sprintf("%s-addr-%d", strings.ToLower(string(c.Currency)), len(c.Addresses))
+	c.Addresses = append(c.Addresses, addr)
+
+	return addr, nil
+}
+
+func (c *Chain) Used() ([]string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	used := make([]string, len(c.Addresses))
+	copy(used, c.Addresses)
+
+	return used, nil
+}
+
+func (c *Chain) Connected() (bool, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Down {
+		return false, ErrNodeDown
+	}
+
+	return true, nil
+}
+
+func (c *Chain) Holding() (uint64, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Down {
+		return 0, ErrNodeDown
+	}
+
+	return c.Held, nil
+}
+
+func (c *Chain) Stop() error { return nil }
@@ -0,0 +1,43 @@
+package simulator
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is advanced manually by the simulator, so scenarios don't depend on
+// wall time.
+type Clock struct {
+	sync.RWMutex
+
+	Started time.Time
+	Current time.Time
+}
+
+func NewClock(start time.Time) *Clock {
+	return &Clock{
+		Started: start,
+		Current: start,
+	}
+}
+
+func (c *Clock) Now() time.Time {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.Current
+}
+
+func (c *Clock) Elapsed() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.Current.Sub(c.Started)
+}
+
+func (c *Clock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.Current = c.Current.Add(d)
+}
@@ -0,0 +1,36 @@
+package simulator
+
+import (
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// Feed scripts the exchange price source. It behaves like the polling loop
+// in currencies.Add: a successful update switches to the exchange source,
+// a failure falls back to the internal price.
+type Feed struct {
+	Pricer *currencies.Pricer
+}
+
+func NewFeed(history *currencies.History, internal uint64) *Feed {
+	f := &Feed{
+		Pricer: &currencies.Pricer{
+			Using:    currencies.INTERNAL,
+			Sources:  make(map[currencies.Source]*currencies.Price),
+			Currency: otc.BTC,
+			History:  history,
+		},
+	}
+	f.Pricer.SetPrice(currencies.INTERNAL, internal)
+
+	return f
+}
+
+func (f *Feed) Set(price uint64) {
+	f.Pricer.SetPrice(currencies.EXCHANGE, price)
+	f.Pricer.SetSource(currencies.EXCHANGE)
+}
+
+func (f *Feed) Fail() {
+	f.Pricer.SetSource(currencies.INTERNAL)
+}
@@ -0,0 +1,332 @@
+package simulator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// Event changes the simulated world at a point in time.
+type Event func(*Simulator) error
+
+// Check asserts on the state of the pipeline once a scenario has finished.
+type Check func(*Simulator) error
+
+type Step struct {
+	At    time.Duration
+	Event Event
+}
+
+type Scenario struct {
+	Name   string
+	Until  time.Duration
+	Steps  []Step
+	Checks []Check
+}
+
+func At(d time.Duration, e Event) Step {
+	return Step{d, e}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+func Bind(name string) Event {
+	return func(s *Simulator) error {
+		_, err := s.Bind(name, otc.BTC)
+		return err
+	}
+}
+
+func Deposit(name string, amount uint64) Event {
+	return func(s *Simulator) error {
+		if s.Users[name] == nil {
+			return fmt.Errorf("user %s not bound", name)
+		}
+
+		hash := s.Watcher.Deposit(s.Users[name].Drop.Address, amount)
+		s.Sent[name] = append(s.Sent[name], hash)
+		return nil
+	}
+}
+
+// Reorg orphans the named user's most recent deposit.
+func Reorg(name string) Event {
+	return func(s *Simulator) error {
+		sent := s.Sent[name]
+		if len(sent) == 0 {
+			return fmt.Errorf("user %s has no deposits", name)
+		}
+
+		s.Watcher.Reorg(sent[len(sent)-1])
+		s.Sent[name] = sent[:len(sent)-1]
+		return nil
+	}
+}
+
+func NodeDown(cur otc.Currency) Event {
+	return func(s *Simulator) error { return s.node(cur, true) }
+}
+
+func NodeUp(cur otc.Currency) Event {
+	return func(s *Simulator) error { return s.node(cur, false) }
+}
+
+func (s *Simulator) node(cur otc.Currency, down bool) error {
+	switch cur {
+	case otc.SKY:
+		s.SKY.SetDown(down)
+	case otc.BTC:
+		s.BTC.SetDown(down)
+	default:
+		return fmt.Errorf("no %s node", cur)
+	}
+	return nil
+}
+
+func WatcherDown() Event {
+	return func(s *Simulator) error { s.Watcher.SetDown(true); return nil }
+}
+
+func WatcherUp() Event {
+	return func(s *Simulator) error { s.Watcher.SetDown(false); return nil }
+}
+
+func Price(price uint64) Event {
+	return func(s *Simulator) error { s.Feed.Set(price); return nil }
+}
+
+func FeedDown() Event {
+	return func(s *Simulator) error { s.Feed.Fail(); return nil }
+}
+
+func Pause() Event {
+	return func(s *Simulator) error { s.Model.Controller.Pause(); return nil }
+}
+
+func Unpause() Event {
+	return func(s *Simulator) error { s.Model.Controller.Unpause(); return nil }
+}
+
+func PauseStage(cur otc.Currency, stage model.Stage) Event {
+	return func(s *Simulator) error {
+		return s.Model.Controller.PauseStage(model.Pause{Currency: cur, Stage: stage})
+	}
+}
+
+func UnpauseStage(cur otc.Currency, stage model.Stage) Event {
+	return func(s *Simulator) error {
+		return s.Model.Controller.UnpauseStage(model.Pause{Currency: cur, Stage: stage})
+	}
+}
+
+// Maintenance schedules a window starting now and lasting d.
+func Maintenance(cur otc.Currency, stage model.Stage, d time.Duration) Event {
+	return func(s *Simulator) error {
+		now := s.Clock.Now()
+		_, err := s.Model.Controller.Schedule(&model.Window{
+			Pause: model.Pause{Currency: cur, Stage: stage},
+			Start: now.Unix(),
+			End:   now.Add(d).Unix(),
+		})
+		return err
+	}
+}
+
+func Minimum(cur otc.Currency, amount uint64) Event {
+	return func(s *Simulator) error { s.Deposits.Minimum[cur] = amount; return nil }
+}
+
+func Window(d time.Duration) Event {
+	return func(s *Simulator) error { s.Deposits.Window = d; return nil }
+}
+
+// Expiry removes users that haven't deposited within d of binding.
+func Expiry(d time.Duration) Event {
+	return func(s *Simulator) error {
+		s.Model.Expirer = &model.Expirer{Model: s.Model, TTL: d}
+		return nil
+	}
+}
+
+// Requote fixes the current price for an order, as an operator would to
+// retry a failed payout.
+func Requote(name string, i int) Event {
+	return func(s *Simulator) error {
+		order, err := s.order(name, i)
+		if err != nil {
+			return err
+		}
+
+		value, obs, err := s.Currencies.Quote(order.User.Drop.Currency, order.Amount)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.Model.Requote(order.Id, "simulator", "", value, obs)
+		return err
+	}
+}
+
+// Transition forces an order into status, as an operator would.
+func Transition(name string, i int, status otc.Status) Event {
+	return func(s *Simulator) error {
+		order, err := s.order(name, i)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.Model.Transition(order.Id, "simulator", "", status)
+		return err
+	}
+}
+
+func Cancel(name string, i int) Event {
+	return func(s *Simulator) error {
+		order, err := s.order(name, i)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.Model.Cancel(order.Id, "simulator", "")
+		return err
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// Bound checks whether the named user is still bound.
+func Bound(name string, bound bool) Check {
+	return func(s *Simulator) error {
+		if s.Users[name] == nil {
+			return fmt.Errorf("user %s not bound", name)
+		}
+
+		_, err := s.Model.Lookup.GetUser(s.Users[name].Id)
+		if (err == nil) != bound {
+			return fmt.Errorf("%s: expected bound %v", name, bound)
+		}
+		return nil
+	}
+}
+
+// Amount checks the total deposit amount of an order.
+func Amount(name string, i int, amount uint64) Check {
+	return func(s *Simulator) error {
+		order, err := s.order(name, i)
+		if err != nil {
+			return err
+		}
+
+		if order.Amount != amount {
+			return fmt.Errorf("%s: order %d: expected %d deposited, got %d",
+				name, i, amount, order.Amount)
+		}
+		return nil
+	}
+}
+
+// OrderCount checks how many orders were generated for the named user.
+func OrderCount(name string, n int) Check {
+	return func(s *Simulator) error {
+		orders, err := s.Orders(name)
+		if err != nil {
+			return err
+		}
+
+		if len(orders) != n {
+			return fmt.Errorf("%s: expected %d orders, got %d", name, n, len(orders))
+		}
+		return nil
+	}
+}
+
+func OrderStatus(name string, i int, status otc.Status) Check {
+	return func(s *Simulator) error {
+		order, err := s.order(name, i)
+		if err != nil {
+			return err
+		}
+
+		if order.Status != status {
+			return fmt.Errorf("%s: order %d: expected %s, got %s",
+				name, i, status, order.Status)
+		}
+		return nil
+	}
+}
+
+// Paid checks the amount of SKY (in droplets) sent for an order.
+func Paid(name string, i int, amount uint64) Check {
+	return func(s *Simulator) error {
+		order, err := s.order(name, i)
+		if err != nil {
+			return err
+		}
+
+		if order.Purchase == nil {
+			return fmt.Errorf("%s: order %d: nothing sent", name, i)
+		}
+
+		if order.Purchase.Amount != amount {
+			return fmt.Errorf("%s: order %d: expected %d sent, got %d",
+				name, i, amount, order.Purchase.Amount)
+		}
+		return nil
+	}
+}
+
+// Rate checks the price an order was filled at, and that it links to the
+// matching price history observation.
+func Rate(name string, i int, price uint64) Check {
+	return func(s *Simulator) error {
+		order, err := s.order(name, i)
+		if err != nil {
+			return err
+		}
+
+		if order.Purchase == nil || order.Purchase.Price == nil {
+			return fmt.Errorf("%s: order %d: no price", name, i)
+		}
+
+		obs, err := s.Currencies.History.Get(order.Purchase.Price.Observation)
+		if err != nil {
+			return fmt.Errorf("%s: order %d: %v", name, i, err)
+		}
+
+		if obs.Value != price || order.Purchase.Price.Executed != price {
+			return fmt.Errorf("%s: order %d: expected rate %d, got %d (observed %d)",
+				name, i, price, order.Purchase.Price.Executed, obs.Value)
+		}
+		return nil
+	}
+}
+
+// Failed checks that the last event of an order recorded an error.
+func Failed(name string, i int) Check {
+	return func(s *Simulator) error {
+		order, err := s.order(name, i)
+		if err != nil {
+			return err
+		}
+
+		if len(order.Events) == 0 || order.Events[len(order.Events)-1].Err == "" {
+			return fmt.Errorf("%s: order %d: expected an error event", name, i)
+		}
+		return nil
+	}
+}
+
+func (s *Simulator) order(name string, i int) (*otc.Order, error) {
+	orders, err := s.Orders(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if i >= len(orders) {
+		return nil, fmt.Errorf("%s: order %d missing", name, i)
+	}
+
+	return orders[i], nil
+}
@@ -0,0 +1,236 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/actor"
+	"github.com/skycoin/services/otc/pkg/api/public"
+	"github.com/skycoin/services/otc/pkg/currencies"
+	"github.com/skycoin/services/otc/pkg/events"
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+	"github.com/skycoin/services/otc/pkg/scanner"
+	"github.com/skycoin/services/otc/pkg/watcher"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const (
+	// same interval model.Start uses between worker ticks
+	INTERVAL time.Duration = time.Second * 5
+	// work created by the scanner is buffered until the next drain
+	BUFFER int = 1024
+)
+
+// Simulator runs the real model pipeline (scanner, router, sender and
+// monitor) against in-memory chains, a fake otc-watcher and a scripted
+// price feed. Workers are ticked in lockstep with a manual clock instead of
+// running in goroutines.
+type Simulator struct {
+	Clock      *Clock
+	SKY        *Chain
+	BTC        *Chain
+	Watcher    *Watcher
+	Feed       *Feed
+	Currencies *currencies.Currencies
+	Model      *model.Model
+	Public     *http.ServeMux
+	Deposits   *scanner.Config
+	Users      map[string]*otc.User
+	Sent       map[string][]string
+	Logs       *log.Logger
+}
+
+// New creates a simulator storing users and orders under dir.
+func New(dir string, logs *log.Logger) (*Simulator, error) {
+	path := filepath.Clean(dir) + "/"
+	if err := model.MakeDirs(path); err != nil {
+		return nil, err
+	}
+
+	clock := NewClock(time.Now().UTC())
+	history, err := currencies.NewHistory("", 0)
+	if err != nil {
+		return nil, err
+	}
+	feed := NewFeed(history, 200000)
+
+	s := &Simulator{
+		Clock:   clock,
+		SKY:     NewChain(otc.SKY, clock, 1000000*1e6, time.Second*30),
+		BTC:     NewChain(otc.BTC, clock, 0, time.Minute*10),
+		Watcher: NewWatcher(500000),
+		Feed:    feed,
+		Users:   make(map[string]*otc.User),
+		Sent:    make(map[string][]string),
+		Logs:    logs,
+		Deposits: &scanner.Config{
+			Minimum: make(map[otc.Currency]uint64),
+			Now:     clock.Now,
+		},
+	}
+
+	s.Currencies = &currencies.Currencies{
+		Prices: map[otc.Currency]*currencies.Pricer{
+			otc.BTC: feed.Pricer,
+		},
+		Connections: map[otc.Currency]currencies.Connection{
+			otc.SKY: s.SKY,
+			otc.BTC: s.BTC,
+		},
+		History: history,
+	}
+
+	broker := events.New()
+	broker.Now = clock.Now
+	history.Events = broker
+
+	controller := model.NewController(nil)
+	controller.Now = clock.Now
+	controller.Events = broker
+
+	workers, _ := model.NewWorkers(&model.Config{
+		Currencies: s.Currencies,
+		Watcher: &watcher.Watcher{
+			Client: &http.Client{Transport: s.Watcher},
+			Node:   "http://otc-watcher",
+		},
+		Deposits: s.Deposits,
+	}, controller)
+
+	work := make(chan *otc.Work, BUFFER)
+	workers.Scanner.Work = work
+	workers.Scanner.Logs = logs
+	workers.Sender.Logs = logs
+	workers.Monitor.Logs = logs
+
+	lookup := model.NewLookup()
+	s.Model = &model.Model{
+		Controller: controller,
+		Lookup:     lookup,
+		Workers:    workers,
+		Router:     actor.New(logs, model.Task(workers, lookup, broker, path)),
+		Work:       work,
+		Logs:       logs,
+		Events:     broker,
+		Path:       path,
+	}
+	s.Model.Controller.Unpause()
+	s.Public = public.New(s.Currencies, s.Model, nil)
+
+	return s, nil
+}
+
+// Tick runs each worker once, in pipeline order, and advances the clock.
+func (s *Simulator) Tick() {
+	if !s.Model.Controller.Paused() {
+		s.Model.Workers.Scanner.Tick()
+		s.drain()
+		s.Model.Router.Tick()
+		s.Model.Workers.Sender.Tick()
+		s.Model.Workers.Monitor.Tick()
+
+		if s.Model.Expirer != nil {
+			s.Model.Expirer.Tick()
+		}
+	}
+
+	s.Clock.Advance(INTERVAL)
+}
+
+// drain moves new work from the scanner to the router, which is done by a
+// goroutine in model.Start.
+func (s *Simulator) drain() {
+	for {
+		select {
+		case work := <-s.Model.Work:
+			s.Model.Receive(work)
+		default:
+			return
+		}
+	}
+}
+
+// Run executes the scenario steps at their scheduled times, ticking the
+// pipeline until the scenario ends, and then runs the checks.
+func (s *Simulator) Run(sc *Scenario) error {
+	steps := make([]Step, len(sc.Steps))
+	copy(steps, sc.Steps)
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].At < steps[j].At
+	})
+
+	for s.Clock.Elapsed() <= sc.Until {
+		for len(steps) > 0 && steps[0].At <= s.Clock.Elapsed() {
+			if err := steps[0].Event(s); err != nil {
+				return fmt.Errorf("%s: at %s: %v", sc.Name, steps[0].At, err)
+			}
+			steps = steps[1:]
+		}
+
+		s.Tick()
+	}
+
+	for _, check := range sc.Checks {
+		if err := check(s); err != nil {
+			return fmt.Errorf("%s: %v", sc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Bind creates a user through the public API, like the web frontend does.
+func (s *Simulator) Bind(name string, cur otc.Currency) (*otc.User, error) {
+	var body bytes.Buffer
+
+	json.NewEncoder(&body).Encode(&struct {
+		Address      string `json:"address"`
+		DropCurrency string `json:"drop_currency"`
+	}{Address(name), string(cur)})
+
+	res := httptest.NewRecorder()
+	s.Public.ServeHTTP(res, httptest.NewRequest("POST", "/api/bind", &body))
+
+	if res.Code != http.StatusOK {
+		return nil, fmt.Errorf("bind returned %d: %s", res.Code, res.Body.String())
+	}
+
+	var bound struct {
+		DropAddress  string `json:"drop_address"`
+		DropCurrency string `json:"drop_currency"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&bound); err != nil {
+		return nil, err
+	}
+
+	user, err := s.Model.Lookup.GetStatus(bound.DropCurrency + ":" + bound.DropAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Users[name] = user
+	return user, nil
+}
+
+// Orders returns the orders generated for the named user.
+func (s *Simulator) Orders(name string) ([]*otc.Order, error) {
+	if s.Users[name] == nil {
+		return nil, fmt.Errorf("user %s not bound", name)
+	}
+
+	return s.Users[name].Orders, nil
+}
+
+// Address derives a deterministic skycoin address from a scenario name.
+func Address(name string) string {
+	pub, _ := cipher.GenerateDeterministicKeyPair([]byte(name))
+	return cipher.AddressFromPubKey(pub).String()
+}
@@ -0,0 +1,458 @@
+package simulator
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/skycoin/services/otc/pkg/model"
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+func Simulate(t *testing.T, sc *Scenario) *Simulator {
+	dir, err := ioutil.TempDir("", "otc-simulator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sim, err := New(dir, log.New(ioutil.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sim.Run(sc); err != nil {
+		t.Fatal(err)
+	}
+
+	return sim
+}
+
+func TestDeposit(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "deposit",
+		Until: time.Minute * 2,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(time.Second*10, Deposit("alice", 1e8)),
+		},
+		Checks: []Check{
+			OrderCount("alice", 1),
+			OrderStatus("alice", 0, otc.DONE),
+			Paid("alice", 0, 500*1e6),
+		},
+	})
+}
+
+func TestUnconfirmed(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "unconfirmed",
+		Until: time.Second * 20,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, Deposit("alice", 1e8)),
+		},
+		Checks: []Check{
+			OrderStatus("alice", 0, otc.CONFIRM),
+		},
+	})
+}
+
+func TestUsers(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "users",
+		Until: time.Minute * 2,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, Bind("bob")),
+			At(time.Second*5, Deposit("alice", 1e8)),
+			At(time.Second*5, Deposit("bob", 2e8)),
+			At(time.Second*30, Deposit("bob", 1e8)),
+		},
+		Checks: []Check{
+			OrderCount("alice", 1),
+			OrderCount("bob", 2),
+			OrderStatus("bob", 0, otc.DONE),
+			OrderStatus("bob", 1, otc.DONE),
+			Paid("bob", 0, 1000*1e6),
+		},
+	})
+}
+
+func TestPrice(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "price",
+		Until: time.Minute * 2,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, Price(100000)),
+			At(time.Second*10, Deposit("alice", 1e8)),
+		},
+		Checks: []Check{
+			Paid("alice", 0, 1000*1e6),
+			Rate("alice", 0, 100000),
+		},
+	})
+}
+
+func TestPriceFeedDown(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "price feed down",
+		Until: time.Minute * 2,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, Price(100000)),
+			At(time.Second*5, FeedDown()),
+			At(time.Second*10, Deposit("alice", 1e8)),
+		},
+		Checks: []Check{
+			Paid("alice", 0, 500*1e6),
+			Rate("alice", 0, 200000),
+		},
+	})
+}
+
+func TestReorgBeforeScan(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "reorg before scan",
+		Until: time.Minute,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(time.Second*10, Deposit("alice", 1e8)),
+			At(time.Second*10, Reorg("alice")),
+		},
+		Checks: []Check{
+			OrderCount("alice", 0),
+		},
+	})
+}
+
+func TestReorgAfterScan(t *testing.T) {
+	// orders aren't reverted once the deposit has been seen
+	Simulate(t, &Scenario{
+		Name:  "reorg after scan",
+		Until: time.Minute * 2,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(time.Second*10, Deposit("alice", 1e8)),
+			At(time.Second*20, Reorg("alice")),
+		},
+		Checks: []Check{
+			OrderCount("alice", 1),
+			OrderStatus("alice", 0, otc.DONE),
+		},
+	})
+}
+
+func TestNodeDown(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "node down",
+		Until: time.Minute * 2,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, NodeDown(otc.SKY)),
+			At(time.Second*10, Deposit("alice", 1e8)),
+			At(time.Minute, NodeUp(otc.SKY)),
+		},
+		Checks: []Check{
+			OrderStatus("alice", 0, otc.SEND),
+			Failed("alice", 0),
+		},
+	})
+}
+
+func TestWatcherDown(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "watcher down",
+		Until: time.Minute * 2,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, WatcherDown()),
+			At(time.Second*10, Deposit("alice", 1e8)),
+			At(time.Minute, WatcherUp()),
+		},
+		Checks: []Check{
+			OrderCount("alice", 1),
+			OrderStatus("alice", 0, otc.DONE),
+		},
+	})
+}
+
+func TestPaused(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "paused",
+		Until: time.Minute,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, Pause()),
+			At(time.Second*10, Deposit("alice", 1e8)),
+		},
+		Checks: []Check{
+			OrderCount("alice", 0),
+		},
+	})
+}
+
+func TestSendPaused(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "send paused",
+		Until: time.Minute * 2,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, PauseStage(otc.BTC, model.SEND)),
+			At(time.Second*10, Deposit("alice", 1e8)),
+		},
+		Checks: []Check{
+			OrderCount("alice", 1),
+			OrderStatus("alice", 0, otc.SEND),
+		},
+	})
+}
+
+func TestMaintenance(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "maintenance",
+		Until: time.Minute,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, Maintenance(otc.BTC, "", time.Minute*2)),
+			At(time.Second*10, Deposit("alice", 1e8)),
+		},
+		Checks: []Check{
+			OrderCount("alice", 0),
+		},
+	})
+
+	Simulate(t, &Scenario{
+		Name:  "maintenance over",
+		Until: time.Minute * 3,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, Maintenance(otc.BTC, "", time.Minute)),
+			At(time.Second*10, Deposit("alice", 1e8)),
+		},
+		Checks: []Check{
+			OrderStatus("alice", 0, otc.DONE),
+		},
+	})
+}
+
+func TestBelowMinimum(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "below minimum",
+		Until: time.Minute * 2,
+		Steps: []Step{
+			At(0, Minimum(otc.BTC, 1e6)),
+			At(0, Bind("alice")),
+			At(time.Second*10, Deposit("alice", 4e5)),
+			At(time.Second*20, Deposit("alice", 4e5)),
+		},
+		Checks: []Check{
+			OrderCount("alice", 1),
+			OrderStatus("alice", 0, otc.BELOW),
+			Amount("alice", 0, 8e5),
+		},
+	})
+
+	Simulate(t, &Scenario{
+		Name:  "minimum reached",
+		Until: time.Minute * 2,
+		Steps: []Step{
+			At(0, Minimum(otc.BTC, 1e6)),
+			At(0, Bind("alice")),
+			At(time.Second*10, Deposit("alice", 4e5)),
+			At(time.Second*20, Deposit("alice", 4e5)),
+			At(time.Second*30, Deposit("alice", 4e5)),
+			At(time.Second*60, Deposit("alice", 1e8)),
+		},
+		Checks: []Check{
+			OrderCount("alice", 2),
+			OrderStatus("alice", 0, otc.DONE),
+			Amount("alice", 0, 12e5),
+			Paid("alice", 0, 6*1e6),
+			OrderStatus("alice", 1, otc.DONE),
+			Amount("alice", 1, 1e8),
+		},
+	})
+}
+
+func TestWindow(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "window",
+		Until: time.Minute * 3,
+		Steps: []Step{
+			At(0, Window(time.Minute)),
+			At(0, Bind("alice")),
+			At(time.Second*10, Deposit("alice", 1e8)),
+			At(time.Second*30, Deposit("alice", 1e8)),
+			At(time.Second*50, Deposit("alice", 1e8)),
+		},
+		Checks: []Check{
+			OrderCount("alice", 1),
+			OrderStatus("alice", 0, otc.DONE),
+			Amount("alice", 0, 3e8),
+			Paid("alice", 0, 1500*1e6),
+		},
+	})
+
+	Simulate(t, &Scenario{
+		Name:  "collecting",
+		Until: time.Second * 40,
+		Steps: []Step{
+			At(0, Window(time.Minute)),
+			At(0, Bind("alice")),
+			At(time.Second*10, Deposit("alice", 1e8)),
+		},
+		Checks: []Check{
+			OrderStatus("alice", 0, otc.COLLECT),
+		},
+	})
+}
+
+func TestExpiry(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "expiry",
+		Until: time.Minute * 3,
+		Steps: []Step{
+			At(0, Expiry(time.Minute*2)),
+			At(0, Bind("alice")),
+			At(0, Bind("bob")),
+			At(time.Second*10, Deposit("bob", 1e8)),
+		},
+		Checks: []Check{
+			Bound("alice", false),
+			Bound("bob", true),
+			OrderStatus("bob", 0, otc.DONE),
+		},
+	})
+}
+
+func TestExpiryRebind(t *testing.T) {
+	// handing the drop address out again restarts its expiry
+	Simulate(t, &Scenario{
+		Name:  "expiry rebind",
+		Until: time.Minute * 3,
+		Steps: []Step{
+			At(0, Expiry(time.Minute*2)),
+			At(0, Bind("alice")),
+			At(time.Second*90, Bind("alice")),
+		},
+		Checks: []Check{
+			Bound("alice", true),
+		},
+	})
+}
+
+func TestRequote(t *testing.T) {
+	// a failed payout is retried at the re-quoted price without a restart
+	Simulate(t, &Scenario{
+		Name:  "requote",
+		Until: time.Minute * 3,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, NodeDown(otc.SKY)),
+			At(time.Second*10, Deposit("alice", 1e8)),
+			At(time.Minute, NodeUp(otc.SKY)),
+			At(time.Minute, Price(100000)),
+			At(time.Minute+time.Second*10, Requote("alice", 0)),
+		},
+		Checks: []Check{
+			OrderStatus("alice", 0, otc.DONE),
+			Paid("alice", 0, 1000*1e6),
+			Rate("alice", 0, 100000),
+		},
+	})
+}
+
+func TestRequoteDropped(t *testing.T) {
+	// a requoted price isn't kept once the order leaves waiting_send, so
+	// sending it again uses the price at that time
+	Simulate(t, &Scenario{
+		Name:  "requote dropped",
+		Until: time.Minute * 3,
+		Steps: []Step{
+			At(0, Bind("alice")),
+			At(0, NodeDown(otc.SKY)),
+			At(time.Second*10, Deposit("alice", 1e8)),
+			At(time.Minute, Price(100000)),
+			At(time.Minute+time.Second*10, Requote("alice", 0)),
+			At(time.Minute*2, Transition("alice", 0, otc.BELOW)),
+			At(time.Minute*2, Price(50000)),
+			At(time.Minute*2, NodeUp(otc.SKY)),
+		},
+		Checks: []Check{
+			OrderStatus("alice", 0, otc.DONE),
+			Paid("alice", 0, 2000*1e6),
+			Rate("alice", 0, 50000),
+		},
+	})
+}
+
+func TestCancel(t *testing.T) {
+	Simulate(t, &Scenario{
+		Name:  "cancel",
+		Until: time.Minute * 2,
+		Steps: []Step{
+			At(0, Minimum(otc.BTC, 1e6)),
+			At(0, Bind("alice")),
+			At(time.Second*10, Deposit("alice", 4e5)),
+			At(time.Second*20, Cancel("alice", 0)),
+			At(time.Second*30, Deposit("alice", 4e5)),
+		},
+		Checks: []Check{
+			OrderCount("alice", 2),
+			OrderStatus("alice", 0, otc.CANCELLED),
+			Amount("alice", 0, 4e5),
+			OrderStatus("alice", 1, otc.BELOW),
+		},
+	})
+}
+
+// TestScannedTwice has the scanner update an order twice before the router
+// picks up either update, which must only pay the order once.
+func TestScannedTwice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otc-simulator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := New(dir, log.New(ioutil.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range []Event{
+		Window(time.Second * 10),
+		Bind("alice"),
+		Deposit("alice", 1e8),
+	} {
+		if err = event(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// collecting, then waiting_send once the window passes
+	s.Model.Workers.Scanner.Tick()
+	collecting := s.Users["alice"].Orders[0]
+	s.Clock.Advance(time.Second * 10)
+	s.Model.Workers.Scanner.Tick()
+	s.drain()
+
+	if collecting.Status != otc.COLLECT {
+		t.Fatalf("scanner changed routed order to %s", collecting.Status)
+	}
+
+	for i := 0; i < 12; i++ {
+		s.Tick()
+	}
+
+	if len(s.SKY.Sent) != 1 {
+		t.Fatalf("expected 1 payout, got %d", len(s.SKY.Sent))
+	}
+	if err = OrderStatus("alice", 0, otc.DONE)(s); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,110 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/skycoin/services/otc/pkg/otc"
+)
+
+// Watcher stands in for otc-watcher. It's used as the transport of the
+// real watcher client, so requests never leave the process.
+type Watcher struct {
+	sync.Mutex
+
+	Down     bool
+	Height   uint64
+	Deposits map[string]otc.Outputs
+	count    int
+}
+
+func NewWatcher(height uint64) *Watcher {
+	return &Watcher{
+		Height:   height,
+		Deposits: make(map[string]otc.Outputs),
+	}
+}
+
+func (w *Watcher) SetDown(down bool) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.Down = down
+}
+
+// Deposit records a new output to addr and returns the transaction hash.
+func (w *Watcher) Deposit(addr string, amount uint64) string {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.Deposits[addr] == nil {
+		w.Deposits[addr] = make(otc.Outputs)
+	}
+
+	w.Height++
+	hash := fmt.Sprintf("deposit-%d", w.count)
+	w.count++
+
+	w.Deposits[addr].Update(hash, 0, &otc.OutputVerbose{
+		Amount:        amount,
+		Confirmations: 1,
+		TxHash:        hash,
+		Addresses:     []string{addr},
+		Height:        w.Height,
+	})
+
+	return hash
+}
+
+// Reorg removes the transaction from every watched address, as if the
+// block containing it was orphaned.
+func (w *Watcher) Reorg(hash string) {
+	w.Lock()
+	defer w.Unlock()
+
+	for _, outputs := range w.Deposits {
+		delete(outputs, hash)
+	}
+}
+
+func (w *Watcher) RoundTrip(req *http.Request) (*http.Response, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.Down {
+		return nil, ErrNodeDown
+	}
+
+	var drop *otc.Drop
+	if err := json.NewDecoder(req.Body).Decode(&drop); err != nil {
+		return w.respond(req, http.StatusBadRequest, nil)
+	}
+
+	outputs := w.Deposits[drop.Address]
+	if outputs == nil {
+		outputs = make(otc.Outputs)
+	}
+
+	return w.respond(req, http.StatusOK, outputs)
+}
+
+func (w *Watcher) respond(req *http.Request, code int, v interface{}) (*http.Response, error) {
+	var buf bytes.Buffer
+
+	if v != nil {
+		if err := json.NewEncoder(&buf).Encode(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return &http.Response{
+		StatusCode: code,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(&buf),
+		Request:    req,
+	}, nil
+}
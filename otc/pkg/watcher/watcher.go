@@ -13,6 +13,8 @@ import (
 type Watcher struct {
 	Client *http.Client
 	Node   string
+	// sent as a bearer token if set
+	Key string
 }
 
 func New(conf *otc.Config) (*Watcher, error) {
@@ -22,6 +24,7 @@ func New(conf *otc.Config) (*Watcher, error) {
 			Timeout:   time.Second * 10,
 		},
 		Node: conf.Watcher.Node,
+		Key:  conf.Watcher.Key,
 	}, nil
 }
 
@@ -32,9 +35,16 @@ func (w *Watcher) Outputs(drop *otc.Drop) (otc.Outputs, error) {
 	json.NewEncoder(&buf).Encode(drop)
 
 	// send POST request to watcher
-	resp, err := w.Client.Post(
-		w.Node+"/outputs", "application/json", &buf,
-	)
+	req, err := http.NewRequest(http.MethodPost, w.Node+"/outputs", &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+w.Key)
+	}
+
+	resp, err := w.Client.Do(req)
 	if err != nil {
 		return nil, err
 	}
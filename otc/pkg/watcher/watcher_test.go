@@ -30,11 +30,18 @@ func TestNew(t *testing.T) {
 
 type Mock struct {
 	Type string
+	// key the request must have, if set
+	Key string
 }
 
 func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
 	res := httptest.NewRecorder()
 
+	if m.Key != "" && req.Header.Get("Authorization") != "Bearer "+m.Key {
+		res.WriteHeader(401)
+		return res.Result(), nil
+	}
+
 	if m.Type == "error" {
 		return nil, fmt.Errorf("error!")
 	} else if m.Type == "bad" {
@@ -77,7 +84,7 @@ func TestOutputs(t *testing.T) {
 func TestOutputsError(t *testing.T) {
 	watcher := &Watcher{
 		Client: &http.Client{
-			Transport: &Mock{"error"},
+			Transport: &Mock{Type: "error"},
 		},
 	}
 
@@ -89,7 +96,7 @@ func TestOutputsError(t *testing.T) {
 func TestOutputsBad(t *testing.T) {
 	watcher := &Watcher{
 		Client: &http.Client{
-			Transport: &Mock{"bad"},
+			Transport: &Mock{Type: "bad"},
 		},
 	}
 
@@ -97,3 +104,20 @@ func TestOutputsBad(t *testing.T) {
 		t.Fatal("should be an error")
 	}
 }
+
+func TestOutputsKey(t *testing.T) {
+	watcher := &Watcher{
+		Client: &http.Client{
+			Transport: &Mock{Key: "key"},
+		},
+	}
+
+	if _, err := watcher.Outputs(&otc.Drop{"address", otc.BTC}); err == nil {
+		t.Fatal("should be unauthorized without the key")
+	}
+
+	watcher.Key = "key"
+	if _, err := watcher.Outputs(&otc.Drop{"address", otc.BTC}); err != nil {
+		t.Fatal(err)
+	}
+}